@@ -3,14 +3,16 @@ package main
 import (
 	"context"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
+	"os"
 	"os/signal"
 	"sync/atomic"
 	"syscall"
 	"time"
 
 	"github.com/bwmarrin/discordgo"
+	"github.com/sigumaa/yururi/internal/attachment"
 	"github.com/sigumaa/yururi/internal/codex"
 	"github.com/sigumaa/yururi/internal/config"
 	"github.com/sigumaa/yururi/internal/discordx"
@@ -27,6 +29,11 @@ func runApplication(configPath string) error {
 	if err != nil {
 		return fmt.Errorf("load config: %w", err)
 	}
+	logCloser, err := configureLogging(os.Stdout, cfg.Log)
+	if err != nil {
+		return fmt.Errorf("configure logging: %w", err)
+	}
+	defer logCloser.Close()
 
 	if err := prompt.EnsureWorkspaceInstructionFiles(cfg.Codex.WorkspaceDir); err != nil {
 		return fmt.Errorf("prepare workspace instruction files: %w", err)
@@ -36,20 +43,29 @@ func runApplication(configPath string) error {
 	if err != nil {
 		return fmt.Errorf("create discord session: %w", err)
 	}
-	discord.Identify.Intents = discordgo.IntentsGuildMessages | discordgo.IntentsMessageContent
+	discord.Identify.Intents = discordIntents(cfg.Discord)
 
 	resolvedObserve, err := resolveObserveTextChannels(discord, cfg.Discord)
 	if err != nil {
-		log.Printf("event=observe_categories_resolve_failed guild=%s categories=%d err=%v", cfg.Discord.GuildID, len(cfg.Discord.ObserveCategoryIDs), err)
+		slog.Error("observe_categories_resolve_failed", "guild", cfg.Discord.GuildID, "categories", len(cfg.Discord.ObserveCategoryIDs), "err", err)
 	} else {
 		added := len(resolvedObserve) - len(cfg.Discord.ObserveChannelIDs)
 		cfg.Discord.ObserveChannelIDs = resolvedObserve
 		if len(cfg.Discord.ObserveCategoryIDs) > 0 {
-			log.Printf("event=observe_categories_resolved guild=%s categories=%d observe_channels=%d added=%d", cfg.Discord.GuildID, len(cfg.Discord.ObserveCategoryIDs), len(cfg.Discord.ObserveChannelIDs), added)
+			slog.Info("observe_categories_resolved", "guild", cfg.Discord.GuildID, "categories", len(cfg.Discord.ObserveCategoryIDs), "observe_channels", len(cfg.Discord.ObserveChannelIDs), "added", added)
 		}
 	}
 
 	gateway := discordx.NewGateway(discord, cfg.Discord)
+	if restored, err := gateway.RestoreDedup(); err != nil {
+		slog.Error("dedup_store_restore_failed", "path", cfg.Discord.Dedup.StorePath, "err", err)
+	} else {
+		slog.Info("dedup_store_restored", "path", cfg.Discord.Dedup.StorePath, "entries", restored)
+	}
+	var attachments *attachment.Store
+	if cfg.Discord.Attachments.Enabled && cfg.Discord.Attachments.CacheDir != "" {
+		attachments = attachment.NewStore(cfg.Discord.Attachments.CacheDir, &http.Client{Timeout: 30 * time.Second}, attachment.WithMaxCacheBytes(cfg.Discord.Attachments.CacheMaxBytes))
+	}
 	var xSearchClient *xai.Client
 	if cfg.XAI.Enabled {
 		xSearchClient = xai.NewClient(xai.Config{
@@ -66,20 +82,76 @@ func runApplication(configPath string) error {
 	if err != nil {
 		return fmt.Errorf("create mcp server: %w", err)
 	}
-	aiClient := codex.NewClient(cfg.Codex, cfg.MCP.URL)
-	coordinator := orchestrator.New(aiClient)
+	mcpSrv.EnableUploads(cfg.Codex.WorkspaceDir, cfg.MCP.UploadMaxBytes)
+	approver := newOwnerApprover(gateway, cfg)
+	codexOpts := []codex.ClientOption{codex.WithPersistentThreads(cfg.Session.Persist)}
+	if approver != nil {
+		codexOpts = append(codexOpts, codex.WithApprover(approver))
+	}
+	aiClient := codex.NewClient(cfg.Codex, cfg.MCP.URL, codexOpts...)
+	rotation, err := buildRotationPolicy(cfg)
+	if err != nil {
+		return fmt.Errorf("build session rotation policy: %w", err)
+	}
+	usageLedger, usageBudget, err := buildUsageBudget(cfg)
+	if err != nil {
+		return fmt.Errorf("build usage budget: %w", err)
+	}
+	if err := usageLedger.Load(); err != nil {
+		slog.Error("usage_ledger_load_failed", "path", cfg.Usage.StorePath, "err", err)
+	}
+	coordinatorOpts := []orchestrator.Option{
+		orchestrator.WithRotationPolicy(rotation),
+		orchestrator.WithUsageBudget(usageLedger, usageBudget),
+	}
+	overrideChannels, err := listOverrideChannels(discord, cfg.Discord)
+	if err != nil {
+		slog.Error("category_overrides_resolve_failed", "guild", cfg.Discord.GuildID, "categories", len(cfg.Discord.CategoryOverrides), "err", err)
+	}
+	if resolver := buildChannelOverrideResolver(cfg.Discord, overrideChannels, gateway.ParentChannelID); resolver != nil {
+		coordinatorOpts = append(coordinatorOpts, orchestrator.WithChannelOverrides(resolver))
+	}
+	if cfg.Session.Persist && cfg.Session.StorePath != "" {
+		coordinatorOpts = append(coordinatorOpts, orchestrator.WithSessionStore(
+			orchestrator.NewFileSessionStore(cfg.Session.StorePath),
+			time.Duration(cfg.Session.TTLSec)*time.Second,
+		))
+	}
+	coordinator := orchestrator.New(aiClient, coordinatorOpts...)
+	if restored, err := coordinator.RestoreSessions(); err != nil {
+		slog.Error("session_store_restore_failed", "path", cfg.Session.StorePath, "err", err)
+	} else if cfg.Session.Persist {
+		slog.Info("session_store_restored", "path", cfg.Session.StorePath, "sessions", restored, "ttl_sec", cfg.Session.TTLSec)
+	}
 
 	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer stop()
 	var runSeq atomic.Uint64
+	var paused atomic.Bool
 
-	dispatcher := dispatch.New(ctx, 128, 1200*time.Millisecond, func(m *discordgo.MessageCreate, meta dispatch.CallbackMetadata) {
+	var dispatcher *dispatch.Dispatcher
+	dispatcher = dispatch.New(ctx, 128, 1200*time.Millisecond, func(m *discordgo.MessageCreate, meta dispatch.CallbackMetadata) {
 		if meta.MergedCount > 1 {
-			log.Printf("event=channel_burst_coalesced guild=%s channel=%s merged=%d latest_message=%s queue_wait_ms=%d", m.GuildID, m.ChannelID, meta.MergedCount, m.ID, durationMS(meta.QueueWait))
+			slog.Info("channel_burst_coalesced", "guild", m.GuildID, "channel", m.ChannelID, "merged", meta.MergedCount, "latest_message", m.ID, "queue_wait_ms", durationMS(meta.QueueWait))
 		}
 		runID := nextRunID(&runSeq, "msg")
-		handleMessage(ctx, cfg, coordinator, gateway, discord, m, meta, runID)
-	})
+		if paused.Load() {
+			slog.Info("message_filtered", "run_id", runID, "message", m.ID, "guild", m.GuildID, "channel", m.ChannelID, "reason", "paused")
+			return
+		}
+		handleMessage(ctx, cfg, coordinator, gateway, attachments, discord, m, meta, runID)
+	}, dispatch.WithAddressDetection(func() string {
+		return botUserID(discord)
+	}, cfg.Persona.OwnerUserID), dispatch.WithEventHandler(func(event dispatch.MessageEvent) {
+		runID := nextRunID(&runSeq, "evt")
+		if paused.Load() {
+			slog.Info("message_event_skipped", "run_id", runID, "kind", event.Kind, "reason", "paused")
+			return
+		}
+		handleMessageEvent(ctx, cfg, coordinator, gateway, discord, dispatcher, event, runID)
+	}))
+
+	go aiClient.RunHealthChecks(ctx, time.Duration(cfg.Codex.HealthCheckIntervalSec)*time.Second)
 
 	errCh := make(chan error, 1)
 	go func() {
@@ -89,50 +161,105 @@ func runApplication(configPath string) error {
 		}
 	}()
 
-	discord.AddHandler(func(_ *discordgo.Session, m *discordgo.MessageCreate) {
+	discord.AddHandler(func(s *discordgo.Session, m *discordgo.MessageCreate) {
+		trackMessageChannel(s.State, m.Message)
 		if dropped := dispatcher.Enqueue(m); dropped {
-			log.Printf("dispatcher queue drop occurred: guild=%s channel=%s latest_message=%s", m.GuildID, m.ChannelID, m.ID)
+			slog.Warn("dispatcher_queue_dropped", "guild", m.GuildID, "channel", m.ChannelID, "latest_message", m.ID)
 		}
 	})
 
-	if err := discord.Open(); err != nil {
-		return fmt.Errorf("open discord session: %w", err)
+	if cfg.Discord.MessageEvents.Enabled {
+		discord.State.MaxMessageCount = messageEventCacheLimit
+		discord.AddHandler(func(_ *discordgo.Session, m *discordgo.MessageUpdate) {
+			if dropped := dispatcher.EnqueueEvent(dispatch.MessageEvent{Kind: dispatch.EventEdit, Message: m.Message, Before: m.BeforeUpdate}); dropped {
+				slog.Warn("dispatcher_event_queue_dropped", "guild", m.GuildID, "channel", m.ChannelID, "message", m.ID, "kind", "edit")
+			}
+		})
+		discord.AddHandler(func(_ *discordgo.Session, m *discordgo.MessageDelete) {
+			if dropped := dispatcher.EnqueueEvent(dispatch.MessageEvent{Kind: dispatch.EventDelete, Message: m.Message, Before: m.BeforeDelete}); dropped {
+				slog.Warn("dispatcher_event_queue_dropped", "guild", m.GuildID, "channel", m.ChannelID, "message", m.ID, "kind", "delete")
+			}
+		})
 	}
 
+	discord.AddHandler(func(_ *discordgo.Session, r *discordgo.MessageReactionAdd) {
+		handleReactionAdd(cfg, coordinator, approver, r)
+	})
+
+	commands := &commandHandler{cfg: cfg, coordinator: coordinator, outbound: gateway, paused: &paused}
+	var runner *heartbeat.Runner
 	if cfg.Heartbeat.Enabled {
-		runner, err := heartbeat.NewRunner(cfg.Heartbeat.Cron, cfg.Heartbeat.Timezone, func(runCtx context.Context) error {
-			return runHeartbeatTurn(runCtx, cfg, aiClient, nextRunID(&runSeq, "hb"))
+		runner, err = heartbeat.NewRunner(cfg.Heartbeat.Cron, cfg.Heartbeat.Timezone, func(runCtx context.Context) error {
+			runID := nextRunID(&runSeq, "hb")
+			if paused.Load() {
+				slog.Info("heartbeat_skipped", "run_id", runID, "reason", "paused")
+				return nil
+			}
+			return runHeartbeatTurn(runCtx, cfg, aiClient, coordinator, runID)
 		})
 		if err != nil {
 			return fmt.Errorf("init heartbeat runner: %w", err)
 		}
+		commands.heartbeat = runner
+	}
+	discord.AddHandler(func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+		commands.HandleInteraction(s, i)
+	})
+	discord.AddHandler(func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+		input, ok := componentEventInput(i)
+		if !ok {
+			return
+		}
+		acknowledgeComponent(s, i)
+		runID := nextRunID(&runSeq, "cmp")
+		if paused.Load() {
+			slog.Info("component_event_skipped", "run_id", runID, "channel", input.ChannelID, "custom_id", input.CustomID, "reason", "paused")
+			return
+		}
+		handleComponentEvent(ctx, cfg, coordinator, gateway, discord, dispatcher, input, runID)
+	})
+
+	if err := discord.Open(); err != nil {
+		return fmt.Errorf("open discord session: %w", err)
+	}
+	if discord.State != nil && discord.State.User != nil {
+		if _, err := discord.ApplicationCommandBulkOverwrite(discord.State.User.ID, cfg.Discord.GuildID, yururiCommands()); err != nil {
+			slog.Error("slash_commands_register_failed", "guild", cfg.Discord.GuildID, "err", err)
+		}
+	}
+
+	if runner != nil {
 		runner.Start(ctx)
 	}
 
-	log.Printf(
-		"yururi started: mcp_url=%s model=%s reasoning=%s x_search_enabled=%t x_search_model=%s",
-		cfg.MCP.URL,
-		cfg.Codex.Model,
-		cfg.Codex.ReasoningEffort,
-		cfg.XAI.Enabled,
-		cfg.XAI.Model,
+	slog.Info(
+		"yururi_started",
+		"mcp_url", cfg.MCP.URL,
+		"model", cfg.Codex.Model,
+		"reasoning", cfg.Codex.ReasoningEffort,
+		"codex_pool_size", aiClient.PoolSize(),
+		"approval_fallback", cfg.Codex.Approval.Fallback,
+		"x_search_enabled", cfg.XAI.Enabled,
+		"x_search_model", cfg.XAI.Model,
 	)
 
 	select {
 	case <-ctx.Done():
 	case err := <-errCh:
 		if err != nil {
-			log.Printf("mcp server stopped with error: %v", err)
+			slog.Error("mcp_server_failed", "err", err)
 		}
 	}
 	stop()
-	log.Printf("event=shutdown_started")
+	slog.Info("shutdown_started")
+	stats := gateway.OutboundStats()
+	slog.Info("outbound_stats", "queued", stats.Queued, "sent", stats.Sent, "retried", stats.Retried, "rate_limited", stats.RateLimited, "failed", stats.Failed, "dropped", stats.Dropped, "pending", stats.Pending)
 	runShutdownStep("discord_close", 2*time.Second, func() {
 		_ = discord.Close()
 	})
 	runShutdownStep("codex_close", 2*time.Second, func() {
 		aiClient.Close()
 	})
-	log.Printf("yururi stopped")
+	slog.Info("yururi_stopped")
 	return nil
 }
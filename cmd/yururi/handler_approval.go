@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/sigumaa/yururi/internal/codex"
+	"github.com/sigumaa/yururi/internal/config"
+)
+
+const (
+	approvalApproveEmoji = "✅"
+	approvalDenyEmoji    = "❌"
+)
+
+// approvalMessenger is the part of the gateway the approver posts through,
+// so approval requests share the outbound queue with every other write.
+type approvalMessenger interface {
+	SendSystemMessage(ctx context.Context, channelID string, content string) (string, error)
+	AddSystemReaction(ctx context.Context, channelID string, messageID string, emoji string) error
+}
+
+// ownerApprover posts Codex approval requests to the owner's channel and
+// waits for the owner to react with ✅ or ❌.
+type ownerApprover struct {
+	messenger approvalMessenger
+	channelID string
+	ownerID   string
+
+	mu      sync.Mutex
+	pending map[string]chan bool
+}
+
+func newOwnerApprover(messenger approvalMessenger, cfg config.Config) *ownerApprover {
+	if cfg.Codex.Approval.Fallback != config.ApprovalFallbackOwner {
+		return nil
+	}
+	return &ownerApprover{
+		messenger: messenger,
+		channelID: cfg.Codex.Approval.OwnerChannelID,
+		ownerID:   strings.TrimSpace(cfg.Persona.OwnerUserID),
+		pending:   map[string]chan bool{},
+	}
+}
+
+func (a *ownerApprover) RequestApproval(ctx context.Context, req codex.ApprovalRequest) (bool, error) {
+	messageID, err := a.messenger.SendSystemMessage(ctx, a.channelID, approvalMessageContent(req))
+	if err != nil {
+		return false, fmt.Errorf("send approval request: %w", err)
+	}
+	answer := make(chan bool, 1)
+	a.mu.Lock()
+	a.pending[messageID] = answer
+	a.mu.Unlock()
+	defer func() {
+		a.mu.Lock()
+		delete(a.pending, messageID)
+		a.mu.Unlock()
+	}()
+
+	for _, emoji := range []string{approvalApproveEmoji, approvalDenyEmoji} {
+		if err := a.messenger.AddSystemReaction(ctx, a.channelID, messageID, emoji); err != nil {
+			slog.Error("approval_reaction_add_failed", "channel", a.channelID, "message", messageID, "emoji", emoji, "err", err)
+		}
+	}
+	slog.Info("approval_requested", "channel", a.channelID, "message", messageID, "kind", req.Kind, "thread", req.ThreadID)
+
+	select {
+	case approved := <-answer:
+		return approved, nil
+	case <-ctx.Done():
+		return false, ctx.Err()
+	}
+}
+
+// HandleReaction resolves a pending request. It reports whether the reaction
+// was an owner answer to one.
+func (a *ownerApprover) HandleReaction(r *discordgo.MessageReaction) bool {
+	if a == nil || r == nil || r.ChannelID != a.channelID || r.UserID != a.ownerID {
+		return false
+	}
+	var approved bool
+	switch r.Emoji.Name {
+	case approvalApproveEmoji:
+		approved = true
+	case approvalDenyEmoji:
+	default:
+		return false
+	}
+	a.mu.Lock()
+	answer, ok := a.pending[r.MessageID]
+	a.mu.Unlock()
+	if !ok {
+		return false
+	}
+	select {
+	case answer <- approved:
+	default:
+	}
+	slog.Info("approval_answered", "channel", r.ChannelID, "message", r.MessageID, "approved", approved)
+	return true
+}
+
+func approvalMessageContent(req codex.ApprovalRequest) string {
+	var b strings.Builder
+	switch req.Kind {
+	case codex.ApprovalKindCommand:
+		b.WriteString("🔐 コマンド実行の承認リクエスト\n")
+	case codex.ApprovalKindFileChange:
+		b.WriteString("🔐 ファイル変更の承認リクエスト\n")
+	default:
+		b.WriteString("🔐 確認リクエスト\n")
+	}
+	if req.Command != "" {
+		fmt.Fprintf(&b, "コマンド: `%s`\n", trimLogString(strings.ReplaceAll(req.Command, "`", "'"), 400))
+	}
+	if req.CWD != "" {
+		fmt.Fprintf(&b, "cwd: `%s`\n", req.CWD)
+	}
+	for i, path := range req.Paths {
+		if i == 10 {
+			fmt.Fprintf(&b, "…ほか%d件\n", len(req.Paths)-i)
+			break
+		}
+		fmt.Fprintf(&b, "- `%s`\n", path)
+	}
+	for _, question := range req.Questions {
+		fmt.Fprintf(&b, "質問: %s\n", trimLogString(question, 200))
+	}
+	if req.Reason != "" {
+		fmt.Fprintf(&b, "理由: %s\n", trimLogString(req.Reason, 200))
+	}
+	fmt.Fprintf(&b, "%s で許可 / %s で拒否", approvalApproveEmoji, approvalDenyEmoji)
+	return b.String()
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/sigumaa/yururi/internal/attachment"
+	"github.com/sigumaa/yururi/internal/config"
+	"github.com/sigumaa/yururi/internal/discordx"
+	"github.com/sigumaa/yururi/internal/prompt"
+)
+
+// ingestAttachments downloads the message's attachments within the channel's
+// limits. It returns them for the prompt together with the image files to
+// send as image inputs.
+func ingestAttachments(ctx context.Context, cfg config.AttachmentsConfig, store *attachment.Store, m *discordgo.MessageCreate, parentChannelID string, runID string) ([]prompt.Attachment, []string) {
+	sources := make([]attachment.Source, 0, len(m.Attachments))
+	for _, a := range m.Attachments {
+		if a == nil || strings.TrimSpace(a.URL) == "" {
+			continue
+		}
+		sources = append(sources, attachment.Source{ID: a.ID, Filename: a.Filename, URL: a.URL, ContentType: a.ContentType, Size: a.Size})
+	}
+	limits := cfg.LimitsFor(m.ChannelID, parentChannelID)
+	files := store.Ingest(ctx, sources, attachment.Limits{
+		MaxPerMessage: limits.MaxPerMessage,
+		MaxImageBytes: limits.MaxImageBytes,
+		MaxTextBytes:  limits.MaxTextBytes,
+		MaxTextChars:  limits.MaxTextChars,
+	})
+
+	out := make([]prompt.Attachment, 0, len(files))
+	var images []string
+	for _, file := range files {
+		if file.Skipped != "" {
+			slog.Info("attachment_skipped", "run_id", runID, "message", m.ID, "attachment", file.ID, "kind", file.Kind, "size", file.Size, "reason", file.Skipped)
+		}
+		if file.Kind == attachment.KindImage && file.Skipped == "" {
+			images = append(images, file.Path)
+		}
+		out = append(out, toPromptAttachment(file))
+	}
+	return out, images
+}
+
+func toPromptAttachment(file attachment.File) prompt.Attachment {
+	name := strings.TrimSpace(file.Filename)
+	if name == "" {
+		name = "attachment"
+	}
+	return prompt.Attachment{
+		Name:      name,
+		URL:       file.URL,
+		Kind:      string(file.Kind),
+		Path:      file.Path,
+		Text:      file.Text,
+		Truncated: file.Truncated,
+		Skipped:   file.Skipped,
+	}
+}
+
+// historyAttachments lists the attachments of a history message. Files that
+// are already cached point at the cached copy, so earlier files can be read
+// without downloading them again; the rest are listed by name and URL.
+func historyAttachments(store *attachment.Store, attachments []discordx.Attachment) []prompt.Attachment {
+	var out []prompt.Attachment
+	for _, a := range attachments {
+		src := attachment.Source{ID: a.ID, Filename: a.Filename, URL: a.URL, ContentType: a.ContentType, Size: a.Size}
+		file := attachment.File{Source: src}
+		if path, ok := store.CachedPath(src); ok {
+			file.Kind = attachment.Classify(src)
+			file.Path = path
+		}
+		out = append(out, toPromptAttachment(file))
+	}
+	return out
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/sigumaa/yururi/internal/config"
+	"github.com/sigumaa/yururi/internal/discordx"
+	"github.com/sigumaa/yururi/internal/orchestrator"
+	"github.com/sigumaa/yururi/internal/prompt"
+)
+
+const (
+	yururiCommandName    = "yururi"
+	commandResponseLimit = 1900
+)
+
+type heartbeatTrigger interface {
+	RunNow() bool
+}
+
+type outboundStatser interface {
+	OutboundStats() discordx.OutboundStats
+}
+
+type interactionResponder interface {
+	InteractionRespond(interaction *discordgo.Interaction, resp *discordgo.InteractionResponse, options ...discordgo.RequestOption) error
+}
+
+// commandHandler serves the owner-only /yururi application command.
+type commandHandler struct {
+	cfg         config.Config
+	coordinator *orchestrator.Coordinator
+	heartbeat   heartbeatTrigger
+	outbound    outboundStatser
+	paused      *atomic.Bool
+}
+
+func yururiCommands() []*discordgo.ApplicationCommand {
+	return []*discordgo.ApplicationCommand{{
+		Name:        yururiCommandName,
+		Description: "yururi の管理コマンド",
+		Options: []*discordgo.ApplicationCommandOption{
+			{Type: discordgo.ApplicationCommandOptionSubCommand, Name: "status", Description: "稼働状況とこのチャンネルのセッションを表示する"},
+			{Type: discordgo.ApplicationCommandOptionSubCommand, Name: "reset-session", Description: "このチャンネルのセッションを破棄する"},
+			{Type: discordgo.ApplicationCommandOptionSubCommand, Name: "heartbeat-now", Description: "heartbeat を今すぐ実行する"},
+			{Type: discordgo.ApplicationCommandOptionSubCommand, Name: "pause", Description: "メッセージ処理と heartbeat を一時停止する"},
+			{Type: discordgo.ApplicationCommandOptionSubCommand, Name: "resume", Description: "一時停止を解除する"},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommandGroup,
+				Name:        "memory",
+				Description: "MEMORY.md の操作",
+				Options: []*discordgo.ApplicationCommandOption{
+					{Type: discordgo.ApplicationCommandOptionSubCommand, Name: "show", Description: "MEMORY.md を表示する"},
+				},
+			},
+		},
+	}}
+}
+
+func (h *commandHandler) HandleInteraction(responder interactionResponder, i *discordgo.InteractionCreate) {
+	if i == nil || i.Interaction == nil || i.Type != discordgo.InteractionApplicationCommand {
+		return
+	}
+	data := i.ApplicationCommandData()
+	if data.Name != yururiCommandName {
+		return
+	}
+	userID := interactionUserID(i.Interaction)
+	subcommand := commandPath(data.Options)
+	owner := strings.TrimSpace(h.cfg.Persona.OwnerUserID)
+	var content string
+	if owner == "" || userID != owner {
+		slog.Warn("slash_command_rejected", "guild", i.GuildID, "channel", i.ChannelID, "user", userID, "command", subcommand, "reason", "not_owner")
+		content = "このコマンドはオーナーのみ使えます。"
+	} else {
+		slog.Info("slash_command_received", "guild", i.GuildID, "channel", i.ChannelID, "user", userID, "command", subcommand)
+		content = h.execute(subcommand, messageChannelKey(i.GuildID, i.ChannelID))
+	}
+	err := responder.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: trimLogString(content, commandResponseLimit),
+			Flags:   discordgo.MessageFlagsEphemeral,
+		},
+	})
+	if err != nil {
+		slog.Error("slash_command_respond_failed", "guild", i.GuildID, "channel", i.ChannelID, "command", subcommand, "err", err)
+	}
+}
+
+func (h *commandHandler) execute(subcommand string, channelKey string) string {
+	switch subcommand {
+	case "status":
+		return h.status(channelKey)
+	case "reset-session":
+		if h.coordinator.ResetSession(channelKey) {
+			slog.Info("session_reset_by_command", "session_key", channelKey)
+			return "このチャンネルのセッションを破棄しました。次のメッセージから新しいthreadで始めます。"
+		}
+		return "このチャンネルにセッションはありません。"
+	case "heartbeat-now":
+		if h.heartbeat == nil {
+			return "heartbeat は無効です。"
+		}
+		if h.paused.Load() {
+			return "一時停止中のため heartbeat を実行しませんでした。resume で再開してください。"
+		}
+		if !h.heartbeat.RunNow() {
+			return "heartbeat は実行中です。"
+		}
+		return "heartbeat を開始しました。"
+	case "pause":
+		h.paused.Store(true)
+		slog.Info("paused_by_command")
+		return "一時停止しました。`/yururi resume` で再開します。"
+	case "resume":
+		h.paused.Store(false)
+		slog.Info("resumed_by_command")
+		return "再開しました。"
+	case "memory show":
+		instructions, err := prompt.LoadWorkspaceInstructions(h.cfg.Codex.WorkspaceDir)
+		if err != nil {
+			return fmt.Sprintf("MEMORY.md を読めませんでした: %v", err)
+		}
+		memory := instructions.Content["MEMORY.md"]
+		if memory == "" {
+			return "MEMORY.md は空です。"
+		}
+		return memory
+	default:
+		return fmt.Sprintf("不明なコマンドです: %s", subcommand)
+	}
+}
+
+func (h *commandHandler) status(channelKey string) string {
+	var b strings.Builder
+	if h.paused.Load() {
+		b.WriteString("状態: 一時停止中\n")
+	} else {
+		b.WriteString("状態: 稼働中\n")
+	}
+	if session, ok := h.coordinator.Session(channelKey); ok {
+		fmt.Fprintf(&b, "セッション: thread=`%s` turns=%d 更新=%s\n", session.ThreadID, session.TurnCount, session.UpdatedAt.Format(time.RFC3339))
+	} else {
+		b.WriteString("セッション: なし\n")
+	}
+	fmt.Fprintf(&b, "実行中のターン: %t\n", h.coordinator.Running(channelKey))
+	channelUsage, totalUsage := h.coordinator.UsageToday(channelKey)
+	fmt.Fprintf(&b, "今日のトークン: このチャンネル %d / 全体 %d\n", channelUsage.TotalTokens, totalUsage.TotalTokens)
+	if h.outbound != nil {
+		stats := h.outbound.OutboundStats()
+		fmt.Fprintf(&b, "送信キュー: 待機 %d / 送信 %d / 再試行 %d (429: %d) / 失敗 %d / 破棄 %d\n", stats.Pending, stats.Sent, stats.Retried, stats.RateLimited, stats.Failed, stats.Dropped)
+	}
+	fmt.Fprintf(&b, "heartbeat: %t", h.heartbeat != nil)
+	return b.String()
+}
+
+// commandPath joins subcommand group and subcommand names, e.g. "memory show".
+func commandPath(options []*discordgo.ApplicationCommandInteractionDataOption) string {
+	var parts []string
+	for len(options) > 0 {
+		option := options[0]
+		if option.Type != discordgo.ApplicationCommandOptionSubCommand && option.Type != discordgo.ApplicationCommandOptionSubCommandGroup {
+			break
+		}
+		parts = append(parts, option.Name)
+		options = option.Options
+	}
+	return strings.Join(parts, " ")
+}
+
+func interactionUserID(i *discordgo.Interaction) string {
+	if i.Member != nil && i.Member.User != nil {
+		return i.Member.User.ID
+	}
+	if i.User != nil {
+		return i.User.ID
+	}
+	return ""
+}
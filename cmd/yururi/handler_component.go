@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/sigumaa/yururi/internal/codex"
+	"github.com/sigumaa/yururi/internal/config"
+	"github.com/sigumaa/yururi/internal/discordx"
+	"github.com/sigumaa/yururi/internal/orchestrator"
+	"github.com/sigumaa/yururi/internal/policy"
+	"github.com/sigumaa/yururi/internal/prompt"
+)
+
+// componentEventInput reads a click on a component sent by send_embed. It
+// reports false for other interactions.
+func componentEventInput(i *discordgo.InteractionCreate) (prompt.ComponentEventInput, bool) {
+	if i == nil || i.Interaction == nil || i.Type != discordgo.InteractionMessageComponent {
+		return prompt.ComponentEventInput{}, false
+	}
+	data := i.MessageComponentData()
+	customID, ok := discordx.ParseComponentCustomID(data.CustomID)
+	if !ok {
+		return prompt.ComponentEventInput{}, false
+	}
+	input := prompt.ComponentEventInput{
+		GuildID:   i.GuildID,
+		ChannelID: i.ChannelID,
+		UserID:    interactionUserID(i.Interaction),
+		CustomID:  customID,
+		Values:    data.Values,
+	}
+	if i.Message != nil {
+		input.MessageID = i.Message.ID
+	}
+	author := i.User
+	if i.Member != nil && i.Member.User != nil {
+		author = i.Member.User
+	}
+	if author != nil {
+		input.UserName = displayAuthorName(&discordgo.MessageCreate{Message: &discordgo.Message{Author: author, Member: i.Member}})
+	}
+	return input, true
+}
+
+// acknowledgeComponent answers the interaction without changing the message,
+// so Discord does not show it as failed while the model works.
+func acknowledgeComponent(responder interactionResponder, i *discordgo.InteractionCreate) {
+	err := responder.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredMessageUpdate,
+	})
+	if err != nil {
+		slog.Error("component_ack_failed", "guild", i.GuildID, "channel", i.ChannelID, "err", err)
+	}
+}
+
+// channelScheduler runs work on a channel's dispatcher worker, so a turn it
+// starts never overlaps a message turn for the same channel.
+type channelScheduler interface {
+	Do(guildID string, channelID string, fn func()) bool
+}
+
+// handleComponentEvent hands a click to the channel's session. It steers the
+// running turn, or schedules a turn since a click is an explicit request.
+func handleComponentEvent(rootCtx context.Context, cfg config.Config, coordinator *orchestrator.Coordinator, gateway *discordx.Gateway, session *discordgo.Session, scheduler channelScheduler, input prompt.ComponentEventInput, runID string) {
+	incoming := withParentChannel(cfg.Discord, gateway, policy.Incoming{
+		GuildID:   input.GuildID,
+		ChannelID: input.ChannelID,
+		AuthorID:  input.UserID,
+	})
+	allowed, reason := policy.Evaluate(cfg.Discord, incoming)
+	if !allowed {
+		slog.Info("component_event_filtered", "run_id", runID, "guild", input.GuildID, "channel", input.ChannelID, "user", input.UserID, "custom_id", input.CustomID, "reason", reason)
+		return
+	}
+	if incoming.GuildID == "" {
+		gateway.RegisterDMChannel(incoming.ChannelID, incoming.AuthorID)
+	}
+
+	channelKey := messageChannelKey(input.GuildID, input.ChannelID)
+	steerCtx, cancelSteer := context.WithTimeout(rootCtx, 3*time.Minute)
+	steered, err := coordinator.SteerActiveTurn(steerCtx, channelKey, prompt.BuildComponentEventNote(input))
+	cancelSteer()
+	if err != nil {
+		slog.Error("component_event_steer_failed", "run_id", runID, "channel", input.ChannelID, "custom_id", input.CustomID, "err", err)
+	}
+	if steered {
+		slog.Info("component_event_steered", "run_id", runID, "channel", input.ChannelID, "custom_id", input.CustomID, "session_key", channelKey)
+		return
+	}
+	if dropped := scheduler.Do(input.GuildID, input.ChannelID, func() {
+		runComponentEventTurn(rootCtx, cfg, coordinator, session, input, channelKey, runID)
+	}); dropped {
+		slog.Warn("component_event_dropped", "run_id", runID, "channel", input.ChannelID, "custom_id", input.CustomID, "reason", "queue_full")
+	}
+}
+
+func runComponentEventTurn(rootCtx context.Context, cfg config.Config, coordinator *orchestrator.Coordinator, session *discordgo.Session, input prompt.ComponentEventInput, channelKey string, runID string) {
+	ctx, cancel := context.WithTimeout(rootCtx, 3*time.Minute)
+	defer cancel()
+
+	instructions, err := prompt.LoadWorkspaceInstructions(cfg.Codex.WorkspaceDir)
+	if err != nil {
+		slog.Error("workspace_instructions_load_failed", "err", err)
+		return
+	}
+	input.ChannelName = channelNameForPrompt(session, input.GuildID, input.ChannelID)
+	bundle := prompt.BuildComponentEventBundle(instructions, input)
+	turnStarted := time.Now()
+	result, err := coordinator.RunMessageTurn(ctx, channelKey, codex.TurnInput{
+		BaseInstructions:      bundle.BaseInstructions,
+		DeveloperInstructions: bundle.DeveloperInstructions,
+		UserPrompt:            bundle.UserPrompt,
+	})
+	if err != nil {
+		slog.Error("component_event_turn_failed", "run_id", runID, "channel", input.ChannelID, "custom_id", input.CustomID, "turn_latency_ms", durationMS(time.Since(turnStarted)), "err", err)
+		return
+	}
+	slog.Info("component_event_turn_completed", "run_id", runID, "channel", input.ChannelID, "custom_id", input.CustomID, "status", result.Status, "thread", result.ThreadID, "turn", result.TurnID, "tool_calls", len(result.ToolCalls), "total_tokens", result.Usage.TotalTokens, "turn_latency_ms", durationMS(time.Since(turnStarted)))
+	for i, toolCall := range result.ToolCalls {
+		logTurnToolCall("component_event", runID, result.ThreadID, result.TurnID, i, toolCall)
+	}
+	logTurnItems("component_event", runID, result)
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/sigumaa/yururi/internal/codex"
+	"github.com/sigumaa/yururi/internal/config"
+	"github.com/sigumaa/yururi/internal/discordx"
+	"github.com/sigumaa/yururi/internal/dispatch"
+	"github.com/sigumaa/yururi/internal/orchestrator"
+	"github.com/sigumaa/yururi/internal/policy"
+	"github.com/sigumaa/yururi/internal/prompt"
+)
+
+// messageEventCacheLimit is the number of messages per channel kept in the
+// session state so edits and deletes can show the previous content.
+const messageEventCacheLimit = 200
+
+// discordIntents subscribes to guild events as well as messages: the session
+// state only caches messages of channels it knows, and guild channels and
+// threads reach it through GUILD_CREATE.
+func discordIntents(cfg config.DiscordConfig) discordgo.Intent {
+	intents := discordgo.IntentsGuilds | discordgo.IntentsGuildMessages | discordgo.IntentsMessageContent | discordgo.IntentsGuildMessageReactions
+	if cfg.DM.Enabled {
+		intents |= discordgo.IntentsDirectMessages | discordgo.IntentsDirectMessageReactions
+	}
+	return intents
+}
+
+// trackMessageChannel adds a DM channel the state has not seen yet, together
+// with its first message. Bots get no DM channels in READY, so without this
+// the state drops every DM message and edits arrive without the old content.
+func trackMessageChannel(state *discordgo.State, m *discordgo.Message) {
+	if state == nil || state.MaxMessageCount == 0 || m == nil || m.GuildID != "" {
+		return
+	}
+	if _, err := state.Channel(m.ChannelID); err == nil {
+		return
+	}
+	if err := state.ChannelAdd(&discordgo.Channel{ID: m.ChannelID, Type: discordgo.ChannelTypeDM}); err != nil {
+		return
+	}
+	_ = state.MessageAdd(m)
+}
+
+// handleMessageEvent hands an edit or delete to the model. It steers the
+// channel's running turn, or is kept for the next turn unless
+// discord.message_events.trigger_turn schedules a turn for it on the
+// channel's dispatcher worker.
+func handleMessageEvent(rootCtx context.Context, cfg config.Config, coordinator *orchestrator.Coordinator, gateway *discordx.Gateway, session *discordgo.Session, scheduler channelScheduler, event dispatch.MessageEvent, runID string) {
+	input, incoming, skip := messageEventInput(event, botUserID(session))
+	if skip != "" {
+		slog.Info("message_event_skipped", "run_id", runID, "kind", event.Kind, "message", input.MessageID, "channel", input.ChannelID, "reason", skip)
+		return
+	}
+	incoming = withParentChannel(cfg.Discord, gateway, incoming)
+	allowed, reason := policy.Evaluate(cfg.Discord, incoming)
+	if !allowed {
+		slog.Info("message_event_filtered", "run_id", runID, "kind", event.Kind, "message", input.MessageID, "guild", input.GuildID, "channel", input.ChannelID, "author", input.AuthorID, "reason", reason)
+		return
+	}
+	if incoming.GuildID == "" {
+		gateway.RegisterDMChannel(incoming.ChannelID, incoming.AuthorID)
+	}
+
+	channelKey := messageChannelKey(input.GuildID, input.ChannelID)
+	note := prompt.BuildMessageEventNote(input)
+	steerCtx, cancelSteer := context.WithTimeout(rootCtx, 3*time.Minute)
+	steered, err := coordinator.SteerActiveTurn(steerCtx, channelKey, note)
+	cancelSteer()
+	if err != nil {
+		slog.Error("message_event_steer_failed", "run_id", runID, "kind", event.Kind, "message", input.MessageID, "channel", input.ChannelID, "err", err)
+	}
+	if steered {
+		slog.Info("message_event_steered", "run_id", runID, "kind", event.Kind, "message", input.MessageID, "channel", input.ChannelID, "session_key", channelKey)
+		return
+	}
+	if !cfg.Discord.MessageEvents.TriggerTurn {
+		coordinator.DeferNote(channelKey, note)
+		slog.Info("message_event_deferred", "run_id", runID, "kind", event.Kind, "message", input.MessageID, "channel", input.ChannelID, "session_key", channelKey)
+		return
+	}
+	if dropped := scheduler.Do(input.GuildID, input.ChannelID, func() {
+		runMessageEventTurn(rootCtx, cfg, coordinator, session, event.Kind, input, channelKey, runID)
+	}); dropped {
+		slog.Warn("message_event_dropped", "run_id", runID, "kind", event.Kind, "message", input.MessageID, "channel", input.ChannelID, "reason", "queue_full")
+	}
+}
+
+func runMessageEventTurn(rootCtx context.Context, cfg config.Config, coordinator *orchestrator.Coordinator, session *discordgo.Session, kind dispatch.EventKind, input prompt.MessageEventInput, channelKey string, runID string) {
+	ctx, cancel := context.WithTimeout(rootCtx, 3*time.Minute)
+	defer cancel()
+
+	instructions, err := prompt.LoadWorkspaceInstructions(cfg.Codex.WorkspaceDir)
+	if err != nil {
+		slog.Error("workspace_instructions_load_failed", "err", err)
+		return
+	}
+	input.ChannelName = channelNameForPrompt(session, input.GuildID, input.ChannelID)
+	bundle := prompt.BuildMessageEventBundle(instructions, input)
+	turnStarted := time.Now()
+	result, err := coordinator.RunMessageTurn(ctx, channelKey, codex.TurnInput{
+		BaseInstructions:      bundle.BaseInstructions,
+		DeveloperInstructions: bundle.DeveloperInstructions,
+		UserPrompt:            bundle.UserPrompt,
+	})
+	if err != nil {
+		slog.Error("message_event_turn_failed", "run_id", runID, "kind", kind, "message", input.MessageID, "channel", input.ChannelID, "turn_latency_ms", durationMS(time.Since(turnStarted)), "err", err)
+		return
+	}
+	slog.Info("message_event_turn_completed", "run_id", runID, "kind", kind, "message", input.MessageID, "channel", input.ChannelID, "status", result.Status, "thread", result.ThreadID, "turn", result.TurnID, "tool_calls", len(result.ToolCalls), "total_tokens", result.Usage.TotalTokens, "turn_latency_ms", durationMS(time.Since(turnStarted)))
+	for i, toolCall := range result.ToolCalls {
+		logTurnToolCall("message_event", runID, result.ThreadID, result.TurnID, i, toolCall)
+	}
+	logTurnItems("message_event", runID, result)
+}
+
+// messageEventInput builds the prompt input and policy input for an event.
+// A non-empty skip reason means the event is not worth telling the model
+// about.
+func messageEventInput(event dispatch.MessageEvent, selfID string) (prompt.MessageEventInput, policy.Incoming, string) {
+	msg := event.Message
+	if msg == nil {
+		return prompt.MessageEventInput{}, policy.Incoming{}, "missing_message"
+	}
+	input := prompt.MessageEventInput{
+		Kind:      string(event.Kind),
+		GuildID:   msg.GuildID,
+		ChannelID: msg.ChannelID,
+		MessageID: msg.ID,
+	}
+	author := msg
+	if event.Kind == dispatch.EventDelete {
+		if event.Before == nil {
+			return input, policy.Incoming{}, "not_cached"
+		}
+		author = event.Before
+		input.Before = event.Before.Content
+	} else {
+		if event.Before != nil {
+			input.Before = event.Before.Content
+			if event.Before.Content == msg.Content {
+				return input, policy.Incoming{}, "content_unchanged"
+			}
+		}
+		input.After = msg.Content
+	}
+	if author.Author == nil {
+		return input, policy.Incoming{}, "missing_author"
+	}
+	if selfID != "" && author.Author.ID == selfID {
+		return input, policy.Incoming{}, "own_message"
+	}
+	input.AuthorID = author.Author.ID
+	input.AuthorName = displayAuthorName(&discordgo.MessageCreate{Message: author})
+	return input, policy.Incoming{
+		GuildID:     msg.GuildID,
+		ChannelID:   msg.ChannelID,
+		AuthorID:    author.Author.ID,
+		AuthorIsBot: author.Author.Bot,
+		WebhookID:   strings.TrimSpace(author.WebhookID),
+	}, ""
+}
+
+func botUserID(session *discordgo.Session) string {
+	if session == nil || session.State == nil || session.State.User == nil {
+		return ""
+	}
+	return session.State.User.ID
+}
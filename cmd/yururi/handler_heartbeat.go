@@ -2,43 +2,56 @@ package main
 
 import (
 	"context"
-	"log"
+	"log/slog"
 	"strings"
 	"time"
 
 	"github.com/sigumaa/yururi/internal/codex"
 	"github.com/sigumaa/yururi/internal/config"
+	"github.com/sigumaa/yururi/internal/orchestrator"
 	"github.com/sigumaa/yururi/internal/prompt"
 )
 
-func runHeartbeatTurn(ctx context.Context, cfg config.Config, runtime heartbeatRuntime, runID string) error {
+func runHeartbeatTurn(ctx context.Context, cfg config.Config, runtime heartbeatRuntime, usage usageAccounting, runID string) error {
 	started := time.Now()
-	log.Printf("event=heartbeat_tick run_id=%s", runID)
+	slog.Info("heartbeat_tick", "run_id", runID)
 
 	instructions, err := prompt.LoadWorkspaceInstructions(cfg.Codex.WorkspaceDir)
 	if err != nil {
 		return err
 	}
 	bundle := prompt.BuildHeartbeatBundle(instructions)
-	result, err := runtime.RunTurn(ctx, codex.TurnInput{
+	input := codex.TurnInput{
 		BaseInstructions:      bundle.BaseInstructions,
 		DeveloperInstructions: bundle.DeveloperInstructions,
 		UserPrompt:            bundle.UserPrompt,
-	})
+	}
+	if usage != nil {
+		input, err = usage.AdmitTurn(orchestrator.HeartbeatUsageScope, input)
+		if err != nil {
+			slog.Warn("heartbeat_turn_refused", "run_id", runID, "err", err)
+			return nil
+		}
+	}
+	result, err := runtime.RunTurn(ctx, input)
+	if usage != nil {
+		usage.RecordUsage(orchestrator.HeartbeatUsageScope, result)
+	}
 	if err != nil {
-		log.Printf("event=heartbeat_turn_failed run_id=%s turn_latency_ms=%d err=%v", runID, durationMS(time.Since(started)), err)
+		slog.Error("heartbeat_turn_failed", "run_id", runID, "turn_latency_ms", durationMS(time.Since(started)), "err", err)
 		return err
 	}
-	log.Printf("event=heartbeat_turn_completed run_id=%s status=%s thread=%s turn=%s tool_calls=%d turn_latency_ms=%d", runID, result.Status, result.ThreadID, result.TurnID, len(result.ToolCalls), durationMS(time.Since(started)))
+	slog.Info("heartbeat_turn_completed", "run_id", runID, "status", result.Status, "thread", result.ThreadID, "turn", result.TurnID, "tool_calls", len(result.ToolCalls), "commands", len(result.Commands), "file_changes", len(result.FileChanges), "input_tokens", result.Usage.InputTokens, "cached_input_tokens", result.Usage.CachedInputTokens, "output_tokens", result.Usage.OutputTokens, "reasoning_tokens", result.Usage.ReasoningOutputTokens, "turn_latency_ms", durationMS(time.Since(started)))
 	if assistantText := strings.TrimSpace(result.AssistantText); assistantText != "" {
-		log.Printf("event=heartbeat_assistant_text run_id=%s thread=%s turn=%s text=%q", runID, result.ThreadID, result.TurnID, assistantText)
+		slog.Debug("heartbeat_assistant_text", "run_id", runID, "thread", result.ThreadID, "turn", result.TurnID, "text", assistantText)
 		logDecisionSummary("heartbeat", runID, result.ThreadID, result.TurnID, assistantText)
 	}
 	for i, toolCall := range result.ToolCalls {
 		logTurnToolCall("heartbeat", runID, result.ThreadID, result.TurnID, i, toolCall)
 	}
+	logTurnItems("heartbeat", runID, result)
 	if strings.TrimSpace(result.ErrorMessage) != "" {
-		log.Printf("event=heartbeat_turn_error_detail run_id=%s err=%s", runID, result.ErrorMessage)
+		slog.Error("heartbeat_turn_error_detail", "run_id", runID, "err", result.ErrorMessage)
 	}
 	return nil
 }
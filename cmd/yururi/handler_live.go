@@ -0,0 +1,119 @@
+package main
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sigumaa/yururi/internal/codex"
+	"github.com/sigumaa/yururi/internal/config"
+	"github.com/sigumaa/yururi/internal/discordx"
+)
+
+const liveThinkingText = "-# 考え中…"
+
+type liveUpdater interface {
+	Update(content string)
+}
+
+type liveTurnView struct {
+	live liveUpdater
+
+	mu       sync.Mutex
+	activity string
+	text     string
+}
+
+func newLiveTurnView(live liveUpdater) *liveTurnView {
+	return &liveTurnView{live: live}
+}
+
+func (v *liveTurnView) Handle(event codex.TurnEvent) {
+	v.mu.Lock()
+	switch event.Kind {
+	case codex.TurnEventStarted:
+	case codex.TurnEventAgentMessageDelta:
+		v.text = event.Text
+	case codex.TurnEventItemStarted:
+		if label := liveActivityLabel(event); label != "" {
+			v.activity = label
+		}
+	case codex.TurnEventItemCompleted:
+		if label := liveActivityLabel(event); label != "" && label == v.activity {
+			v.activity = ""
+		}
+	default:
+		v.mu.Unlock()
+		return
+	}
+	content := v.renderLocked()
+	v.mu.Unlock()
+
+	v.live.Update(content)
+}
+
+func (v *liveTurnView) renderLocked() string {
+	text := strings.TrimSpace(v.text)
+	if maybeDecisionOutput(text) {
+		text = ""
+	}
+	switch {
+	case text == "" && v.activity == "":
+		return liveThinkingText
+	case text == "":
+		return v.activity
+	case v.activity == "":
+		return text
+	default:
+		return text + "\n" + v.activity
+	}
+}
+
+func liveActivityLabel(event codex.TurnEvent) string {
+	detail := trimLogString(event.Detail, 80)
+	switch strings.ToLower(strings.ReplaceAll(event.ItemType, "_", "")) {
+	case "mcptoolcall":
+		if detail == "" {
+			return "-# 🔧 ツールを実行中…"
+		}
+		return "-# 🔧 " + detail + " を実行中…"
+	case "websearch":
+		if detail == "" {
+			return "-# 🔍 web検索中…"
+		}
+		return "-# 🔍 「" + detail + "」をweb検索中…"
+	case "commandexecution":
+		return "-# ⚙️ コマンドを実行中…"
+	case "reasoning":
+		return "-# 💭 考え中…"
+	default:
+		return ""
+	}
+}
+
+func liveMessageOptions(cfg config.LiveMessageConfig) discordx.LiveMessageOptions {
+	return discordx.LiveMessageOptions{
+		StartDelay:   time.Duration(cfg.StartDelayMS) * time.Millisecond,
+		EditInterval: time.Duration(cfg.EditIntervalMS) * time.Millisecond,
+	}
+}
+
+// liveFinalText decides what the live message should become once the turn
+// ends. It is removed unless keep_final_text is set and the turn produced
+// plain text without posting through a tool.
+func liveFinalText(cfg config.LiveMessageConfig, result codex.TurnResult) string {
+	if !cfg.KeepFinalText {
+		return ""
+	}
+	text := strings.TrimSpace(result.AssistantText)
+	if text == "" || maybeDecisionOutput(text) {
+		return ""
+	}
+	for _, call := range result.ToolCalls {
+		switch call.Tool {
+		case "send_message", "reply_message", "send_embed", "upload_file", "send_direct_message":
+			return ""
+		}
+	}
+	return text
+}
@@ -2,11 +2,13 @@ package main
 
 import (
 	"context"
-	"log"
+	"errors"
+	"log/slog"
 	"strings"
 	"time"
 
 	"github.com/bwmarrin/discordgo"
+	"github.com/sigumaa/yururi/internal/attachment"
 	"github.com/sigumaa/yururi/internal/codex"
 	"github.com/sigumaa/yururi/internal/config"
 	"github.com/sigumaa/yururi/internal/discordx"
@@ -16,7 +18,7 @@ import (
 	"github.com/sigumaa/yururi/internal/prompt"
 )
 
-func handleMessage(rootCtx context.Context, cfg config.Config, coordinator *orchestrator.Coordinator, gateway *discordx.Gateway, session *discordgo.Session, m *discordgo.MessageCreate, meta dispatch.CallbackMetadata, runID string) {
+func handleMessage(rootCtx context.Context, cfg config.Config, coordinator *orchestrator.Coordinator, gateway *discordx.Gateway, attachments *attachment.Store, session *discordgo.Session, m *discordgo.MessageCreate, meta dispatch.CallbackMetadata, runID string) {
 	authorID := ""
 	authorIsBot := false
 	authorName := ""
@@ -25,84 +27,158 @@ func handleMessage(rootCtx context.Context, cfg config.Config, coordinator *orch
 		authorIsBot = m.Author.Bot
 		authorName = displayAuthorName(m)
 	}
-	log.Printf("event=message_received run_id=%s message=%s guild=%s channel=%s author=%s merged=%d queue_wait_ms=%d enqueued_at=%s", runID, m.ID, m.GuildID, m.ChannelID, authorID, normalizeMergedCount(meta.MergedCount), durationMS(meta.QueueWait), meta.EnqueuedAt.UTC().Format(time.RFC3339Nano))
+	slog.Info("message_received", "run_id", runID, "message", m.ID, "guild", m.GuildID, "channel", m.ChannelID, "author", authorID, "merged", normalizeMergedCount(meta.MergedCount), "addressed", fallbackForLog(string(meta.Addressed), "none"), "queue_wait_ms", durationMS(meta.QueueWait), "enqueued_at", meta.EnqueuedAt.UTC().Format(time.RFC3339Nano))
 
-	incoming := policy.Incoming{
+	incoming := withParentChannel(cfg.Discord, gateway, policy.Incoming{
 		GuildID:     m.GuildID,
 		ChannelID:   m.ChannelID,
 		AuthorID:    authorID,
 		AuthorIsBot: authorIsBot,
 		WebhookID:   m.WebhookID,
-	}
+	})
 	allowed, reason := policy.Evaluate(cfg.Discord, incoming)
 	if !allowed {
-		log.Printf("event=message_filtered run_id=%s message=%s guild=%s channel=%s author=%s reason=%s", runID, m.ID, m.GuildID, m.ChannelID, authorID, reason)
+		slog.Info("message_filtered", "run_id", runID, "message", m.ID, "guild", m.GuildID, "channel", m.ChannelID, "author", authorID, "reason", reason)
 		return
 	}
 
+	// Evaluate only lets guild-less messages through when DMs are enabled
+	// and the author is allowlisted.
+	isDM := m.GuildID == ""
+	if isDM {
+		gateway.RegisterDMChannel(m.ChannelID, authorID)
+	}
+
 	ctx, cancel := context.WithTimeout(rootCtx, 3*time.Minute)
 	defer cancel()
 
 	historyLimit := calculateHistoryLimit(meta.MergedCount)
 	history, err := gateway.ReadMessageHistory(ctx, m.ChannelID, m.ID, historyLimit)
 	if err != nil {
-		log.Printf("event=history_read_failed run_id=%s guild=%s channel=%s message=%s err=%v", runID, m.GuildID, m.ChannelID, m.ID, err)
+		slog.Error("history_read_failed", "run_id", runID, "guild", m.GuildID, "channel", m.ChannelID, "message", m.ID, "err", err)
+	}
+	recent := toPromptMessages(history, attachments)
+	current := prompt.RuntimeMessage{
+		ID:         m.ID,
+		AuthorID:   authorID,
+		AuthorName: authorName,
+		Content:    mergeMessageContent(m),
+		CreatedAt:  m.Timestamp,
+	}
+	var images []string
+	if attachments != nil && len(m.Attachments) > 0 {
+		current.Content = strings.TrimSpace(m.Content)
+		current.Attachments, images = ingestAttachments(ctx, cfg.Discord.Attachments, attachments, m, incoming.ParentChannelID, runID)
 	}
-	recent := toPromptMessages(history)
 
 	instructions, err := prompt.LoadWorkspaceInstructions(cfg.Codex.WorkspaceDir)
 	if err != nil {
-		log.Printf("load workspace instructions failed: err=%v", err)
+		slog.Error("workspace_instructions_load_failed", "err", err)
 		return
 	}
-	channelName := m.ChannelID
-	if session != nil {
-		if ch, err := session.Channel(m.ChannelID); err == nil && ch != nil && strings.TrimSpace(ch.Name) != "" {
-			channelName = ch.Name
-		}
-	}
+	channelName := channelNameForPrompt(session, m.GuildID, m.ChannelID)
 	bundle := prompt.BuildMessageBundle(instructions, prompt.MessageInput{
 		GuildID:     m.GuildID,
 		ChannelID:   m.ChannelID,
 		ChannelName: channelName,
 		MergedCount: meta.MergedCount,
 		IsOwner:     authorID != "" && authorID == cfg.Persona.OwnerUserID,
-		Current: prompt.RuntimeMessage{
-			ID:         m.ID,
-			AuthorID:   authorID,
-			AuthorName: authorName,
-			Content:    mergeMessageContent(m),
-			CreatedAt:  m.Timestamp,
-		},
-		Recent: recent,
+		Addressed:   string(meta.Addressed),
+		IsDM:        isDM,
+		Current:     current,
+		Recent:      recent,
 	})
 
 	turnStarted := time.Now()
-	log.Printf("event=codex_turn_started run_id=%s message=%s guild=%s channel=%s author=%s", runID, m.ID, m.GuildID, m.ChannelID, authorID)
-	channelKey := orchestrator.ChannelKey(m.GuildID, m.ChannelID)
-	result, err := coordinator.RunMessageTurn(ctx, channelKey, codex.TurnInput{
+	slog.Info("codex_turn_started", "run_id", runID, "message", m.ID, "guild", m.GuildID, "channel", m.ChannelID, "author", authorID)
+	channelKey := messageChannelKey(m.GuildID, m.ChannelID)
+	input := codex.TurnInput{
 		BaseInstructions:      bundle.BaseInstructions,
 		DeveloperInstructions: bundle.DeveloperInstructions,
 		UserPrompt:            bundle.UserPrompt,
-	})
+		Images:                images,
+	}
+	if cfg.Routing.Enabled {
+		route := policy.RouteMessage(cfg.Routing, messageTraits(cfg, session, m, current, recent, meta.MergedCount))
+		input.Model = route.Model
+		input.ReasoningEffort = route.ReasoningEffort
+		slog.Info("model_routed", "run_id", runID, "message", m.ID, "channel", m.ChannelID, "tier", fallbackForLog(route.Tier, "default"), "rule", route.Rule, "model", fallbackForLog(route.Model, "default"), "reasoning_effort", fallbackForLog(route.ReasoningEffort, "default"))
+	}
+	var live *discordx.LiveMessage
+	if cfg.Discord.LiveMessage.Enabled {
+		live, err = gateway.StartLiveMessage(m.ChannelID, m.ID, liveMessageOptions(cfg.Discord.LiveMessage))
+		if err != nil {
+			slog.Info("live_message_skipped", "run_id", runID, "channel", m.ChannelID, "message", m.ID, "reason", err)
+		} else {
+			input.OnEvent = newLiveTurnView(live).Handle
+		}
+	}
+	result, err := coordinator.RunMessageTurn(ctx, channelKey, input)
+	if live != nil {
+		if finishErr := live.Finish(liveFinalText(cfg.Discord.LiveMessage, result)); finishErr != nil {
+			slog.Error("live_message_failed", "run_id", runID, "channel", m.ChannelID, "message", m.ID, "live_message", live.MessageID(), "err", finishErr)
+		}
+	}
+	if errors.Is(err, orchestrator.ErrUsageBudgetExhausted) {
+		slog.Warn("codex_turn_refused", "run_id", runID, "guild", m.GuildID, "channel", m.ChannelID, "message", m.ID, "err", err)
+		return
+	}
+	if errors.Is(err, context.Canceled) {
+		slog.Info("codex_turn_cancelled", "run_id", runID, "guild", m.GuildID, "channel", m.ChannelID, "message", m.ID, "turn_latency_ms", durationMS(time.Since(turnStarted)), "err", err)
+		return
+	}
 	if err != nil {
-		log.Printf("event=codex_turn_failed run_id=%s guild=%s channel=%s message=%s turn_latency_ms=%d err=%v", runID, m.GuildID, m.ChannelID, m.ID, durationMS(time.Since(turnStarted)), err)
+		slog.Error("codex_turn_failed", "run_id", runID, "guild", m.GuildID, "channel", m.ChannelID, "message", m.ID, "turn_latency_ms", durationMS(time.Since(turnStarted)), "err", err)
 		return
 	}
-	log.Printf("event=codex_turn_completed run_id=%s message=%s guild=%s channel=%s author=%s status=%s thread=%s turn=%s tool_calls=%d turn_latency_ms=%d", runID, m.ID, m.GuildID, m.ChannelID, authorID, result.Status, result.ThreadID, result.TurnID, len(result.ToolCalls), durationMS(time.Since(turnStarted)))
+	slog.Info("codex_turn_completed", "run_id", runID, "message", m.ID, "guild", m.GuildID, "channel", m.ChannelID, "author", authorID, "status", result.Status, "thread", result.ThreadID, "turn", result.TurnID, "tool_calls", len(result.ToolCalls), "commands", len(result.Commands), "file_changes", len(result.FileChanges), "input_tokens", result.Usage.InputTokens, "cached_input_tokens", result.Usage.CachedInputTokens, "output_tokens", result.Usage.OutputTokens, "reasoning_tokens", result.Usage.ReasoningOutputTokens, "turn_latency_ms", durationMS(time.Since(turnStarted)))
 	if strings.TrimSpace(result.AssistantText) != "" {
-		log.Printf("event=assistant_text run_id=%s message=%s thread=%s turn=%s text=%q", runID, m.ID, result.ThreadID, result.TurnID, result.AssistantText)
+		slog.Debug("assistant_text", "run_id", runID, "message", m.ID, "thread", result.ThreadID, "turn", result.TurnID, "text", result.AssistantText)
 		logDecisionSummary("message", runID, result.ThreadID, result.TurnID, result.AssistantText)
 	}
 	for i, toolCall := range result.ToolCalls {
 		logTurnToolCall("message", runID, result.ThreadID, result.TurnID, i, toolCall)
 	}
+	logTurnItems("message", runID, result)
 	if strings.TrimSpace(result.ErrorMessage) != "" {
-		log.Printf("event=codex_turn_error_detail run_id=%s message=%s err=%s", runID, m.ID, result.ErrorMessage)
+		slog.Error("codex_turn_error_detail", "run_id", runID, "message", m.ID, "err", result.ErrorMessage)
+	}
+}
+
+func channelNameForPrompt(session *discordgo.Session, guildID string, channelID string) string {
+	if guildID == "" {
+		return "DM"
+	}
+	if session != nil {
+		if ch, err := session.Channel(channelID); err == nil && ch != nil && strings.TrimSpace(ch.Name) != "" {
+			return ch.Name
+		}
+	}
+	return channelID
+}
+
+// messageChannelKey keeps DM sessions apart from guild channel sessions.
+func messageChannelKey(guildID string, channelID string) string {
+	if strings.TrimSpace(guildID) == "" {
+		return orchestrator.DMChannelKey(channelID)
 	}
+	return orchestrator.ChannelKey(guildID, channelID)
+}
+
+type parentChannelResolver interface {
+	ParentChannelID(channelID string) string
 }
 
-func toPromptMessages(messages []discordx.Message) []prompt.RuntimeMessage {
+// withParentChannel looks up the parent channel only when the filter needs
+// it, since an unknown channel costs an API request.
+func withParentChannel(discordCfg config.DiscordConfig, resolver parentChannelResolver, incoming policy.Incoming) policy.Incoming {
+	if policy.NeedsParentChannel(discordCfg, incoming) {
+		incoming.ParentChannelID = resolver.ParentChannelID(incoming.ChannelID)
+	}
+	return incoming
+}
+
+func toPromptMessages(messages []discordx.Message, attachments *attachment.Store) []prompt.RuntimeMessage {
 	if len(messages) == 0 {
 		return nil
 	}
@@ -110,11 +186,12 @@ func toPromptMessages(messages []discordx.Message) []prompt.RuntimeMessage {
 	for i := len(messages) - 1; i >= 0; i-- {
 		msg := messages[i]
 		reversed = append(reversed, prompt.RuntimeMessage{
-			ID:         msg.ID,
-			AuthorID:   msg.AuthorID,
-			AuthorName: msg.AuthorName,
-			Content:    msg.Content,
-			CreatedAt:  msg.CreatedAt,
+			ID:          msg.ID,
+			AuthorID:    msg.AuthorID,
+			AuthorName:  msg.AuthorName,
+			Content:     msg.Content,
+			CreatedAt:   msg.CreatedAt,
+			Attachments: historyAttachments(attachments, msg.Attachments),
 		})
 	}
 	return reversed
@@ -151,6 +228,74 @@ func mergeMessageContent(m *discordgo.MessageCreate) string {
 	return content + "\nattachments: " + strings.Join(parts, ", ")
 }
 
+// messageTraits describes a message for model routing. Content is what the
+// prompt carries for the turn: the earlier messages of the coalesced batch,
+// which reach the prompt through history, and the current message with its
+// inlined text attachments.
+func messageTraits(cfg config.Config, session *discordgo.Session, m *discordgo.MessageCreate, current prompt.RuntimeMessage, recent []prompt.RuntimeMessage, mergedCount int) policy.MessageTraits {
+	botUserID := ""
+	if session != nil && session.State != nil && session.State.User != nil {
+		botUserID = session.State.User.ID
+	}
+	batch := append(batchedMessages(recent, mergedCount, botUserID), current)
+	contents := make([]string, 0, len(batch))
+	traits := policy.MessageTraits{Attachments: len(m.Attachments)}
+	for i, msg := range batch {
+		if content := routingContent(msg); content != "" {
+			contents = append(contents, content)
+		}
+		if i < len(batch)-1 {
+			traits.Attachments += len(msg.Attachments)
+		}
+	}
+	traits.Content = strings.Join(contents, "\n")
+	if m.Author != nil {
+		traits.FromOwner = m.Author.ID != "" && m.Author.ID == cfg.Persona.OwnerUserID
+	}
+	if botUserID != "" {
+		for _, user := range m.Mentions {
+			if user != nil && user.ID == botUserID {
+				traits.MentionsBot = true
+				break
+			}
+		}
+	}
+	return traits
+}
+
+// batchedMessages returns the messages merged into the current one, oldest
+// first. They are the newest mergedCount-1 messages of recent that the bot
+// did not send.
+func batchedMessages(recent []prompt.RuntimeMessage, mergedCount int, botUserID string) []prompt.RuntimeMessage {
+	want := mergedCount - 1
+	start := len(recent)
+	for start > 0 && want > 0 {
+		start--
+		if botUserID != "" && recent[start].AuthorID == botUserID {
+			continue
+		}
+		want--
+	}
+	var out []prompt.RuntimeMessage
+	for _, msg := range recent[start:] {
+		if botUserID != "" && msg.AuthorID == botUserID {
+			continue
+		}
+		out = append(out, msg)
+	}
+	return out
+}
+
+func routingContent(msg prompt.RuntimeMessage) string {
+	parts := []string{strings.TrimSpace(msg.Content)}
+	for _, a := range msg.Attachments {
+		if a.Text != "" {
+			parts = append(parts, strings.TrimSpace(a.Text))
+		}
+	}
+	return strings.TrimSpace(strings.Join(parts, "\n"))
+}
+
 func displayAuthorName(m *discordgo.MessageCreate) string {
 	if m == nil || m.Author == nil {
 		return "unknown"
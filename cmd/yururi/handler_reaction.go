@@ -0,0 +1,46 @@
+package main
+
+import (
+	"log/slog"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/sigumaa/yururi/internal/config"
+	"github.com/sigumaa/yururi/internal/orchestrator"
+	"github.com/sigumaa/yururi/internal/policy"
+)
+
+func handleReactionAdd(cfg config.Config, coordinator *orchestrator.Coordinator, approver *ownerApprover, r *discordgo.MessageReactionAdd) {
+	if r == nil || r.MessageReaction == nil {
+		return
+	}
+	if approver.HandleReaction(r.MessageReaction) {
+		return
+	}
+	if !isStopReaction(cfg, r.MessageReaction) {
+		return
+	}
+	channelKey := messageChannelKey(r.GuildID, r.ChannelID)
+	cancelled := coordinator.CancelChannel(channelKey)
+	slog.Info("stop_reaction_received", "guild", r.GuildID, "channel", r.ChannelID, "message", r.MessageID, "user", r.UserID, "cancelled", cancelled)
+}
+
+// isStopReaction accepts the stop emoji from the owner in the guild, and in
+// DMs also from the allowlisted user the conversation belongs to.
+func isStopReaction(cfg config.Config, r *discordgo.MessageReaction) bool {
+	if r.Emoji.Name != cfg.Discord.StopReactionEmoji {
+		return false
+	}
+	owner := strings.TrimSpace(cfg.Persona.OwnerUserID)
+	if r.GuildID == "" {
+		if !cfg.Discord.DM.Enabled || r.UserID == "" {
+			return false
+		}
+		allowed, _ := policy.Evaluate(cfg.Discord, policy.Incoming{ChannelID: r.ChannelID, AuthorID: r.UserID})
+		return allowed || r.UserID == owner
+	}
+	if owner == "" || r.UserID != owner {
+		return false
+	}
+	return r.GuildID == cfg.Discord.GuildID
+}
@@ -3,7 +3,8 @@ package main
 import (
 	"encoding/json"
 	"fmt"
-	"log"
+	"log/slog"
+	"strconv"
 	"strings"
 	"sync/atomic"
 	"time"
@@ -75,32 +76,76 @@ func logDecisionSummary(eventPrefix string, runID string, threadID string, turnI
 	}
 	decision, err := codex.ParseDecisionOutput(text)
 	if err != nil {
-		log.Printf("event=%s_decision_parse_failed run_id=%s thread=%s turn=%s err=%v", eventPrefix, runID, threadID, turnID, err)
+		slog.Error(eventPrefix+"_decision_parse_failed", "run_id", runID, "thread", threadID, "turn", turnID, "err", err)
 		return
 	}
-	log.Printf(
-		"event=%s_decision_summary run_id=%s thread=%s turn=%s action=%s content=%q",
-		eventPrefix,
-		runID,
-		threadID,
-		turnID,
-		decision.Action,
-		trimLogString(decision.Content, maxHeartbeatLogValueLen),
+	slog.Info(
+		eventPrefix+"_decision_summary",
+		"run_id", runID,
+		"thread", threadID,
+		"turn", turnID,
+		"action", decision.Action,
+		"content", trimLogString(decision.Content, maxHeartbeatLogValueLen),
 	)
 }
 
 func logTurnToolCall(eventPrefix string, runID string, threadID string, turnID string, index int, toolCall codex.MCPToolCall) {
-	log.Printf(
-		"event=%s_tool_call run_id=%s thread=%s turn=%s index=%d server=%s tool=%s status=%s arguments=%q result=%q",
-		eventPrefix,
-		runID,
-		threadID,
-		turnID,
-		index,
-		toolCall.Server,
-		toolCall.Tool,
-		toolCall.Status,
-		trimLogAny(toolCall.Arguments, maxHeartbeatLogValueLen),
-		trimLogAny(toolCall.Result, maxHeartbeatLogValueLen),
+	slog.Info(
+		eventPrefix+"_tool_call",
+		"run_id", runID,
+		"thread", threadID,
+		"turn", turnID,
+		"index", index,
+		"server", toolCall.Server,
+		"tool", toolCall.Tool,
+		"status", toolCall.Status,
+		"arguments", trimLogAny(toolCall.Arguments, maxHeartbeatLogValueLen),
+		"result", trimLogAny(toolCall.Result, maxHeartbeatLogValueLen),
 	)
 }
+
+// logTurnItems logs what the turn did outside MCP tools: commands it ran,
+// files it changed, searches and reasoning summaries.
+func logTurnItems(eventPrefix string, runID string, result codex.TurnResult) {
+	for i, command := range result.Commands {
+		exitCode := "none"
+		if command.ExitCode != nil {
+			exitCode = strconv.Itoa(*command.ExitCode)
+		}
+		slog.Info(
+			eventPrefix+"_command",
+			"run_id", runID,
+			"thread", result.ThreadID,
+			"turn", result.TurnID,
+			"index", i,
+			"status", command.Status,
+			"exit_code", exitCode,
+			"duration_ms", command.DurationMS,
+			"output_bytes", command.OutputBytes,
+			"cwd", command.CWD,
+			"command", trimLogString(command.Command, maxHeartbeatLogValueLen),
+		)
+	}
+	for i, change := range result.FileChanges {
+		slog.Info(
+			eventPrefix+"_file_change",
+			"run_id", runID,
+			"thread", result.ThreadID,
+			"turn", result.TurnID,
+			"index", i,
+			"status", change.Status,
+			"kind", change.Kind,
+			"path", change.Path,
+			"move_path", change.MovePath,
+			"diff_bytes", change.DiffBytes,
+			"added", change.AddedLines,
+			"removed", change.RemovedLines,
+		)
+	}
+	for i, search := range result.WebSearches {
+		slog.Info(eventPrefix+"_web_search", "run_id", runID, "thread", result.ThreadID, "turn", result.TurnID, "index", i, "query", trimLogString(search.Query, maxHeartbeatLogValueLen))
+	}
+	for i, reasoning := range result.Reasoning {
+		slog.Debug(eventPrefix+"_reasoning", "run_id", runID, "thread", result.ThreadID, "turn", result.TurnID, "index", i, "summary", trimLogString(reasoning.Summary, maxHeartbeatLogValueLen))
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/sigumaa/yururi/internal/config"
+	"github.com/sigumaa/yururi/internal/logging"
+)
+
+// configureLogging makes the logger built from cfg the default, so slog
+// calls and lines from the log package go through it. main calls it with an
+// empty config before the real one is loaded.
+func configureLogging(dst io.Writer, cfg config.LogConfig) (io.Closer, error) {
+	level, err := logging.ParseLevel(cfg.Level)
+	if err != nil {
+		return nil, err
+	}
+	logger, closer, err := logging.New(dst, logging.Options{
+		Format:         cfg.Format,
+		Level:          level,
+		Color:          cfg.Format != config.LogFormatJSON && shouldEnableLogColor(),
+		FilePath:       cfg.File.Path,
+		FileFormat:     cfg.File.Format,
+		FileMaxBytes:   int64(cfg.File.MaxSizeMB) << 20,
+		FileMaxBackups: cfg.File.MaxBackups,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("open log file: %w", err)
+	}
+	slog.SetDefault(logger)
+	return closer, nil
+}
+
+func shouldEnableLogColor() bool {
+	if enabled, ok := boolFromEnv("YURURI_LOG_COLOR"); ok {
+		return enabled
+	}
+	if _, disabled := os.LookupEnv("NO_COLOR"); disabled {
+		return false
+	}
+	info, err := os.Stdout.Stat()
+	if err != nil {
+		return false
+	}
+	return (info.Mode() & os.ModeCharDevice) != 0
+}
+
+func boolFromEnv(key string) (bool, bool) {
+	value, ok := os.LookupEnv(key)
+	if !ok {
+		return false, false
+	}
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "1", "true", "yes", "on":
+		return true, true
+	case "0", "false", "no", "off":
+		return false, true
+	default:
+		return false, false
+	}
+}
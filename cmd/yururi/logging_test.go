@@ -0,0 +1,59 @@
+package main
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sigumaa/yururi/internal/config"
+)
+
+func TestConfigureLogging(t *testing.T) {
+	previous := slog.Default()
+	t.Cleanup(func() { slog.SetDefault(previous) })
+	t.Setenv("YURURI_LOG_COLOR", "false")
+
+	path := filepath.Join(t.TempDir(), "yururi.log")
+	var console strings.Builder
+	closer, err := configureLogging(&console, config.LogConfig{Format: config.LogFormatText, Level: "warn", File: config.LogFileConfig{Path: path, Format: config.LogFormatJSON}})
+	if err != nil {
+		t.Fatalf("configureLogging() error = %v", err)
+	}
+	slog.Info("message_received", "run_id", "msg-1")
+	slog.Warn("outbound_retry", "channel", "c1")
+	if err := closer.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	if got := console.String(); !strings.Contains(got, "event=outbound_retry level=WARN channel=c1") || strings.Contains(got, "message_received") {
+		t.Fatalf("console = %q", got)
+	}
+	body, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	if !strings.Contains(string(body), `"event":"outbound_retry"`) {
+		t.Fatalf("file = %q", body)
+	}
+
+	if _, err := configureLogging(&console, config.LogConfig{Level: "verbose"}); err == nil {
+		t.Fatal("configureLogging(verbose) error = nil")
+	}
+}
+
+func TestBoolFromEnvValue(t *testing.T) {
+	t.Setenv("YURURI_LOG_COLOR", "true")
+	if got, ok := boolFromEnv("YURURI_LOG_COLOR"); !ok || !got {
+		t.Fatalf("boolFromEnv(true) = (%v, %v), want (true, true)", got, ok)
+	}
+	t.Setenv("YURURI_LOG_COLOR", "false")
+	if got, ok := boolFromEnv("YURURI_LOG_COLOR"); !ok || got {
+		t.Fatalf("boolFromEnv(false) = (%v, %v), want (false, true)", got, ok)
+	}
+	t.Setenv("YURURI_LOG_COLOR", "invalid")
+	if got, ok := boolFromEnv("YURURI_LOG_COLOR"); ok || got {
+		t.Fatalf("boolFromEnv(invalid) = (%v, %v), want (false, false)", got, ok)
+	}
+}
@@ -2,16 +2,21 @@ package main
 
 import (
 	"flag"
-	"log"
+	"log/slog"
 	"os"
+
+	"github.com/sigumaa/yururi/internal/config"
 )
 
 func main() {
 	configPath := flag.String("config", "runtime/config.yaml", "path to config yaml")
 	flag.Parse()
-	configureLogOutput(os.Stdout)
+	if _, err := configureLogging(os.Stdout, config.LogConfig{}); err != nil {
+		slog.Error("logging_configure_failed", "err", err)
+	}
 
 	if err := runApplication(*configPath); err != nil {
-		log.Fatalf("yururi failed: %v", err)
+		slog.Error("yururi_failed", "err", err)
+		os.Exit(1)
 	}
 }
@@ -3,13 +3,20 @@ package main
 import (
 	"context"
 	"math"
+	"os"
+	"path/filepath"
 	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/bwmarrin/discordgo"
+	"github.com/sigumaa/yururi/internal/attachment"
 	"github.com/sigumaa/yururi/internal/codex"
 	"github.com/sigumaa/yururi/internal/config"
+	"github.com/sigumaa/yururi/internal/discordx"
+	"github.com/sigumaa/yururi/internal/dispatch"
+	"github.com/sigumaa/yururi/internal/orchestrator"
 	"github.com/sigumaa/yururi/internal/prompt"
 )
 
@@ -40,6 +47,50 @@ func TestCalculateHistoryLimit(t *testing.T) {
 	}
 }
 
+func TestMessageTraits(t *testing.T) {
+	t.Parallel()
+
+	session := &discordgo.Session{State: discordgo.NewState()}
+	session.State.User = &discordgo.User{ID: "bot"}
+	cfg := config.Config{Persona: config.PersonaConfig{OwnerUserID: "owner"}}
+	m := &discordgo.MessageCreate{Message: &discordgo.Message{
+		Content:     "<@bot> これ見て",
+		Author:      &discordgo.User{ID: "owner"},
+		Mentions:    []*discordgo.User{{ID: "other"}, {ID: "bot"}},
+		Attachments: []*discordgo.MessageAttachment{{ID: "a1"}},
+	}}
+
+	current := prompt.RuntimeMessage{
+		ID:          "m3",
+		Content:     m.Content,
+		Attachments: []prompt.Attachment{{Name: "notes.txt", Kind: "text", Text: "https://example.com/a を参照"}},
+	}
+	recent := []prompt.RuntimeMessage{
+		{ID: "m0", AuthorID: "owner", Content: "古い話"},
+		{ID: "m1", AuthorID: "owner", Content: "これは何？", Attachments: []prompt.Attachment{{Name: "a.png"}}},
+		{ID: "b1", AuthorID: "bot", Content: "途中の返信？"},
+		{ID: "m2", AuthorID: "other", Content: "あれは？"},
+	}
+
+	got := messageTraits(cfg, session, m, current, nil, 1)
+	if !got.MentionsBot || !got.FromOwner || got.Attachments != 1 {
+		t.Fatalf("messageTraits() = %+v", got)
+	}
+	if want := m.Content + "\nhttps://example.com/a を参照"; got.Content != want {
+		t.Fatalf("messageTraits() content = %q, want %q", got.Content, want)
+	}
+	got = messageTraits(cfg, session, m, current, recent, 3)
+	if want := "これは何？\nあれは？\n" + m.Content + "\nhttps://example.com/a を参照"; got.Content != want {
+		t.Fatalf("messageTraits() batch content = %q, want %q", got.Content, want)
+	}
+	if got.Attachments != 2 {
+		t.Fatalf("messageTraits() batch attachments = %d, want 2", got.Attachments)
+	}
+	if got := messageTraits(cfg, nil, m, current, nil, 1); got.MentionsBot {
+		t.Fatalf("messageTraits() without session = %+v, want no bot mention", got)
+	}
+}
+
 func TestRunHeartbeatTurnCallsRuntime(t *testing.T) {
 	t.Parallel()
 
@@ -52,7 +103,7 @@ func TestRunHeartbeatTurnCallsRuntime(t *testing.T) {
 	}
 	runtime := &heartbeatRuntimeStub{}
 
-	if err := runHeartbeatTurn(context.Background(), cfg, runtime, "hb-test"); err != nil {
+	if err := runHeartbeatTurn(context.Background(), cfg, runtime, nil, "hb-test"); err != nil {
 		t.Fatalf("runHeartbeatTurn() error = %v", err)
 	}
 	if got := len(runtime.calls); got != 1 {
@@ -66,6 +117,47 @@ func TestRunHeartbeatTurnCallsRuntime(t *testing.T) {
 	}
 }
 
+type usageAccountingStub struct {
+	admitErr error
+	recorded []codex.TurnResult
+}
+
+func (s *usageAccountingStub) AdmitTurn(_ string, input codex.TurnInput) (codex.TurnInput, error) {
+	return input, s.admitErr
+}
+
+func (s *usageAccountingStub) RecordUsage(_ string, result codex.TurnResult) {
+	s.recorded = append(s.recorded, result)
+}
+
+func TestRunHeartbeatTurnRespectsUsageBudget(t *testing.T) {
+	t.Parallel()
+
+	workspaceDir := t.TempDir()
+	if err := prompt.EnsureWorkspaceInstructionFiles(workspaceDir); err != nil {
+		t.Fatalf("EnsureWorkspaceInstructionFiles() error = %v", err)
+	}
+	cfg := config.Config{Codex: config.CodexConfig{WorkspaceDir: workspaceDir}}
+
+	refused := &usageAccountingStub{admitErr: orchestrator.ErrUsageBudgetExhausted}
+	runtime := &heartbeatRuntimeStub{}
+	if err := runHeartbeatTurn(context.Background(), cfg, runtime, refused, "hb-refused"); err != nil {
+		t.Fatalf("runHeartbeatTurn() error = %v, want nil for refused turn", err)
+	}
+	if len(runtime.calls) != 0 {
+		t.Fatalf("runtime RunTurn calls = %d, want 0", len(runtime.calls))
+	}
+
+	admitted := &usageAccountingStub{}
+	runtime = &heartbeatRuntimeStub{}
+	if err := runHeartbeatTurn(context.Background(), cfg, runtime, admitted, "hb-admitted"); err != nil {
+		t.Fatalf("runHeartbeatTurn() error = %v", err)
+	}
+	if len(admitted.recorded) != 1 || admitted.recorded[0].TurnID != "turn-test" {
+		t.Fatalf("recorded usage = %#v, want the heartbeat turn", admitted.recorded)
+	}
+}
+
 func TestTrimLogString(t *testing.T) {
 	t.Parallel()
 
@@ -148,12 +240,13 @@ func TestExpandObserveChannelIDsByCategory(t *testing.T) {
 		{ID: "text-1", ParentID: "cat-a", Type: discordgo.ChannelTypeGuildText},
 		{ID: "news-1", ParentID: "cat-a", Type: discordgo.ChannelTypeGuildNews},
 		{ID: "thread-1", ParentID: "cat-a", Type: discordgo.ChannelTypeGuildPublicThread},
+		{ID: "forum-1", ParentID: "cat-a", Type: discordgo.ChannelTypeGuildForum},
 		{ID: "manual-1", ParentID: "cat-a", Type: discordgo.ChannelTypeGuildText},
 		{ID: "text-3", ParentID: "cat-c", Type: discordgo.ChannelTypeGuildText},
 	}
 
 	got := expandObserveChannelIDsByCategory(base, categories, channels)
-	want := []string{"manual-1", "manual-2", "text-1", "text-2"}
+	want := []string{"manual-1", "manual-2", "forum-1", "text-1", "text-2"}
 	if len(got) != len(want) {
 		t.Fatalf("expandObserveChannelIDsByCategory() len = %d, want %d (%v)", len(got), len(want), got)
 	}
@@ -164,6 +257,49 @@ func TestExpandObserveChannelIDsByCategory(t *testing.T) {
 	}
 }
 
+func TestBuildChannelOverrideResolver(t *testing.T) {
+	t.Parallel()
+
+	if resolver := buildChannelOverrideResolver(config.DiscordConfig{}, nil, nil); resolver != nil {
+		t.Fatal("buildChannelOverrideResolver() without overrides should be nil")
+	}
+
+	resolver := buildChannelOverrideResolver(config.DiscordConfig{
+		ChannelOverrides: map[string]config.ChannelOverrideConfig{
+			"text-1": {ReasoningEffort: "high"},
+		},
+		CategoryOverrides: map[string]config.ChannelOverrideConfig{
+			"cat-a": {Model: "gpt-5.3-codex-mini", ReasoningEffort: "low"},
+		},
+	}, []*discordgo.Channel{
+		{ID: "text-1", ParentID: "cat-a"},
+		{ID: "text-2", ParentID: "cat-a"},
+	}, func(channelID string) string {
+		if channelID == "thread-1" {
+			return "text-1"
+		}
+		return ""
+	})
+
+	tests := []struct {
+		key        string
+		wantModel  string
+		wantEffort string
+	}{
+		{key: "guild:text-1", wantModel: "gpt-5.3-codex-mini", wantEffort: "high"},
+		{key: "guild:text-2", wantModel: "gpt-5.3-codex-mini", wantEffort: "low"},
+		{key: "guild:text-3"},
+		{key: "guild:thread-1", wantModel: "gpt-5.3-codex-mini", wantEffort: "high"},
+		{key: "heartbeat"},
+	}
+	for _, tc := range tests {
+		got := resolver(tc.key)
+		if got.Model != tc.wantModel || got.ReasoningEffort != tc.wantEffort {
+			t.Fatalf("resolver(%q) = %+v, want model=%q effort=%q", tc.key, got, tc.wantModel, tc.wantEffort)
+		}
+	}
+}
+
 func TestResolveObserveTextChannelsWithoutSession(t *testing.T) {
 	t.Parallel()
 
@@ -186,6 +322,138 @@ func TestResolveObserveTextChannelsWithoutSession(t *testing.T) {
 	}
 }
 
+func TestBuildRotationPolicyUsesHeartbeatTimezone(t *testing.T) {
+	t.Parallel()
+
+	policy, err := buildRotationPolicy(config.Config{
+		Heartbeat: config.HeartbeatConfig{Timezone: "Asia/Tokyo"},
+		Session: config.SessionConfig{Rotation: config.SessionRotationConfig{
+			IdleTimeoutSec:    600,
+			MaxTurns:          40,
+			DailyRolloverHour: 4,
+		}},
+	})
+	if err != nil {
+		t.Fatalf("buildRotationPolicy() error = %v", err)
+	}
+	if policy.IdleTimeout != 10*time.Minute {
+		t.Fatalf("IdleTimeout = %s, want 10m", policy.IdleTimeout)
+	}
+	if policy.MaxTurns != 40 {
+		t.Fatalf("MaxTurns = %d, want 40", policy.MaxTurns)
+	}
+	if policy.RolloverLocation == nil || policy.RolloverLocation.String() != "Asia/Tokyo" {
+		t.Fatalf("RolloverLocation = %v, want Asia/Tokyo", policy.RolloverLocation)
+	}
+
+	if _, err := buildRotationPolicy(config.Config{
+		Heartbeat: config.HeartbeatConfig{Timezone: "Invalid/Zone"},
+		Session:   config.SessionConfig{Rotation: config.SessionRotationConfig{DailyRolloverHour: 4}},
+	}); err == nil {
+		t.Fatal("buildRotationPolicy() error = nil, want timezone error")
+	}
+}
+
+func TestIsStopReaction(t *testing.T) {
+	t.Parallel()
+
+	cfg := config.Config{
+		Discord: config.DiscordConfig{GuildID: "g1", StopReactionEmoji: "🛑", DM: config.DMConfig{Enabled: true, AllowedUserIDs: []string{"dm-user"}}},
+		Persona: config.PersonaConfig{OwnerUserID: "owner"},
+	}
+	tests := []struct {
+		name     string
+		reaction discordgo.MessageReaction
+		want     bool
+	}{
+		{name: "owner stop", reaction: discordgo.MessageReaction{UserID: "owner", GuildID: "g1", Emoji: discordgo.Emoji{Name: "🛑"}}, want: true},
+		{name: "other user", reaction: discordgo.MessageReaction{UserID: "someone", GuildID: "g1", Emoji: discordgo.Emoji{Name: "🛑"}}, want: false},
+		{name: "other emoji", reaction: discordgo.MessageReaction{UserID: "owner", GuildID: "g1", Emoji: discordgo.Emoji{Name: "👍"}}, want: false},
+		{name: "other guild", reaction: discordgo.MessageReaction{UserID: "owner", GuildID: "g2", Emoji: discordgo.Emoji{Name: "🛑"}}, want: false},
+		{name: "owner in dm", reaction: discordgo.MessageReaction{UserID: "owner", ChannelID: "d1", Emoji: discordgo.Emoji{Name: "🛑"}}, want: true},
+		{name: "allowlisted user in dm", reaction: discordgo.MessageReaction{UserID: "dm-user", ChannelID: "d1", Emoji: discordgo.Emoji{Name: "🛑"}}, want: true},
+		{name: "other user in dm", reaction: discordgo.MessageReaction{UserID: "someone", ChannelID: "d1", Emoji: discordgo.Emoji{Name: "🛑"}}, want: false},
+		{name: "allowlisted user in guild", reaction: discordgo.MessageReaction{UserID: "dm-user", GuildID: "g1", Emoji: discordgo.Emoji{Name: "🛑"}}, want: false},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			if got := isStopReaction(cfg, &tc.reaction); got != tc.want {
+				t.Fatalf("isStopReaction() = %t, want %t", got, tc.want)
+			}
+		})
+	}
+}
+
+type liveUpdaterStub struct {
+	updates []string
+}
+
+func (s *liveUpdaterStub) Update(content string) {
+	s.updates = append(s.updates, content)
+}
+
+func TestLiveTurnViewRendersTextAndActivity(t *testing.T) {
+	t.Parallel()
+
+	stub := &liveUpdaterStub{}
+	view := newLiveTurnView(stub)
+	view.Handle(codex.TurnEvent{Kind: codex.TurnEventStarted})
+	view.Handle(codex.TurnEvent{Kind: codex.TurnEventItemStarted, ItemType: "webSearch", Detail: "golang 1.24"})
+	view.Handle(codex.TurnEvent{Kind: codex.TurnEventAgentMessageDelta, Text: "調べた結果"})
+	view.Handle(codex.TurnEvent{Kind: codex.TurnEventItemCompleted, ItemType: "webSearch", Detail: "golang 1.24"})
+
+	want := []string{
+		liveThinkingText,
+		"-# 🔍 「golang 1.24」をweb検索中…",
+		"調べた結果\n-# 🔍 「golang 1.24」をweb検索中…",
+		"調べた結果",
+	}
+	if len(stub.updates) != len(want) {
+		t.Fatalf("updates = %q, want %q", stub.updates, want)
+	}
+	for i := range want {
+		if stub.updates[i] != want[i] {
+			t.Fatalf("updates[%d] = %q, want %q", i, stub.updates[i], want[i])
+		}
+	}
+}
+
+func TestLiveFinalText(t *testing.T) {
+	t.Parallel()
+
+	keep := config.LiveMessageConfig{KeepFinalText: true}
+	tests := []struct {
+		name   string
+		cfg    config.LiveMessageConfig
+		result codex.TurnResult
+		want   string
+	}{
+		{name: "disabled", cfg: config.LiveMessageConfig{}, result: codex.TurnResult{AssistantText: "answer"}, want: ""},
+		{name: "plain text", cfg: keep, result: codex.TurnResult{AssistantText: "answer"}, want: "answer"},
+		{name: "decision output", cfg: keep, result: codex.TurnResult{AssistantText: `{"action":"noop"}`}, want: ""},
+		{
+			name: "posted via tool",
+			cfg:  keep,
+			result: codex.TurnResult{
+				AssistantText: "answer",
+				ToolCalls:     []codex.MCPToolCall{{Server: "discord", Tool: "reply_message"}},
+			},
+			want: "",
+		},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			if got := liveFinalText(tc.cfg, tc.result); got != tc.want {
+				t.Fatalf("liveFinalText() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
 type heartbeatRuntimeStub struct {
 	calls  []codex.TurnInput
 	result codex.TurnResult
@@ -206,3 +474,402 @@ func (s *heartbeatRuntimeStub) RunTurn(_ context.Context, input codex.TurnInput)
 	}
 	return s.result, nil
 }
+
+type approvalMessengerStub struct {
+	sent      chan string
+	reactions []string
+}
+
+func (s *approvalMessengerStub) SendSystemMessage(_ context.Context, _ string, content string) (string, error) {
+	s.sent <- content
+	return "approval-1", nil
+}
+
+func (s *approvalMessengerStub) AddSystemReaction(_ context.Context, _ string, _ string, emoji string) error {
+	s.reactions = append(s.reactions, emoji)
+	return nil
+}
+
+func TestOwnerApproverWaitsForOwnerReaction(t *testing.T) {
+	t.Parallel()
+
+	cfg := config.Config{
+		Persona: config.PersonaConfig{OwnerUserID: "owner"},
+		Codex: config.CodexConfig{Approval: config.CodexApprovalConfig{
+			Fallback:       config.ApprovalFallbackOwner,
+			OwnerChannelID: "admin",
+		}},
+	}
+	stub := &approvalMessengerStub{sent: make(chan string, 1)}
+	approver := newOwnerApprover(stub, cfg)
+
+	type result struct {
+		approved bool
+		err      error
+	}
+	done := make(chan result, 1)
+	go func() {
+		approved, err := approver.RequestApproval(context.Background(), codex.ApprovalRequest{
+			Kind:    codex.ApprovalKindCommand,
+			Command: "make deploy",
+		})
+		done <- result{approved: approved, err: err}
+	}()
+
+	content := <-stub.sent
+	if !strings.Contains(content, "make deploy") {
+		t.Fatalf("approval message = %q, want command", content)
+	}
+	// Wait until the request is registered before reacting.
+	for {
+		approver.mu.Lock()
+		_, ok := approver.pending["approval-1"]
+		approver.mu.Unlock()
+		if ok {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	if approver.HandleReaction(&discordgo.MessageReaction{UserID: "someone", ChannelID: "admin", MessageID: "approval-1", Emoji: discordgo.Emoji{Name: "✅"}}) {
+		t.Fatal("HandleReaction(other user) = true, want false")
+	}
+	if !approver.HandleReaction(&discordgo.MessageReaction{UserID: "owner", ChannelID: "admin", MessageID: "approval-1", Emoji: discordgo.Emoji{Name: "✅"}}) {
+		t.Fatal("HandleReaction(owner) = false, want true")
+	}
+	got := <-done
+	if got.err != nil || !got.approved {
+		t.Fatalf("RequestApproval() = %t, %v, want approved", got.approved, got.err)
+	}
+}
+
+func TestOwnerApproverTimesOut(t *testing.T) {
+	t.Parallel()
+
+	cfg := config.Config{
+		Persona: config.PersonaConfig{OwnerUserID: "owner"},
+		Codex: config.CodexConfig{Approval: config.CodexApprovalConfig{
+			Fallback:       config.ApprovalFallbackOwner,
+			OwnerChannelID: "admin",
+		}},
+	}
+	approver := newOwnerApprover(&approvalMessengerStub{sent: make(chan string, 1)}, cfg)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	approved, err := approver.RequestApproval(ctx, codex.ApprovalRequest{Kind: codex.ApprovalKindFileChange, Paths: []string{"a.md"}})
+	if approved || err == nil {
+		t.Fatalf("RequestApproval() = %t, %v, want timeout error", approved, err)
+	}
+	if newOwnerApprover(nil, config.Config{}) != nil {
+		t.Fatal("newOwnerApprover() without owner fallback = non-nil, want nil")
+	}
+}
+
+type interactionResponderStub struct {
+	responses []*discordgo.InteractionResponse
+}
+
+func (s *interactionResponderStub) InteractionRespond(_ *discordgo.Interaction, resp *discordgo.InteractionResponse, _ ...discordgo.RequestOption) error {
+	s.responses = append(s.responses, resp)
+	return nil
+}
+
+type heartbeatTriggerStub struct {
+	calls int
+}
+
+func (s *heartbeatTriggerStub) RunNow() bool {
+	s.calls++
+	return s.calls == 1
+}
+
+func yururiInteraction(userID string, options ...*discordgo.ApplicationCommandInteractionDataOption) *discordgo.InteractionCreate {
+	return &discordgo.InteractionCreate{Interaction: &discordgo.Interaction{
+		Type:      discordgo.InteractionApplicationCommand,
+		GuildID:   "guild",
+		ChannelID: "channel",
+		Member:    &discordgo.Member{User: &discordgo.User{ID: userID}},
+		Data: discordgo.ApplicationCommandInteractionData{
+			Name:    yururiCommandName,
+			Options: options,
+		},
+	}}
+}
+
+func subcommandOption(names ...string) *discordgo.ApplicationCommandInteractionDataOption {
+	option := &discordgo.ApplicationCommandInteractionDataOption{Type: discordgo.ApplicationCommandOptionSubCommand, Name: names[len(names)-1]}
+	for i := len(names) - 2; i >= 0; i-- {
+		option = &discordgo.ApplicationCommandInteractionDataOption{
+			Type:    discordgo.ApplicationCommandOptionSubCommandGroup,
+			Name:    names[i],
+			Options: []*discordgo.ApplicationCommandInteractionDataOption{option},
+		}
+	}
+	return option
+}
+
+func TestCommandHandlerRestrictsToOwner(t *testing.T) {
+	t.Parallel()
+
+	var paused atomic.Bool
+	handler := &commandHandler{
+		cfg:         config.Config{Persona: config.PersonaConfig{OwnerUserID: "owner"}},
+		coordinator: orchestrator.New(nil),
+		paused:      &paused,
+	}
+	responder := &interactionResponderStub{}
+
+	handler.HandleInteraction(responder, yururiInteraction("someone", subcommandOption("pause")))
+	if paused.Load() {
+		t.Fatal("non-owner pause should be rejected")
+	}
+	handler.HandleInteraction(responder, yururiInteraction("owner", subcommandOption("pause")))
+	if !paused.Load() {
+		t.Fatal("owner pause should pause")
+	}
+	if len(responder.responses) != 2 {
+		t.Fatalf("responses = %d, want 2", len(responder.responses))
+	}
+	for _, resp := range responder.responses {
+		if resp.Data == nil || resp.Data.Flags != discordgo.MessageFlagsEphemeral {
+			t.Fatalf("response = %#v, want ephemeral", resp.Data)
+		}
+	}
+	if !strings.Contains(responder.responses[0].Data.Content, "オーナー") {
+		t.Fatalf("rejection content = %q", responder.responses[0].Data.Content)
+	}
+}
+
+func TestCommandHandlerExecute(t *testing.T) {
+	t.Parallel()
+
+	workspace := t.TempDir()
+	if err := os.WriteFile(filepath.Join(workspace, "MEMORY.md"), []byte("覚えていること\n"), 0o644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	var paused atomic.Bool
+	trigger := &heartbeatTriggerStub{}
+	handler := &commandHandler{
+		cfg:         config.Config{Codex: config.CodexConfig{WorkspaceDir: workspace}},
+		coordinator: orchestrator.New(nil),
+		heartbeat:   trigger,
+		outbound:    outboundStatsStub{Pending: 1, Sent: 5, Dropped: 2},
+		paused:      &paused,
+	}
+
+	if got := commandPath([]*discordgo.ApplicationCommandInteractionDataOption{subcommandOption("memory", "show")}); got != "memory show" {
+		t.Fatalf("commandPath() = %q, want memory show", got)
+	}
+	if got := handler.execute("memory show", "guild:channel"); got != "覚えていること" {
+		t.Fatalf("memory show = %q", got)
+	}
+	if got := handler.execute("heartbeat-now", "guild:channel"); !strings.Contains(got, "開始") {
+		t.Fatalf("first heartbeat-now = %q", got)
+	}
+	if got := handler.execute("heartbeat-now", "guild:channel"); !strings.Contains(got, "実行中") {
+		t.Fatalf("second heartbeat-now = %q", got)
+	}
+	if got := handler.execute("reset-session", "guild:channel"); !strings.Contains(got, "ありません") {
+		t.Fatalf("reset-session = %q", got)
+	}
+	paused.Store(true)
+	if got := handler.execute("heartbeat-now", "guild:channel"); !strings.Contains(got, "一時停止中") {
+		t.Fatalf("paused heartbeat-now = %q", got)
+	}
+	if trigger.calls != 2 {
+		t.Fatalf("heartbeat RunNow calls = %d, want 2", trigger.calls)
+	}
+	if got := handler.execute("status", "guild:channel"); !strings.Contains(got, "一時停止中") || !strings.Contains(got, "セッション: なし") || !strings.Contains(got, "送信キュー: 待機 1 / 送信 5") {
+		t.Fatalf("status = %q", got)
+	}
+}
+
+type outboundStatsStub discordx.OutboundStats
+
+func (s outboundStatsStub) OutboundStats() discordx.OutboundStats {
+	return discordx.OutboundStats(s)
+}
+
+func TestMessageEventInput(t *testing.T) {
+	t.Parallel()
+
+	user := &discordgo.User{ID: "u1", Username: "user"}
+	edited := &discordgo.Message{ID: "m1", GuildID: "g1", ChannelID: "c1", Content: "new", Author: user}
+	tests := []struct {
+		name       string
+		event      dispatch.MessageEvent
+		wantSkip   string
+		wantBefore string
+		wantAfter  string
+	}{
+		{
+			name:       "edit",
+			event:      dispatch.MessageEvent{Kind: dispatch.EventEdit, Message: edited, Before: &discordgo.Message{ID: "m1", Content: "old", Author: user}},
+			wantBefore: "old",
+			wantAfter:  "new",
+		},
+		{name: "edit without cache", event: dispatch.MessageEvent{Kind: dispatch.EventEdit, Message: edited}, wantAfter: "new"},
+		{
+			name:     "embed update",
+			event:    dispatch.MessageEvent{Kind: dispatch.EventEdit, Message: edited, Before: &discordgo.Message{ID: "m1", Content: "new", Author: user}},
+			wantSkip: "content_unchanged",
+		},
+		{
+			name:     "partial update",
+			event:    dispatch.MessageEvent{Kind: dispatch.EventEdit, Message: &discordgo.Message{ID: "m1", GuildID: "g1", ChannelID: "c1"}},
+			wantSkip: "missing_author",
+		},
+		{
+			name:     "own message",
+			event:    dispatch.MessageEvent{Kind: dispatch.EventEdit, Message: &discordgo.Message{ID: "m2", GuildID: "g1", ChannelID: "c1", Content: "live", Author: &discordgo.User{ID: "bot", Bot: true}}},
+			wantSkip: "own_message",
+		},
+		{
+			name:       "delete",
+			event:      dispatch.MessageEvent{Kind: dispatch.EventDelete, Message: &discordgo.Message{ID: "m1", GuildID: "g1", ChannelID: "c1"}, Before: &discordgo.Message{ID: "m1", Content: "old", Author: user}},
+			wantBefore: "old",
+		},
+		{
+			name:     "delete without cache",
+			event:    dispatch.MessageEvent{Kind: dispatch.EventDelete, Message: &discordgo.Message{ID: "m1", GuildID: "g1", ChannelID: "c1"}},
+			wantSkip: "not_cached",
+		},
+	}
+	for _, tc := range tests {
+		input, incoming, skip := messageEventInput(tc.event, "bot")
+		if skip != tc.wantSkip {
+			t.Fatalf("%s: skip = %q, want %q", tc.name, skip, tc.wantSkip)
+		}
+		if skip != "" {
+			continue
+		}
+		if input.Before != tc.wantBefore || input.After != tc.wantAfter || input.AuthorID != "u1" || input.AuthorName != "user" {
+			t.Fatalf("%s: input = %+v", tc.name, input)
+		}
+		if incoming.GuildID != "g1" || incoming.ChannelID != "c1" || incoming.AuthorID != "u1" {
+			t.Fatalf("%s: incoming = %+v", tc.name, incoming)
+		}
+	}
+}
+
+func TestMessageEventBeforeFromSessionState(t *testing.T) {
+	t.Parallel()
+
+	if discordIntents(config.DiscordConfig{})&discordgo.IntentsGuilds == 0 {
+		t.Fatal("discordIntents() lacks IntentsGuilds")
+	}
+	session, err := discordgo.New("Bot test")
+	if err != nil {
+		t.Fatalf("discordgo.New() error = %v", err)
+	}
+	session.State.MaxMessageCount = messageEventCacheLimit
+	user := &discordgo.User{ID: "u1", Username: "user"}
+	guild := &discordgo.Guild{ID: "g1", Channels: []*discordgo.Channel{{ID: "c1", GuildID: "g1", Type: discordgo.ChannelTypeGuildText}}}
+	if err := session.State.OnInterface(session, &discordgo.GuildCreate{Guild: guild}); err != nil {
+		t.Fatalf("GuildCreate error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		guildID string
+		channel string
+	}{
+		{name: "guild channel", guildID: "g1", channel: "c1"},
+		{name: "dm channel", channel: "d1"},
+	}
+	for _, tc := range tests {
+		created := &discordgo.Message{ID: "m-" + tc.channel, GuildID: tc.guildID, ChannelID: tc.channel, Content: "old", Author: user}
+		_ = session.State.OnInterface(session, &discordgo.MessageCreate{Message: created})
+		trackMessageChannel(session.State, created)
+
+		update := &discordgo.MessageUpdate{Message: &discordgo.Message{ID: created.ID, GuildID: tc.guildID, ChannelID: tc.channel, Content: "new", Author: user}}
+		if err := session.State.OnInterface(session, update); err != nil {
+			t.Fatalf("%s: MessageUpdate error = %v", tc.name, err)
+		}
+		input, _, skip := messageEventInput(dispatch.MessageEvent{Kind: dispatch.EventEdit, Message: update.Message, Before: update.BeforeUpdate}, "bot")
+		if skip != "" || input.Before != "old" || input.After != "new" {
+			t.Fatalf("%s: edit input = %+v, skip = %q", tc.name, input, skip)
+		}
+
+		deleted := &discordgo.MessageDelete{Message: &discordgo.Message{ID: created.ID, GuildID: tc.guildID, ChannelID: tc.channel}}
+		if err := session.State.OnInterface(session, deleted); err != nil {
+			t.Fatalf("%s: MessageDelete error = %v", tc.name, err)
+		}
+		input, _, skip = messageEventInput(dispatch.MessageEvent{Kind: dispatch.EventDelete, Message: deleted.Message, Before: deleted.BeforeDelete}, "bot")
+		if skip != "" || input.Before != "new" {
+			t.Fatalf("%s: delete input = %+v, skip = %q", tc.name, input, skip)
+		}
+	}
+}
+
+func TestComponentEventInput(t *testing.T) {
+	t.Parallel()
+
+	component := func(customID string, values ...string) *discordgo.InteractionCreate {
+		return &discordgo.InteractionCreate{Interaction: &discordgo.Interaction{
+			Type:      discordgo.InteractionMessageComponent,
+			GuildID:   "g1",
+			ChannelID: "c1",
+			Message:   &discordgo.Message{ID: "m1"},
+			Member:    &discordgo.Member{Nick: "しゆい", User: &discordgo.User{ID: "u1", Username: "user"}},
+			Data:      discordgo.MessageComponentInteractionData{CustomID: customID, Values: values},
+		}}
+	}
+	tests := []struct {
+		name        string
+		interaction *discordgo.InteractionCreate
+		wantOK      bool
+		wantID      string
+		wantValues  string
+	}{
+		{name: "button", interaction: component("yururi:approve"), wantOK: true, wantID: "approve"},
+		{name: "select", interaction: component("yururi:topic", "go", "rust"), wantOK: true, wantID: "topic", wantValues: "go,rust"},
+		{name: "foreign component", interaction: component("other:approve")},
+		{name: "slash command", interaction: &discordgo.InteractionCreate{Interaction: &discordgo.Interaction{Type: discordgo.InteractionApplicationCommand}}},
+		{name: "nil", interaction: nil},
+	}
+	for _, tc := range tests {
+		input, ok := componentEventInput(tc.interaction)
+		if ok != tc.wantOK {
+			t.Fatalf("%s: ok = %t, want %t", tc.name, ok, tc.wantOK)
+		}
+		if !ok {
+			continue
+		}
+		if input.CustomID != tc.wantID || strings.Join(input.Values, ",") != tc.wantValues {
+			t.Fatalf("%s: input = %+v", tc.name, input)
+		}
+		if input.GuildID != "g1" || input.ChannelID != "c1" || input.MessageID != "m1" || input.UserID != "u1" || input.UserName != "しゆい" {
+			t.Fatalf("%s: input = %+v", tc.name, input)
+		}
+	}
+}
+
+func TestToPromptMessagesListsHistoryAttachments(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "a1-photo.png"), []byte("png"), 0o644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	store := attachment.NewStore(dir, nil)
+	history := []discordx.Message{
+		{ID: "m2", AuthorID: "u1", Attachments: []discordx.Attachment{{ID: "a2", Filename: "other.png", URL: "https://cdn.example/other.png"}}},
+		{ID: "m1", AuthorID: "u1", Content: "見て", Attachments: []discordx.Attachment{{ID: "a1", Filename: "photo.png", URL: "https://cdn.example/photo.png"}}},
+	}
+
+	got := toPromptMessages(history, store)
+	if len(got) != 2 || got[0].ID != "m1" {
+		t.Fatalf("toPromptMessages() = %+v, want oldest first", got)
+	}
+	if len(got[0].Attachments) != 1 || got[0].Attachments[0].Path != filepath.Join(dir, "a1-photo.png") || got[0].Attachments[0].Kind != "image" {
+		t.Fatalf("cached attachments = %+v", got[0].Attachments)
+	}
+	if len(got[1].Attachments) != 1 || got[1].Attachments[0].Path != "" || got[1].Attachments[0].URL != "https://cdn.example/other.png" {
+		t.Fatalf("uncached attachments = %+v, want name and URL only", got[1].Attachments)
+	}
+	if withoutStore := toPromptMessages(history, nil); len(withoutStore[0].Attachments) != 1 || withoutStore[0].Attachments[0].Path != "" {
+		t.Fatalf("attachments without store = %+v", withoutStore[0].Attachments)
+	}
+}
@@ -2,13 +2,14 @@ package main
 
 import (
 	"fmt"
-	"log"
+	"log/slog"
 	"sort"
 	"strings"
 	"time"
 
 	"github.com/bwmarrin/discordgo"
 	"github.com/sigumaa/yururi/internal/config"
+	"github.com/sigumaa/yururi/internal/orchestrator"
 )
 
 func runShutdownStep(name string, timeout time.Duration, fn func()) bool {
@@ -24,17 +25,17 @@ func runShutdownStep(name string, timeout time.Duration, fn func()) bool {
 
 	if timeout <= 0 {
 		<-done
-		log.Printf("event=shutdown_step_completed step=%s latency_ms=%d", name, durationMS(time.Since(started)))
+		slog.Info("shutdown_step_completed", "step", name, "latency_ms", durationMS(time.Since(started)))
 		return false
 	}
 	timer := time.NewTimer(timeout)
 	defer timer.Stop()
 	select {
 	case <-done:
-		log.Printf("event=shutdown_step_completed step=%s latency_ms=%d", name, durationMS(time.Since(started)))
+		slog.Info("shutdown_step_completed", "step", name, "latency_ms", durationMS(time.Since(started)))
 		return false
 	case <-timer.C:
-		log.Printf("event=shutdown_step_timeout step=%s timeout_ms=%d", name, durationMS(timeout))
+		slog.Warn("shutdown_step_timeout", "step", name, "timeout_ms", durationMS(timeout))
 		return true
 	}
 }
@@ -86,7 +87,7 @@ func expandObserveChannelIDsByCategory(base []string, categoryIDs []string, chan
 		if _, ok := categorySet[strings.TrimSpace(ch.ParentID)]; !ok {
 			continue
 		}
-		if ch.Type != discordgo.ChannelTypeGuildText {
+		if ch.Type != discordgo.ChannelTypeGuildText && ch.Type != discordgo.ChannelTypeGuildForum {
 			continue
 		}
 		channelID := strings.TrimSpace(ch.ID)
@@ -104,6 +105,52 @@ func expandObserveChannelIDsByCategory(base []string, categoryIDs []string, chan
 	return append(out, discovered...)
 }
 
+// buildChannelOverrideResolver resolves per-channel codex overrides. Category
+// overrides use the channel parents listed at startup. Threads use the
+// override of the parent returned by threadParent, which may be nil.
+func buildChannelOverrideResolver(discordCfg config.DiscordConfig, channels []*discordgo.Channel, threadParent func(string) string) orchestrator.OverrideResolver {
+	if len(discordCfg.ChannelOverrides) == 0 && len(discordCfg.CategoryOverrides) == 0 {
+		return nil
+	}
+	parents := make(map[string]string, len(channels))
+	for _, ch := range channels {
+		if ch == nil || strings.TrimSpace(ch.ParentID) == "" {
+			continue
+		}
+		parents[strings.TrimSpace(ch.ID)] = strings.TrimSpace(ch.ParentID)
+	}
+	return func(channelKey string) orchestrator.ChannelOverride {
+		_, channelID := orchestrator.ParseChannelKey(channelKey)
+		if channelID == "" {
+			return orchestrator.ChannelOverride{}
+		}
+		if threadParent != nil {
+			if parentID := threadParent(channelID); parentID != "" {
+				channelID = parentID
+			}
+		}
+		override := discordCfg.ResolveOverride(channelID, parents[channelID])
+		return orchestrator.ChannelOverride{
+			Model:           override.Model,
+			ReasoningEffort: override.ReasoningEffort,
+			Sandbox:         override.Sandbox,
+			MCPServers:      override.MCPServers,
+		}
+	}
+}
+
+func listOverrideChannels(session *discordgo.Session, discordCfg config.DiscordConfig) ([]*discordgo.Channel, error) {
+	guildID := strings.TrimSpace(discordCfg.GuildID)
+	if len(discordCfg.CategoryOverrides) == 0 || session == nil || guildID == "" {
+		return nil, nil
+	}
+	channels, err := session.GuildChannels(guildID)
+	if err != nil {
+		return nil, fmt.Errorf("list guild channels: %w", err)
+	}
+	return channels, nil
+}
+
 func uniqueTrimmedValues(values []string) []string {
 	out := make([]string, 0, len(values))
 	seen := make(map[string]struct{}, len(values))
@@ -121,6 +168,54 @@ func uniqueTrimmedValues(values []string) []string {
 	return out
 }
 
+func buildRotationPolicy(cfg config.Config) (orchestrator.RotationPolicy, error) {
+	rotation := cfg.Session.Rotation
+	policy := orchestrator.RotationPolicy{
+		IdleTimeout:       time.Duration(rotation.IdleTimeoutSec) * time.Second,
+		MaxTurns:          rotation.MaxTurns,
+		MaxAge:            time.Duration(rotation.MaxAgeSec) * time.Second,
+		DailyRolloverHour: rotation.DailyRolloverHour,
+	}
+	if rotation.DailyRolloverHour < 0 {
+		return policy, nil
+	}
+	loc, err := heartbeatLocation(cfg)
+	if err != nil {
+		return orchestrator.RotationPolicy{}, fmt.Errorf("load rollover timezone: %w", err)
+	}
+	policy.RolloverLocation = loc
+	return policy, nil
+}
+
+// buildUsageBudget returns the ledger and budget for the coordinator. Usage
+// days follow heartbeat.timezone like the session rollover.
+func buildUsageBudget(cfg config.Config) (*orchestrator.UsageLedger, orchestrator.UsageBudget, error) {
+	loc, err := heartbeatLocation(cfg)
+	if err != nil {
+		return nil, orchestrator.UsageBudget{}, fmt.Errorf("load usage timezone: %w", err)
+	}
+	action := orchestrator.UsageBudgetActionRefuse
+	if cfg.Usage.ExhaustedAction == config.UsageExhaustedDowngrade {
+		action = orchestrator.UsageBudgetActionDowngrade
+	}
+	budget := orchestrator.UsageBudget{
+		DailyTokens:              cfg.Usage.DailyTokenBudget,
+		ChannelDailyTokens:       cfg.Usage.ChannelDailyTokenBudget,
+		HeartbeatDailyTokens:     cfg.Usage.HeartbeatDailyTokenBudget,
+		Action:                   action,
+		DowngradeReasoningEffort: cfg.Usage.DowngradeReasoningEffort,
+	}
+	return orchestrator.NewUsageLedger(cfg.Usage.StorePath, loc), budget, nil
+}
+
+func heartbeatLocation(cfg config.Config) (*time.Location, error) {
+	timezone := strings.TrimSpace(cfg.Heartbeat.Timezone)
+	if timezone == "" {
+		timezone = "UTC"
+	}
+	return time.LoadLocation(timezone)
+}
+
 func fallbackForLog(value string, fallback string) string {
 	v := strings.TrimSpace(value)
 	if v == "" {
@@ -10,6 +10,11 @@ type heartbeatRuntime interface {
 	RunTurn(ctx context.Context, input codex.TurnInput) (codex.TurnResult, error)
 }
 
+type usageAccounting interface {
+	AdmitTurn(scope string, input codex.TurnInput) (codex.TurnInput, error)
+	RecordUsage(scope string, result codex.TurnResult)
+}
+
 const (
 	maxHeartbeatLogValueLen = 280
 )
@@ -0,0 +1,332 @@
+package attachment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+type Kind string
+
+const (
+	KindImage Kind = "image"
+	KindText  Kind = "text"
+	KindOther Kind = "other"
+)
+
+const (
+	SkipUnsupported = "unsupported"
+	SkipTooLarge    = "too_large"
+	SkipLimit       = "limit"
+	SkipFailed      = "download_failed"
+)
+
+var (
+	imageExtensions = map[string]struct{}{".png": {}, ".jpg": {}, ".jpeg": {}, ".gif": {}, ".webp": {}}
+	textExtensions  = map[string]struct{}{".txt": {}, ".md": {}, ".go": {}, ".log": {}, ".csv": {}}
+)
+
+var errTooLarge = errors.New("attachment exceeds size limit")
+
+// Limits caps what is ingested from one message. A zero byte limit ingests
+// nothing of that kind; a zero MaxTextChars inlines text files whole.
+type Limits struct {
+	MaxPerMessage int
+	MaxImageBytes int64
+	MaxTextBytes  int64
+	MaxTextChars  int
+}
+
+// Source is an attachment as Discord reports it. Size is in bytes.
+type Source struct {
+	ID          string
+	Filename    string
+	URL         string
+	ContentType string
+	Size        int
+}
+
+// File is an ingested attachment. Path is the cached copy for images and
+// text files, Text is the inlined content of text files, and Skipped tells
+// why an attachment was not ingested.
+type File struct {
+	Source
+	Kind      Kind
+	Path      string
+	Text      string
+	Truncated bool
+	Skipped   string
+}
+
+// Store downloads attachments into dir, named by attachment ID, so the same
+// attachment is only downloaded once. With a size limit the least recently
+// used files are removed once the cache grows past it.
+type Store struct {
+	dir      string
+	client   *http.Client
+	maxBytes int64
+
+	pruneMu sync.Mutex
+}
+
+type Option func(*Store)
+
+// WithMaxCacheBytes caps the size of the cache directory. 0 keeps every file.
+func WithMaxCacheBytes(n int64) Option {
+	return func(s *Store) {
+		s.maxBytes = n
+	}
+}
+
+func NewStore(dir string, client *http.Client, opts ...Option) *Store {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	s := &Store{dir: strings.TrimSpace(dir), client: client}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(s)
+		}
+	}
+	return s
+}
+
+func Classify(src Source) Kind {
+	contentType := strings.ToLower(strings.TrimSpace(src.ContentType))
+	ext := strings.ToLower(filepath.Ext(src.Filename))
+	if _, ok := imageExtensions[ext]; ok || (ext == "" && strings.HasPrefix(contentType, "image/")) {
+		return KindImage
+	}
+	if _, ok := textExtensions[ext]; ok {
+		return KindText
+	}
+	return KindOther
+}
+
+func (s *Store) Ingest(ctx context.Context, sources []Source, limits Limits) []File {
+	files := make([]File, 0, len(sources))
+	for i, src := range sources {
+		file := File{Source: src, Kind: Classify(src)}
+		switch {
+		case i >= limits.MaxPerMessage:
+			file.Skipped = SkipLimit
+		case file.Kind == KindOther:
+			file.Skipped = SkipUnsupported
+		default:
+			s.ingest(ctx, &file, limits)
+		}
+		files = append(files, file)
+	}
+	return files
+}
+
+func (s *Store) ingest(ctx context.Context, file *File, limits Limits) {
+	maxBytes := limits.MaxImageBytes
+	if file.Kind == KindText {
+		maxBytes = limits.MaxTextBytes
+	}
+	if maxBytes <= 0 || int64(file.Size) > maxBytes {
+		file.Skipped = SkipTooLarge
+		return
+	}
+	path, err := s.fetch(ctx, file.Source, maxBytes)
+	if errors.Is(err, errTooLarge) {
+		file.Skipped = SkipTooLarge
+		return
+	}
+	if err != nil {
+		slog.Error("attachment_download_failed", "attachment", file.ID, "filename", file.Filename, "err", err)
+		file.Skipped = SkipFailed
+		return
+	}
+	file.Path = path
+	if file.Kind != KindText {
+		return
+	}
+	body, err := os.ReadFile(path)
+	if err != nil {
+		slog.Error("attachment_read_failed", "attachment", file.ID, "path", path, "err", err)
+		file.Skipped = SkipFailed
+		return
+	}
+	file.Text, file.Truncated = truncateRunes(strings.ToValidUTF8(string(body), "�"), limits.MaxTextChars)
+}
+
+// CachedPath returns the cached copy of src without downloading it.
+func (s *Store) CachedPath(src Source) (string, bool) {
+	if s == nil {
+		return "", false
+	}
+	path := s.cachePath(src)
+	if path == "" {
+		return "", false
+	}
+	if _, err := os.Stat(path); err != nil {
+		return "", false
+	}
+	return path, true
+}
+
+func (s *Store) fetch(ctx context.Context, src Source, maxBytes int64) (string, error) {
+	if path, ok := s.CachedPath(src); ok {
+		now := time.Now()
+		_ = os.Chtimes(path, now, now)
+		return path, nil
+	}
+	path := s.cachePath(src)
+	if path == "" {
+		return "", errors.New("attachment id and cache dir are required")
+	}
+	u, err := url.Parse(src.URL)
+	if err != nil || u.Scheme != "https" {
+		return "", fmt.Errorf("attachment url must be https: %q", src.URL)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("download status %d", resp.StatusCode)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxBytes+1))
+	if err != nil {
+		return "", err
+	}
+	if int64(len(body)) > maxBytes {
+		return "", errTooLarge
+	}
+	if err := os.MkdirAll(s.dir, 0o755); err != nil {
+		return "", fmt.Errorf("create attachment cache dir: %w", err)
+	}
+	tmp, err := os.CreateTemp(s.dir, ".download-*")
+	if err != nil {
+		return "", fmt.Errorf("create attachment temp file: %w", err)
+	}
+	tmpPath := tmp.Name()
+	if _, err := tmp.Write(body); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmpPath)
+		return "", fmt.Errorf("write attachment: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmpPath)
+		return "", fmt.Errorf("close attachment: %w", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		_ = os.Remove(tmpPath)
+		return "", fmt.Errorf("store attachment: %w", err)
+	}
+	slog.Info("attachment_downloaded", "attachment", src.ID, "filename", src.Filename, "bytes", len(body), "path", path)
+	s.prune(path)
+	return path, nil
+}
+
+// prune removes the oldest cached files until the cache fits maxBytes. keep
+// is the file just stored, which is never removed.
+func (s *Store) prune(keep string) {
+	if s.maxBytes <= 0 {
+		return
+	}
+	s.pruneMu.Lock()
+	defer s.pruneMu.Unlock()
+
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		slog.Error("attachment_cache_prune_failed", "dir", s.dir, "err", err)
+		return
+	}
+	type cached struct {
+		path    string
+		size    int64
+		modTime time.Time
+	}
+	var files []cached
+	var total int64
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() || strings.HasPrefix(entry.Name(), ".download-") {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		files = append(files, cached{path: filepath.Join(s.dir, entry.Name()), size: info.Size(), modTime: info.ModTime()})
+		total += info.Size()
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].modTime.Before(files[j].modTime) })
+	removed := 0
+	for _, f := range files {
+		if total <= s.maxBytes {
+			break
+		}
+		if f.path == keep {
+			continue
+		}
+		if err := os.Remove(f.path); err != nil && !os.IsNotExist(err) {
+			slog.Error("attachment_cache_prune_failed", "path", f.path, "err", err)
+			continue
+		}
+		total -= f.size
+		removed++
+	}
+	if removed > 0 {
+		slog.Info("attachment_cache_pruned", "dir", s.dir, "removed", removed, "bytes", total)
+	}
+}
+
+// cachePath names the cached copy after the attachment ID and keeps the
+// extension so the file type stays recognizable.
+func (s *Store) cachePath(src Source) string {
+	id := sanitizeName(src.ID)
+	if s.dir == "" || id == "" {
+		return ""
+	}
+	name := sanitizeName(filepath.Base(src.Filename))
+	if name == "" {
+		return filepath.Join(s.dir, id)
+	}
+	return filepath.Join(s.dir, id+"-"+name)
+}
+
+func sanitizeName(name string) string {
+	var b strings.Builder
+	for _, r := range strings.TrimSpace(name) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '-', r == '_':
+			b.WriteRune(r)
+		default:
+			b.WriteRune('_')
+		}
+	}
+	return strings.Trim(b.String(), ".")
+}
+
+func truncateRunes(text string, limit int) (string, bool) {
+	if limit <= 0 || utf8.RuneCountInString(text) <= limit {
+		return text, false
+	}
+	count := 0
+	for i := range text {
+		if count == limit {
+			return text[:i], true
+		}
+		count++
+	}
+	return text, false
+}
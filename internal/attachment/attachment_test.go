@@ -0,0 +1,147 @@
+package attachment
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestClassify(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		src  Source
+		want Kind
+	}{
+		{src: Source{Filename: "photo.PNG"}, want: KindImage},
+		{src: Source{Filename: "image", ContentType: "image/jpeg"}, want: KindImage},
+		{src: Source{Filename: "notes.md", ContentType: "text/markdown"}, want: KindText},
+		{src: Source{Filename: "main.go"}, want: KindText},
+		{src: Source{Filename: "data.csv"}, want: KindText},
+		{src: Source{Filename: "movie.mp4", ContentType: "video/mp4"}, want: KindOther},
+		{src: Source{Filename: "archive.zip"}, want: KindOther},
+	}
+	for _, tc := range tests {
+		if got := Classify(tc.src); got != tc.want {
+			t.Fatalf("Classify(%+v) = %q, want %q", tc.src, got, tc.want)
+		}
+	}
+}
+
+func TestStoreIngestDownloadsOnceAndAppliesLimits(t *testing.T) {
+	t.Parallel()
+
+	var downloads atomic.Int32
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		downloads.Add(1)
+		switch r.URL.Path {
+		case "/notes.md":
+			_, _ = w.Write([]byte("一二三四五六七八九十"))
+		case "/photo.png":
+			_, _ = w.Write([]byte("png-bytes"))
+		case "/big.png":
+			_, _ = w.Write([]byte(strings.Repeat("x", 100)))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	store := NewStore(t.TempDir(), srv.Client())
+	limits := Limits{MaxPerMessage: 4, MaxImageBytes: 50, MaxTextBytes: 100, MaxTextChars: 5}
+	sources := []Source{
+		{ID: "1", Filename: "notes.md", URL: srv.URL + "/notes.md"},
+		{ID: "2", Filename: "photo.png", URL: srv.URL + "/photo.png"},
+		{ID: "3", Filename: "big.png", URL: srv.URL + "/big.png"},
+		{ID: "4", Filename: "movie.mp4", URL: srv.URL + "/movie.mp4"},
+		{ID: "5", Filename: "extra.png", URL: srv.URL + "/photo.png"},
+	}
+	files := store.Ingest(context.Background(), sources, limits)
+	if len(files) != len(sources) {
+		t.Fatalf("Ingest() returned %d files, want %d", len(files), len(sources))
+	}
+	if files[0].Text != "一二三四五" || !files[0].Truncated || files[0].Path == "" {
+		t.Fatalf("text file = %+v", files[0])
+	}
+	if files[1].Skipped != "" || !strings.HasSuffix(files[1].Path, "2-photo.png") {
+		t.Fatalf("image file = %+v", files[1])
+	}
+	wantSkips := []string{"", "", SkipTooLarge, SkipUnsupported, SkipLimit}
+	for i, want := range wantSkips {
+		if files[i].Skipped != want {
+			t.Fatalf("files[%d].Skipped = %q, want %q", i, files[i].Skipped, want)
+		}
+	}
+	if got := downloads.Load(); got != 3 {
+		t.Fatalf("downloads = %d, want 3", got)
+	}
+
+	again := store.Ingest(context.Background(), sources[:2], limits)
+	if again[0].Path != files[0].Path || again[1].Path != files[1].Path {
+		t.Fatalf("cached paths differ: %+v vs %+v", again, files[:2])
+	}
+	if got := downloads.Load(); got != 3 {
+		t.Fatalf("downloads after replay = %d, want 3", got)
+	}
+	if path, ok := store.CachedPath(sources[1]); !ok || path != files[1].Path {
+		t.Fatalf("CachedPath() = %q, %t", path, ok)
+	}
+	if _, ok := store.CachedPath(sources[2]); ok {
+		t.Fatal("CachedPath() of an oversized download = true, want false")
+	}
+}
+
+func TestStoreIngestRejectsOversizedBeforeDownload(t *testing.T) {
+	t.Parallel()
+
+	store := NewStore(t.TempDir(), nil)
+	files := store.Ingest(context.Background(), []Source{
+		{ID: "1", Filename: "huge.png", URL: "https://cdn.example/huge.png", Size: 1 << 30},
+		{ID: "2", Filename: "plain.txt", URL: "http://cdn.example/plain.txt"},
+	}, Limits{MaxPerMessage: 2, MaxImageBytes: 1 << 20, MaxTextBytes: 1 << 20})
+	if files[0].Skipped != SkipTooLarge {
+		t.Fatalf("files[0].Skipped = %q, want %q", files[0].Skipped, SkipTooLarge)
+	}
+	if files[1].Skipped != SkipFailed {
+		t.Fatalf("files[1].Skipped = %q, want %q for a non-https url", files[1].Skipped, SkipFailed)
+	}
+}
+
+func TestStorePrunesLeastRecentlyUsed(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(strings.Repeat("x", 10)))
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	store := NewStore(dir, srv.Client(), WithMaxCacheBytes(25))
+	limits := Limits{MaxPerMessage: 1, MaxImageBytes: 100}
+	old := time.Now().Add(-time.Hour)
+	for i, id := range []string{"1", "2"} {
+		store.Ingest(context.Background(), []Source{{ID: id, Filename: "a.png", URL: srv.URL + "/a.png"}}, limits)
+		path, _ := store.CachedPath(Source{ID: id, Filename: "a.png"})
+		stamp := old.Add(time.Duration(i) * time.Minute)
+		if err := os.Chtimes(path, stamp, stamp); err != nil {
+			t.Fatalf("Chtimes() error = %v", err)
+		}
+	}
+	// Using the first file again makes the second the least recently used.
+	store.Ingest(context.Background(), []Source{{ID: "1", Filename: "a.png", URL: srv.URL + "/a.png"}}, limits)
+	store.Ingest(context.Background(), []Source{{ID: "3", Filename: "a.png", URL: srv.URL + "/a.png"}}, limits)
+
+	for id, want := range map[string]bool{"1": true, "2": false, "3": true} {
+		if _, ok := store.CachedPath(Source{ID: id, Filename: "a.png"}); ok != want {
+			t.Fatalf("CachedPath(%s) = %t, want %t", id, ok, want)
+		}
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 2 {
+		t.Fatalf("cache entries = %d, want 2", len(entries))
+	}
+}
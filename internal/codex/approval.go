@@ -0,0 +1,423 @@
+package codex
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/sigumaa/yururi/internal/config"
+)
+
+type ApprovalKind string
+
+const (
+	ApprovalKindCommand    ApprovalKind = "command"
+	ApprovalKindFileChange ApprovalKind = "file_change"
+	ApprovalKindUserInput  ApprovalKind = "user_input"
+)
+
+// ApprovalRequest is a server request from the app-server that needs a
+// yes/no answer before the turn can continue.
+type ApprovalRequest struct {
+	Kind      ApprovalKind
+	Method    string
+	ThreadID  string
+	TurnID    string
+	Command   string
+	CWD       string
+	Paths     []string
+	Reason    string
+	Questions []string
+}
+
+// Approver asks a human to decide requests that the policy leaves open.
+type Approver interface {
+	RequestApproval(ctx context.Context, req ApprovalRequest) (bool, error)
+}
+
+func WithApprover(approver Approver) ClientOption {
+	return func(c *Client) {
+		c.approver = approver
+	}
+}
+
+type approvalDecision string
+
+const (
+	approvalApprove approvalDecision = "approve"
+	approvalDeny    approvalDecision = "deny"
+	approvalOwner   approvalDecision = "owner"
+)
+
+type approvalPolicy struct {
+	allowCommands []string
+	denyCommands  []string
+	allowPaths    []string
+	workspaceDir  string
+	workspaceOnly bool
+	fallback      approvalDecision
+	ownerTimeout  time.Duration
+}
+
+func newApprovalPolicy(cfg config.CodexApprovalConfig, workspaceDir string) approvalPolicy {
+	fallback := approvalDecision(strings.ToLower(strings.TrimSpace(cfg.Fallback)))
+	switch fallback {
+	case approvalApprove, approvalDeny, approvalOwner:
+	default:
+		fallback = approvalApprove
+	}
+	allowPaths := make([]string, 0, len(cfg.AllowPaths))
+	for _, path := range cfg.AllowPaths {
+		if trimmed := strings.TrimSpace(path); trimmed != "" {
+			allowPaths = append(allowPaths, filepath.Clean(trimmed))
+		}
+	}
+	workspace := strings.TrimSpace(workspaceDir)
+	if workspace != "" {
+		workspace = filepath.Clean(workspace)
+	}
+	return approvalPolicy{
+		allowCommands: cleanPatterns(cfg.AllowCommands),
+		denyCommands:  cleanPatterns(cfg.DenyCommands),
+		allowPaths:    allowPaths,
+		workspaceDir:  workspace,
+		workspaceOnly: cfg.WorkspaceOnly,
+		fallback:      fallback,
+		ownerTimeout:  time.Duration(cfg.OwnerTimeoutSec) * time.Second,
+	}
+}
+
+// appServerPolicy is the approvalPolicy sent with thread/start. With
+// "never" the app-server asks nothing, so any configured rule needs it to
+// ask: every untrusted command when commands are listed, otherwise whenever
+// a command or change leaves the sandbox.
+func (p approvalPolicy) appServerPolicy() string {
+	switch {
+	case len(p.allowCommands) > 0 || len(p.denyCommands) > 0:
+		return "untrusted"
+	case p.fallback != approvalApprove || p.workspaceOnly || len(p.allowPaths) > 0:
+		return "on-request"
+	default:
+		return "never"
+	}
+}
+
+func (p approvalPolicy) evaluate(req ApprovalRequest) (approvalDecision, string) {
+	switch req.Kind {
+	case ApprovalKindCommand:
+		return p.evaluateCommand(req)
+	case ApprovalKindFileChange:
+		return p.evaluateFileChange(req)
+	default:
+		return p.fallback, "no rule for " + string(req.Kind)
+	}
+}
+
+func (p approvalPolicy) evaluateCommand(req ApprovalRequest) (approvalDecision, string) {
+	if p.workspaceOnly && req.CWD != "" && !p.insideWorkspace(p.resolve(req.CWD, "")) {
+		return approvalDeny, fmt.Sprintf("cwd %q is outside workspace", req.CWD)
+	}
+	command := strings.TrimSpace(req.Command)
+	if command == "" {
+		return p.fallback, "command is empty"
+	}
+	for _, pattern := range p.denyCommands {
+		if matchCommandPattern(pattern, command) {
+			return approvalDeny, fmt.Sprintf("matched deny pattern %q", pattern)
+		}
+	}
+	for _, pattern := range p.allowCommands {
+		if matchCommandPattern(pattern, command) {
+			return approvalApprove, fmt.Sprintf("matched allow pattern %q", pattern)
+		}
+	}
+	return p.fallback, "not matched by command patterns"
+}
+
+func (p approvalPolicy) evaluateFileChange(req ApprovalRequest) (approvalDecision, string) {
+	if len(req.Paths) == 0 {
+		return p.fallback, "changed paths unknown"
+	}
+	allowed := true
+	for _, raw := range req.Paths {
+		path := p.resolve(raw, req.CWD)
+		if p.workspaceOnly && !p.insideWorkspace(path) {
+			return approvalDeny, fmt.Sprintf("path %q is outside workspace", raw)
+		}
+		if !p.allowedPath(path) {
+			allowed = false
+		}
+	}
+	if allowed {
+		return approvalApprove, "all paths allowed"
+	}
+	return p.fallback, "not matched by allow_paths"
+}
+
+// allowedPath reports whether path is under allow_paths. With no allow_paths
+// configured, workspace_only makes the workspace itself the allowed prefix.
+func (p approvalPolicy) allowedPath(path string) bool {
+	if len(p.allowPaths) == 0 {
+		return p.workspaceOnly && p.workspaceDir != "" && pathWithin(p.workspaceDir, path)
+	}
+	for _, prefix := range p.allowPaths {
+		if pathWithin(prefix, path) {
+			return true
+		}
+	}
+	return false
+}
+
+// insideWorkspace is permissive when no workspace is configured, since the
+// app-server then runs in the process working directory.
+func (p approvalPolicy) insideWorkspace(path string) bool {
+	if p.workspaceDir == "" {
+		return true
+	}
+	return pathWithin(p.workspaceDir, path)
+}
+
+func (p approvalPolicy) resolve(path string, cwd string) string {
+	path = strings.TrimSpace(path)
+	if filepath.IsAbs(path) {
+		return filepath.Clean(path)
+	}
+	base := strings.TrimSpace(cwd)
+	if base == "" || !filepath.IsAbs(base) {
+		base = filepath.Join(p.workspaceDir, base)
+	}
+	return filepath.Join(base, path)
+}
+
+func pathWithin(root string, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) && !filepath.IsAbs(rel)
+}
+
+// approvalGate applies the policy and, for undecided requests, the owner.
+type approvalGate struct {
+	policy   approvalPolicy
+	approver Approver
+}
+
+func (g *approvalGate) decide(ctx context.Context, req ApprovalRequest) bool {
+	if g == nil {
+		return true
+	}
+	decision, reason := g.policy.evaluate(req)
+	if decision == approvalOwner {
+		decision, reason = g.askOwner(ctx, req)
+	}
+	slog.Info(
+		"codex_approval_decision",
+		"kind", req.Kind,
+		"method", req.Method,
+		"thread", req.ThreadID,
+		"turn", req.TurnID,
+		"decision", decision,
+		"reason", reason,
+		"command", trimLogString(req.Command, 200),
+		"cwd", req.CWD,
+		"paths", req.Paths,
+	)
+	return decision == approvalApprove
+}
+
+func (g *approvalGate) askOwner(ctx context.Context, req ApprovalRequest) (approvalDecision, string) {
+	if g.approver == nil {
+		return approvalDeny, "owner approval is not available"
+	}
+	if g.policy.ownerTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, g.policy.ownerTimeout)
+		defer cancel()
+	}
+	approved, err := g.approver.RequestApproval(ctx, req)
+	switch {
+	case err != nil:
+		return approvalDeny, "owner approval failed: " + err.Error()
+	case approved:
+		return approvalApprove, "approved by owner"
+	default:
+		return approvalDeny, "denied by owner"
+	}
+}
+
+// approvalRequestFromMessage extracts the fields the policy looks at. Paths
+// for v2 file change approvals come from the item announced in item/started.
+func approvalRequestFromMessage(msg rpcMessage, itemPaths func(itemID string) []string) (ApprovalRequest, bool) {
+	params := decodeNotificationParams(msg.Params)
+	req := ApprovalRequest{
+		Method:   msg.Method,
+		ThreadID: notificationThreadID(params),
+		TurnID:   notificationTurnID(params),
+		CWD:      stringParam(params, "cwd"),
+		Reason:   stringParam(params, "reason"),
+	}
+	switch normalizeMethod(msg.Method) {
+	case "item_command_execution_request_approval", "exec_command_approval":
+		req.Kind = ApprovalKindCommand
+		req.Command = commandParam(params["command"])
+	case "item_file_change_request_approval", "apply_patch_approval":
+		req.Kind = ApprovalKindFileChange
+		req.Paths = fileChangePaths(params)
+		if itemPaths != nil {
+			req.Paths = appendUnique(req.Paths, itemPaths(stringParam(params, "itemId"))...)
+		}
+		if root := stringParam(params, "grantRoot"); root != "" {
+			req.Paths = appendUnique(req.Paths, root)
+		}
+	case "item_tool_request_user_input":
+		req.Kind = ApprovalKindUserInput
+		req.Questions = questionTexts(params)
+	default:
+		return ApprovalRequest{}, false
+	}
+	return req, true
+}
+
+func commandParam(raw any) string {
+	switch v := raw.(type) {
+	case string:
+		return strings.TrimSpace(v)
+	case []any:
+		parts := make([]string, 0, len(v))
+		for _, part := range v {
+			if s, ok := part.(string); ok {
+				parts = append(parts, s)
+			}
+		}
+		return strings.TrimSpace(strings.Join(parts, " "))
+	default:
+		return ""
+	}
+}
+
+// fileChangePaths reads paths from the legacy fileChanges map and from a
+// changes list of {path} objects.
+func fileChangePaths(params map[string]any) []string {
+	var paths []string
+	if changes, ok := params["fileChanges"].(map[string]any); ok {
+		for path := range changes {
+			paths = appendUnique(paths, path)
+		}
+	}
+	if changes, ok := params["changes"].([]any); ok {
+		for _, raw := range changes {
+			change, ok := raw.(map[string]any)
+			if !ok {
+				continue
+			}
+			if path, _ := change["path"].(string); strings.TrimSpace(path) != "" {
+				paths = appendUnique(paths, path)
+			}
+		}
+	}
+	return paths
+}
+
+func questionTexts(params map[string]any) []string {
+	questions, _ := params["questions"].([]any)
+	out := make([]string, 0, len(questions))
+	for _, raw := range questions {
+		q, ok := raw.(map[string]any)
+		if !ok {
+			continue
+		}
+		for _, key := range []string{"question", "header", "id"} {
+			if text, _ := q[key].(string); strings.TrimSpace(text) != "" {
+				out = append(out, strings.TrimSpace(text))
+				break
+			}
+		}
+	}
+	return out
+}
+
+func stringParam(params map[string]any, key string) string {
+	value, _ := params[key].(string)
+	return strings.TrimSpace(value)
+}
+
+func appendUnique(values []string, more ...string) []string {
+	for _, v := range more {
+		v = strings.TrimSpace(v)
+		if v == "" {
+			continue
+		}
+		seen := false
+		for _, existing := range values {
+			if existing == v {
+				seen = true
+				break
+			}
+		}
+		if !seen {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+func cleanPatterns(patterns []string) []string {
+	out := make([]string, 0, len(patterns))
+	for _, pattern := range patterns {
+		if trimmed := strings.TrimSpace(pattern); trimmed != "" {
+			out = append(out, trimmed)
+		}
+	}
+	return out
+}
+
+// matchCommandPattern matches value against a pattern where '*' matches any
+// run of characters, including spaces and slashes.
+func matchCommandPattern(pattern string, value string) bool {
+	p := strings.TrimSpace(pattern)
+	v := strings.TrimSpace(value)
+	if p == "" {
+		return false
+	}
+
+	patternIndex := 0
+	valueIndex := 0
+	starPatternIndex := -1
+	starValueIndex := 0
+
+	for valueIndex < len(v) {
+		if patternIndex < len(p) && p[patternIndex] == '*' {
+			starPatternIndex = patternIndex
+			starValueIndex = valueIndex
+			patternIndex++
+			continue
+		}
+		if patternIndex < len(p) && p[patternIndex] == v[valueIndex] {
+			patternIndex++
+			valueIndex++
+			continue
+		}
+		if starPatternIndex == -1 {
+			return false
+		}
+		patternIndex = starPatternIndex + 1
+		starValueIndex++
+		valueIndex = starValueIndex
+	}
+	for patternIndex < len(p) && p[patternIndex] == '*' {
+		patternIndex++
+	}
+	return patternIndex == len(p)
+}
+
+func trimLogString(text string, maxLen int) string {
+	runes := []rune(strings.TrimSpace(text))
+	if len(runes) <= maxLen {
+		return string(runes)
+	}
+	return string(runes[:maxLen-3]) + "..."
+}
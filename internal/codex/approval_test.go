@@ -0,0 +1,290 @@
+package codex
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/sigumaa/yururi/internal/config"
+)
+
+func TestApprovalPolicyEvaluate(t *testing.T) {
+	t.Parallel()
+
+	policy := newApprovalPolicy(config.CodexApprovalConfig{
+		AllowCommands: []string{"git status*", "ls *"},
+		DenyCommands:  []string{"rm -rf *", "*sudo *"},
+		AllowPaths:    []string{"/work/notes"},
+		WorkspaceOnly: true,
+		Fallback:      "owner",
+	}, "/work")
+
+	tests := []struct {
+		name string
+		req  ApprovalRequest
+		want approvalDecision
+	}{
+		{
+			name: "allowed command",
+			req:  ApprovalRequest{Kind: ApprovalKindCommand, Command: "git status --short"},
+			want: approvalApprove,
+		},
+		{
+			name: "deny wins over allow",
+			req:  ApprovalRequest{Kind: ApprovalKindCommand, Command: "ls && sudo reboot"},
+			want: approvalDeny,
+		},
+		{
+			name: "unmatched command falls back",
+			req:  ApprovalRequest{Kind: ApprovalKindCommand, Command: "go test ./..."},
+			want: approvalOwner,
+		},
+		{
+			name: "cwd outside workspace",
+			req:  ApprovalRequest{Kind: ApprovalKindCommand, Command: "git status", CWD: "/etc"},
+			want: approvalDeny,
+		},
+		{
+			name: "file change under allow path",
+			req:  ApprovalRequest{Kind: ApprovalKindFileChange, Paths: []string{"notes/today.md", "/work/notes/a.md"}},
+			want: approvalApprove,
+		},
+		{
+			name: "file change in workspace but not allow path",
+			req:  ApprovalRequest{Kind: ApprovalKindFileChange, Paths: []string{"AGENTS.md"}},
+			want: approvalOwner,
+		},
+		{
+			name: "file change escaping workspace",
+			req:  ApprovalRequest{Kind: ApprovalKindFileChange, Paths: []string{"notes/../../etc/passwd"}},
+			want: approvalDeny,
+		},
+		{
+			name: "file change with unknown paths",
+			req:  ApprovalRequest{Kind: ApprovalKindFileChange},
+			want: approvalOwner,
+		},
+		{
+			name: "user input uses fallback",
+			req:  ApprovalRequest{Kind: ApprovalKindUserInput},
+			want: approvalOwner,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, reason := policy.evaluate(tc.req)
+			if got != tc.want {
+				t.Fatalf("evaluate() = %s (%s), want %s", got, reason, tc.want)
+			}
+		})
+	}
+}
+
+func TestApprovalPolicyWorkspaceIsDefaultAllowPath(t *testing.T) {
+	t.Parallel()
+
+	policy := newApprovalPolicy(config.CodexApprovalConfig{WorkspaceOnly: true, Fallback: "deny"}, "/work")
+	if got, _ := policy.evaluate(ApprovalRequest{Kind: ApprovalKindFileChange, Paths: []string{"memory/today.md"}}); got != approvalApprove {
+		t.Fatalf("evaluate(inside workspace) = %s, want approve", got)
+	}
+	if got, _ := policy.evaluate(ApprovalRequest{Kind: ApprovalKindFileChange, Paths: []string{"/tmp/x"}}); got != approvalDeny {
+		t.Fatalf("evaluate(outside workspace) = %s, want deny", got)
+	}
+}
+
+type fakeApprover struct {
+	approved bool
+	err      error
+	calls    int
+}
+
+func (f *fakeApprover) RequestApproval(_ context.Context, _ ApprovalRequest) (bool, error) {
+	f.calls++
+	return f.approved, f.err
+}
+
+func TestApprovalPolicyAppServerPolicy(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		cfg  config.CodexApprovalConfig
+		want string
+	}{
+		{name: "no rules", cfg: config.CodexApprovalConfig{Fallback: config.ApprovalFallbackApprove}, want: "never"},
+		{name: "workspace only", cfg: config.CodexApprovalConfig{WorkspaceOnly: true, Fallback: config.ApprovalFallbackApprove}, want: "on-request"},
+		{name: "owner fallback", cfg: config.CodexApprovalConfig{Fallback: config.ApprovalFallbackOwner}, want: "on-request"},
+		{name: "allow paths", cfg: config.CodexApprovalConfig{AllowPaths: []string{"notes"}}, want: "on-request"},
+		{name: "command rules", cfg: config.CodexApprovalConfig{DenyCommands: []string{"rm *"}, Fallback: config.ApprovalFallbackOwner}, want: "untrusted"},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			if got := newApprovalPolicy(tc.cfg, "/work").appServerPolicy(); got != tc.want {
+				t.Fatalf("appServerPolicy() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestApprovalGateAsksOwnerOnlyForUndecidedRequests(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		approver  *fakeApprover
+		command   string
+		want      bool
+		wantCalls int
+	}{
+		{name: "owner approves", approver: &fakeApprover{approved: true}, command: "make", want: true, wantCalls: 1},
+		{name: "owner denies", approver: &fakeApprover{}, command: "make", want: false, wantCalls: 1},
+		{name: "owner unreachable", approver: &fakeApprover{err: errors.New("timeout")}, command: "make", want: false, wantCalls: 1},
+		{name: "policy decides", approver: &fakeApprover{approved: true}, command: "rm -rf /", want: false, wantCalls: 0},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			gate := &approvalGate{
+				policy:   newApprovalPolicy(config.CodexApprovalConfig{DenyCommands: []string{"rm -rf *"}, Fallback: "owner"}, ""),
+				approver: tc.approver,
+			}
+			got := gate.decide(context.Background(), ApprovalRequest{Kind: ApprovalKindCommand, Command: tc.command})
+			if got != tc.want {
+				t.Fatalf("decide() = %t, want %t", got, tc.want)
+			}
+			if tc.approver.calls != tc.wantCalls {
+				t.Fatalf("approver calls = %d, want %d", tc.approver.calls, tc.wantCalls)
+			}
+		})
+	}
+}
+
+func TestApprovalGateWithoutApproverDeniesOwnerRequests(t *testing.T) {
+	t.Parallel()
+
+	gate := &approvalGate{policy: newApprovalPolicy(config.CodexApprovalConfig{Fallback: "owner"}, "")}
+	if gate.decide(context.Background(), ApprovalRequest{Kind: ApprovalKindCommand, Command: "make"}) {
+		t.Fatal("decide() = true, want false without approver")
+	}
+}
+
+func TestApprovalRequestFromMessage(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		method    string
+		params    string
+		itemPaths []string
+		want      ApprovalRequest
+	}{
+		{
+			name:   "v2 command",
+			method: "item/commandExecution/requestApproval",
+			params: `{"threadId":"th","turnId":"tu","command":"git status","cwd":"/work"}`,
+			want:   ApprovalRequest{Kind: ApprovalKindCommand, ThreadID: "th", TurnID: "tu", Command: "git status", CWD: "/work"},
+		},
+		{
+			name:   "legacy command argv",
+			method: "execCommandApproval",
+			params: `{"conversationId":"th","command":["bash","-lc","ls"]}`,
+			want:   ApprovalRequest{Kind: ApprovalKindCommand, ThreadID: "th", Command: "bash -lc ls"},
+		},
+		{
+			name:      "v2 file change uses tracked item",
+			method:    "item/fileChange/requestApproval",
+			params:    `{"threadId":"th","itemId":"item-1","grantRoot":"/work/notes"}`,
+			itemPaths: []string{"notes/a.md"},
+			want:      ApprovalRequest{Kind: ApprovalKindFileChange, ThreadID: "th", Paths: []string{"notes/a.md", "/work/notes"}},
+		},
+		{
+			name:   "legacy patch",
+			method: "applyPatchApproval",
+			params: `{"fileChanges":{"/work/a.md":{}}}`,
+			want:   ApprovalRequest{Kind: ApprovalKindFileChange, Paths: []string{"/work/a.md"}},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			msg := rpcMessage{ID: json.RawMessage(`1`), Method: tc.method, Params: json.RawMessage(tc.params)}
+			got, ok := approvalRequestFromMessage(msg, func(string) []string { return tc.itemPaths })
+			if !ok {
+				t.Fatal("approvalRequestFromMessage() ok = false, want true")
+			}
+			if got.Kind != tc.want.Kind || got.ThreadID != tc.want.ThreadID || got.TurnID != tc.want.TurnID ||
+				got.Command != tc.want.Command || got.CWD != tc.want.CWD || !equalStrings(got.Paths, tc.want.Paths) {
+				t.Fatalf("approvalRequestFromMessage() = %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+
+	if _, ok := approvalRequestFromMessage(rpcMessage{Method: "unknown/request"}, nil); ok {
+		t.Fatal("approvalRequestFromMessage(unknown) ok = true, want false")
+	}
+}
+
+func TestServerRequestResponseDeclinesDeniedRequests(t *testing.T) {
+	t.Parallel()
+
+	gate := &approvalGate{policy: newApprovalPolicy(config.CodexApprovalConfig{Fallback: "deny"}, "")}
+	result, rpcErr := serverRequestResponse(context.Background(), rpcMessage{
+		Method: "item/commandExecution/requestApproval",
+		Params: json.RawMessage(`{"command":"make"}`),
+	}, gate, nil)
+	if rpcErr != nil {
+		t.Fatalf("serverRequestResponse() rpcErr = %#v", rpcErr)
+	}
+	if decision := result.(map[string]any)["decision"]; decision != "decline" {
+		t.Fatalf("decision = %#v, want decline", decision)
+	}
+
+	result, _ = serverRequestResponse(context.Background(), rpcMessage{
+		Method: "item/tool/requestUserInput",
+		Params: json.RawMessage(`{"questions":[{"id":"q1","options":[{"label":"Accept"},{"label":"Decline"}]}]}`),
+	}, gate, nil)
+	answers := result.(map[string]any)["answers"].(map[string]any)
+	if got := answers["q1"].(map[string]any)["answers"].([]string)[0]; got != "Decline" {
+		t.Fatalf("user input answer = %q, want Decline", got)
+	}
+}
+
+func TestMatchCommandPattern(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		pattern string
+		value   string
+		want    bool
+	}{
+		{pattern: "git *", value: "git log --oneline", want: true},
+		{pattern: "git *", value: "gitk", want: false},
+		{pattern: "*/bin/rm *", value: "/usr/bin/rm -f a", want: true},
+		{pattern: "ls", value: "ls -la", want: false},
+		{pattern: "", value: "ls", want: false},
+	}
+	for _, tc := range tests {
+		if got := matchCommandPattern(tc.pattern, tc.value); got != tc.want {
+			t.Fatalf("matchCommandPattern(%q, %q) = %t, want %t", tc.pattern, tc.value, got, tc.want)
+		}
+	}
+}
+
+func equalStrings(a []string, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -5,23 +5,27 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
+	"log/slog"
 	"os"
 	"os/exec"
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 	"unicode"
 
 	"github.com/sigumaa/yururi/internal/config"
 )
 
+const initRequestID = 1
+
 const (
-	initRequestID   = 1
-	threadRequestID = 2
-	turnRequestID   = 3
+	turnInterruptGrace = 10 * time.Second
+	defaultSandbox     = "workspace-write"
 )
 
+var errTurnNotInterrupted = errors.New("turn did not stop after interrupt")
+
 type Client struct {
 	command         string
 	args            []string
@@ -31,23 +35,29 @@ type Client struct {
 	homeDir         string
 	mcpURL          string
 	mcpServers      map[string]config.CodexMCPServerConfig
+	persistThreads  bool
+	approver        Approver
+	approvals       *approvalGate
 
-	mu      sync.Mutex
-	session *appServerSession
-}
-
-type appServerSession struct {
-	cmd           *exec.Cmd
-	stdin         io.WriteCloser
-	enc           *json.Encoder
-	dec           *json.Decoder
-	nextRequestID int
+	mu       sync.Mutex
+	slots    []*appServerSlot
+	affinity map[string]threadBinding
 }
 
 type TurnInput struct {
 	BaseInstructions      string
 	DeveloperInstructions string
 	UserPrompt            string
+	OnEvent               StreamHandler
+	// ReasoningEffort overrides codex.reasoning_effort for turn/start.
+	ReasoningEffort string
+	// Model, Sandbox and MCPServers override the client configuration for
+	// threads started or resumed with this input.
+	Model      string
+	Sandbox    string
+	MCPServers map[string]config.CodexMCPServerConfig
+	// Images are local image files sent with the prompt.
+	Images []string
 }
 
 type TurnResult struct {
@@ -57,6 +67,11 @@ type TurnResult struct {
 	AssistantText string
 	ErrorMessage  string
 	ToolCalls     []MCPToolCall
+	Commands      []CommandExecution
+	FileChanges   []FileChange
+	WebSearches   []WebSearch
+	Reasoning     []Reasoning
+	Usage         TokenUsage
 }
 
 type MCPToolCall struct {
@@ -80,9 +95,17 @@ type rpcError struct {
 	Message string `json:"message"`
 }
 
-func NewClient(cfg config.CodexConfig, mcpURL string) *Client {
+type ClientOption func(*Client)
+
+func WithPersistentThreads(enabled bool) ClientOption {
+	return func(c *Client) {
+		c.persistThreads = enabled
+	}
+}
+
+func NewClient(cfg config.CodexConfig, mcpURL string, opts ...ClientOption) *Client {
 	args := append([]string(nil), cfg.Args...)
-	return &Client{
+	c := &Client{
 		command:         cfg.Command,
 		args:            args,
 		model:           cfg.Model,
@@ -91,87 +114,178 @@ func NewClient(cfg config.CodexConfig, mcpURL string) *Client {
 		homeDir:         cfg.HomeDir,
 		mcpURL:          strings.TrimSpace(mcpURL),
 		mcpServers:      copyMCPServers(cfg.MCPServers),
+		affinity:        map[string]threadBinding{},
+	}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(c)
+		}
+	}
+	c.approvals = &approvalGate{
+		policy:   newApprovalPolicy(cfg.Approval, cfg.WorkspaceDir),
+		approver: c.approver,
 	}
+	c.slots = newSlots(c, cfg.PoolSize)
+	return c
 }
 
 func (c *Client) RunTurn(ctx context.Context, input TurnInput) (TurnResult, error) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
+	slot := c.acquireSlot(nil)
+	defer slot.release()
 
 	var result TurnResult
-	err := c.runWithSessionRetryLocked(ctx, func() error {
-		threadID, err := c.startThreadLocked(input)
+	err := slot.runWithSession(ctx, func(session *appServerSession) error {
+		threadID, err := slot.startThread(ctx, session, input)
 		if err != nil {
 			return err
 		}
-		result, err = c.startTurnLocked(threadID, input.UserPrompt)
+		result, err = slot.startTurn(ctx, session, threadID, input)
 		return err
 	})
 	if err != nil {
-		return TurnResult{}, err
+		return result, err
 	}
 	return result, nil
 }
 
 func (c *Client) StartThread(ctx context.Context, input TurnInput) (string, error) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
+	slot := c.acquireSlot(nil)
+	defer slot.release()
 
 	var threadID string
-	err := c.runWithSessionRetryLocked(ctx, func() error {
+	var generation uint64
+	err := slot.runWithSession(ctx, func(session *appServerSession) error {
 		var err error
-		threadID, err = c.startThreadLocked(input)
+		threadID, err = slot.startThread(ctx, session, input)
+		generation = session.generation
 		return err
 	})
 	if err != nil {
 		return "", err
 	}
+	c.bindThread(threadID, slot, generation)
 	return threadID, nil
 }
 
-func (c *Client) StartTurn(ctx context.Context, threadID string, prompt string) (TurnResult, error) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
+func (c *Client) ResumeThread(ctx context.Context, threadID string, input TurnInput) (string, error) {
+	binding, _ := c.bindingForThread(threadID)
+	slot := c.acquireSlot(binding.slot)
+	defer slot.release()
+
+	var resumedThreadID string
+	var generation uint64
+	err := slot.runWithSession(ctx, func(session *appServerSession) error {
+		var err error
+		resumedThreadID, err = slot.resumeThread(ctx, session, threadID, input)
+		generation = session.generation
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+	c.bindThread(resumedThreadID, slot, generation)
+	return resumedThreadID, nil
+}
+
+func (c *Client) StartTurn(ctx context.Context, threadID string, input TurnInput) (TurnResult, error) {
+	binding, bound := c.bindingForThread(threadID)
+	slot := c.acquireSlot(binding.slot)
+	defer slot.release()
 
 	var result TurnResult
-	err := c.runWithSessionRetryLocked(ctx, func() error {
+	var generation uint64
+	err := slot.runWithSession(ctx, func(session *appServerSession) error {
+		if err := slot.reloadThread(ctx, session, threadID, input, binding, bound); err != nil {
+			return err
+		}
 		var err error
-		result, err = c.startTurnLocked(threadID, prompt)
+		result, err = slot.startTurn(ctx, session, threadID, input)
+		generation = session.generation
 		return err
 	})
 	if err != nil {
-		return TurnResult{}, err
+		return result, err
 	}
+	c.bindThread(threadID, slot, generation)
 	return result, nil
 }
 
-func (c *Client) SteerTurn(ctx context.Context, threadID string, expectedTurnID string, prompt string) (TurnResult, error) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
+func (c *Client) SteerTurn(ctx context.Context, threadID string, expectedTurnID string, input TurnInput) (TurnResult, error) {
+	binding, bound := c.bindingForThread(threadID)
+	slot := c.acquireSlot(binding.slot)
+	defer slot.release()
 
 	var result TurnResult
-	err := c.runWithSessionRetryLocked(ctx, func() error {
+	var generation uint64
+	err := slot.runWithSession(ctx, func(session *appServerSession) error {
+		if err := slot.reloadThread(ctx, session, threadID, input, binding, bound); err != nil {
+			return err
+		}
 		var err error
-		result, err = c.steerTurnLocked(threadID, expectedTurnID, prompt)
+		result, err = slot.steerTurn(ctx, session, threadID, expectedTurnID, input)
+		generation = session.generation
 		return err
 	})
 	if err != nil {
-		return TurnResult{}, err
+		return result, err
 	}
+	c.bindThread(threadID, slot, generation)
 	return result, nil
 }
 
+// AppendTurnInput adds prompt to a turn that is still running without
+// waiting for the turn to finish.
+func (c *Client) AppendTurnInput(ctx context.Context, threadID string, turnID string, prompt string) error {
+	threadID = strings.TrimSpace(threadID)
+	turnID = strings.TrimSpace(turnID)
+	if threadID == "" || turnID == "" {
+		return errors.New("thread id and turn id are required")
+	}
+	binding, _ := c.bindingForThread(threadID)
+	slot := c.acquireSlot(binding.slot)
+	defer slot.release()
+
+	return slot.runWithSession(ctx, func(session *appServerSession) error {
+		resp, err := session.call(ctx, "turn/steer", turnSteerParams(threadID, turnID, turnInput(prompt, nil)))
+		if err != nil {
+			return fmt.Errorf("read turn/steer response: %w", err)
+		}
+		return rpcCallError("turn/steer", resp.Error)
+	})
+}
+
+func (c *Client) turnModel(input TurnInput) string {
+	if model := strings.TrimSpace(input.Model); model != "" {
+		return model
+	}
+	return c.model
+}
+
+func (c *Client) turnEffort(input TurnInput) string {
+	if effort := strings.TrimSpace(input.ReasoningEffort); effort != "" {
+		return effort
+	}
+	return c.reasoningEffort
+}
+
 func (c *Client) Close() {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-	c.stopSessionLocked()
+	for _, slot := range c.slots {
+		slot.mu.Lock()
+		slot.stopSessionLocked()
+		slot.mu.Unlock()
+	}
 }
 
-func (c *Client) ensureSessionLocked() error {
-	if c.session != nil {
-		return nil
+func (s *appServerSlot) ensureSessionLocked() error {
+	if s.session != nil {
+		if !s.session.hasExited() {
+			return nil
+		}
+		slog.Info("codex_process_exited", "slot", s.index, "err", s.session.waitErr)
+		s.stopSessionLocked()
 	}
 
+	c := s.client
 	cmd := exec.Command(c.command, c.args...)
 	if c.workspaceDir != "" {
 		cmd.Dir = c.workspaceDir
@@ -194,48 +308,57 @@ func (c *Client) ensureSessionLocked() error {
 	if err := cmd.Start(); err != nil {
 		return fmt.Errorf("start codex: %w", err)
 	}
-	go io.Copy(io.Discard, stderr)
 
-	dec := json.NewDecoder(stdout)
-	dec.UseNumber()
-	c.session = &appServerSession{
-		cmd:           cmd,
-		stdin:         stdin,
-		enc:           json.NewEncoder(stdin),
-		dec:           dec,
-		nextRequestID: initRequestID,
-	}
+	s.generation++
+	session := newAppServerSession(cmd, stdin, stdout, stderr, s.index, s.generation, c.approvals)
+	s.session = session
 
-	initID := c.nextRequestIDLocked()
-	if err := sendRequest(c.session.enc, initID, "initialize", map[string]any{
+	initResp, err := session.call(context.Background(), "initialize", map[string]any{
 		"capabilities": nil,
 		"clientInfo": map[string]any{
 			"name":    "yururi",
 			"version": "phase-full",
 		},
-	}); err != nil {
-		c.stopSessionLocked()
-		return err
-	}
-	initResp, err := readUntilResponse(c.session.dec, c.session.enc, initID, nil)
+	})
 	if err != nil {
-		c.stopSessionLocked()
-		return fmt.Errorf("read initialize response: %w", err)
+		s.stopSessionLocked()
+		return session.withStderr(fmt.Errorf("read initialize response: %w", err))
 	}
 	if initResp.Error != nil {
-		c.stopSessionLocked()
-		return rpcCallError("initialize", initResp.Error)
+		s.stopSessionLocked()
+		return session.withStderr(rpcCallError("initialize", initResp.Error))
 	}
 
-	if err := sendNotification(c.session.enc, "initialized", map[string]any{}); err != nil {
-		c.stopSessionLocked()
-		return err
+	if err := session.notify("initialized", map[string]any{}); err != nil {
+		s.stopSessionLocked()
+		return session.withStderr(err)
 	}
 
 	return nil
 }
 
-func (c *Client) runWithSessionRetryLocked(ctx context.Context, run func() error) error {
+func (s *appServerSlot) currentSession() (*appServerSession, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.ensureSessionLocked(); err != nil {
+		return nil, err
+	}
+	return s.session, nil
+}
+
+func (s *appServerSlot) discardSession(session *appServerSession) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.session == session {
+		s.stopSessionLocked()
+	}
+}
+
+// runWithSession runs fn against the slot's session, restarting the process
+// and retrying once when the session itself fails. JSON-RPC errors and
+// interrupted turns leave the session running for other turns; a turn that
+// ignored its interrupt takes the process down with it.
+func (s *appServerSlot) runWithSession(ctx context.Context, fn func(*appServerSession) error) error {
 	if ctx == nil {
 		ctx = context.Background()
 	}
@@ -245,34 +368,74 @@ func (c *Client) runWithSessionRetryLocked(ctx context.Context, run func() error
 		if err := ctx.Err(); err != nil {
 			return err
 		}
-		if err := c.ensureSessionLocked(); err != nil {
+		session, err := s.currentSession()
+		if err != nil {
 			return err
 		}
-		if err := run(); err == nil {
+		err = fn(session)
+		if err == nil {
 			return nil
-		} else {
-			lastErr = err
-			c.stopSessionLocked()
 		}
+		if errors.Is(err, errTurnNotInterrupted) {
+			s.discardSession(session)
+			return session.withStderr(err)
+		}
+		if ctx.Err() != nil || !isSessionFailure(err) {
+			return err
+		}
+		lastErr = session.withStderr(err)
+		slog.Error("codex_session_failed", "slot", s.index, "generation", session.generation, "attempt", attempt+1, "err", lastErr)
+		s.discardSession(session)
 	}
 	return lastErr
 }
 
-func (c *Client) startThreadLocked(input TurnInput) (string, error) {
-	if c.session == nil {
-		return "", errors.New("codex session is not initialized")
+// reloadThread resumes a persistent thread on session unless it was last used
+// by the same process. Unbound threads (restored from the session store or
+// pruned from affinity) are resumed as well, since the chosen slot has never
+// loaded them.
+func (s *appServerSlot) reloadThread(ctx context.Context, session *appServerSession, threadID string, input TurnInput, binding threadBinding, bound bool) error {
+	if !s.client.persistThreads || (bound && binding.generation == session.generation) {
+		return nil
 	}
+	reason := "process_replaced"
+	if !bound {
+		reason = "unbound"
+	}
+	if _, err := s.resumeThread(ctx, session, threadID, input); err != nil {
+		return fmt.Errorf("reload thread (%s): %w", reason, err)
+	}
+	slog.Info("codex_thread_reloaded", "slot", s.index, "thread", threadID, "reason", reason)
+	return nil
+}
 
-	threadReqID := c.nextRequestIDLocked()
-	if err := sendRequest(c.session.enc, threadReqID, "thread/start", threadStartParams(input, c.model, c.workspaceDir, c.reasoningEffort, c.mcpURL, c.mcpServers)); err != nil {
-		return "", err
+func (s *appServerSlot) startThread(ctx context.Context, session *appServerSession, input TurnInput) (string, error) {
+	c := s.client
+	params := threadStartParams(input, c.model, c.workspaceDir, c.reasoningEffort, c.mcpURL, c.mcpServers)
+	params["ephemeral"] = !c.persistThreads
+	params["approvalPolicy"] = c.approvals.policy.appServerPolicy()
+	return threadRequest(ctx, session, "thread/start", params)
+}
+
+func (s *appServerSlot) resumeThread(ctx context.Context, session *appServerSession, threadID string, input TurnInput) (string, error) {
+	threadID = strings.TrimSpace(threadID)
+	if threadID == "" {
+		return "", errors.New("thread id is required")
 	}
-	threadResp, err := readUntilResponse(c.session.dec, c.session.enc, threadReqID, nil)
+
+	c := s.client
+	params := threadResumeParams(threadID, input, c.model, c.workspaceDir, c.reasoningEffort, c.mcpURL, c.mcpServers)
+	params["approvalPolicy"] = c.approvals.policy.appServerPolicy()
+	return threadRequest(ctx, session, "thread/resume", params)
+}
+
+func threadRequest(ctx context.Context, session *appServerSession, method string, params map[string]any) (string, error) {
+	threadResp, err := session.call(ctx, method, params)
 	if err != nil {
-		return "", fmt.Errorf("read thread/start response: %w", err)
+		return "", fmt.Errorf("read %s response: %w", method, err)
 	}
 	if threadResp.Error != nil {
-		return "", rpcCallError("thread/start", threadResp.Error)
+		return "", rpcCallError(method, threadResp.Error)
 	}
 	threadID, err := extractThreadID(threadResp.Result)
 	if err != nil {
@@ -281,21 +444,18 @@ func (c *Client) startThreadLocked(input TurnInput) (string, error) {
 	return threadID, nil
 }
 
-func (c *Client) startTurnLocked(threadID string, prompt string) (TurnResult, error) {
+func (s *appServerSlot) startTurn(ctx context.Context, session *appServerSession, threadID string, input TurnInput) (TurnResult, error) {
 	threadID = strings.TrimSpace(threadID)
 	if threadID == "" {
 		return TurnResult{}, errors.New("thread id is required")
 	}
 
-	result, err := c.runTurnRequestLocked("turn/start", turnStartParams(threadID, prompt))
-	if err != nil {
-		return TurnResult{}, err
-	}
+	result, err := runTurnRequest(ctx, session, "turn/start", threadID, turnStartParams(threadID, turnInput(input.UserPrompt, input.Images), s.client.turnModel(input), s.client.turnEffort(input)), input.OnEvent)
 	result.ThreadID = threadID
-	return result, nil
+	return result, err
 }
 
-func (c *Client) steerTurnLocked(threadID string, expectedTurnID string, prompt string) (TurnResult, error) {
+func (s *appServerSlot) steerTurn(ctx context.Context, session *appServerSession, threadID string, expectedTurnID string, input TurnInput) (TurnResult, error) {
 	threadID = strings.TrimSpace(threadID)
 	if threadID == "" {
 		return TurnResult{}, errors.New("thread id is required")
@@ -305,84 +465,109 @@ func (c *Client) steerTurnLocked(threadID string, expectedTurnID string, prompt
 		return TurnResult{}, errors.New("expected turn id is required")
 	}
 
-	result, err := c.runTurnRequestLocked("turn/steer", turnSteerParams(threadID, expectedTurnID, prompt))
-	if err != nil {
-		return TurnResult{}, err
-	}
+	result, err := runTurnRequest(ctx, session, "turn/steer", threadID, turnSteerParams(threadID, expectedTurnID, turnInput(input.UserPrompt, input.Images)), input.OnEvent)
 	result.ThreadID = threadID
-	return result, nil
+	return result, err
 }
 
-func (c *Client) runTurnRequestLocked(method string, params map[string]any) (TurnResult, error) {
-	if c.session == nil {
-		return TurnResult{}, errors.New("codex session is not initialized")
-	}
-
-	turnReqID := c.nextRequestIDLocked()
-	if err := sendRequest(c.session.enc, turnReqID, method, params); err != nil {
-		return TurnResult{}, err
-	}
-
-	aggregator := newTurnAggregator()
-	onNotification := func(msg rpcMessage) {
-		method := normalizeMethod(msg.Method)
-		params := decodeNotificationParams(msg.Params)
-		aggregator.consume(method, params)
-	}
-
-	turnResp, err := readUntilResponse(c.session.dec, c.session.enc, turnReqID, onNotification)
+// runTurnRequest returns what the turn produced so far along with an error,
+// so the usage of interrupted turns is still recorded.
+func runTurnRequest(ctx context.Context, session *appServerSession, method string, threadID string, params map[string]any, onEvent StreamHandler) (TurnResult, error) {
+	sub := session.subscribe(threadID)
+	defer session.unsubscribe(sub)
+
+	// The turn request itself is not abandoned on cancellation: the server may
+	// already be running the turn, and its ID is needed to interrupt it.
+	callCtx, cancelCall := context.WithTimeout(context.WithoutCancel(ctx), turnInterruptGrace)
+	turnResp, err := session.call(callCtx, method, params)
+	cancelCall()
 	if err != nil {
 		return TurnResult{}, fmt.Errorf("read %s response: %w", method, err)
 	}
 	if turnResp.Error != nil {
 		return TurnResult{}, rpcCallError(method, turnResp.Error)
 	}
+
+	aggregator := newTurnAggregator(threadID, onEvent)
 	turnID, _ := extractTurnID(turnResp.Result)
-	aggregator.turnID = turnID
+	sub.setTurnID(turnID)
+	aggregator.started(turnID)
 
 	for !aggregator.Completed() {
-		msg, err := readOneMessage(c.session.dec)
-		if err != nil {
-			return TurnResult{}, fmt.Errorf("wait turn/completed: %w", err)
+		if ctx.Err() != nil {
+			err := interruptTurn(session, sub, aggregator, threadID, ctx.Err())
+			return aggregator.result(), err
 		}
-		if msg.Method != "" && len(msg.ID) > 0 {
-			if err := handleServerRequest(c.session.enc, msg); err != nil {
-				return TurnResult{}, fmt.Errorf("handle server request: %w", err)
+		msg, err := session.next(ctx, sub)
+		if err != nil {
+			if ctx.Err() != nil {
+				continue
 			}
-			continue
-		}
-		if msg.Method != "" {
-			onNotification(msg)
+			return aggregator.result(), fmt.Errorf("wait turn/completed: %w", err)
 		}
+		aggregator.consume(normalizeMethod(msg.Method), decodeNotificationParams(msg.Params))
 	}
+	return aggregator.result(), nil
+}
 
+func (a *turnAggregator) result() TurnResult {
 	return TurnResult{
-		TurnID:        aggregator.turnID,
-		Status:        aggregator.status,
-		AssistantText: aggregator.FinalText(),
-		ErrorMessage:  aggregator.errorMessage,
-		ToolCalls:     aggregator.toolCalls,
-	}, nil
+		TurnID:        a.turnID,
+		Status:        a.status,
+		AssistantText: a.FinalText(),
+		ErrorMessage:  a.errorMessage,
+		ToolCalls:     a.toolCalls,
+		Commands:      a.commands,
+		FileChanges:   a.fileChanges,
+		WebSearches:   a.webSearches,
+		Reasoning:     a.reasoning,
+		Usage:         a.tokenUsage(),
+	}
 }
 
-func (c *Client) nextRequestIDLocked() int {
-	id := c.session.nextRequestID
-	c.session.nextRequestID++
-	return id
+// tokenUsage prefers the v2 notifications. The server sends the legacy
+// token_count event for the same model calls, so the two are never added.
+func (a *turnAggregator) tokenUsage() TokenUsage {
+	if !a.usage.IsZero() {
+		return a.usage
+	}
+	return a.legacyUsage
 }
 
-func (c *Client) stopSessionLocked() {
-	if c.session == nil {
-		return
+// interruptTurn asks the server to stop a cancelled turn and waits up to
+// turnInterruptGrace for turn/completed. If the turn does not stop, the
+// returned error tells runWithSession to kill the process.
+func interruptTurn(session *appServerSession, sub *turnSubscription, aggregator *turnAggregator, threadID string, cause error) error {
+	graceCtx, cancel := context.WithTimeout(context.Background(), turnInterruptGrace)
+	defer cancel()
+
+	turnID := aggregator.turnID
+	resp, err := session.call(graceCtx, "turn/interrupt", turnInterruptParams(threadID, turnID))
+	switch {
+	case err != nil:
+		slog.Error("codex_turn_interrupt_failed", "thread", threadID, "turn", turnID, "err", err)
+	case resp.Error != nil:
+		slog.Error("codex_turn_interrupt_failed", "thread", threadID, "turn", turnID, "err", rpcCallError("turn/interrupt", resp.Error))
 	}
-	_ = c.session.stdin.Close()
-	if c.session.cmd != nil && c.session.cmd.Process != nil {
-		_ = c.session.cmd.Process.Kill()
+
+	for !aggregator.Completed() {
+		msg, err := session.next(graceCtx, sub)
+		if err != nil {
+			slog.Warn("codex_turn_interrupt_timeout", "thread", threadID, "turn", turnID, "grace_ms", turnInterruptGrace.Milliseconds())
+			return fmt.Errorf("turn %s did not stop after interrupt: %w", turnID, errors.Join(errTurnNotInterrupted, cause))
+		}
+		aggregator.consume(normalizeMethod(msg.Method), decodeNotificationParams(msg.Params))
 	}
-	if c.session.cmd != nil {
-		_ = c.session.cmd.Wait()
+	slog.Info("codex_turn_interrupted", "thread", threadID, "turn", turnID, "status", aggregator.status)
+	return fmt.Errorf("turn interrupted: %w", cause)
+}
+
+func (s *appServerSlot) stopSessionLocked() {
+	if s.session == nil {
+		return
 	}
-	c.session = nil
+	s.session.close()
+	s.session = nil
 }
 
 func sendRequest(enc *json.Encoder, id int, method string, params any) error {
@@ -437,69 +622,44 @@ func sendNotification(enc *json.Encoder, method string, params any) error {
 	return nil
 }
 
-func readUntilResponse(dec *json.Decoder, enc *json.Encoder, id int, onNotification func(rpcMessage)) (rpcMessage, error) {
-	for {
-		msg, err := readOneMessage(dec)
-		if err != nil {
-			return rpcMessage{}, err
-		}
-
-		if msg.Method != "" && len(msg.ID) > 0 {
-			if err := handleServerRequest(enc, msg); err != nil {
-				return rpcMessage{}, err
-			}
-			continue
-		}
-
-		if msg.Method != "" && len(msg.ID) == 0 {
-			if onNotification != nil {
-				onNotification(msg)
-			}
-			continue
-		}
-
-		if len(msg.ID) == 0 {
-			continue
-		}
-		msgID, err := parseID(msg.ID)
-		if err != nil {
-			continue
-		}
-		if msgID == id {
-			return msg, nil
+// serverRequestResponse answers approval and user-input requests from the
+// app-server according to the approval gate.
+func serverRequestResponse(ctx context.Context, msg rpcMessage, gate *approvalGate, itemPaths func(string) []string) (any, *rpcError) {
+	req, ok := approvalRequestFromMessage(msg, itemPaths)
+	if !ok {
+		return nil, &rpcError{Code: -32601, Message: "unsupported server request: " + msg.Method}
+	}
+	approved := gate.decide(ctx, req)
+	if req.Kind != ApprovalKindUserInput {
+		if approved {
+			return map[string]any{"decision": "approve"}, nil
 		}
+		return map[string]any{"decision": "decline"}, nil
 	}
-}
 
-func handleServerRequest(enc *json.Encoder, msg rpcMessage) error {
-	method := normalizeMethod(msg.Method)
-	switch method {
-	case "item_command_execution_request_approval", "item_file_change_request_approval", "exec_command_approval", "apply_patch_approval":
-		return sendResponse(enc, msg.ID, map[string]any{"decision": "approve"}, nil)
-	case "item_tool_request_user_input":
-		answers := map[string]any{}
-		params := decodeNotificationParams(msg.Params)
-		questionsRaw, ok := getValueAtPath(params, "questions")
-		if ok {
-			if questions, castOK := questionsRaw.([]any); castOK {
-				for _, q := range questions {
-					obj, ok := q.(map[string]any)
-					if !ok {
-						continue
-					}
-					qid, _ := obj["id"].(string)
-					if strings.TrimSpace(qid) == "" {
-						continue
-					}
-					label := pickOptionLabel(obj)
-					answers[qid] = map[string]any{"answers": []string{label}}
+	answers := map[string]any{}
+	params := decodeNotificationParams(msg.Params)
+	questionsRaw, ok := getValueAtPath(params, "questions")
+	if ok {
+		if questions, castOK := questionsRaw.([]any); castOK {
+			for _, q := range questions {
+				obj, ok := q.(map[string]any)
+				if !ok {
+					continue
+				}
+				qid, _ := obj["id"].(string)
+				if strings.TrimSpace(qid) == "" {
+					continue
 				}
+				label := pickOptionLabel(obj)
+				if !approved {
+					label = pickDeclineLabel(obj)
+				}
+				answers[qid] = map[string]any{"answers": []string{label}}
 			}
 		}
-		return sendResponse(enc, msg.ID, map[string]any{"answers": answers}, nil)
-	default:
-		return sendResponse(enc, msg.ID, nil, &rpcError{Code: -32601, Message: "unsupported server request: " + msg.Method})
 	}
+	return map[string]any{"answers": answers}, nil
 }
 
 func pickOptionLabel(question map[string]any) string {
@@ -551,6 +711,28 @@ func pickOptionLabel(question map[string]any) string {
 	return best
 }
 
+// pickDeclineLabel returns the first negative option, or "" when the
+// question offers none.
+func pickDeclineLabel(question map[string]any) string {
+	options, _ := question["options"].([]any)
+	for _, raw := range options {
+		option, ok := raw.(map[string]any)
+		if !ok {
+			continue
+		}
+		label, _ := option["label"].(string)
+		lowered := strings.ToLower(strings.TrimSpace(label))
+		if strings.Contains(lowered, "decline") ||
+			strings.Contains(lowered, "cancel") ||
+			strings.Contains(lowered, "reject") ||
+			strings.Contains(lowered, "deny") ||
+			lowered == "no" {
+			return label
+		}
+	}
+	return ""
+}
+
 func readOneMessage(dec *json.Decoder) (rpcMessage, error) {
 	var msg rpcMessage
 	if err := dec.Decode(&msg); err != nil {
@@ -632,6 +814,8 @@ func getFirstNonEmptyStringAtPaths(params map[string]any, paths ...[]string) str
 }
 
 type turnAggregator struct {
+	threadID          string
+	onEvent           StreamHandler
 	deltaBuilder      strings.Builder
 	agentMessageText  string
 	completed         bool
@@ -639,21 +823,56 @@ type turnAggregator struct {
 	errorMessage      string
 	turnID            string
 	toolCalls         []MCPToolCall
+	commands          []CommandExecution
+	fileChanges       []FileChange
+	webSearches       []WebSearch
+	reasoning         []Reasoning
+	usage             TokenUsage
+	legacyUsage       TokenUsage
 	turnCompletedText string
 }
 
-func newTurnAggregator() *turnAggregator {
-	return &turnAggregator{}
+func newTurnAggregator(threadID string, onEvent StreamHandler) *turnAggregator {
+	return &turnAggregator{
+		threadID: strings.TrimSpace(threadID),
+		onEvent:  onEvent,
+	}
+}
+
+func (a *turnAggregator) started(turnID string) {
+	if strings.TrimSpace(turnID) != "" {
+		a.turnID = turnID
+	}
+	a.emit(TurnEvent{Kind: TurnEventStarted})
+}
+
+func (a *turnAggregator) emit(event TurnEvent) {
+	if a.onEvent == nil {
+		return
+	}
+	event.ThreadID = a.threadID
+	event.TurnID = a.turnID
+	a.onEvent(event)
 }
 
 func (a *turnAggregator) consume(method string, params map[string]any) {
 	switch {
 	case isAgentMessageDeltaMethod(method):
 		a.consumeAgentMessageDelta(params)
+	case isItemStartedMethod(method):
+		a.consumeItemStarted(params)
 	case isItemCompletedMethod(method):
 		a.consumeItemCompleted(params)
 	case isTurnCompletedMethod(method):
 		a.consumeTurnCompleted(params)
+	case method == "thread_token_usage_updated":
+		if usage, ok := lastTokenUsage(params); ok {
+			a.usage = a.usage.Add(usage)
+		}
+	case method == "codex_event_token_count":
+		if usage, ok := lastTokenUsage(params); ok {
+			a.legacyUsage = a.legacyUsage.Add(usage)
+		}
 	case method == "error":
 		a.consumeError(params)
 	}
@@ -665,14 +884,19 @@ func (a *turnAggregator) consumeAgentMessageDelta(params map[string]any) {
 		return
 	}
 	a.deltaBuilder.WriteString(delta)
+	a.emit(TurnEvent{Kind: TurnEventAgentMessageDelta, Delta: delta, Text: a.deltaBuilder.String()})
 }
 
-func (a *turnAggregator) consumeItemCompleted(params map[string]any) {
-	itemRaw, ok := getValueAtPath(params, "item")
+func (a *turnAggregator) consumeItemStarted(params map[string]any) {
+	item, ok := itemFromParams(params)
 	if !ok {
 		return
 	}
-	item, ok := itemRaw.(map[string]any)
+	a.emit(itemEvent(TurnEventItemStarted, item))
+}
+
+func (a *turnAggregator) consumeItemCompleted(params map[string]any) {
+	item, ok := itemFromParams(params)
 	if !ok {
 		return
 	}
@@ -692,11 +916,25 @@ func (a *turnAggregator) consumeItemCompleted(params map[string]any) {
 		call.Result = item["result"]
 		call.Status = normalizeToolStatus(item["status"])
 		a.toolCalls = append(a.toolCalls, call)
+	case "commandexecution":
+		a.commands = append(a.commands, commandExecutionFromItem(item))
+	case "filechange":
+		a.fileChanges = append(a.fileChanges, fileChangesFromItem(item)...)
+	case "websearch":
+		a.webSearches = append(a.webSearches, webSearchFromItem(item))
+	case "reasoning":
+		if reasoning, ok := reasoningFromItem(item); ok {
+			a.reasoning = append(a.reasoning, reasoning)
+		}
 	}
+	a.emit(itemEvent(TurnEventItemCompleted, item))
 }
 
 func (a *turnAggregator) consumeTurnCompleted(params map[string]any) {
 	a.completed = true
+	defer func() {
+		a.emit(TurnEvent{Kind: TurnEventCompleted, Status: a.status, Text: a.FinalText()})
+	}()
 	if id, ok := getStringAtPath(params, "turn", "id"); ok && strings.TrimSpace(id) != "" {
 		a.turnID = id
 	}
@@ -840,6 +1078,10 @@ func isAgentMessageDeltaMethod(method string) bool {
 	return strings.Contains(method, "agent_message_delta")
 }
 
+func isItemStartedMethod(method string) bool {
+	return method == "item_started"
+}
+
 func isItemCompletedMethod(method string) bool {
 	return method == "item_completed"
 }
@@ -849,9 +1091,29 @@ func isTurnCompletedMethod(method string) bool {
 }
 
 func threadStartParams(input TurnInput, model string, cwd string, reasoningEffort string, mcpURL string, extraMCPServers map[string]config.CodexMCPServerConfig) map[string]any {
+	if override := strings.TrimSpace(input.Model); override != "" {
+		model = override
+	}
+	if override := strings.TrimSpace(input.ReasoningEffort); override != "" {
+		reasoningEffort = override
+	}
+	sandbox := defaultSandbox
+	if override := strings.TrimSpace(input.Sandbox); override != "" {
+		sandbox = override
+	}
+	if len(input.MCPServers) > 0 {
+		merged := copyMCPServers(extraMCPServers)
+		if merged == nil {
+			merged = map[string]config.CodexMCPServerConfig{}
+		}
+		for name, server := range input.MCPServers {
+			merged[name] = server
+		}
+		extraMCPServers = merged
+	}
 	params := map[string]any{
 		"approvalPolicy":         "never",
-		"sandbox":                "workspace-write",
+		"sandbox":                sandbox,
 		"baseInstructions":       input.BaseInstructions,
 		"developerInstructions":  input.DeveloperInstructions,
 		"ephemeral":              true,
@@ -880,6 +1142,14 @@ func threadStartParams(input TurnInput, model string, cwd string, reasoningEffor
 	return params
 }
 
+func threadResumeParams(threadID string, input TurnInput, model string, cwd string, reasoningEffort string, mcpURL string, extraMCPServers map[string]config.CodexMCPServerConfig) map[string]any {
+	params := threadStartParams(input, model, cwd, reasoningEffort, mcpURL, extraMCPServers)
+	delete(params, "ephemeral")
+	delete(params, "experimentalRawEvents")
+	params["threadId"] = threadID
+	return params
+}
+
 func buildMCPServersConfig(discordMCPURL string, extraMCPServers map[string]config.CodexMCPServerConfig) map[string]any {
 	mcpServers := map[string]any{}
 	if discordMCPURL != "" {
@@ -956,27 +1226,51 @@ func copyMCPServers(src map[string]config.CodexMCPServerConfig) map[string]confi
 	return dst
 }
 
-func turnStartParams(threadID string, prompt string) map[string]any {
-	return map[string]any{
+// turnStartParams always carries the model and effort when known, because a
+// turn/start override sticks to the thread for later turns.
+func turnStartParams(threadID string, input []map[string]any, model string, effort string) map[string]any {
+	params := map[string]any{
 		"threadId": threadID,
-		"input":    turnInput(prompt),
+		"input":    input,
+	}
+	if model = strings.TrimSpace(model); model != "" {
+		params["model"] = model
+	}
+	if effort = strings.TrimSpace(effort); effort != "" {
+		params["effort"] = effort
 	}
+	return params
 }
 
-func turnSteerParams(threadID string, expectedTurnID string, prompt string) map[string]any {
+func turnSteerParams(threadID string, expectedTurnID string, input []map[string]any) map[string]any {
 	return map[string]any{
 		"threadId":       threadID,
 		"expectedTurnId": expectedTurnID,
-		"input":          turnInput(prompt),
+		"input":          input,
+	}
+}
+
+func turnInterruptParams(threadID string, turnID string) map[string]any {
+	return map[string]any{
+		"threadId": threadID,
+		"turnId":   turnID,
 	}
 }
 
-func turnInput(prompt string) []map[string]any {
-	return []map[string]any{{
+// turnInput sends the prompt followed by images as localImage items, which
+// the app-server reads from disk.
+func turnInput(prompt string, images []string) []map[string]any {
+	input := []map[string]any{{
 		"type":          "text",
 		"text":          prompt,
 		"text_elements": []any{},
 	}}
+	for _, path := range images {
+		if path = strings.TrimSpace(path); path != "" {
+			input = append(input, map[string]any{"type": "localImage", "path": path})
+		}
+	}
+	return input
 }
 
 func extractThreadID(raw json.RawMessage) (string, error) {
@@ -1098,9 +1392,32 @@ func upsertEnv(env []string, key string, value string) []string {
 	return env
 }
 
+type callError struct {
+	method  string
+	code    int
+	message string
+}
+
+func (e *callError) Error() string {
+	return fmt.Sprintf("%s failed: code=%d message=%s", e.method, e.code, e.message)
+}
+
 func rpcCallError(method string, err *rpcError) error {
 	if err == nil {
 		return nil
 	}
-	return fmt.Errorf("%s failed: code=%d message=%s", method, err.Code, err.Message)
+	return &callError{method: method, code: err.Code, message: err.Message}
+}
+
+// isSessionFailure reports whether err means the app-server process is no
+// longer usable, as opposed to a request the server rejected.
+func isSessionFailure(err error) bool {
+	if err == nil {
+		return false
+	}
+	var rejected *callError
+	if errors.As(err, &rejected) {
+		return false
+	}
+	return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
 }
@@ -3,6 +3,8 @@ package codex
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"os"
 	"strings"
 	"testing"
@@ -11,6 +13,13 @@ import (
 	"github.com/sigumaa/yururi/internal/config"
 )
 
+// The mock scenarios expect the session counter's IDs for the first thread
+// and turn requests after initialize.
+const (
+	threadRequestID = initRequestID + 1
+	turnRequestID   = initRequestID + 2
+)
+
 func TestNormalizeMethod(t *testing.T) {
 	t.Parallel()
 
@@ -120,6 +129,54 @@ func TestThreadStartParamsIncludesExtraMCPServers(t *testing.T) {
 	}
 }
 
+func TestThreadStartParamsAppliesInputOverrides(t *testing.T) {
+	t.Parallel()
+
+	input := TurnInput{
+		Model:           "gpt-5.3-codex-mini",
+		ReasoningEffort: "high",
+		Sandbox:         "read-only",
+		MCPServers: map[string]config.CodexMCPServerConfig{
+			"notes": {URL: "http://127.0.0.1:40000/mcp"},
+		},
+	}
+	params := threadStartParams(input, "gpt-5.3-codex", "/tmp/work", "medium", "http://127.0.0.1:39393/mcp", map[string]config.CodexMCPServerConfig{
+		"search": {URL: "http://127.0.0.1:40001/mcp"},
+	})
+
+	if params["model"] != "gpt-5.3-codex-mini" {
+		t.Fatalf("model = %#v, want override", params["model"])
+	}
+	if params["sandbox"] != "read-only" {
+		t.Fatalf("sandbox = %#v, want read-only", params["sandbox"])
+	}
+	configValue, _ := params["config"].(map[string]any)
+	if got, _ := configValue["model_reasoning_effort"].(string); got != "high" {
+		t.Fatalf("model_reasoning_effort = %q, want high", got)
+	}
+	mcpServers, _ := configValue["mcp_servers"].(map[string]any)
+	for _, name := range []string{"discord", "search", "notes"} {
+		if _, ok := mcpServers[name]; !ok {
+			t.Fatalf("mcp_servers missing %q: %#v", name, mcpServers)
+		}
+	}
+}
+
+func TestThreadResumeParamsTargetsThread(t *testing.T) {
+	t.Parallel()
+
+	params := threadResumeParams("thread-9", TurnInput{BaseInstructions: "base"}, "gpt-5.3-codex", "/tmp/work", "medium", "http://127.0.0.1:39393/mcp", nil)
+	if params["threadId"] != "thread-9" {
+		t.Fatalf("threadResumeParams threadId = %#v, want thread-9", params["threadId"])
+	}
+	if _, ok := params["ephemeral"]; ok {
+		t.Fatalf("threadResumeParams ephemeral should be absent: %#v", params["ephemeral"])
+	}
+	if params["baseInstructions"] != "base" {
+		t.Fatalf("threadResumeParams baseInstructions = %#v, want base", params["baseInstructions"])
+	}
+}
+
 func TestRunTurnReturnsAssistantText(t *testing.T) {
 	t.Setenv("YURURI_MOCK_CODEX_HELPER", "1")
 	workspaceDir := t.TempDir()
@@ -201,6 +258,7 @@ func TestRunTurnHandlesApprovalRequest(t *testing.T) {
 		ReasoningEffort: "medium",
 		WorkspaceDir:    workspaceDir,
 		HomeDir:         homeDir,
+		Approval:        config.CodexApprovalConfig{WorkspaceOnly: true, Fallback: config.ApprovalFallbackApprove},
 	}, "http://127.0.0.1:39393/mcp")
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
@@ -222,7 +280,7 @@ func TestRunTurnHandlesApprovalRequest(t *testing.T) {
 func TestTurnSteerParamsIncludesExpectedTurnID(t *testing.T) {
 	t.Parallel()
 
-	params := turnSteerParams("thread-1", "turn-1", "follow up")
+	params := turnSteerParams("thread-1", "turn-1", turnInput("follow up", nil))
 	if params["threadId"] != "thread-1" {
 		t.Fatalf("turnSteerParams threadId = %#v, want thread-1", params["threadId"])
 	}
@@ -241,6 +299,46 @@ func TestTurnSteerParamsIncludesExpectedTurnID(t *testing.T) {
 	}
 }
 
+func TestTurnStartParamsModelAndEffort(t *testing.T) {
+	t.Parallel()
+
+	client := &Client{model: "gpt-5.3-codex", reasoningEffort: "medium"}
+	tests := []struct {
+		name       string
+		input      TurnInput
+		wantModel  string
+		wantEffort string
+	}{
+		{name: "configured", input: TurnInput{}, wantModel: "gpt-5.3-codex", wantEffort: "medium"},
+		{name: "override", input: TurnInput{Model: "gpt-5.3-codex-mini", ReasoningEffort: "low"}, wantModel: "gpt-5.3-codex-mini", wantEffort: "low"},
+	}
+	for _, tc := range tests {
+		params := turnStartParams("thread-1", turnInput("hi", nil), client.turnModel(tc.input), client.turnEffort(tc.input))
+		if params["model"] != tc.wantModel || params["effort"] != tc.wantEffort {
+			t.Fatalf("%s: model/effort = %#v/%#v, want %q/%q", tc.name, params["model"], params["effort"], tc.wantModel, tc.wantEffort)
+		}
+	}
+	params := turnStartParams("thread-1", turnInput("hi", nil), "", "")
+	if _, ok := params["effort"]; ok {
+		t.Fatal("turnStartParams() without effort set effort")
+	}
+	if _, ok := params["model"]; ok {
+		t.Fatal("turnStartParams() without model set model")
+	}
+}
+
+func TestTurnInputAppendsLocalImages(t *testing.T) {
+	t.Parallel()
+
+	input := turnInput("look", []string{"/work/.yururi/attachments/1-a.png", " "})
+	if len(input) != 2 {
+		t.Fatalf("turnInput() = %#v, want text and one image", input)
+	}
+	if input[1]["type"] != "localImage" || input[1]["path"] != "/work/.yururi/attachments/1-a.png" {
+		t.Fatalf("turnInput()[1] = %#v", input[1])
+	}
+}
+
 func TestStartThreadStartTurnAndSteerTurn(t *testing.T) {
 	t.Setenv("YURURI_MOCK_CODEX_HELPER", "1")
 	workspaceDir := t.TempDir()
@@ -269,7 +367,7 @@ func TestStartThreadStartTurnAndSteerTurn(t *testing.T) {
 		t.Fatalf("StartThread() thread id = %q, want thread-1", threadID)
 	}
 
-	startResult, err := client.StartTurn(ctx, threadID, "start prompt")
+	startResult, err := client.StartTurn(ctx, threadID, TurnInput{UserPrompt: "start prompt"})
 	if err != nil {
 		t.Fatalf("StartTurn() error = %v", err)
 	}
@@ -298,7 +396,7 @@ func TestStartThreadStartTurnAndSteerTurn(t *testing.T) {
 		t.Fatalf("StartTurn() tool status = %q, want completed", startResult.ToolCalls[0].Status)
 	}
 
-	steerResult, err := client.SteerTurn(ctx, threadID, startResult.TurnID, "steer prompt")
+	steerResult, err := client.SteerTurn(ctx, threadID, startResult.TurnID, TurnInput{UserPrompt: "steer prompt"})
 	if err != nil {
 		t.Fatalf("SteerTurn() error = %v", err)
 	}
@@ -319,6 +417,140 @@ func TestStartThreadStartTurnAndSteerTurn(t *testing.T) {
 	}
 }
 
+func TestStartTurnResumesUnboundPersistentThread(t *testing.T) {
+	t.Setenv("YURURI_MOCK_CODEX_HELPER", "1")
+
+	client := NewClient(config.CodexConfig{
+		Command:         os.Args[0],
+		Args:            []string{"-test.run=^TestMockCodexProcess$", "--", "resume-unbound"},
+		Model:           "gpt-5.3-codex",
+		ReasoningEffort: "medium",
+		WorkspaceDir:    t.TempDir(),
+		HomeDir:         t.TempDir(),
+	}, "http://127.0.0.1:39393/mcp", WithPersistentThreads(true))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	result, err := client.StartTurn(ctx, "thread-saved", TurnInput{UserPrompt: "restored prompt"})
+	if err != nil {
+		t.Fatalf("StartTurn() error = %v", err)
+	}
+	if result.AssistantText != "restored reply" {
+		t.Fatalf("StartTurn() assistant text = %q, want %q", result.AssistantText, "restored reply")
+	}
+	if _, bound := client.bindingForThread("thread-saved"); !bound {
+		t.Fatal("StartTurn() did not bind the resumed thread")
+	}
+}
+
+func TestAppendTurnInputSteersRunningTurn(t *testing.T) {
+	t.Setenv("YURURI_MOCK_CODEX_HELPER", "1")
+
+	client := NewClient(config.CodexConfig{
+		Command:         os.Args[0],
+		Args:            []string{"-test.run=^TestMockCodexProcess$", "--", "append-input"},
+		Model:           "gpt-5.3-codex",
+		ReasoningEffort: "medium",
+		WorkspaceDir:    t.TempDir(),
+		HomeDir:         t.TempDir(),
+	}, "http://127.0.0.1:39393/mcp")
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	appended := make(chan error, 1)
+	result, err := client.RunTurn(ctx, TurnInput{
+		BaseInstructions:      "base",
+		DeveloperInstructions: "dev",
+		UserPrompt:            "long task",
+		OnEvent: func(event TurnEvent) {
+			if event.Kind == TurnEventStarted {
+				go func() {
+					appended <- client.AppendTurnInput(ctx, event.ThreadID, event.TurnID, "edited")
+				}()
+			}
+		},
+	})
+	if err != nil {
+		t.Fatalf("RunTurn() error = %v", err)
+	}
+	if err := <-appended; err != nil {
+		t.Fatalf("AppendTurnInput() error = %v", err)
+	}
+	if result.AssistantText != "noted" {
+		t.Fatalf("RunTurn() assistant text = %q, want noted", result.AssistantText)
+	}
+}
+
+func TestRunTurnInterruptsOnCancel(t *testing.T) {
+	t.Setenv("YURURI_MOCK_CODEX_HELPER", "1")
+	workspaceDir := t.TempDir()
+	homeDir := t.TempDir()
+
+	client := NewClient(config.CodexConfig{
+		Command:         os.Args[0],
+		Args:            []string{"-test.run=^TestMockCodexProcess$", "--", "interrupt"},
+		Model:           "gpt-5.3-codex",
+		ReasoningEffort: "medium",
+		WorkspaceDir:    workspaceDir,
+		HomeDir:         homeDir,
+	}, "http://127.0.0.1:39393/mcp")
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	turnCtx, cancelTurn := context.WithCancel(ctx)
+	defer cancelTurn()
+
+	_, err := client.RunTurn(turnCtx, TurnInput{
+		BaseInstructions:      "base",
+		DeveloperInstructions: "dev",
+		UserPrompt:            "long task",
+		OnEvent: func(event TurnEvent) {
+			if event.Kind == TurnEventStarted {
+				cancelTurn()
+			}
+		},
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("RunTurn() error = %v, want context.Canceled", err)
+	}
+	if errors.Is(err, errTurnNotInterrupted) {
+		t.Fatalf("RunTurn() error = %v, want a clean interrupt", err)
+	}
+	if client.slots[0].session == nil {
+		t.Fatal("session was discarded after a clean interrupt")
+	}
+}
+
+func TestRunTurnAttachesStderrWhenProcessDies(t *testing.T) {
+	t.Setenv("YURURI_MOCK_CODEX_HELPER", "1")
+
+	client := NewClient(config.CodexConfig{
+		Command:      os.Args[0],
+		Args:         []string{"-test.run=^TestMockCodexProcess$", "--", "crash-on-start"},
+		WorkspaceDir: t.TempDir(),
+		HomeDir:      t.TempDir(),
+	}, "http://127.0.0.1:39393/mcp")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := client.RunTurn(ctx, TurnInput{UserPrompt: "hello"})
+	if err == nil {
+		t.Fatal("RunTurn() error = nil, want failure")
+	}
+	if !strings.Contains(err.Error(), "not logged in") {
+		t.Fatalf("RunTurn() error = %v, want stderr tail attached", err)
+	}
+	var withStderr *stderrError
+	if !errors.As(err, &withStderr) || len(withStderr.Stderr()) == 0 {
+		t.Fatalf("RunTurn() error = %#v, want *stderrError", err)
+	}
+}
+
 func TestExtractThreadIDSupportsString(t *testing.T) {
 	t.Parallel()
 
@@ -347,6 +579,11 @@ func TestMockCodexProcess(t *testing.T) {
 		t.Fatal("mock codex scenario is required")
 	}
 
+	if scenario == "crash-on-start" {
+		fmt.Fprintln(os.Stderr, "error: not logged in, run codex login")
+		os.Exit(1)
+	}
+
 	dec := json.NewDecoder(os.Stdin)
 	dec.UseNumber()
 	enc := json.NewEncoder(os.Stdout)
@@ -365,6 +602,12 @@ func TestMockCodexProcess(t *testing.T) {
 		runMockApprovalRequestScenario(t, dec, enc)
 	case "split-start-steer":
 		runMockSplitStartSteerScenario(t, dec, enc)
+	case "interrupt":
+		runMockInterruptScenario(t, dec, enc)
+	case "append-input":
+		runMockAppendInputScenario(t, dec, enc)
+	case "resume-unbound":
+		runMockResumeUnboundScenario(t, dec, enc)
 	default:
 		t.Fatalf("unknown mock codex scenario: %s", scenario)
 	}
@@ -426,7 +669,7 @@ func runMockUserInputRequestScenario(t *testing.T, dec *json.Decoder, enc *json.
 func runMockApprovalRequestScenario(t *testing.T, dec *json.Decoder, enc *json.Encoder) {
 	t.Helper()
 
-	expectMockThreadStart(t, dec, enc)
+	expectMockThreadStartWithApproval(t, dec, enc, "on-request")
 	expectMockTurnStart(t, dec, enc, turnRequestID, "thread-1", "approval", "turn-1")
 
 	writeMockRequestFromServer(t, enc, json.RawMessage(`61`), "item/commandExecution/requestApproval", map[string]any{})
@@ -481,12 +724,73 @@ func runMockSplitStartSteerScenario(t *testing.T, dec *json.Decoder, enc *json.E
 	})
 }
 
+func runMockResumeUnboundScenario(t *testing.T, dec *json.Decoder, enc *json.Encoder) {
+	t.Helper()
+
+	resumeReq := readMockRequest(t, dec, threadRequestID, "thread/resume")
+	resumeParams := decodeNotificationParams(resumeReq.Params)
+	if resumeParams["threadId"] != "thread-saved" {
+		t.Fatalf("thread/resume threadId = %#v, want thread-saved", resumeParams["threadId"])
+	}
+	writeMockResponse(t, enc, threadRequestID, map[string]any{"thread": map[string]any{"id": "thread-saved"}})
+	expectMockTurnStart(t, dec, enc, turnRequestID, "thread-saved", "restored prompt", "turn-1")
+
+	writeMockNotification(t, enc, "item/completed", map[string]any{
+		"item": map[string]any{"type": "agentMessage", "text": "restored reply"},
+	})
+	writeMockNotification(t, enc, "turn/completed", map[string]any{
+		"turn": map[string]any{"id": "turn-1", "status": "completed"},
+	})
+}
+
+func runMockAppendInputScenario(t *testing.T, dec *json.Decoder, enc *json.Encoder) {
+	t.Helper()
+
+	expectMockThreadStart(t, dec, enc)
+	expectMockTurnStart(t, dec, enc, turnRequestID, "thread-1", "long task", "turn-1")
+
+	steerReq := readMockRequest(t, dec, turnRequestID+1, "turn/steer")
+	assertTurnSteerParams(t, decodeNotificationParams(steerReq.Params), "thread-1", "turn-1", "edited")
+	writeMockResponse(t, enc, turnRequestID+1, map[string]any{"turnId": "turn-1"})
+	writeMockNotification(t, enc, "item/completed", map[string]any{
+		"item": map[string]any{"type": "agentMessage", "text": "noted"},
+	})
+	writeMockNotification(t, enc, "turn/completed", map[string]any{
+		"turn": map[string]any{"id": "turn-1", "status": "completed"},
+	})
+}
+
+func runMockInterruptScenario(t *testing.T, dec *json.Decoder, enc *json.Encoder) {
+	t.Helper()
+
+	expectMockThreadStart(t, dec, enc)
+	expectMockTurnStart(t, dec, enc, turnRequestID, "thread-1", "long task", "turn-1")
+
+	interruptReq := readMockRequest(t, dec, turnRequestID+1, "turn/interrupt")
+	params := decodeNotificationParams(interruptReq.Params)
+	if params["threadId"] != "thread-1" || params["turnId"] != "turn-1" {
+		t.Fatalf("turn/interrupt params = %#v, want thread-1/turn-1", params)
+	}
+	writeMockResponse(t, enc, turnRequestID+1, map[string]any{})
+	writeMockNotification(t, enc, "turn/completed", map[string]any{
+		"threadId": "thread-1",
+		"turn":     map[string]any{"id": "turn-1", "status": "interrupted"},
+	})
+	readRawMessage(t, dec)
+}
+
 func expectMockThreadStart(t *testing.T, dec *json.Decoder, enc *json.Encoder) {
 	t.Helper()
 
+	expectMockThreadStartWithApproval(t, dec, enc, "never")
+}
+
+func expectMockThreadStartWithApproval(t *testing.T, dec *json.Decoder, enc *json.Encoder, approvalPolicy string) {
+	t.Helper()
+
 	threadReq := readMockRequest(t, dec, threadRequestID, "thread/start")
 	threadParams := decodeNotificationParams(threadReq.Params)
-	assertThreadConfig(t, threadParams)
+	assertThreadConfig(t, threadParams, approvalPolicy)
 	writeMockResponse(t, enc, threadRequestID, map[string]any{"thread": map[string]any{"id": "thread-1"}})
 }
 
@@ -499,11 +803,11 @@ func expectMockTurnStart(t *testing.T, dec *json.Decoder, enc *json.Encoder, req
 	writeMockResponse(t, enc, requestID, map[string]any{"turn": map[string]any{"id": responseTurnID}})
 }
 
-func assertThreadConfig(t *testing.T, params map[string]any) {
+func assertThreadConfig(t *testing.T, params map[string]any, approvalPolicy string) {
 	t.Helper()
 
-	if params["approvalPolicy"] != "never" {
-		t.Fatalf("thread/start approvalPolicy = %#v, want never", params["approvalPolicy"])
+	if params["approvalPolicy"] != approvalPolicy {
+		t.Fatalf("thread/start approvalPolicy = %#v, want %s", params["approvalPolicy"], approvalPolicy)
 	}
 	if params["sandbox"] != "workspace-write" {
 		t.Fatalf("thread/start sandbox = %#v, want workspace-write", params["sandbox"])
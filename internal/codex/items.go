@@ -0,0 +1,163 @@
+package codex
+
+import (
+	"strings"
+)
+
+// CommandExecution is a shell command the agent ran during a turn.
+// ExitCode is nil when the command did not finish.
+type CommandExecution struct {
+	Command     string
+	CWD         string
+	Status      string
+	ExitCode    *int
+	DurationMS  int64
+	OutputBytes int
+}
+
+// FileChange is one path touched by a fileChange item.
+type FileChange struct {
+	Path         string
+	Kind         string
+	MovePath     string
+	Status       string
+	DiffBytes    int
+	AddedLines   int
+	RemovedLines int
+}
+
+type WebSearch struct {
+	Query string
+}
+
+type Reasoning struct {
+	Summary string
+}
+
+func commandExecutionFromItem(item map[string]any) CommandExecution {
+	record := CommandExecution{
+		Command: commandParam(item["command"]),
+		CWD:     stringParam(item, "cwd"),
+		Status:  normalizeToolStatus(item["status"]),
+	}
+	if code, ok := numberParam(item["exitCode"]); ok {
+		exitCode := int(code)
+		record.ExitCode = &exitCode
+	}
+	if duration, ok := numberParam(item["durationMs"]); ok {
+		record.DurationMS = duration
+	}
+	if output, ok := item["aggregatedOutput"].(string); ok {
+		record.OutputBytes = len(output)
+	}
+	return record
+}
+
+func fileChangesFromItem(item map[string]any) []FileChange {
+	changes, _ := item["changes"].([]any)
+	status := normalizeToolStatus(item["status"])
+	out := make([]FileChange, 0, len(changes))
+	for _, raw := range changes {
+		change, ok := raw.(map[string]any)
+		if !ok {
+			continue
+		}
+		path := stringParam(change, "path")
+		if path == "" {
+			continue
+		}
+		record := FileChange{Path: path, Status: status}
+		record.Kind, record.MovePath = fileChangeKind(change["kind"])
+		diff, _ := change["diff"].(string)
+		record.DiffBytes = len(diff)
+		record.AddedLines, record.RemovedLines = countDiffLines(diff, record.Kind)
+		out = append(out, record)
+	}
+	return out
+}
+
+// fileChangeKind accepts both "update" and {"type":"update","move_path":...}.
+func fileChangeKind(raw any) (string, string) {
+	switch v := raw.(type) {
+	case string:
+		return strings.TrimSpace(v), ""
+	case map[string]any:
+		kind := stringParam(v, "type")
+		movePath := stringParam(v, "move_path")
+		if movePath == "" {
+			movePath = stringParam(v, "movePath")
+		}
+		return kind, movePath
+	default:
+		return "", ""
+	}
+}
+
+// countDiffLines counts +/- lines of a unified diff. Added and deleted files
+// carry their whole content, so every line counts toward that side.
+func countDiffLines(diff string, kind string) (int, int) {
+	if strings.TrimSpace(diff) == "" {
+		return 0, 0
+	}
+	lines := strings.Split(strings.TrimRight(diff, "\n"), "\n")
+	if !strings.Contains(diff, "@@") {
+		switch kind {
+		case "add":
+			return len(lines), 0
+		case "delete":
+			return 0, len(lines)
+		}
+	}
+	added, removed := 0, 0
+	for _, line := range lines {
+		switch {
+		case strings.HasPrefix(line, "+++"), strings.HasPrefix(line, "---"):
+		case strings.HasPrefix(line, "+"):
+			added++
+		case strings.HasPrefix(line, "-"):
+			removed++
+		}
+	}
+	return added, removed
+}
+
+func webSearchFromItem(item map[string]any) WebSearch {
+	return WebSearch{Query: stringParam(item, "query")}
+}
+
+func reasoningFromItem(item map[string]any) (Reasoning, bool) {
+	var parts []string
+	switch summary := item["summary"].(type) {
+	case string:
+		parts = append(parts, summary)
+	case []any:
+		for _, raw := range summary {
+			switch v := raw.(type) {
+			case string:
+				parts = append(parts, v)
+			case map[string]any:
+				if text, _ := v["text"].(string); text != "" {
+					parts = append(parts, text)
+				}
+			}
+		}
+	}
+	text := strings.TrimSpace(strings.Join(parts, "\n"))
+	if text == "" {
+		return Reasoning{}, false
+	}
+	return Reasoning{Summary: text}, true
+}
+
+func numberParam(raw any) (int64, bool) {
+	switch v := raw.(type) {
+	case float64:
+		return int64(v), true
+	case int:
+		return int64(v), true
+	case int64:
+		return v, true
+	default:
+		return 0, false
+	}
+}
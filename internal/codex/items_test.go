@@ -0,0 +1,94 @@
+package codex
+
+import "testing"
+
+func TestTurnAggregatorRecordsWorkspaceItems(t *testing.T) {
+	t.Parallel()
+
+	aggregator := newTurnAggregator("thread-1", nil)
+	aggregator.started("turn-1")
+	aggregator.consume("item_completed", map[string]any{"item": map[string]any{
+		"type":             "commandExecution",
+		"command":          "go test ./...",
+		"cwd":              "/work",
+		"status":           "completed",
+		"exitCode":         float64(1),
+		"durationMs":       float64(1234),
+		"aggregatedOutput": "FAIL\n",
+	}})
+	aggregator.consume("item_completed", map[string]any{"item": map[string]any{
+		"type":   "fileChange",
+		"status": "completed",
+		"changes": []any{
+			map[string]any{"path": "notes/a.md", "kind": map[string]any{"type": "update"}, "diff": "@@ -1,2 +1,2 @@\n-old\n+new\n+more\n context\n"},
+			map[string]any{"path": "notes/b.md", "kind": "add", "diff": "line1\nline2\n"},
+		},
+	}})
+	aggregator.consume("item_completed", map[string]any{"item": map[string]any{"type": "webSearch", "query": "yururi"}})
+	aggregator.consume("item_completed", map[string]any{"item": map[string]any{"type": "reasoning", "summary": []any{"考えた", "まとめた"}}})
+	aggregator.consume("item_completed", map[string]any{"item": map[string]any{"type": "reasoning", "summary": []any{}}})
+
+	if len(aggregator.commands) != 1 {
+		t.Fatalf("commands = %#v, want 1", aggregator.commands)
+	}
+	command := aggregator.commands[0]
+	if command.Command != "go test ./..." || command.CWD != "/work" || command.DurationMS != 1234 || command.OutputBytes != 5 {
+		t.Fatalf("command = %#v", command)
+	}
+	if command.ExitCode == nil || *command.ExitCode != 1 {
+		t.Fatalf("command.ExitCode = %v, want 1", command.ExitCode)
+	}
+
+	wantChanges := []FileChange{
+		{Path: "notes/a.md", Kind: "update", Status: "completed", DiffBytes: 41, AddedLines: 2, RemovedLines: 1},
+		{Path: "notes/b.md", Kind: "add", Status: "completed", DiffBytes: 12, AddedLines: 2},
+	}
+	if len(aggregator.fileChanges) != len(wantChanges) {
+		t.Fatalf("fileChanges = %#v, want %d", aggregator.fileChanges, len(wantChanges))
+	}
+	for i, want := range wantChanges {
+		if aggregator.fileChanges[i] != want {
+			t.Fatalf("fileChanges[%d] = %#v, want %#v", i, aggregator.fileChanges[i], want)
+		}
+	}
+	if len(aggregator.webSearches) != 1 || aggregator.webSearches[0].Query != "yururi" {
+		t.Fatalf("webSearches = %#v", aggregator.webSearches)
+	}
+	if len(aggregator.reasoning) != 1 || aggregator.reasoning[0].Summary != "考えた\nまとめた" {
+		t.Fatalf("reasoning = %#v", aggregator.reasoning)
+	}
+}
+
+func TestCommandExecutionWithoutExitCode(t *testing.T) {
+	t.Parallel()
+
+	record := commandExecutionFromItem(map[string]any{"command": []any{"bash", "-lc", "sleep 100"}, "status": "inProgress"})
+	if record.ExitCode != nil {
+		t.Fatalf("ExitCode = %v, want nil", *record.ExitCode)
+	}
+	if record.Command != "bash -lc sleep 100" || record.Status != "inProgress" {
+		t.Fatalf("record = %#v", record)
+	}
+}
+
+func TestCountDiffLines(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		diff        string
+		kind        string
+		wantAdded   int
+		wantRemoved int
+	}{
+		{name: "empty", diff: "", kind: "update"},
+		{name: "unified", diff: "--- a/x\n+++ b/x\n@@ -1 +1 @@\n-a\n+b\n", kind: "update", wantAdded: 1, wantRemoved: 1},
+		{name: "deleted content", diff: "a\nb\nc", kind: "delete", wantRemoved: 3},
+	}
+	for _, tc := range tests {
+		added, removed := countDiffLines(tc.diff, tc.kind)
+		if added != tc.wantAdded || removed != tc.wantRemoved {
+			t.Fatalf("%s: countDiffLines() = %d/%d, want %d/%d", tc.name, added, removed, tc.wantAdded, tc.wantRemoved)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package codex
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const threadAffinityTTL = 48 * time.Hour
+
+// appServerSlot owns one app-server process. A thread is always routed back
+// to the slot that loaded it; mu only guards process start and stop.
+type appServerSlot struct {
+	client   *Client
+	index    int
+	inflight atomic.Int32
+
+	mu         sync.Mutex
+	session    *appServerSession
+	generation uint64
+}
+
+type threadBinding struct {
+	slot       *appServerSlot
+	generation uint64
+	lastUsed   time.Time
+}
+
+func newSlots(c *Client, size int) []*appServerSlot {
+	if size <= 0 {
+		size = 1
+	}
+	slots := make([]*appServerSlot, size)
+	for i := range slots {
+		slots[i] = &appServerSlot{client: c, index: i}
+	}
+	return slots
+}
+
+func (c *Client) PoolSize() int {
+	return len(c.slots)
+}
+
+// acquireSlot reserves preferred, or the least busy slot when preferred is nil.
+func (c *Client) acquireSlot(preferred *appServerSlot) *appServerSlot {
+	c.mu.Lock()
+	slot := preferred
+	if slot == nil {
+		slot = c.slots[0]
+		for _, candidate := range c.slots[1:] {
+			if candidate.inflight.Load() < slot.inflight.Load() {
+				slot = candidate
+			}
+		}
+	}
+	slot.inflight.Add(1)
+	c.mu.Unlock()
+	return slot
+}
+
+func (s *appServerSlot) release() {
+	s.inflight.Add(-1)
+}
+
+func (c *Client) bindingForThread(threadID string) (threadBinding, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	binding, ok := c.affinity[strings.TrimSpace(threadID)]
+	return binding, ok
+}
+
+func (c *Client) bindThread(threadID string, slot *appServerSlot, generation uint64) {
+	threadID = strings.TrimSpace(threadID)
+	if threadID == "" {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.affinity[threadID] = threadBinding{
+		slot:       slot,
+		generation: generation,
+		lastUsed:   time.Now(),
+	}
+}
+
+// RunHealthChecks replaces app-server processes that have exited and prunes
+// stale thread affinity until ctx is done.
+func (c *Client) RunHealthChecks(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			c.checkHealth(time.Now())
+		}
+	}
+}
+
+func (c *Client) checkHealth(now time.Time) {
+	for _, slot := range c.slots {
+		if !slot.mu.TryLock() {
+			continue
+		}
+		if slot.session != nil && slot.session.hasExited() {
+			if err := slot.ensureSessionLocked(); err != nil {
+				slog.Error("codex_process_replace_failed", "slot", slot.index, "err", err)
+			} else {
+				slog.Info("codex_process_replaced", "slot", slot.index, "generation", slot.generation)
+			}
+		}
+		slot.mu.Unlock()
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for threadID, binding := range c.affinity {
+		if now.Sub(binding.lastUsed) > threadAffinityTTL {
+			delete(c.affinity, threadID)
+		}
+	}
+}
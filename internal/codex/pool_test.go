@@ -0,0 +1,84 @@
+package codex
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sigumaa/yururi/internal/config"
+)
+
+func TestAcquireSlotPrefersIdleSlot(t *testing.T) {
+	t.Parallel()
+
+	client := NewClient(config.CodexConfig{PoolSize: 2}, "")
+	if got := client.PoolSize(); got != 2 {
+		t.Fatalf("PoolSize() = %d, want 2", got)
+	}
+
+	first := client.acquireSlot(nil)
+	second := client.acquireSlot(nil)
+	if second == first {
+		t.Fatal("acquireSlot() returned the busy slot, want the idle one")
+	}
+	second.release()
+	first.release()
+	if third := client.acquireSlot(nil); third.inflight.Load() != 1 {
+		t.Fatalf("inflight = %d after release, want 1", third.inflight.Load())
+	}
+}
+
+func TestNewClientDefaultsToSingleSlot(t *testing.T) {
+	t.Parallel()
+
+	client := NewClient(config.CodexConfig{}, "")
+	if got := client.PoolSize(); got != 1 {
+		t.Fatalf("PoolSize() = %d, want 1", got)
+	}
+}
+
+func TestThreadAffinityRoutesToOwningSlot(t *testing.T) {
+	t.Parallel()
+
+	client := NewClient(config.CodexConfig{PoolSize: 3}, "")
+	owner := client.slots[2]
+	client.bindThread("thread-1", owner, 4)
+
+	binding, ok := client.bindingForThread("thread-1")
+	if !ok {
+		t.Fatal("bindingForThread() ok = false, want true")
+	}
+	if binding.slot != owner || binding.generation != 4 {
+		t.Fatalf("binding = slot %d gen %d, want slot 2 gen 4", binding.slot.index, binding.generation)
+	}
+	if _, ok := client.bindingForThread("thread-unknown"); ok {
+		t.Fatal("bindingForThread(unknown) ok = true, want false")
+	}
+
+	slot := client.acquireSlot(binding.slot)
+	if slot != owner {
+		t.Fatalf("acquireSlot(binding) = slot %d, want slot 2", slot.index)
+	}
+	slot.release()
+}
+
+func TestCheckHealthPrunesStaleAffinity(t *testing.T) {
+	t.Parallel()
+
+	client := NewClient(config.CodexConfig{PoolSize: 1}, "")
+	client.bindThread("thread-old", client.slots[0], 1)
+	client.bindThread("thread-new", client.slots[0], 1)
+	client.mu.Lock()
+	old := client.affinity["thread-old"]
+	old.lastUsed = time.Now().Add(-threadAffinityTTL - time.Minute)
+	client.affinity["thread-old"] = old
+	client.mu.Unlock()
+
+	client.checkHealth(time.Now())
+
+	if _, ok := client.bindingForThread("thread-old"); ok {
+		t.Fatal("stale affinity was not pruned")
+	}
+	if _, ok := client.bindingForThread("thread-new"); !ok {
+		t.Fatal("fresh affinity was pruned")
+	}
+}
@@ -0,0 +1,419 @@
+package codex
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	sessionCloseTimeout = 2 * time.Second
+	stderrDrainTimeout  = 500 * time.Millisecond
+	stderrBufferLines   = 200
+	stderrErrorLines    = 20
+	maxStderrLineBytes  = 1000
+)
+
+var errSessionClosed = errors.New("codex session closed")
+
+// appServerSession is one app-server process. A background reader delivers
+// responses to waiting callers by request ID and notifications to per-thread
+// subscribers, so several requests and turns can be in flight at once.
+type appServerSession struct {
+	cmd        *exec.Cmd
+	stdin      io.WriteCloser
+	stdout     io.ReadCloser
+	slot       int
+	generation uint64
+	approvals  *approvalGate
+	exited     chan struct{}
+	waitErr    error
+
+	stderr     *stderrBuffer
+	stderrDone chan struct{}
+
+	writeMu sync.Mutex
+	enc     *json.Encoder
+
+	mu            sync.Mutex
+	nextRequestID int
+	pending       map[int]chan rpcMessage
+	subscribers   map[*turnSubscription]struct{}
+	itemPaths     map[string][]string
+
+	readerDone chan struct{}
+	readErr    error
+}
+
+// turnSubscription receives notifications for one thread. turnID narrows
+// delivery once the turn has been assigned an ID.
+type turnSubscription struct {
+	threadID string
+
+	mu     sync.Mutex
+	turnID string
+	queue  []rpcMessage
+	signal chan struct{}
+}
+
+func newAppServerSession(cmd *exec.Cmd, stdin io.WriteCloser, stdout io.ReadCloser, stderr io.Reader, slot int, generation uint64, approvals *approvalGate) *appServerSession {
+	s := &appServerSession{
+		cmd:           cmd,
+		stdin:         stdin,
+		stdout:        stdout,
+		slot:          slot,
+		generation:    generation,
+		approvals:     approvals,
+		exited:        make(chan struct{}),
+		stderr:        newStderrBuffer(stderrBufferLines),
+		stderrDone:    make(chan struct{}),
+		enc:           json.NewEncoder(stdin),
+		nextRequestID: initRequestID,
+		pending:       map[int]chan rpcMessage{},
+		subscribers:   map[*turnSubscription]struct{}{},
+		itemPaths:     map[string][]string{},
+		readerDone:    make(chan struct{}),
+	}
+	dec := json.NewDecoder(stdout)
+	dec.UseNumber()
+	go s.readLoop(dec)
+	if stderr != nil {
+		go s.readStderr(stderr)
+	} else {
+		close(s.stderrDone)
+	}
+	if cmd != nil {
+		// cmd.Wait closes stdout and stderr, so it must not run until both
+		// readers have drained everything the process wrote before exiting.
+		go func() {
+			<-s.readerDone
+			<-s.stderrDone
+			s.waitErr = cmd.Wait()
+			close(s.exited)
+		}()
+	}
+	return s
+}
+
+func (s *appServerSession) readLoop(dec *json.Decoder) {
+	var err error
+	for {
+		msg, readErr := readOneMessage(dec)
+		if readErr != nil {
+			err = readErr
+			break
+		}
+		switch {
+		case msg.Method != "" && len(msg.ID) > 0:
+			go s.answerServerRequest(msg)
+		case msg.Method != "":
+			s.routeNotification(msg)
+		case len(msg.ID) > 0:
+			s.deliverResponse(msg)
+		}
+	}
+
+	s.mu.Lock()
+	s.readErr = err
+	s.mu.Unlock()
+	close(s.readerDone)
+}
+
+func (s *appServerSession) readStderr(stderr io.Reader) {
+	defer close(s.stderrDone)
+	scanner := bufio.NewScanner(stderr)
+	scanner.Buffer(make([]byte, 0, 4096), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		s.stderr.add(line)
+		slog.Info("codex_stderr", "slot", s.slot, "generation", s.generation, "line", truncateStderrLine(line))
+	}
+}
+
+// withStderr attaches the most recent stderr lines to err. It waits briefly
+// for the stderr reader so output written just before a crash is included.
+func (s *appServerSession) withStderr(err error) error {
+	if err == nil {
+		return nil
+	}
+	select {
+	case <-s.stderrDone:
+	case <-time.After(stderrDrainTimeout):
+	}
+	lines := s.stderr.tail(stderrErrorLines)
+	if len(lines) == 0 {
+		return err
+	}
+	return &stderrError{err: err, stderr: lines}
+}
+
+func (s *appServerSession) deliverResponse(msg rpcMessage) {
+	id, err := parseID(msg.ID)
+	if err != nil {
+		return
+	}
+	s.mu.Lock()
+	ch, ok := s.pending[id]
+	delete(s.pending, id)
+	s.mu.Unlock()
+	if ok {
+		ch <- msg
+	}
+}
+
+func (s *appServerSession) routeNotification(msg rpcMessage) {
+	params := decodeNotificationParams(msg.Params)
+	threadID := notificationThreadID(params)
+	turnID := notificationTurnID(params)
+
+	s.mu.Lock()
+	s.trackFileChangeLocked(msg.Method, params)
+	// A notification without a thread ID cannot be attributed while several
+	// threads share the process, and an error among them would fail every
+	// turn, so it is only delivered to a lone subscriber.
+	if threadID == "" && len(s.subscribers) > 1 {
+		subscribers := len(s.subscribers)
+		s.mu.Unlock()
+		slog.Warn("codex_notification_dropped", "slot", s.slot, "generation", s.generation, "method", msg.Method, "subscribers", subscribers, "reason", "no_thread_id")
+		return
+	}
+	targets := make([]*turnSubscription, 0, len(s.subscribers))
+	for sub := range s.subscribers {
+		if sub.accepts(threadID, turnID) {
+			targets = append(targets, sub)
+		}
+	}
+	s.mu.Unlock()
+
+	for _, sub := range targets {
+		sub.push(msg)
+	}
+}
+
+// trackFileChangeLocked remembers the paths of in-progress file change items,
+// since v2 file change approvals only carry the item ID.
+func (s *appServerSession) trackFileChangeLocked(method string, params map[string]any) {
+	item, ok := itemFromParams(params)
+	if !ok {
+		return
+	}
+	itemType, _ := item["type"].(string)
+	itemID, _ := item["id"].(string)
+	if normalizeItemType(itemType) != "filechange" || strings.TrimSpace(itemID) == "" {
+		return
+	}
+	switch {
+	case isItemStartedMethod(normalizeMethod(method)):
+		s.itemPaths[itemID] = fileChangePaths(item)
+	case isItemCompletedMethod(normalizeMethod(method)):
+		delete(s.itemPaths, itemID)
+	}
+}
+
+func (s *appServerSession) pathsForItem(itemID string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.itemPaths[strings.TrimSpace(itemID)]...)
+}
+
+func (s *appServerSession) answerServerRequest(msg rpcMessage) {
+	// Owner approval can wait for minutes; give up once the session ends.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-s.readerDone:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	result, rpcErr := serverRequestResponse(ctx, msg, s.approvals, s.pathsForItem)
+	if err := s.respond(msg.ID, result, rpcErr); err != nil {
+		slog.Error("codex_server_request_failed", "method", msg.Method, "err", err)
+	}
+}
+
+// call sends a request and waits for its response, the end of the session,
+// or ctx, whichever comes first.
+func (s *appServerSession) call(ctx context.Context, method string, params any) (rpcMessage, error) {
+	ch := make(chan rpcMessage, 1)
+	s.mu.Lock()
+	id := s.nextRequestID
+	s.nextRequestID++
+	s.pending[id] = ch
+	s.mu.Unlock()
+
+	s.writeMu.Lock()
+	err := sendRequest(s.enc, id, method, params)
+	s.writeMu.Unlock()
+	if err != nil {
+		s.forget(id)
+		return rpcMessage{}, err
+	}
+
+	select {
+	case msg := <-ch:
+		return msg, nil
+	case <-s.readerDone:
+		s.forget(id)
+		return rpcMessage{}, s.closedError()
+	case <-ctx.Done():
+		s.forget(id)
+		return rpcMessage{}, ctx.Err()
+	}
+}
+
+func (s *appServerSession) notify(method string, params any) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	return sendNotification(s.enc, method, params)
+}
+
+func (s *appServerSession) respond(id json.RawMessage, result any, rpcErr *rpcError) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	return sendResponse(s.enc, id, result, rpcErr)
+}
+
+func (s *appServerSession) forget(id int) {
+	s.mu.Lock()
+	delete(s.pending, id)
+	s.mu.Unlock()
+}
+
+func (s *appServerSession) subscribe(threadID string) *turnSubscription {
+	sub := &turnSubscription{
+		threadID: strings.TrimSpace(threadID),
+		signal:   make(chan struct{}, 1),
+	}
+	s.mu.Lock()
+	s.subscribers[sub] = struct{}{}
+	s.mu.Unlock()
+	return sub
+}
+
+func (s *appServerSession) unsubscribe(sub *turnSubscription) {
+	s.mu.Lock()
+	delete(s.subscribers, sub)
+	s.mu.Unlock()
+}
+
+// next returns the next notification for sub, blocking until one arrives,
+// the session ends, or ctx is done.
+func (s *appServerSession) next(ctx context.Context, sub *turnSubscription) (rpcMessage, error) {
+	for {
+		if msg, ok := sub.pop(); ok {
+			return msg, nil
+		}
+		select {
+		case <-sub.signal:
+		case <-s.readerDone:
+			if msg, ok := sub.pop(); ok {
+				return msg, nil
+			}
+			return rpcMessage{}, s.closedError()
+		case <-ctx.Done():
+			return rpcMessage{}, ctx.Err()
+		}
+	}
+}
+
+func (s *appServerSession) closedError() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.readErr != nil && !errors.Is(s.readErr, io.EOF) {
+		return fmt.Errorf("%w: %v", errSessionClosed, s.readErr)
+	}
+	return errSessionClosed
+}
+
+func (s *appServerSession) hasExited() bool {
+	select {
+	case <-s.exited:
+		return true
+	default:
+		return false
+	}
+}
+
+func (s *appServerSession) close() {
+	_ = s.stdin.Close()
+	if s.cmd != nil && s.cmd.Process != nil {
+		_ = s.cmd.Process.Kill()
+	}
+	select {
+	case <-s.exited:
+	case <-time.After(sessionCloseTimeout):
+		// A grandchild may still hold stdout open; unblock the reader.
+		_ = s.stdout.Close()
+		<-s.exited
+	}
+}
+
+func (sub *turnSubscription) setTurnID(turnID string) {
+	sub.mu.Lock()
+	sub.turnID = strings.TrimSpace(turnID)
+	sub.mu.Unlock()
+}
+
+// accepts reports whether a notification belongs to this subscription.
+// Notifications that carry no thread ID are accepted; routeNotification only
+// offers them when this is the only subscriber.
+func (sub *turnSubscription) accepts(threadID string, turnID string) bool {
+	if threadID != "" && sub.threadID != "" && threadID != sub.threadID {
+		return false
+	}
+	sub.mu.Lock()
+	defer sub.mu.Unlock()
+	return turnID == "" || sub.turnID == "" || turnID == sub.turnID
+}
+
+func (sub *turnSubscription) push(msg rpcMessage) {
+	sub.mu.Lock()
+	sub.queue = append(sub.queue, msg)
+	sub.mu.Unlock()
+	select {
+	case sub.signal <- struct{}{}:
+	default:
+	}
+}
+
+func (sub *turnSubscription) pop() (rpcMessage, bool) {
+	sub.mu.Lock()
+	defer sub.mu.Unlock()
+	if len(sub.queue) == 0 {
+		return rpcMessage{}, false
+	}
+	msg := sub.queue[0]
+	sub.queue = sub.queue[1:]
+	return msg, true
+}
+
+func notificationThreadID(params map[string]any) string {
+	return strings.TrimSpace(getFirstNonEmptyStringAtPaths(params,
+		[]string{"threadId"},
+		[]string{"thread_id"},
+		[]string{"conversationId"},
+		[]string{"turn", "threadId"},
+	))
+}
+
+func notificationTurnID(params map[string]any) string {
+	return strings.TrimSpace(getFirstNonEmptyStringAtPaths(params,
+		[]string{"turnId"},
+		[]string{"turn_id"},
+		[]string{"turn", "id"},
+	))
+}
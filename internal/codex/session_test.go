@@ -0,0 +1,185 @@
+package codex
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"testing"
+	"time"
+)
+
+type fakeAppServer struct {
+	dec     *json.Decoder
+	enc     *json.Encoder
+	stdout  *io.PipeWriter
+	session *appServerSession
+}
+
+func newFakeAppServer(t *testing.T) *fakeAppServer {
+	t.Helper()
+	stdinR, stdinW := io.Pipe()
+	stdoutR, stdoutW := io.Pipe()
+	t.Cleanup(func() {
+		_ = stdinR.Close()
+		_ = stdoutW.Close()
+	})
+	dec := json.NewDecoder(stdinR)
+	dec.UseNumber()
+	return &fakeAppServer{
+		dec:     dec,
+		enc:     json.NewEncoder(stdoutW),
+		stdout:  stdoutW,
+		session: newAppServerSession(nil, stdinW, stdoutR, nil, 0, 1, nil),
+	}
+}
+
+func (f *fakeAppServer) readRequest(t *testing.T) rpcMessage {
+	t.Helper()
+	var msg rpcMessage
+	if err := f.dec.Decode(&msg); err != nil {
+		t.Errorf("decode request: %v", err)
+	}
+	return msg
+}
+
+func (f *fakeAppServer) write(t *testing.T, payload map[string]any) {
+	t.Helper()
+	payload["jsonrpc"] = "2.0"
+	if err := f.enc.Encode(payload); err != nil {
+		t.Errorf("encode message: %v", err)
+	}
+}
+
+func TestSessionDeliversResponsesByRequestID(t *testing.T) {
+	t.Parallel()
+
+	server := newFakeAppServer(t)
+	go func() {
+		first := server.readRequest(t)
+		second := server.readRequest(t)
+		var firstID, secondID any
+		_ = json.Unmarshal(first.ID, &firstID)
+		_ = json.Unmarshal(second.ID, &secondID)
+		server.write(t, map[string]any{"id": secondID, "result": map[string]any{"method": second.Method}})
+		server.write(t, map[string]any{"id": firstID, "result": map[string]any{"method": first.Method}})
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	type outcome struct {
+		method string
+		got    string
+		err    error
+	}
+	results := make(chan outcome, 2)
+	for _, method := range []string{"thread/start", "thread/resume"} {
+		method := method
+		go func() {
+			resp, err := server.session.call(ctx, method, nil)
+			got, _ := decodeNotificationParams(resp.Result)["method"].(string)
+			results <- outcome{method: method, got: got, err: err}
+		}()
+	}
+	for i := 0; i < 2; i++ {
+		res := <-results
+		if res.err != nil {
+			t.Fatalf("call(%s) error = %v", res.method, res.err)
+		}
+		if res.got != res.method {
+			t.Fatalf("call(%s) got response for %s", res.method, res.got)
+		}
+	}
+}
+
+func TestSessionRoutesNotificationsByThread(t *testing.T) {
+	t.Parallel()
+
+	server := newFakeAppServer(t)
+	subA := server.session.subscribe("thread-a")
+	subB := server.session.subscribe("thread-b")
+	subB.setTurnID("turn-b")
+
+	server.write(t, map[string]any{"method": "item/completed", "params": map[string]any{"threadId": "thread-a", "turnId": "turn-a"}})
+	server.write(t, map[string]any{"method": "item/completed", "params": map[string]any{"threadId": "thread-b", "turnId": "turn-stale"}})
+	server.write(t, map[string]any{"method": "turn/completed", "params": map[string]any{"threadId": "thread-b", "turn": map[string]any{"id": "turn-b"}}})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	msgA, err := server.session.next(ctx, subA)
+	if err != nil {
+		t.Fatalf("next(subA) error = %v", err)
+	}
+	if msgA.Method != "item/completed" {
+		t.Fatalf("subA method = %q, want item/completed", msgA.Method)
+	}
+	msgB, err := server.session.next(ctx, subB)
+	if err != nil {
+		t.Fatalf("next(subB) error = %v", err)
+	}
+	if msgB.Method != "turn/completed" {
+		t.Fatalf("subB method = %q, want turn/completed (stale turn filtered)", msgB.Method)
+	}
+	if msg, ok := subA.pop(); ok {
+		t.Fatalf("subA received other thread's notification: %#v", msg)
+	}
+}
+
+func TestSessionRoutesThreadlessNotifications(t *testing.T) {
+	t.Parallel()
+
+	server := newFakeAppServer(t)
+	subA := server.session.subscribe("thread-a")
+
+	server.write(t, map[string]any{"method": "error", "params": map[string]any{"error": map[string]any{"message": "lone"}}})
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if msg, err := server.session.next(ctx, subA); err != nil || msg.Method != "error" {
+		t.Fatalf("next(subA) = %#v, %v, want the threadless error", msg, err)
+	}
+
+	subB := server.session.subscribe("thread-b")
+	server.write(t, map[string]any{"method": "error", "params": map[string]any{"error": map[string]any{"message": "shared"}}})
+	server.write(t, map[string]any{"method": "turn/completed", "params": map[string]any{"threadId": "thread-b", "turn": map[string]any{"id": "turn-b"}}})
+	if msg, err := server.session.next(ctx, subB); err != nil || msg.Method != "turn/completed" {
+		t.Fatalf("next(subB) = %#v, %v, want turn/completed without the shared error", msg, err)
+	}
+	if msg, ok := subA.pop(); ok {
+		t.Fatalf("subA received a threadless notification while threads were shared: %#v", msg)
+	}
+}
+
+func TestSessionNextHonorsContext(t *testing.T) {
+	t.Parallel()
+
+	server := newFakeAppServer(t)
+	sub := server.session.subscribe("thread-1")
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := server.session.next(ctx, sub); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("next() error = %v, want deadline exceeded", err)
+	}
+}
+
+func TestSessionCallFailsWhenReaderEnds(t *testing.T) {
+	t.Parallel()
+
+	server := newFakeAppServer(t)
+	go func() {
+		server.readRequest(t)
+		_ = server.stdout.Close()
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	_, err := server.session.call(ctx, "turn/start", nil)
+	if !errors.Is(err, errSessionClosed) {
+		t.Fatalf("call() error = %v, want errSessionClosed", err)
+	}
+	if !isSessionFailure(err) {
+		t.Fatal("isSessionFailure() = false, want true for a closed session")
+	}
+	if isSessionFailure(rpcCallError("turn/steer", &rpcError{Code: -32600, Message: "no active turn"})) {
+		t.Fatal("isSessionFailure() = true, want false for a rejected request")
+	}
+}
@@ -0,0 +1,84 @@
+package codex
+
+import (
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// stderrBuffer keeps the last max lines written by an app-server process.
+type stderrBuffer struct {
+	mu    sync.Mutex
+	lines []string
+	next  int
+	full  bool
+}
+
+func newStderrBuffer(max int) *stderrBuffer {
+	if max <= 0 {
+		max = 1
+	}
+	return &stderrBuffer{lines: make([]string, max)}
+}
+
+func (b *stderrBuffer) add(line string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.lines[b.next] = truncateStderrLine(line)
+	b.next = (b.next + 1) % len(b.lines)
+	if b.next == 0 {
+		b.full = true
+	}
+}
+
+// tail returns up to n of the most recent lines, oldest first.
+func (b *stderrBuffer) tail(n int) []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	count := b.next
+	if b.full {
+		count = len(b.lines)
+	}
+	if n <= 0 || n > count {
+		n = count
+	}
+	out := make([]string, 0, n)
+	for i := count - n; i < count; i++ {
+		idx := i
+		if b.full {
+			idx = (b.next + i) % len(b.lines)
+		}
+		out = append(out, b.lines[idx])
+	}
+	return out
+}
+
+type stderrError struct {
+	err    error
+	stderr []string
+}
+
+func (e *stderrError) Error() string {
+	return e.err.Error() + " (stderr: " + strings.Join(e.stderr, " | ") + ")"
+}
+
+func (e *stderrError) Unwrap() error {
+	return e.err
+}
+
+// Stderr returns the app-server stderr lines captured before the failure.
+func (e *stderrError) Stderr() []string {
+	return append([]string(nil), e.stderr...)
+}
+
+func truncateStderrLine(line string) string {
+	if len(line) <= maxStderrLineBytes {
+		return line
+	}
+	cut := maxStderrLineBytes
+	for cut > 0 && !utf8.RuneStart(line[cut]) {
+		cut--
+	}
+	return line[:cut] + "..."
+}
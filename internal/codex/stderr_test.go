@@ -0,0 +1,60 @@
+package codex
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestStderrBufferKeepsMostRecentLines(t *testing.T) {
+	t.Parallel()
+
+	buf := newStderrBuffer(3)
+	if got := buf.tail(10); len(got) != 0 {
+		t.Fatalf("tail() on empty buffer = %v, want empty", got)
+	}
+	for i := 1; i <= 5; i++ {
+		buf.add(fmt.Sprintf("line-%d", i))
+	}
+
+	tests := []struct {
+		n    int
+		want []string
+	}{
+		{n: 0, want: []string{"line-3", "line-4", "line-5"}},
+		{n: 2, want: []string{"line-4", "line-5"}},
+		{n: 10, want: []string{"line-3", "line-4", "line-5"}},
+	}
+	for _, tc := range tests {
+		got := buf.tail(tc.n)
+		if strings.Join(got, ",") != strings.Join(tc.want, ",") {
+			t.Fatalf("tail(%d) = %v, want %v", tc.n, got, tc.want)
+		}
+	}
+}
+
+func TestStderrErrorWrapsCause(t *testing.T) {
+	t.Parallel()
+
+	err := &stderrError{err: errSessionClosed, stderr: []string{"panic: boom", "exit 2"}}
+	if !errors.Is(err, errSessionClosed) {
+		t.Fatal("errors.Is(stderrError, errSessionClosed) = false, want true")
+	}
+	if got := err.Error(); got != "codex session closed (stderr: panic: boom | exit 2)" {
+		t.Fatalf("Error() = %q", got)
+	}
+}
+
+func TestTruncateStderrLineKeepsValidUTF8(t *testing.T) {
+	t.Parallel()
+
+	line := strings.Repeat("あ", maxStderrLineBytes)
+	got := truncateStderrLine(line)
+	if !strings.HasSuffix(got, "...") {
+		t.Fatalf("truncateStderrLine() = %q..., want truncated", got[:12])
+	}
+	if strings.ContainsRune(got, '�') {
+		t.Fatal("truncateStderrLine() split a multi-byte rune")
+	}
+}
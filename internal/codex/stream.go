@@ -0,0 +1,65 @@
+package codex
+
+import "strings"
+
+type TurnEventKind string
+
+const (
+	TurnEventStarted           TurnEventKind = "turn_started"
+	TurnEventAgentMessageDelta TurnEventKind = "agent_message_delta"
+	TurnEventItemStarted       TurnEventKind = "item_started"
+	TurnEventItemCompleted     TurnEventKind = "item_completed"
+	TurnEventCompleted         TurnEventKind = "turn_completed"
+)
+
+// TurnEvent is a progress notification for a running turn.
+// Text carries the assistant text accumulated so far.
+type TurnEvent struct {
+	Kind     TurnEventKind
+	ThreadID string
+	TurnID   string
+	ItemType string
+	Detail   string
+	Delta    string
+	Text     string
+	Status   string
+}
+
+// StreamHandler is called synchronously while the turn is read; it must not block.
+type StreamHandler func(TurnEvent)
+
+func itemFromParams(params map[string]any) (map[string]any, bool) {
+	itemRaw, ok := getValueAtPath(params, "item")
+	if !ok {
+		return nil, false
+	}
+	item, ok := itemRaw.(map[string]any)
+	return item, ok
+}
+
+func itemEvent(kind TurnEventKind, item map[string]any) TurnEvent {
+	itemType, _ := item["type"].(string)
+	return TurnEvent{
+		Kind:     kind,
+		ItemType: strings.TrimSpace(itemType),
+		Detail:   itemDetail(item),
+		Status:   normalizeToolStatus(item["status"]),
+	}
+}
+
+func itemDetail(item map[string]any) string {
+	itemType, _ := item["type"].(string)
+	switch normalizeItemType(itemType) {
+	case "mcptoolcall":
+		tool, _ := item["tool"].(string)
+		return strings.TrimSpace(tool)
+	case "commandexecution":
+		command, _ := item["command"].(string)
+		return strings.TrimSpace(command)
+	case "websearch":
+		query, _ := item["query"].(string)
+		return strings.TrimSpace(query)
+	default:
+		return ""
+	}
+}
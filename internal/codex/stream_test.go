@@ -0,0 +1,58 @@
+package codex
+
+import "testing"
+
+func TestTurnAggregatorEmitsStreamEvents(t *testing.T) {
+	t.Parallel()
+
+	var events []TurnEvent
+	aggregator := newTurnAggregator("thread-1", func(event TurnEvent) {
+		events = append(events, event)
+	})
+	aggregator.started("turn-1")
+	aggregator.consume("item_started", map[string]any{
+		"item": map[string]any{"type": "mcpToolCall", "tool": "read_message_history", "status": "inProgress"},
+	})
+	aggregator.consume("item_agent_message_delta", map[string]any{"delta": "こん"})
+	aggregator.consume("item_agent_message_delta", map[string]any{"delta": "にちは"})
+	aggregator.consume("turn_completed", map[string]any{"turn": map[string]any{"id": "turn-1", "status": "completed"}})
+
+	wantKinds := []TurnEventKind{
+		TurnEventStarted,
+		TurnEventItemStarted,
+		TurnEventAgentMessageDelta,
+		TurnEventAgentMessageDelta,
+		TurnEventCompleted,
+	}
+	if len(events) != len(wantKinds) {
+		t.Fatalf("events = %d, want %d: %#v", len(events), len(wantKinds), events)
+	}
+	for i, want := range wantKinds {
+		if events[i].Kind != want {
+			t.Fatalf("events[%d].Kind = %q, want %q", i, events[i].Kind, want)
+		}
+		if events[i].ThreadID != "thread-1" || events[i].TurnID != "turn-1" {
+			t.Fatalf("events[%d] ids = %q/%q, want thread-1/turn-1", i, events[i].ThreadID, events[i].TurnID)
+		}
+	}
+	if got := events[1].Detail; got != "read_message_history" {
+		t.Fatalf("item started detail = %q, want read_message_history", got)
+	}
+	if got := events[3].Text; got != "こんにちは" {
+		t.Fatalf("delta accumulated text = %q, want こんにちは", got)
+	}
+	if got := events[4].Status; got != "completed" {
+		t.Fatalf("completed status = %q, want completed", got)
+	}
+}
+
+func TestTurnAggregatorWithoutHandler(t *testing.T) {
+	t.Parallel()
+
+	aggregator := newTurnAggregator("thread-1", nil)
+	aggregator.started("turn-1")
+	aggregator.consume("item_agent_message_delta", map[string]any{"delta": "hi"})
+	if got := aggregator.FinalText(); got != "hi" {
+		t.Fatalf("FinalText() = %q, want hi", got)
+	}
+}
@@ -0,0 +1,56 @@
+package codex
+
+// TokenUsage counts tokens consumed by a turn. CachedInputTokens and
+// ReasoningOutputTokens are subsets of InputTokens and OutputTokens.
+type TokenUsage struct {
+	InputTokens           int64
+	CachedInputTokens     int64
+	OutputTokens          int64
+	ReasoningOutputTokens int64
+	TotalTokens           int64
+}
+
+func (u TokenUsage) Add(other TokenUsage) TokenUsage {
+	return TokenUsage{
+		InputTokens:           u.InputTokens + other.InputTokens,
+		CachedInputTokens:     u.CachedInputTokens + other.CachedInputTokens,
+		OutputTokens:          u.OutputTokens + other.OutputTokens,
+		ReasoningOutputTokens: u.ReasoningOutputTokens + other.ReasoningOutputTokens,
+		TotalTokens:           u.TotalTokens + other.TotalTokens,
+	}
+}
+
+func (u TokenUsage) IsZero() bool {
+	return u == TokenUsage{}
+}
+
+// lastTokenUsage reads the usage of the latest model call from either the v2
+// thread/tokenUsage/updated notification or the legacy token_count event.
+func lastTokenUsage(params map[string]any) (TokenUsage, bool) {
+	if raw, ok := getValueAtPath(params, "tokenUsage", "last"); ok {
+		if usage, ok := raw.(map[string]any); ok {
+			return tokenUsageFromMap(usage,
+				"inputTokens", "cachedInputTokens", "outputTokens", "reasoningOutputTokens", "totalTokens"), true
+		}
+	}
+	if raw, ok := getValueAtPath(params, "msg", "info", "last_token_usage"); ok {
+		if usage, ok := raw.(map[string]any); ok {
+			return tokenUsageFromMap(usage,
+				"input_tokens", "cached_input_tokens", "output_tokens", "reasoning_output_tokens", "total_tokens"), true
+		}
+	}
+	return TokenUsage{}, false
+}
+
+func tokenUsageFromMap(usage map[string]any, input string, cached string, output string, reasoning string, total string) TokenUsage {
+	out := TokenUsage{}
+	out.InputTokens, _ = numberParam(usage[input])
+	out.CachedInputTokens, _ = numberParam(usage[cached])
+	out.OutputTokens, _ = numberParam(usage[output])
+	out.ReasoningOutputTokens, _ = numberParam(usage[reasoning])
+	out.TotalTokens, _ = numberParam(usage[total])
+	if out.TotalTokens == 0 {
+		out.TotalTokens = out.InputTokens + out.OutputTokens
+	}
+	return out
+}
@@ -0,0 +1,63 @@
+package codex
+
+import "testing"
+
+func TestTurnAggregatorSumsTokenUsage(t *testing.T) {
+	t.Parallel()
+
+	v2 := func(input, cached, output, reasoning, total float64) map[string]any {
+		return map[string]any{"tokenUsage": map[string]any{
+			"total": map[string]any{"inputTokens": float64(999)},
+			"last": map[string]any{
+				"inputTokens":           input,
+				"cachedInputTokens":     cached,
+				"outputTokens":          output,
+				"reasoningOutputTokens": reasoning,
+				"totalTokens":           total,
+			},
+		}}
+	}
+	legacy := func(input, output float64) map[string]any {
+		return map[string]any{"msg": map[string]any{"type": "token_count", "info": map[string]any{
+			"last_token_usage": map[string]any{"input_tokens": input, "output_tokens": output},
+		}}}
+	}
+	type notification struct {
+		method string
+		params map[string]any
+	}
+	tests := []struct {
+		name          string
+		notifications []notification
+		want          TokenUsage
+	}{
+		{
+			name: "v2 and legacy for the same calls",
+			notifications: []notification{
+				{method: "codex_event_token_count", params: legacy(100, 20)},
+				{method: "thread_token_usage_updated", params: v2(100, 40, 20, 5, 120)},
+				{method: "thread_token_usage_updated", params: v2(50, 0, 10, 0, 60)},
+				{method: "codex_event_token_count", params: legacy(50, 10)},
+				{method: "codex_event_token_count", params: map[string]any{"msg": map[string]any{"type": "token_count", "info": nil}}},
+			},
+			want: TokenUsage{InputTokens: 150, CachedInputTokens: 40, OutputTokens: 30, ReasoningOutputTokens: 5, TotalTokens: 180},
+		},
+		{
+			name: "legacy only",
+			notifications: []notification{
+				{method: "codex_event_token_count", params: legacy(100, 20)},
+				{method: "codex_event_token_count", params: legacy(50, 10)},
+			},
+			want: TokenUsage{InputTokens: 150, OutputTokens: 30, TotalTokens: 180},
+		},
+	}
+	for _, tc := range tests {
+		aggregator := newTurnAggregator("thread-1", nil)
+		for _, n := range tc.notifications {
+			aggregator.consume(n.method, n.params)
+		}
+		if got := aggregator.result().Usage; got != tc.want {
+			t.Fatalf("%s: usage = %+v, want %+v", tc.name, got, tc.want)
+		}
+	}
+}
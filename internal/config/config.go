@@ -9,20 +9,65 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
 
 const (
-	defaultCodexCommand         = "codex"
-	defaultCodexModel           = "gpt-5.3-codex"
-	defaultCodexReasoningEffort = "medium"
-	defaultMCPBind              = "127.0.0.1:39393"
-	defaultHeartbeatCron        = "0 */30 * * * *"
-	defaultHeartbeatTimezone    = "Asia/Tokyo"
-	defaultXAIBaseURL           = "https://api.x.ai/v1"
-	defaultXAIModel             = "grok-4-1-fast-non-reasoning"
-	defaultXAITimeoutSec        = 30
+	defaultCodexCommand                = "codex"
+	defaultCodexModel                  = "gpt-5.3-codex"
+	defaultCodexReasoningEffort        = "medium"
+	defaultCodexPoolSize               = 1
+	defaultCodexHealthCheckIntervalSec = 30
+	defaultMCPBind                     = "127.0.0.1:39393"
+	defaultMCPUploadMaxBytes           = 8 << 20
+	defaultHeartbeatCron               = "0 */30 * * * *"
+	defaultHeartbeatTimezone           = "Asia/Tokyo"
+	defaultXAIBaseURL                  = "https://api.x.ai/v1"
+	defaultXAIModel                    = "grok-4-1-fast-non-reasoning"
+	defaultXAITimeoutSec               = 30
+	defaultSessionTTLSec               = 24 * 60 * 60
+	sessionStoreFileName               = "sessions.json"
+	defaultLiveStartDelayMS            = 5000
+	defaultLiveEditIntervalMS          = 1500
+	defaultStopReactionEmoji           = "🛑"
+	defaultApprovalOwnerTimeoutSec     = 300
+	usageStoreFileName                 = "usage.json"
+	auditLogFileName                   = "audit.jsonl"
+	dedupStoreFileName                 = "dedup.json"
+	defaultDedupWindowSec              = 10 * 60
+	defaultDedupSimilarityThreshold    = 0.9
+	defaultAttachmentsMaxPerMessage    = 4
+	defaultAttachmentsMaxImageBytes    = 8 << 20
+	defaultAttachmentsMaxTextBytes     = 256 << 10
+	defaultAttachmentsMaxTextChars     = 8000
+	defaultAttachmentsCacheMaxBytes    = 512 << 20
+	defaultUsageDowngradeEffort        = "low"
+	defaultLogFileMaxSizeMB            = 50
+	defaultLogFileMaxBackups           = 5
+)
+
+const (
+	ApprovalFallbackApprove = "approve"
+	ApprovalFallbackDeny    = "deny"
+	ApprovalFallbackOwner   = "owner"
+)
+
+const (
+	UsageExhaustedRefuse    = "refuse"
+	UsageExhaustedDowngrade = "downgrade"
+)
+
+const (
+	LogFormatText = "text"
+	LogFormatJSON = "json"
+)
+
+const (
+	SandboxReadOnly         = "read-only"
+	SandboxWorkspaceWrite   = "workspace-write"
+	SandboxDangerFullAccess = "danger-full-access"
 )
 
 var defaultCodexArgs = []string{"--search", "app-server", "--listen", "stdio://"}
@@ -34,17 +79,138 @@ type Config struct {
 	MCP       MCPConfig       `yaml:"mcp"`
 	Heartbeat HeartbeatConfig `yaml:"heartbeat"`
 	XAI       XAIConfig       `yaml:"xai"`
+	Session   SessionConfig   `yaml:"session"`
+	Usage     UsageConfig     `yaml:"usage"`
+	Routing   RoutingConfig   `yaml:"routing"`
+	Log       LogConfig       `yaml:"log"`
 }
 
 type DiscordConfig struct {
-	Token              string   `yaml:"token"`
-	GuildID            string   `yaml:"guild_id"`
-	ReadChannelIDs     []string `yaml:"read_channel_ids"`
-	WriteChannelIDs    []string `yaml:"write_channel_ids"`
-	ObserveChannelIDs  []string `yaml:"observe_channel_ids"`
-	ObserveCategoryIDs []string `yaml:"observe_category_ids"`
-	ExcludedChannelIDs []string `yaml:"excluded_channel_ids"`
-	AllowedBotUserIDs  []string `yaml:"allowed_bot_user_ids"`
+	Token              string              `yaml:"token"`
+	GuildID            string              `yaml:"guild_id"`
+	ReadChannelIDs     []string            `yaml:"read_channel_ids"`
+	WriteChannelIDs    []string            `yaml:"write_channel_ids"`
+	ObserveChannelIDs  []string            `yaml:"observe_channel_ids"`
+	ObserveCategoryIDs []string            `yaml:"observe_category_ids"`
+	ExcludedChannelIDs []string            `yaml:"excluded_channel_ids"`
+	AllowedBotUserIDs  []string            `yaml:"allowed_bot_user_ids"`
+	LiveMessage        LiveMessageConfig   `yaml:"live_message"`
+	StopReactionEmoji  string              `yaml:"stop_reaction_emoji"`
+	DM                 DMConfig            `yaml:"dm"`
+	MessageEvents      MessageEventsConfig `yaml:"message_events"`
+	Attachments        AttachmentsConfig   `yaml:"attachments"`
+	Dedup              DedupConfig         `yaml:"dedup"`
+	// AuditLogPath records edits and deletes of the bot's own messages.
+	AuditLogPath string `yaml:"audit_log_path"`
+	// ChannelOverrides and CategoryOverrides are keyed by Discord ID. A
+	// channel override wins over its category's override field by field.
+	ChannelOverrides  map[string]ChannelOverrideConfig `yaml:"channel_overrides"`
+	CategoryOverrides map[string]ChannelOverrideConfig `yaml:"category_overrides"`
+}
+
+type ChannelOverrideConfig struct {
+	Model           string                          `yaml:"model"`
+	ReasoningEffort string                          `yaml:"reasoning_effort"`
+	Sandbox         string                          `yaml:"sandbox"`
+	MCPServers      map[string]CodexMCPServerConfig `yaml:"mcp_servers"`
+}
+
+// DMConfig enables direct messages. AllowedUserIDs defaults to the owner.
+type DMConfig struct {
+	Enabled        bool     `yaml:"enabled"`
+	AllowedUserIDs []string `yaml:"allowed_user_ids"`
+}
+
+// MessageEventsConfig controls how edits and deletes reach the model. They
+// steer the running turn or wait for the next one; TriggerTurn starts a turn
+// for them instead of waiting.
+type MessageEventsConfig struct {
+	Enabled     bool `yaml:"enabled"`
+	TriggerTurn bool `yaml:"trigger_turn"`
+}
+
+// AttachmentsConfig controls how attachments reach the model. Images are
+// sent as image inputs and text files are inlined; both are cached under
+// CacheDir. ChannelLimits overrides the limits per channel, field by field.
+type AttachmentsConfig struct {
+	Enabled       bool                              `yaml:"enabled"`
+	CacheDir      string                            `yaml:"cache_dir"`
+	CacheMaxBytes int64                             `yaml:"cache_max_bytes"`
+	MaxPerMessage int                               `yaml:"max_per_message"`
+	MaxImageBytes int64                             `yaml:"max_image_bytes"`
+	MaxTextBytes  int64                             `yaml:"max_text_bytes"`
+	MaxTextChars  int                               `yaml:"max_text_chars"`
+	ChannelLimits map[string]AttachmentLimitsConfig `yaml:"channel_limits"`
+}
+
+// AttachmentLimitsConfig is a per-channel override. Zero fields keep the
+// global limit.
+type AttachmentLimitsConfig struct {
+	MaxPerMessage int   `yaml:"max_per_message"`
+	MaxImageBytes int64 `yaml:"max_image_bytes"`
+	MaxTextBytes  int64 `yaml:"max_text_bytes"`
+	MaxTextChars  int   `yaml:"max_text_chars"`
+}
+
+// DedupConfig controls duplicate suppression of the bot's own posts. A post
+// is suppressed when it matches one sent within the window exactly, or is at
+// least SimilarityThreshold similar to one; 0 turns similarity matching off.
+type DedupConfig struct {
+	StorePath           string         `yaml:"store_path"`
+	WindowSec           int            `yaml:"window_sec"`
+	SimilarityThreshold float64        `yaml:"similarity_threshold"`
+	ChannelWindowSec    map[string]int `yaml:"channel_window_sec"`
+}
+
+// WindowFor returns the window for channelID. A thread without its own entry
+// uses its parent channel's.
+func (c DedupConfig) WindowFor(channelID string, parentChannelID string) time.Duration {
+	sec, ok := c.ChannelWindowSec[channelID]
+	if !ok {
+		sec, ok = c.ChannelWindowSec[parentChannelID]
+	}
+	if !ok {
+		sec = c.WindowSec
+	}
+	return time.Duration(sec) * time.Second
+}
+
+// LimitsFor returns the limits for channelID. A thread without its own entry
+// uses its parent channel's.
+func (c AttachmentsConfig) LimitsFor(channelID string, parentChannelID string) AttachmentLimitsConfig {
+	limits := AttachmentLimitsConfig{
+		MaxPerMessage: c.MaxPerMessage,
+		MaxImageBytes: c.MaxImageBytes,
+		MaxTextBytes:  c.MaxTextBytes,
+		MaxTextChars:  c.MaxTextChars,
+	}
+	override, ok := c.ChannelLimits[channelID]
+	if !ok {
+		override, ok = c.ChannelLimits[parentChannelID]
+	}
+	if !ok {
+		return limits
+	}
+	if override.MaxPerMessage > 0 {
+		limits.MaxPerMessage = override.MaxPerMessage
+	}
+	if override.MaxImageBytes > 0 {
+		limits.MaxImageBytes = override.MaxImageBytes
+	}
+	if override.MaxTextBytes > 0 {
+		limits.MaxTextBytes = override.MaxTextBytes
+	}
+	if override.MaxTextChars > 0 {
+		limits.MaxTextChars = override.MaxTextChars
+	}
+	return limits
+}
+
+type LiveMessageConfig struct {
+	Enabled        bool `yaml:"enabled"`
+	StartDelayMS   int  `yaml:"start_delay_ms"`
+	EditIntervalMS int  `yaml:"edit_interval_ms"`
+	KeepFinalText  bool `yaml:"keep_final_text"`
 }
 
 type PersonaConfig struct {
@@ -52,15 +218,28 @@ type PersonaConfig struct {
 }
 
 type CodexConfig struct {
-	Command         string                          `yaml:"command"`
-	Args            []string                        `yaml:"args"`
-	Model           string                          `yaml:"model"`
-	ReasoningEffort string                          `yaml:"reasoning_effort"`
-	WorkspaceDir    string                          `yaml:"workspace_dir"`
-	CWD             string                          `yaml:"cwd"`
-	HomeDir         string                          `yaml:"home_dir"`
-	Home            string                          `yaml:"home"`
-	MCPServers      map[string]CodexMCPServerConfig `yaml:"mcp_servers"`
+	Command                string                          `yaml:"command"`
+	Args                   []string                        `yaml:"args"`
+	Model                  string                          `yaml:"model"`
+	ReasoningEffort        string                          `yaml:"reasoning_effort"`
+	WorkspaceDir           string                          `yaml:"workspace_dir"`
+	CWD                    string                          `yaml:"cwd"`
+	HomeDir                string                          `yaml:"home_dir"`
+	Home                   string                          `yaml:"home"`
+	MCPServers             map[string]CodexMCPServerConfig `yaml:"mcp_servers"`
+	PoolSize               int                             `yaml:"pool_size"`
+	HealthCheckIntervalSec int                             `yaml:"health_check_interval_sec"`
+	Approval               CodexApprovalConfig             `yaml:"approval"`
+}
+
+type CodexApprovalConfig struct {
+	AllowCommands   []string `yaml:"allow_commands"`
+	DenyCommands    []string `yaml:"deny_commands"`
+	AllowPaths      []string `yaml:"allow_paths"`
+	WorkspaceOnly   bool     `yaml:"workspace_only"`
+	Fallback        string   `yaml:"fallback"`
+	OwnerChannelID  string   `yaml:"owner_channel_id"`
+	OwnerTimeoutSec int      `yaml:"owner_timeout_sec"`
 }
 
 type CodexMCPServerConfig struct {
@@ -72,9 +251,10 @@ type CodexMCPServerConfig struct {
 }
 
 type MCPConfig struct {
-	Bind       string              `yaml:"bind"`
-	URL        string              `yaml:"url"`
-	ToolPolicy MCPToolPolicyConfig `yaml:"tool_policy"`
+	Bind           string              `yaml:"bind"`
+	URL            string              `yaml:"url"`
+	UploadMaxBytes int64               `yaml:"upload_max_bytes"`
+	ToolPolicy     MCPToolPolicyConfig `yaml:"tool_policy"`
 }
 
 type MCPToolPolicyConfig struct {
@@ -96,6 +276,73 @@ type XAIConfig struct {
 	TimeoutSec int    `yaml:"timeout_sec"`
 }
 
+type SessionConfig struct {
+	Persist   bool                  `yaml:"persist"`
+	StorePath string                `yaml:"store_path"`
+	TTLSec    int                   `yaml:"ttl_sec"`
+	Rotation  SessionRotationConfig `yaml:"rotation"`
+}
+
+type SessionRotationConfig struct {
+	IdleTimeoutSec    int `yaml:"idle_timeout_sec"`
+	MaxTurns          int `yaml:"max_turns"`
+	MaxAgeSec         int `yaml:"max_age_sec"`
+	DailyRolloverHour int `yaml:"daily_rollover_hour"`
+}
+
+type UsageConfig struct {
+	StorePath                 string `yaml:"store_path"`
+	DailyTokenBudget          int64  `yaml:"daily_token_budget"`
+	ChannelDailyTokenBudget   int64  `yaml:"channel_daily_token_budget"`
+	HeartbeatDailyTokenBudget int64  `yaml:"heartbeat_daily_token_budget"`
+	ExhaustedAction           string `yaml:"exhausted_action"`
+	DowngradeReasoningEffort  string `yaml:"downgrade_reasoning_effort"`
+}
+
+// RoutingConfig picks a model tier per message. Rules are checked in order
+// and the first match wins; messages matching no rule use DefaultTier.
+type RoutingConfig struct {
+	Enabled     bool                         `yaml:"enabled"`
+	DefaultTier string                       `yaml:"default_tier"`
+	Tiers       map[string]RoutingTierConfig `yaml:"tiers"`
+	Rules       []RoutingRuleConfig          `yaml:"rules"`
+}
+
+type RoutingTierConfig struct {
+	Model           string `yaml:"model"`
+	ReasoningEffort string `yaml:"reasoning_effort"`
+}
+
+// RoutingRuleConfig matches when every set condition holds. Zero values and
+// nil flags are unset.
+type RoutingRuleConfig struct {
+	Tier           string `yaml:"tier"`
+	MinLength      int    `yaml:"min_length"`
+	MaxLength      int    `yaml:"max_length"`
+	MinQuestions   int    `yaml:"min_questions"`
+	MinLinks       int    `yaml:"min_links"`
+	MinAttachments int    `yaml:"min_attachments"`
+	MentionsBot    *bool  `yaml:"mentions_bot"`
+	FromOwner      *bool  `yaml:"from_owner"`
+}
+
+// LogConfig selects the console output. File, when its path is set, also
+// writes every record to a file rotated by size.
+type LogConfig struct {
+	Format string        `yaml:"format"`
+	Level  string        `yaml:"level"`
+	File   LogFileConfig `yaml:"file"`
+}
+
+// LogFileConfig uses the console format when Format is empty. A zero
+// MaxSizeMB never rotates.
+type LogFileConfig struct {
+	Path       string `yaml:"path"`
+	Format     string `yaml:"format"`
+	MaxSizeMB  int    `yaml:"max_size_mb"`
+	MaxBackups int    `yaml:"max_backups"`
+}
+
 var (
 	currentMCPToolPolicyMu sync.RWMutex
 	currentMCPToolPolicy   MCPToolPolicyConfig
@@ -103,14 +350,41 @@ var (
 
 func Load(path string) (Config, error) {
 	cfg := Config{
+		Discord: DiscordConfig{
+			LiveMessage: LiveMessageConfig{
+				StartDelayMS:   defaultLiveStartDelayMS,
+				EditIntervalMS: defaultLiveEditIntervalMS,
+			},
+			MessageEvents: MessageEventsConfig{Enabled: true},
+			Attachments: AttachmentsConfig{
+				Enabled:       true,
+				MaxPerMessage: defaultAttachmentsMaxPerMessage,
+				MaxImageBytes: defaultAttachmentsMaxImageBytes,
+				MaxTextBytes:  defaultAttachmentsMaxTextBytes,
+				MaxTextChars:  defaultAttachmentsMaxTextChars,
+				CacheMaxBytes: defaultAttachmentsCacheMaxBytes,
+			},
+			Dedup: DedupConfig{
+				WindowSec:           defaultDedupWindowSec,
+				SimilarityThreshold: defaultDedupSimilarityThreshold,
+			},
+		},
 		Codex: CodexConfig{
-			Command:         defaultCodexCommand,
-			Args:            append([]string(nil), defaultCodexArgs...),
-			Model:           defaultCodexModel,
-			ReasoningEffort: defaultCodexReasoningEffort,
+			Command:                defaultCodexCommand,
+			Args:                   append([]string(nil), defaultCodexArgs...),
+			Model:                  defaultCodexModel,
+			ReasoningEffort:        defaultCodexReasoningEffort,
+			PoolSize:               defaultCodexPoolSize,
+			HealthCheckIntervalSec: defaultCodexHealthCheckIntervalSec,
+			Approval: CodexApprovalConfig{
+				WorkspaceOnly:   true,
+				Fallback:        ApprovalFallbackApprove,
+				OwnerTimeoutSec: defaultApprovalOwnerTimeoutSec,
+			},
 		},
 		MCP: MCPConfig{
-			Bind: defaultMCPBind,
+			Bind:           defaultMCPBind,
+			UploadMaxBytes: defaultMCPUploadMaxBytes,
 		},
 		Heartbeat: HeartbeatConfig{
 			Enabled:  true,
@@ -123,6 +397,25 @@ func Load(path string) (Config, error) {
 			Model:      defaultXAIModel,
 			TimeoutSec: defaultXAITimeoutSec,
 		},
+		Session: SessionConfig{
+			Persist: true,
+			TTLSec:  defaultSessionTTLSec,
+			Rotation: SessionRotationConfig{
+				DailyRolloverHour: -1,
+			},
+		},
+		Usage: UsageConfig{
+			ExhaustedAction:          UsageExhaustedRefuse,
+			DowngradeReasoningEffort: defaultUsageDowngradeEffort,
+		},
+		Log: LogConfig{
+			Format: LogFormatText,
+			Level:  "info",
+			File: LogFileConfig{
+				MaxSizeMB:  defaultLogFileMaxSizeMB,
+				MaxBackups: defaultLogFileMaxBackups,
+			},
+		},
 	}
 
 	body, err := os.ReadFile(path)
@@ -170,6 +463,21 @@ func (c Config) Validate() error {
 	if len(c.Codex.Args) == 0 {
 		return errors.New("codex.args is required")
 	}
+	if c.Discord.Dedup.SimilarityThreshold < 0 || c.Discord.Dedup.SimilarityThreshold > 1 {
+		return fmt.Errorf("discord.dedup.similarity_threshold must be between 0 and 1: %v", c.Discord.Dedup.SimilarityThreshold)
+	}
+	switch c.Codex.Approval.Fallback {
+	case ApprovalFallbackApprove, ApprovalFallbackDeny:
+	case ApprovalFallbackOwner:
+		if c.Codex.Approval.OwnerChannelID == "" {
+			return errors.New("codex.approval.owner_channel_id is required when codex.approval.fallback=owner")
+		}
+		if strings.TrimSpace(c.Persona.OwnerUserID) == "" {
+			return errors.New("persona.owner_user_id is required when codex.approval.fallback=owner")
+		}
+	default:
+		return fmt.Errorf("codex.approval.fallback must be one of approve, deny, owner: %q", c.Codex.Approval.Fallback)
+	}
 	if c.MCP.Bind == "" {
 		return errors.New("mcp.bind is required")
 	}
@@ -189,9 +497,75 @@ func (c Config) Validate() error {
 			return errors.New("xai.api_key is required when xai.enabled=true")
 		}
 	}
+	if c.Discord.DM.Enabled && len(c.Discord.DM.AllowedUserIDs) == 0 {
+		return errors.New("discord.dm.allowed_user_ids or persona.owner_user_id is required when discord.dm.enabled=true")
+	}
+	for _, kind := range []struct {
+		name      string
+		overrides map[string]ChannelOverrideConfig
+	}{
+		{name: "channel_overrides", overrides: c.Discord.ChannelOverrides},
+		{name: "category_overrides", overrides: c.Discord.CategoryOverrides},
+	} {
+		for id, override := range kind.overrides {
+			switch override.Sandbox {
+			case "", SandboxReadOnly, SandboxWorkspaceWrite, SandboxDangerFullAccess:
+			default:
+				return fmt.Errorf("discord.%s.%s.sandbox must be one of read-only, workspace-write, danger-full-access: %q", kind.name, id, override.Sandbox)
+			}
+		}
+	}
+	switch c.Usage.ExhaustedAction {
+	case UsageExhaustedRefuse, UsageExhaustedDowngrade:
+	default:
+		return fmt.Errorf("usage.exhausted_action must be one of refuse, downgrade: %q", c.Usage.ExhaustedAction)
+	}
+	if c.Routing.Enabled {
+		if c.Routing.DefaultTier != "" {
+			if _, ok := c.Routing.Tiers[c.Routing.DefaultTier]; !ok {
+				return fmt.Errorf("routing.default_tier %q is not defined in routing.tiers", c.Routing.DefaultTier)
+			}
+		}
+		for i, rule := range c.Routing.Rules {
+			if _, ok := c.Routing.Tiers[rule.Tier]; !ok {
+				return fmt.Errorf("routing.rules[%d].tier %q is not defined in routing.tiers", i, rule.Tier)
+			}
+		}
+	}
+	if c.Session.Rotation.DailyRolloverHour < -1 || c.Session.Rotation.DailyRolloverHour > 23 {
+		return errors.New("session.rotation.daily_rollover_hour must be between -1 and 23")
+	}
+	for _, format := range []struct {
+		name  string
+		value string
+	}{
+		{name: "log.format", value: c.Log.Format},
+		{name: "log.file.format", value: c.Log.File.Format},
+	} {
+		switch format.value {
+		case "", LogFormatText, LogFormatJSON:
+		default:
+			return fmt.Errorf("%s must be one of text, json: %q", format.name, format.value)
+		}
+	}
+	switch c.Log.Level {
+	case "debug", "info", "warn", "error":
+	default:
+		return fmt.Errorf("log.level must be one of debug, info, warn, error: %q", c.Log.Level)
+	}
 	return nil
 }
 
+func (c Config) StateDir() string {
+	if c.Codex.HomeDir != "" {
+		return filepath.Join(c.Codex.HomeDir, "yururi")
+	}
+	if c.Codex.WorkspaceDir != "" {
+		return filepath.Join(c.Codex.WorkspaceDir, ".yururi")
+	}
+	return ""
+}
+
 func (c *Config) normalize(configBaseDir string) {
 	if c.Codex.WorkspaceDir == "" {
 		c.Codex.WorkspaceDir = c.Codex.CWD
@@ -206,27 +580,7 @@ func (c *Config) normalize(configBaseDir string) {
 	if c.Codex.HomeDir != "" {
 		c.Codex.HomeDir = resolvePath(configBaseDir, c.Codex.HomeDir)
 	}
-	for name, server := range c.Codex.MCPServers {
-		normalized := CodexMCPServerConfig{
-			URL:         strings.TrimSpace(server.URL),
-			Command:     strings.TrimSpace(server.Command),
-			Args:        cleanList(server.Args),
-			BearerToken: strings.TrimSpace(server.BearerToken),
-		}
-		if len(server.Headers) > 0 {
-			normalized.Headers = make(map[string]string, len(server.Headers))
-			for k, v := range server.Headers {
-				key := strings.TrimSpace(k)
-				val := strings.TrimSpace(v)
-				if key == "" || val == "" {
-					continue
-				}
-				normalized.Headers[key] = val
-			}
-		}
-		normalized = applyBearerTokenToServer(normalized)
-		c.Codex.MCPServers[name] = normalized
-	}
+	normalizeMCPServers(c.Codex.MCPServers)
 
 	if strings.TrimSpace(c.MCP.URL) == "" {
 		c.MCP.URL = "http://" + c.MCP.Bind + "/mcp"
@@ -244,16 +598,222 @@ func (c *Config) normalize(configBaseDir string) {
 	if c.XAI.TimeoutSec <= 0 {
 		c.XAI.TimeoutSec = defaultXAITimeoutSec
 	}
+	if c.Codex.PoolSize <= 0 {
+		c.Codex.PoolSize = defaultCodexPoolSize
+	}
+	if c.MCP.UploadMaxBytes <= 0 {
+		c.MCP.UploadMaxBytes = defaultMCPUploadMaxBytes
+	}
+	if c.Codex.HealthCheckIntervalSec < 0 {
+		c.Codex.HealthCheckIntervalSec = 0
+	}
+	c.Codex.Approval.AllowCommands = cleanList(c.Codex.Approval.AllowCommands)
+	c.Codex.Approval.DenyCommands = cleanList(c.Codex.Approval.DenyCommands)
+	allowPaths := cleanList(c.Codex.Approval.AllowPaths)
+	for i, path := range allowPaths {
+		allowPaths[i] = resolvePath(c.Codex.WorkspaceDir, path)
+	}
+	c.Codex.Approval.AllowPaths = allowPaths
+	c.Codex.Approval.Fallback = strings.ToLower(strings.TrimSpace(c.Codex.Approval.Fallback))
+	if c.Codex.Approval.Fallback == "" {
+		c.Codex.Approval.Fallback = ApprovalFallbackApprove
+	}
+	c.Codex.Approval.OwnerChannelID = strings.TrimSpace(c.Codex.Approval.OwnerChannelID)
+	if c.Codex.Approval.OwnerTimeoutSec <= 0 {
+		c.Codex.Approval.OwnerTimeoutSec = defaultApprovalOwnerTimeoutSec
+	}
+	c.Discord.StopReactionEmoji = strings.TrimSpace(c.Discord.StopReactionEmoji)
+	if c.Discord.StopReactionEmoji == "" {
+		c.Discord.StopReactionEmoji = defaultStopReactionEmoji
+	}
+	if c.Discord.LiveMessage.StartDelayMS < 0 {
+		c.Discord.LiveMessage.StartDelayMS = 0
+	}
+	if c.Discord.LiveMessage.EditIntervalMS <= 0 {
+		c.Discord.LiveMessage.EditIntervalMS = defaultLiveEditIntervalMS
+	}
+	if c.Session.TTLSec <= 0 {
+		c.Session.TTLSec = defaultSessionTTLSec
+	}
+	if c.Session.Rotation.IdleTimeoutSec < 0 {
+		c.Session.Rotation.IdleTimeoutSec = 0
+	}
+	if c.Session.Rotation.MaxTurns < 0 {
+		c.Session.Rotation.MaxTurns = 0
+	}
+	if c.Session.Rotation.MaxAgeSec < 0 {
+		c.Session.Rotation.MaxAgeSec = 0
+	}
+	if strings.TrimSpace(c.Session.StorePath) != "" {
+		c.Session.StorePath = resolvePath(configBaseDir, c.Session.StorePath)
+	} else if stateDir := c.StateDir(); stateDir != "" {
+		c.Session.StorePath = filepath.Join(stateDir, sessionStoreFileName)
+	}
+	if strings.TrimSpace(c.Discord.Attachments.CacheDir) != "" {
+		c.Discord.Attachments.CacheDir = resolvePath(configBaseDir, c.Discord.Attachments.CacheDir)
+	} else if c.Codex.WorkspaceDir != "" {
+		c.Discord.Attachments.CacheDir = filepath.Join(c.Codex.WorkspaceDir, ".yururi", "attachments")
+	}
+	if c.Discord.Attachments.MaxPerMessage < 0 {
+		c.Discord.Attachments.MaxPerMessage = 0
+	}
+	if c.Discord.Attachments.CacheMaxBytes < 0 {
+		c.Discord.Attachments.CacheMaxBytes = 0
+	}
+	if strings.TrimSpace(c.Discord.AuditLogPath) != "" {
+		c.Discord.AuditLogPath = resolvePath(configBaseDir, c.Discord.AuditLogPath)
+	} else if stateDir := c.StateDir(); stateDir != "" {
+		c.Discord.AuditLogPath = filepath.Join(stateDir, auditLogFileName)
+	}
+	if strings.TrimSpace(c.Discord.Dedup.StorePath) != "" {
+		c.Discord.Dedup.StorePath = resolvePath(configBaseDir, c.Discord.Dedup.StorePath)
+	} else if stateDir := c.StateDir(); stateDir != "" {
+		c.Discord.Dedup.StorePath = filepath.Join(stateDir, dedupStoreFileName)
+	}
+	if c.Discord.Dedup.WindowSec <= 0 {
+		c.Discord.Dedup.WindowSec = defaultDedupWindowSec
+	}
+	if strings.TrimSpace(c.Usage.StorePath) != "" {
+		c.Usage.StorePath = resolvePath(configBaseDir, c.Usage.StorePath)
+	} else if stateDir := c.StateDir(); stateDir != "" {
+		c.Usage.StorePath = filepath.Join(stateDir, usageStoreFileName)
+	}
+	if c.Usage.DailyTokenBudget < 0 {
+		c.Usage.DailyTokenBudget = 0
+	}
+	if c.Usage.ChannelDailyTokenBudget < 0 {
+		c.Usage.ChannelDailyTokenBudget = 0
+	}
+	if c.Usage.HeartbeatDailyTokenBudget < 0 {
+		c.Usage.HeartbeatDailyTokenBudget = 0
+	}
+	c.Usage.ExhaustedAction = strings.ToLower(strings.TrimSpace(c.Usage.ExhaustedAction))
+	if c.Usage.ExhaustedAction == "" {
+		c.Usage.ExhaustedAction = UsageExhaustedRefuse
+	}
+	c.Usage.DowngradeReasoningEffort = strings.TrimSpace(c.Usage.DowngradeReasoningEffort)
+	if c.Usage.DowngradeReasoningEffort == "" {
+		c.Usage.DowngradeReasoningEffort = defaultUsageDowngradeEffort
+	}
+	c.Routing.DefaultTier = strings.TrimSpace(c.Routing.DefaultTier)
+	if len(c.Routing.Tiers) > 0 {
+		tiers := make(map[string]RoutingTierConfig, len(c.Routing.Tiers))
+		for name, tier := range c.Routing.Tiers {
+			tiers[strings.TrimSpace(name)] = RoutingTierConfig{
+				Model:           strings.TrimSpace(tier.Model),
+				ReasoningEffort: strings.TrimSpace(tier.ReasoningEffort),
+			}
+		}
+		c.Routing.Tiers = tiers
+	}
+	for i := range c.Routing.Rules {
+		c.Routing.Rules[i].Tier = strings.TrimSpace(c.Routing.Rules[i].Tier)
+	}
+	c.Discord.ChannelOverrides = normalizeChannelOverrides(c.Discord.ChannelOverrides)
+	c.Discord.CategoryOverrides = normalizeChannelOverrides(c.Discord.CategoryOverrides)
 	c.Discord.ReadChannelIDs = cleanList(c.Discord.ReadChannelIDs)
 	c.Discord.WriteChannelIDs = cleanList(c.Discord.WriteChannelIDs)
 	c.Discord.ObserveChannelIDs = cleanList(c.Discord.ObserveChannelIDs)
 	c.Discord.ObserveCategoryIDs = cleanList(c.Discord.ObserveCategoryIDs)
 	c.Discord.ExcludedChannelIDs = cleanList(c.Discord.ExcludedChannelIDs)
 	c.Discord.AllowedBotUserIDs = cleanList(c.Discord.AllowedBotUserIDs)
+	c.Discord.DM.AllowedUserIDs = cleanList(c.Discord.DM.AllowedUserIDs)
+	if len(c.Discord.DM.AllowedUserIDs) == 0 && strings.TrimSpace(c.Persona.OwnerUserID) != "" {
+		c.Discord.DM.AllowedUserIDs = []string{strings.TrimSpace(c.Persona.OwnerUserID)}
+	}
+	c.Log.Format = strings.ToLower(strings.TrimSpace(c.Log.Format))
+	if c.Log.Format == "" {
+		c.Log.Format = LogFormatText
+	}
+	c.Log.File.Format = strings.ToLower(strings.TrimSpace(c.Log.File.Format))
+	c.Log.Level = strings.ToLower(strings.TrimSpace(c.Log.Level))
+	if c.Log.Level == "" {
+		c.Log.Level = "info"
+	}
+	if strings.TrimSpace(c.Log.File.Path) != "" {
+		c.Log.File.Path = resolvePath(configBaseDir, c.Log.File.Path)
+	}
+	if c.Log.File.MaxSizeMB < 0 {
+		c.Log.File.MaxSizeMB = 0
+	}
+	if c.Log.File.MaxBackups < 0 {
+		c.Log.File.MaxBackups = 0
+	}
 	c.MCP.ToolPolicy.AllowPatterns = cleanList(c.MCP.ToolPolicy.AllowPatterns)
 	c.MCP.ToolPolicy.DenyPatterns = cleanList(c.MCP.ToolPolicy.DenyPatterns)
 }
 
+func normalizeMCPServers(servers map[string]CodexMCPServerConfig) {
+	for name, server := range servers {
+		normalized := CodexMCPServerConfig{
+			URL:         strings.TrimSpace(server.URL),
+			Command:     strings.TrimSpace(server.Command),
+			Args:        cleanList(server.Args),
+			BearerToken: strings.TrimSpace(server.BearerToken),
+		}
+		if len(server.Headers) > 0 {
+			normalized.Headers = make(map[string]string, len(server.Headers))
+			for k, v := range server.Headers {
+				key := strings.TrimSpace(k)
+				val := strings.TrimSpace(v)
+				if key == "" || val == "" {
+					continue
+				}
+				normalized.Headers[key] = val
+			}
+		}
+		normalized = applyBearerTokenToServer(normalized)
+		servers[name] = normalized
+	}
+}
+
+func normalizeChannelOverrides(overrides map[string]ChannelOverrideConfig) map[string]ChannelOverrideConfig {
+	if len(overrides) == 0 {
+		return nil
+	}
+	out := make(map[string]ChannelOverrideConfig, len(overrides))
+	for id, override := range overrides {
+		key := strings.TrimSpace(id)
+		if key == "" {
+			continue
+		}
+		override.Model = strings.TrimSpace(override.Model)
+		override.ReasoningEffort = strings.TrimSpace(override.ReasoningEffort)
+		override.Sandbox = strings.ToLower(strings.TrimSpace(override.Sandbox))
+		normalizeMCPServers(override.MCPServers)
+		out[key] = override
+	}
+	return out
+}
+
+// ResolveOverride merges the category override and then the channel
+// override. Empty fields fall through to the codex defaults; MCP servers are
+// merged by name.
+func (d DiscordConfig) ResolveOverride(channelID string, categoryID string) ChannelOverrideConfig {
+	var resolved ChannelOverrideConfig
+	for _, override := range []ChannelOverrideConfig{
+		d.CategoryOverrides[strings.TrimSpace(categoryID)],
+		d.ChannelOverrides[strings.TrimSpace(channelID)],
+	} {
+		if override.Model != "" {
+			resolved.Model = override.Model
+		}
+		if override.ReasoningEffort != "" {
+			resolved.ReasoningEffort = override.ReasoningEffort
+		}
+		if override.Sandbox != "" {
+			resolved.Sandbox = override.Sandbox
+		}
+		for name, server := range override.MCPServers {
+			if resolved.MCPServers == nil {
+				resolved.MCPServers = map[string]CodexMCPServerConfig{}
+			}
+			resolved.MCPServers[name] = server
+		}
+	}
+	return resolved
+}
+
 func resolvePath(baseDir string, rawPath string) string {
 	path := strings.TrimSpace(rawPath)
 	if path == "" {
@@ -302,6 +862,35 @@ func applyEnvOverrides(cfg *Config) {
 	applyList("DISCORD_OBSERVE_CATEGORY_IDS", &cfg.Discord.ObserveCategoryIDs)
 	applyList("DISCORD_EXCLUDED_CHANNEL_IDS", &cfg.Discord.ExcludedChannelIDs)
 	applyList("DISCORD_ALLOWED_BOT_USER_IDS", &cfg.Discord.AllowedBotUserIDs)
+	applyString("DISCORD_STOP_REACTION_EMOJI", &cfg.Discord.StopReactionEmoji)
+	applyString("DISCORD_AUDIT_LOG_PATH", &cfg.Discord.AuditLogPath)
+	applyString("DISCORD_DEDUP_STORE_PATH", &cfg.Discord.Dedup.StorePath)
+	if v, ok := os.LookupEnv("DISCORD_ATTACHMENTS_ENABLED"); ok {
+		cfg.Discord.Attachments.Enabled = parseBool(v, cfg.Discord.Attachments.Enabled)
+	}
+	applyString("DISCORD_ATTACHMENTS_CACHE_DIR", &cfg.Discord.Attachments.CacheDir)
+	if v, ok := os.LookupEnv("DISCORD_DM_ENABLED"); ok {
+		cfg.Discord.DM.Enabled = parseBool(v, cfg.Discord.DM.Enabled)
+	}
+	applyList("DISCORD_DM_ALLOWED_USER_IDS", &cfg.Discord.DM.AllowedUserIDs)
+	if v, ok := os.LookupEnv("DISCORD_MESSAGE_EVENTS_ENABLED"); ok {
+		cfg.Discord.MessageEvents.Enabled = parseBool(v, cfg.Discord.MessageEvents.Enabled)
+	}
+	if v, ok := os.LookupEnv("DISCORD_MESSAGE_EVENTS_TRIGGER_TURN"); ok {
+		cfg.Discord.MessageEvents.TriggerTurn = parseBool(v, cfg.Discord.MessageEvents.TriggerTurn)
+	}
+	if v, ok := os.LookupEnv("DISCORD_LIVE_MESSAGE_ENABLED"); ok {
+		cfg.Discord.LiveMessage.Enabled = parseBool(v, cfg.Discord.LiveMessage.Enabled)
+	}
+	if v, ok := os.LookupEnv("DISCORD_LIVE_MESSAGE_START_DELAY_MS"); ok {
+		cfg.Discord.LiveMessage.StartDelayMS = parseInt(v, cfg.Discord.LiveMessage.StartDelayMS)
+	}
+	if v, ok := os.LookupEnv("DISCORD_LIVE_MESSAGE_EDIT_INTERVAL_MS"); ok {
+		cfg.Discord.LiveMessage.EditIntervalMS = parseInt(v, cfg.Discord.LiveMessage.EditIntervalMS)
+	}
+	if v, ok := os.LookupEnv("DISCORD_LIVE_MESSAGE_KEEP_FINAL_TEXT"); ok {
+		cfg.Discord.LiveMessage.KeepFinalText = parseBool(v, cfg.Discord.LiveMessage.KeepFinalText)
+	}
 	applyString("PERSONA_OWNER_USER_ID", &cfg.Persona.OwnerUserID)
 	applyString("CODEX_COMMAND", &cfg.Codex.Command)
 	if v, ok := os.LookupEnv("CODEX_ARGS"); ok {
@@ -313,6 +902,23 @@ func applyEnvOverrides(cfg *Config) {
 	applyString("CODEX_WORKSPACE_DIR", &cfg.Codex.WorkspaceDir)
 	applyString("CODEX_HOME", &cfg.Codex.HomeDir)
 	applyString("CODEX_HOME_DIR", &cfg.Codex.HomeDir)
+	if v, ok := os.LookupEnv("CODEX_POOL_SIZE"); ok {
+		cfg.Codex.PoolSize = parseInt(v, cfg.Codex.PoolSize)
+	}
+	if v, ok := os.LookupEnv("CODEX_HEALTH_CHECK_INTERVAL_SEC"); ok {
+		cfg.Codex.HealthCheckIntervalSec = parseInt(v, cfg.Codex.HealthCheckIntervalSec)
+	}
+	applyList("CODEX_APPROVAL_ALLOW_COMMANDS", &cfg.Codex.Approval.AllowCommands)
+	applyList("CODEX_APPROVAL_DENY_COMMANDS", &cfg.Codex.Approval.DenyCommands)
+	applyList("CODEX_APPROVAL_ALLOW_PATHS", &cfg.Codex.Approval.AllowPaths)
+	if v, ok := os.LookupEnv("CODEX_APPROVAL_WORKSPACE_ONLY"); ok {
+		cfg.Codex.Approval.WorkspaceOnly = parseBool(v, cfg.Codex.Approval.WorkspaceOnly)
+	}
+	applyString("CODEX_APPROVAL_FALLBACK", &cfg.Codex.Approval.Fallback)
+	applyString("CODEX_APPROVAL_OWNER_CHANNEL_ID", &cfg.Codex.Approval.OwnerChannelID)
+	if v, ok := os.LookupEnv("CODEX_APPROVAL_OWNER_TIMEOUT_SEC"); ok {
+		cfg.Codex.Approval.OwnerTimeoutSec = parseInt(v, cfg.Codex.Approval.OwnerTimeoutSec)
+	}
 	applyString("MCP_BIND", &cfg.MCP.Bind)
 	applyString("MCP_URL", &cfg.MCP.URL)
 	applyList("MCP_TOOL_POLICY_ALLOW_PATTERNS", &cfg.MCP.ToolPolicy.AllowPatterns)
@@ -331,6 +937,44 @@ func applyEnvOverrides(cfg *Config) {
 	if v, ok := os.LookupEnv("XAI_TIMEOUT_SEC"); ok {
 		cfg.XAI.TimeoutSec = parseInt(v, cfg.XAI.TimeoutSec)
 	}
+	if v, ok := os.LookupEnv("SESSION_PERSIST"); ok {
+		cfg.Session.Persist = parseBool(v, cfg.Session.Persist)
+	}
+	applyString("SESSION_STORE_PATH", &cfg.Session.StorePath)
+	if v, ok := os.LookupEnv("SESSION_TTL_SEC"); ok {
+		cfg.Session.TTLSec = parseInt(v, cfg.Session.TTLSec)
+	}
+	if v, ok := os.LookupEnv("SESSION_ROTATION_IDLE_TIMEOUT_SEC"); ok {
+		cfg.Session.Rotation.IdleTimeoutSec = parseInt(v, cfg.Session.Rotation.IdleTimeoutSec)
+	}
+	if v, ok := os.LookupEnv("SESSION_ROTATION_MAX_TURNS"); ok {
+		cfg.Session.Rotation.MaxTurns = parseInt(v, cfg.Session.Rotation.MaxTurns)
+	}
+	if v, ok := os.LookupEnv("SESSION_ROTATION_MAX_AGE_SEC"); ok {
+		cfg.Session.Rotation.MaxAgeSec = parseInt(v, cfg.Session.Rotation.MaxAgeSec)
+	}
+	if v, ok := os.LookupEnv("SESSION_ROTATION_DAILY_ROLLOVER_HOUR"); ok {
+		cfg.Session.Rotation.DailyRolloverHour = parseInt(v, cfg.Session.Rotation.DailyRolloverHour)
+	}
+	if v, ok := os.LookupEnv("ROUTING_ENABLED"); ok {
+		cfg.Routing.Enabled = parseBool(v, cfg.Routing.Enabled)
+	}
+	applyString("ROUTING_DEFAULT_TIER", &cfg.Routing.DefaultTier)
+	applyString("USAGE_STORE_PATH", &cfg.Usage.StorePath)
+	if v, ok := os.LookupEnv("USAGE_DAILY_TOKEN_BUDGET"); ok {
+		cfg.Usage.DailyTokenBudget = parseInt64(v, cfg.Usage.DailyTokenBudget)
+	}
+	if v, ok := os.LookupEnv("USAGE_CHANNEL_DAILY_TOKEN_BUDGET"); ok {
+		cfg.Usage.ChannelDailyTokenBudget = parseInt64(v, cfg.Usage.ChannelDailyTokenBudget)
+	}
+	if v, ok := os.LookupEnv("USAGE_HEARTBEAT_DAILY_TOKEN_BUDGET"); ok {
+		cfg.Usage.HeartbeatDailyTokenBudget = parseInt64(v, cfg.Usage.HeartbeatDailyTokenBudget)
+	}
+	applyString("USAGE_EXHAUSTED_ACTION", &cfg.Usage.ExhaustedAction)
+	applyString("USAGE_DOWNGRADE_REASONING_EFFORT", &cfg.Usage.DowngradeReasoningEffort)
+	applyString("LOG_FORMAT", &cfg.Log.Format)
+	applyString("LOG_LEVEL", &cfg.Log.Level)
+	applyString("LOG_FILE_PATH", &cfg.Log.File.Path)
 	if v, ok := os.LookupEnv("CODEX_MCP_TWILOG_BEARER_TOKEN"); ok {
 		name := "twilog-mcp"
 		server := cfg.Codex.MCPServers[name]
@@ -453,6 +1097,18 @@ func parseInt(raw string, fallback int) int {
 	return n
 }
 
+func parseInt64(raw string, fallback int64) int64 {
+	v := strings.TrimSpace(raw)
+	if v == "" {
+		return fallback
+	}
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		return fallback
+	}
+	return n
+}
+
 func setCurrentMCPToolPolicy(policy MCPToolPolicyConfig) {
 	currentMCPToolPolicyMu.Lock()
 	defer currentMCPToolPolicyMu.Unlock()
@@ -3,7 +3,9 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestLoadSetsDefaults(t *testing.T) {
@@ -45,6 +47,9 @@ codex:
 	if !cfg.Heartbeat.Enabled {
 		t.Fatal("Heartbeat.Enabled = false, want true by default")
 	}
+	if !cfg.Discord.MessageEvents.Enabled || cfg.Discord.MessageEvents.TriggerTurn {
+		t.Fatalf("Discord.MessageEvents = %+v, want enabled without trigger_turn", cfg.Discord.MessageEvents)
+	}
 	if cfg.XAI.Enabled {
 		t.Fatal("XAI.Enabled = true, want false by default")
 	}
@@ -60,6 +65,66 @@ codex:
 	if len(cfg.Discord.ObserveCategoryIDs) != 0 {
 		t.Fatalf("Discord.ObserveCategoryIDs = %v, want empty", cfg.Discord.ObserveCategoryIDs)
 	}
+	if !cfg.Session.Persist {
+		t.Fatal("Session.Persist = false, want true by default")
+	}
+	if cfg.Session.TTLSec != 86400 {
+		t.Fatalf("Session.TTLSec = %d, want 86400", cfg.Session.TTLSec)
+	}
+	if want := filepath.Join(dir, ".codex-home", "yururi", "sessions.json"); cfg.Session.StorePath != want {
+		t.Fatalf("Session.StorePath = %q, want %q", cfg.Session.StorePath, want)
+	}
+	if want := filepath.Join(dir, ".codex-home", "yururi", "usage.json"); cfg.Usage.StorePath != want {
+		t.Fatalf("Usage.StorePath = %q, want %q", cfg.Usage.StorePath, want)
+	}
+	if want := filepath.Join(dir, ".codex-home", "yururi", "audit.jsonl"); cfg.Discord.AuditLogPath != want {
+		t.Fatalf("Discord.AuditLogPath = %q, want %q", cfg.Discord.AuditLogPath, want)
+	}
+	if want := filepath.Join(dir, ".codex-home", "yururi", "dedup.json"); cfg.Discord.Dedup.StorePath != want {
+		t.Fatalf("Discord.Dedup.StorePath = %q, want %q", cfg.Discord.Dedup.StorePath, want)
+	}
+	if cfg.Discord.Dedup.WindowSec != 600 || cfg.Discord.Dedup.SimilarityThreshold != 0.9 {
+		t.Fatalf("Discord.Dedup = %+v, want 600s window and 0.9 threshold", cfg.Discord.Dedup)
+	}
+	if want := filepath.Join(dir, "workspace", ".yururi", "attachments"); !cfg.Discord.Attachments.Enabled || cfg.Discord.Attachments.CacheDir != want || cfg.Discord.Attachments.CacheMaxBytes != 512<<20 {
+		t.Fatalf("Discord.Attachments = %+v, want enabled with 512MiB cache dir %q", cfg.Discord.Attachments, want)
+	}
+	if cfg.Usage.ExhaustedAction != UsageExhaustedRefuse || cfg.Usage.DowngradeReasoningEffort != "low" || cfg.Usage.DailyTokenBudget != 0 {
+		t.Fatalf("Usage = %+v, want refuse/low with no budget", cfg.Usage)
+	}
+	if cfg.Session.Rotation.DailyRolloverHour != -1 {
+		t.Fatalf("Session.Rotation.DailyRolloverHour = %d, want -1", cfg.Session.Rotation.DailyRolloverHour)
+	}
+	if cfg.Discord.StopReactionEmoji != "🛑" {
+		t.Fatalf("Discord.StopReactionEmoji = %q, want 🛑", cfg.Discord.StopReactionEmoji)
+	}
+	if cfg.MCP.UploadMaxBytes != 8<<20 {
+		t.Fatalf("MCP.UploadMaxBytes = %d, want %d", cfg.MCP.UploadMaxBytes, 8<<20)
+	}
+	if cfg.Codex.PoolSize != 1 {
+		t.Fatalf("Codex.PoolSize = %d, want 1", cfg.Codex.PoolSize)
+	}
+	if cfg.Codex.HealthCheckIntervalSec != 30 {
+		t.Fatalf("Codex.HealthCheckIntervalSec = %d, want 30", cfg.Codex.HealthCheckIntervalSec)
+	}
+	if cfg.Codex.Approval.Fallback != ApprovalFallbackApprove || !cfg.Codex.Approval.WorkspaceOnly {
+		t.Fatalf("Codex.Approval = %+v, want fallback approve with workspace_only", cfg.Codex.Approval)
+	}
+	if cfg.Codex.Approval.OwnerTimeoutSec != 300 {
+		t.Fatalf("Codex.Approval.OwnerTimeoutSec = %d, want 300", cfg.Codex.Approval.OwnerTimeoutSec)
+	}
+	if cfg.Discord.LiveMessage.Enabled {
+		t.Fatal("Discord.LiveMessage.Enabled = true, want false by default")
+	}
+	if cfg.Discord.LiveMessage.StartDelayMS != 5000 || cfg.Discord.LiveMessage.EditIntervalMS != 1500 {
+		t.Fatalf("Discord.LiveMessage = %+v, want start 5000ms / edit 1500ms", cfg.Discord.LiveMessage)
+	}
+	if cfg.Log.Format != LogFormatText || cfg.Log.Level != "info" || cfg.Log.File.Path != "" {
+		t.Fatalf("Log = %+v, want text/info without file", cfg.Log)
+	}
+	if cfg.Log.File.MaxSizeMB != 50 || cfg.Log.File.MaxBackups != 5 {
+		t.Fatalf("Log.File = %+v, want 50MB with 5 backups", cfg.Log.File)
+	}
 }
 
 func TestLoadResolvesRelativeWorkspaceAndHomeFromConfigDir(t *testing.T) {
@@ -288,6 +353,328 @@ codex:
 	}
 }
 
+func TestLoadRejectsInvalidRolloverHour(t *testing.T) {
+	dir := t.TempDir()
+	cfgPath := filepath.Join(dir, "config.yaml")
+	body := `discord:
+  token: "token"
+  guild_id: "guild"
+  read_channel_ids: ["channel"]
+persona:
+  owner_user_id: "owner"
+codex:
+  command: "codex"
+  args: ["--search", "app-server", "--listen", "stdio://"]
+session:
+  rotation:
+    daily_rollover_hour: 24
+`
+	if err := os.WriteFile(cfgPath, []byte(body), 0o644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	if _, err := Load(cfgPath); err == nil {
+		t.Fatal("Load() error = nil, want rollover hour validation error")
+	}
+}
+
+func TestLoadLogConfig(t *testing.T) {
+	tests := []struct {
+		name      string
+		log       string
+		env       map[string]string
+		wantErr   bool
+		wantLevel string
+		wantPath  string
+	}{
+		{
+			name:      "file path is resolved from config dir",
+			log:       "log:\n  format: JSON\n  level: Debug\n  file:\n    path: logs/yururi.log\n",
+			wantLevel: "debug",
+			wantPath:  "logs/yururi.log",
+		},
+		{
+			name:      "env overrides",
+			log:       "log:\n  level: info\n",
+			env:       map[string]string{"LOG_LEVEL": "warn", "LOG_FILE_PATH": "env.log"},
+			wantLevel: "warn",
+			wantPath:  "env.log",
+		},
+		{
+			name:    "unknown format",
+			log:     "log:\n  format: xml\n",
+			wantErr: true,
+		},
+		{
+			name:    "unknown level",
+			log:     "log:\n  level: verbose\n",
+			wantErr: true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			cfgPath := filepath.Join(dir, "config.yaml")
+			body := `discord:
+  token: "token"
+  guild_id: "guild"
+  read_channel_ids: ["channel"]
+persona:
+  owner_user_id: "owner"
+codex:
+  command: "codex"
+  args: ["--search", "app-server", "--listen", "stdio://"]
+` + tc.log
+			if err := os.WriteFile(cfgPath, []byte(body), 0o644); err != nil {
+				t.Fatalf("WriteFile() error = %v", err)
+			}
+			for key, value := range tc.env {
+				t.Setenv(key, value)
+			}
+
+			cfg, err := Load(cfgPath)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("Load() error = %v, wantErr %t", err, tc.wantErr)
+			}
+			if tc.wantErr {
+				return
+			}
+			if cfg.Log.Level != tc.wantLevel {
+				t.Fatalf("Log.Level = %q, want %q", cfg.Log.Level, tc.wantLevel)
+			}
+			if want := filepath.Join(dir, tc.wantPath); cfg.Log.File.Path != want {
+				t.Fatalf("Log.File.Path = %q, want %q", cfg.Log.File.Path, want)
+			}
+		})
+	}
+}
+
+func TestLoadValidatesApprovalPolicy(t *testing.T) {
+	tests := []struct {
+		name     string
+		approval string
+		wantErr  bool
+	}{
+		{name: "unknown fallback", approval: "    fallback: ask\n", wantErr: true},
+		{name: "owner without channel", approval: "    fallback: owner\n", wantErr: true},
+		{name: "owner with channel", approval: "    fallback: owner\n    owner_channel_id: \"admin\"\n"},
+		{name: "deny", approval: "    fallback: DENY\n"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			cfgPath := filepath.Join(dir, "config.yaml")
+			body := `discord:
+  token: "token"
+  guild_id: "guild"
+  read_channel_ids: ["channel"]
+persona:
+  owner_user_id: "owner"
+codex:
+  command: "codex"
+  args: ["--search", "app-server", "--listen", "stdio://"]
+  workspace_dir: "./workspace"
+  approval:
+    allow_paths: ["notes"]
+` + tc.approval
+			if err := os.WriteFile(cfgPath, []byte(body), 0o644); err != nil {
+				t.Fatalf("WriteFile() error = %v", err)
+			}
+
+			cfg, err := Load(cfgPath)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatal("Load() error = nil, want approval validation error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Load() error = %v", err)
+			}
+			wantPath := filepath.Join(cfg.Codex.WorkspaceDir, "notes")
+			if len(cfg.Codex.Approval.AllowPaths) != 1 || cfg.Codex.Approval.AllowPaths[0] != wantPath {
+				t.Fatalf("Codex.Approval.AllowPaths = %v, want [%s]", cfg.Codex.Approval.AllowPaths, wantPath)
+			}
+		})
+	}
+}
+
+func TestLoadResolvesChannelOverrides(t *testing.T) {
+	dir := t.TempDir()
+	cfgPath := filepath.Join(dir, "config.yaml")
+	body := `discord:
+  token: "token"
+  guild_id: "guild"
+  read_channel_ids: ["general", "dev"]
+  category_overrides:
+    " work ":
+      model: "gpt-5.3-codex-mini"
+      sandbox: "Read-Only"
+      mcp_servers:
+        search:
+          url: " http://127.0.0.1:40001/mcp "
+  channel_overrides:
+    dev:
+      reasoning_effort: "high"
+      sandbox: "workspace-write"
+      mcp_servers:
+        notes:
+          url: "http://127.0.0.1:40000/mcp"
+          bearer_token: "secret"
+codex:
+  command: "codex"
+  args: ["--search", "app-server", "--listen", "stdio://"]
+`
+	if err := os.WriteFile(cfgPath, []byte(body), 0o644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	cfg, err := Load(cfgPath)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	got := cfg.Discord.ResolveOverride("dev", "work")
+	if got.Model != "gpt-5.3-codex-mini" || got.ReasoningEffort != "high" || got.Sandbox != SandboxWorkspaceWrite {
+		t.Fatalf("ResolveOverride(dev, work) = %+v", got)
+	}
+	if got.MCPServers["search"].URL != "http://127.0.0.1:40001/mcp" {
+		t.Fatalf("search server = %+v", got.MCPServers["search"])
+	}
+	if got.MCPServers["notes"].Headers["Authorization"] != "Bearer secret" {
+		t.Fatalf("notes server = %+v", got.MCPServers["notes"])
+	}
+
+	categoryOnly := cfg.Discord.ResolveOverride("general", "work")
+	if categoryOnly.Sandbox != SandboxReadOnly || categoryOnly.ReasoningEffort != "" || len(categoryOnly.MCPServers) != 1 {
+		t.Fatalf("ResolveOverride(general, work) = %+v", categoryOnly)
+	}
+	if none := cfg.Discord.ResolveOverride("general", ""); none.Model != "" || none.MCPServers != nil {
+		t.Fatalf("ResolveOverride(general) = %+v, want empty", none)
+	}
+}
+
+func TestLoadRejectsInvalidOverrideSandbox(t *testing.T) {
+	dir := t.TempDir()
+	cfgPath := filepath.Join(dir, "config.yaml")
+	body := `discord:
+  token: "token"
+  guild_id: "guild"
+  read_channel_ids: ["general"]
+  channel_overrides:
+    general:
+      sandbox: "full"
+codex:
+  command: "codex"
+  args: ["--search", "app-server", "--listen", "stdio://"]
+`
+	if err := os.WriteFile(cfgPath, []byte(body), 0o644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	if _, err := Load(cfgPath); err == nil {
+		t.Fatal("Load() error = nil, want sandbox validation error")
+	}
+}
+
+func TestLoadDefaultsDMAllowlistToOwner(t *testing.T) {
+	tests := []struct {
+		name      string
+		dm        string
+		owner     string
+		wantUsers []string
+		wantErr   bool
+	}{
+		{name: "owner fallback", dm: "  dm:\n    enabled: true\n", owner: "owner", wantUsers: []string{"owner"}},
+		{name: "explicit allowlist", dm: "  dm:\n    enabled: true\n    allowed_user_ids: [\" u1 \", \"\"]\n", owner: "owner", wantUsers: []string{"u1"}},
+		{name: "no users", dm: "  dm:\n    enabled: true\n", wantErr: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			cfgPath := filepath.Join(dir, "config.yaml")
+			body := `discord:
+  token: "token"
+  guild_id: "guild"
+  read_channel_ids: ["channel"]
+` + tc.dm + `persona:
+  owner_user_id: "` + tc.owner + `"
+codex:
+  command: "codex"
+  args: ["--search", "app-server", "--listen", "stdio://"]
+`
+			if err := os.WriteFile(cfgPath, []byte(body), 0o644); err != nil {
+				t.Fatalf("WriteFile() error = %v", err)
+			}
+
+			cfg, err := Load(cfgPath)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatal("Load() error = nil, want dm validation error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Load() error = %v", err)
+			}
+			if strings.Join(cfg.Discord.DM.AllowedUserIDs, ",") != strings.Join(tc.wantUsers, ",") {
+				t.Fatalf("DM.AllowedUserIDs = %#v, want %#v", cfg.Discord.DM.AllowedUserIDs, tc.wantUsers)
+			}
+		})
+	}
+}
+
+func TestLoadValidatesRoutingTiers(t *testing.T) {
+	tests := []struct {
+		name    string
+		routing string
+		wantErr bool
+	}{
+		{name: "valid", routing: "  default_tier: \" standard \"\n  rules:\n    - tier: deep\n      min_length: 200\n"},
+		{name: "unknown default tier", routing: "  default_tier: \"huge\"\n", wantErr: true},
+		{name: "unknown rule tier", routing: "  rules:\n    - tier: huge\n", wantErr: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			cfgPath := filepath.Join(dir, "config.yaml")
+			body := `discord:
+  token: "token"
+  guild_id: "guild"
+  read_channel_ids: ["channel"]
+codex:
+  command: "codex"
+  args: ["--search", "app-server", "--listen", "stdio://"]
+routing:
+  enabled: true
+  tiers:
+    standard: {}
+    deep:
+      reasoning_effort: " high "
+` + tc.routing
+			if err := os.WriteFile(cfgPath, []byte(body), 0o644); err != nil {
+				t.Fatalf("WriteFile() error = %v", err)
+			}
+
+			cfg, err := Load(cfgPath)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatal("Load() error = nil, want routing validation error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Load() error = %v", err)
+			}
+			if cfg.Routing.DefaultTier != "standard" {
+				t.Fatalf("Routing.DefaultTier = %q, want standard", cfg.Routing.DefaultTier)
+			}
+			if cfg.Routing.Tiers["deep"].ReasoningEffort != "high" {
+				t.Fatalf("Routing.Tiers[deep] = %+v", cfg.Routing.Tiers["deep"])
+			}
+		})
+	}
+}
+
 func TestLoadAppliesMCPBearerTokenFromConfig(t *testing.T) {
 	dir := t.TempDir()
 	cfgPath := filepath.Join(dir, "config.yaml")
@@ -414,3 +801,57 @@ codex:
 		t.Fatalf("twilog auth args = %v, want rewritten auth header", server.Args)
 	}
 }
+
+func TestAttachmentsLimitsFor(t *testing.T) {
+	cfg := AttachmentsConfig{
+		MaxPerMessage: 4,
+		MaxImageBytes: 1000,
+		MaxTextBytes:  500,
+		MaxTextChars:  100,
+		ChannelLimits: map[string]AttachmentLimitsConfig{
+			"c-strict": {MaxPerMessage: 1, MaxImageBytes: 10},
+			"c-parent": {MaxTextChars: 20},
+		},
+	}
+	tests := []struct {
+		name     string
+		channel  string
+		parent   string
+		wantMax  int
+		wantImg  int64
+		wantText int64
+		wantChar int
+	}{
+		{name: "global", channel: "c-other", wantMax: 4, wantImg: 1000, wantText: 500, wantChar: 100},
+		{name: "channel override", channel: "c-strict", wantMax: 1, wantImg: 10, wantText: 500, wantChar: 100},
+		{name: "thread inherits parent", channel: "t1", parent: "c-parent", wantMax: 4, wantImg: 1000, wantText: 500, wantChar: 20},
+	}
+	for _, tc := range tests {
+		got := cfg.LimitsFor(tc.channel, tc.parent)
+		if got.MaxPerMessage != tc.wantMax || got.MaxImageBytes != tc.wantImg || got.MaxTextBytes != tc.wantText || got.MaxTextChars != tc.wantChar {
+			t.Fatalf("%s: LimitsFor() = %+v", tc.name, got)
+		}
+	}
+}
+
+func TestDedupWindowFor(t *testing.T) {
+	cfg := DedupConfig{
+		WindowSec:        600,
+		ChannelWindowSec: map[string]int{"c-news": 86400, "c-parent": 60},
+	}
+	tests := []struct {
+		name    string
+		channel string
+		parent  string
+		want    time.Duration
+	}{
+		{name: "global", channel: "c-other", want: 10 * time.Minute},
+		{name: "channel override", channel: "c-news", want: 24 * time.Hour},
+		{name: "thread inherits parent", channel: "t1", parent: "c-parent", want: time.Minute},
+	}
+	for _, tc := range tests {
+		if got := cfg.WindowFor(tc.channel, tc.parent); got != tc.want {
+			t.Fatalf("%s: WindowFor() = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,296 @@
+package dedup
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	storeVersion = 1
+	// shingleRunes is the length of the character shingles compared for near
+	// duplicates. Characters rather than words keep Japanese text comparable.
+	shingleRunes = 3
+	// minShingles keeps short texts to exact matching, where a changed word
+	// already moves the similarity a lot.
+	minShingles = 8
+)
+
+// Post is something the bot wrote. Key identifies it exactly; Text, when set,
+// is also compared with earlier posts for near duplicates.
+type Post struct {
+	ChannelID string
+	Key       string
+	Text      string
+}
+
+// Store remembers recent posts per channel until their window expires. With
+// a path it survives restarts; without one it only lives in memory.
+type Store struct {
+	path      string
+	threshold float64
+	now       func() time.Time
+
+	mu       sync.Mutex
+	channels map[string][]entry
+}
+
+type entry struct {
+	Hash      string    `json:"hash"`
+	Shingles  []uint32  `json:"shingles,omitempty"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+type storeFile struct {
+	Version  int                `json:"version"`
+	Channels map[string][]entry `json:"channels"`
+}
+
+// NewStore keeps posts in path. Texts at least threshold similar count as
+// duplicates; a zero threshold only matches exactly.
+func NewStore(path string, threshold float64) *Store {
+	return &Store{
+		path:      strings.TrimSpace(path),
+		threshold: threshold,
+		now:       time.Now,
+		channels:  map[string][]entry{},
+	}
+}
+
+// Load restores the posts saved by an earlier run, dropping expired ones. It
+// returns how many were restored.
+func (s *Store) Load() (int, error) {
+	if s == nil || s.path == "" {
+		return 0, nil
+	}
+	body, err := os.ReadFile(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("read dedup store: %w", err)
+	}
+	var file storeFile
+	if err := json.Unmarshal(body, &file); err != nil {
+		corruptPath := s.path + ".corrupt"
+		if renameErr := os.Rename(s.path, corruptPath); renameErr != nil {
+			return 0, fmt.Errorf("decode dedup store: %w (move aside: %v)", err, renameErr)
+		}
+		return 0, fmt.Errorf("decode dedup store (moved to %s): %w", corruptPath, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.channels = map[string][]entry{}
+	for channelID, entries := range file.Channels {
+		s.channels[channelID] = entries
+	}
+	s.pruneLocked(s.now())
+	restored := 0
+	for _, entries := range s.channels {
+		restored += len(entries)
+	}
+	return restored, nil
+}
+
+// Seen reports whether post matches an unexpired post in its channel.
+func (s *Store) Seen(post Post) bool {
+	hash := Signature(post.Key)
+	if s == nil || post.ChannelID == "" || hash == "" {
+		return false
+	}
+	shingles := shingleSet(post.Text)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	for _, e := range s.channels[post.ChannelID] {
+		if !now.Before(e.ExpiresAt) {
+			continue
+		}
+		if e.Hash == hash {
+			return true
+		}
+		if s.threshold > 0 && len(shingles) > 0 && jaccard(shingles, e.Shingles) >= s.threshold {
+			return true
+		}
+	}
+	return false
+}
+
+// Remember records post for window and saves the store.
+func (s *Store) Remember(post Post, window time.Duration) error {
+	hash := Signature(post.Key)
+	if s == nil || post.ChannelID == "" || hash == "" || window <= 0 {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.pruneLocked(now)
+	entries := withoutHash(s.channels[post.ChannelID], hash)
+	s.channels[post.ChannelID] = append(entries, entry{
+		Hash:      hash,
+		Shingles:  shingleSet(post.Text),
+		ExpiresAt: now.Add(window).UTC(),
+	})
+	return s.flushLocked()
+}
+
+// Forget drops the post with key so it may be sent again.
+func (s *Store) Forget(channelID string, key string) error {
+	hash := Signature(key)
+	if s == nil || channelID == "" || hash == "" {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries, ok := s.channels[channelID]
+	if !ok {
+		return nil
+	}
+	kept := withoutHash(entries, hash)
+	if len(kept) == len(entries) {
+		return nil
+	}
+	if len(kept) == 0 {
+		delete(s.channels, channelID)
+	} else {
+		s.channels[channelID] = kept
+	}
+	return s.flushLocked()
+}
+
+func (s *Store) pruneLocked(now time.Time) {
+	for channelID, entries := range s.channels {
+		kept := entries[:0]
+		for _, e := range entries {
+			if now.Before(e.ExpiresAt) {
+				kept = append(kept, e)
+			}
+		}
+		if len(kept) == 0 {
+			delete(s.channels, channelID)
+			continue
+		}
+		s.channels[channelID] = kept
+	}
+}
+
+func withoutHash(entries []entry, hash string) []entry {
+	kept := make([]entry, 0, len(entries))
+	for _, e := range entries {
+		if e.Hash != hash {
+			kept = append(kept, e)
+		}
+	}
+	return kept
+}
+
+func (s *Store) flushLocked() error {
+	if s.path == "" {
+		return nil
+	}
+	body, err := json.Marshal(storeFile{Version: storeVersion, Channels: s.channels})
+	if err != nil {
+		return fmt.Errorf("encode dedup store: %w", err)
+	}
+	return writeFileAtomic(s.path, body)
+}
+
+// Signature hashes text after folding case and whitespace, so posts that
+// differ only in those are the same.
+func Signature(text string) string {
+	normalized := normalize(text)
+	if normalized == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
+
+func normalize(text string) string {
+	return strings.ToLower(strings.Join(strings.Fields(text), " "))
+}
+
+// shingleSet returns the sorted hashes of text's shingles, or nil for texts
+// too short for near-duplicate matching.
+func shingleSet(text string) []uint32 {
+	runes := []rune(normalize(text))
+	if len(runes)-shingleRunes+1 < minShingles {
+		return nil
+	}
+	seen := map[uint32]struct{}{}
+	set := make([]uint32, 0, len(runes)-shingleRunes+1)
+	for i := 0; i+shingleRunes <= len(runes); i++ {
+		h := fnv.New32a()
+		_, _ = h.Write([]byte(string(runes[i : i+shingleRunes])))
+		sum := h.Sum32()
+		if _, ok := seen[sum]; ok {
+			continue
+		}
+		seen[sum] = struct{}{}
+		set = append(set, sum)
+	}
+	sort.Slice(set, func(i, j int) bool { return set[i] < set[j] })
+	return set
+}
+
+// jaccard compares two sorted sets.
+func jaccard(a []uint32, b []uint32) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	common := 0
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		switch {
+		case a[i] == b[j]:
+			common++
+			i++
+			j++
+		case a[i] < b[j]:
+			i++
+		default:
+			j++
+		}
+	}
+	return float64(common) / float64(len(a)+len(b)-common)
+}
+
+func writeFileAtomic(path string, body []byte) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return fmt.Errorf("create dedup store dir: %w", err)
+	}
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("create temp dedup store: %w", err)
+	}
+	tmpPath := tmp.Name()
+	_, writeErr := tmp.Write(body)
+	closeErr := tmp.Close()
+	if err := errors.Join(writeErr, closeErr); err != nil {
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("write dedup store: %w", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("replace dedup store: %w", err)
+	}
+	return nil
+}
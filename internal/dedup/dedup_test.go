@@ -0,0 +1,143 @@
+package dedup
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const announcement = "今夜21時からメンテナンスを行います。作業中はbotが一時的に応答しなくなります。"
+
+func TestStoreSeen(t *testing.T) {
+	t.Parallel()
+
+	store := NewStore("", 0.8)
+	if err := store.Remember(Post{ChannelID: "c1", Key: announcement, Text: announcement}, time.Hour); err != nil {
+		t.Fatalf("Remember() error = %v", err)
+	}
+	if err := store.Remember(Post{ChannelID: "c1", Key: "file\nreport.csv\nabc"}, time.Hour); err != nil {
+		t.Fatalf("Remember() error = %v", err)
+	}
+
+	tests := []struct {
+		name string
+		post Post
+		want bool
+	}{
+		{name: "exact", post: Post{ChannelID: "c1", Key: announcement, Text: announcement}, want: true},
+		{name: "case and spacing", post: Post{ChannelID: "c1", Key: "FILE  report.csv abc"}, want: true},
+		{name: "near duplicate", post: Post{ChannelID: "c1", Key: "x", Text: "今夜21時からメンテナンスを行います！作業中はbotが一時的に応答しなくなります。"}, want: true},
+		{name: "near duplicate without text", post: Post{ChannelID: "c1", Key: "今夜21時からメンテナンスを行います！作業中はbotが一時的に応答しなくなります。"}, want: false},
+		{name: "different text", post: Post{ChannelID: "c1", Key: "y", Text: "明日の天気は晴れのち曇り、夜から雨が降る見込みです。"}, want: false},
+		{name: "other channel", post: Post{ChannelID: "c2", Key: announcement, Text: announcement}, want: false},
+		{name: "empty", post: Post{ChannelID: "c1"}, want: false},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			if got := store.Seen(tc.post); got != tc.want {
+				t.Fatalf("Seen() = %t, want %t", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestStoreShortTextsOnlyMatchExactly(t *testing.T) {
+	t.Parallel()
+
+	store := NewStore("", 0.5)
+	if err := store.Remember(Post{ChannelID: "c1", Key: "おはよう", Text: "おはよう"}, time.Hour); err != nil {
+		t.Fatalf("Remember() error = %v", err)
+	}
+	if store.Seen(Post{ChannelID: "c1", Key: "おはよう！", Text: "おはよう！"}) {
+		t.Fatal("Seen() = true for a short near match")
+	}
+
+	exact := NewStore("", 0)
+	if err := exact.Remember(Post{ChannelID: "c1", Key: announcement, Text: announcement}, time.Hour); err != nil {
+		t.Fatalf("Remember() error = %v", err)
+	}
+	if exact.Seen(Post{ChannelID: "c1", Key: "x", Text: announcement + "。"}) {
+		t.Fatal("Seen() = true for a near match with threshold 0")
+	}
+}
+
+func TestStorePersistsAndExpires(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "state", "dedup.json")
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	store := NewStore(path, 0.9)
+	store.now = func() time.Time { return now }
+	for _, post := range []struct {
+		key    string
+		window time.Duration
+	}{
+		{key: "short lived", window: time.Minute},
+		{key: "long lived", window: time.Hour},
+		{key: "forgotten", window: time.Hour},
+	} {
+		if err := store.Remember(Post{ChannelID: "c1", Key: post.key}, post.window); err != nil {
+			t.Fatalf("Remember(%s) error = %v", post.key, err)
+		}
+	}
+	if err := store.Forget("c1", "forgotten"); err != nil {
+		t.Fatalf("Forget() error = %v", err)
+	}
+
+	restarted := NewStore(path, 0.9)
+	restarted.now = func() time.Time { return now.Add(10 * time.Minute) }
+	restored, err := restarted.Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if restored != 1 {
+		t.Fatalf("Load() restored = %d, want 1", restored)
+	}
+	if !restarted.Seen(Post{ChannelID: "c1", Key: "long lived"}) {
+		t.Fatal("Seen(long lived) = false after restart")
+	}
+	if restarted.Seen(Post{ChannelID: "c1", Key: "short lived"}) || restarted.Seen(Post{ChannelID: "c1", Key: "forgotten"}) {
+		t.Fatal("expired or forgotten post is still seen")
+	}
+	restarted.now = func() time.Time { return now.Add(2 * time.Hour) }
+	if restarted.Seen(Post{ChannelID: "c1", Key: "long lived"}) {
+		t.Fatal("Seen(long lived) = true after its window")
+	}
+}
+
+func TestStoreLoadMissingAndNil(t *testing.T) {
+	t.Parallel()
+
+	store := NewStore(filepath.Join(t.TempDir(), "missing.json"), 0.9)
+	if restored, err := store.Load(); err != nil || restored != 0 {
+		t.Fatalf("Load() = %d, %v, want 0, nil", restored, err)
+	}
+	broken := filepath.Join(t.TempDir(), "broken.json")
+	if err := os.WriteFile(broken, []byte("{"), 0o600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	brokenStore := NewStore(broken, 0.9)
+	if _, err := brokenStore.Load(); err == nil {
+		t.Fatal("Load(broken) error = nil, want error")
+	}
+	if err := brokenStore.Remember(Post{ChannelID: "c1", Key: "a"}, time.Hour); err != nil {
+		t.Fatalf("Remember() error = %v", err)
+	}
+	if body, err := os.ReadFile(broken + ".corrupt"); err != nil || string(body) != "{" {
+		t.Fatalf(".corrupt after Remember() = %q, %v, want original file", body, err)
+	}
+
+	var nilStore *Store
+	if nilStore.Seen(Post{ChannelID: "c1", Key: "a"}) {
+		t.Fatal("nil Seen() = true")
+	}
+	if err := nilStore.Remember(Post{ChannelID: "c1", Key: "a"}, time.Hour); err != nil {
+		t.Fatalf("nil Remember() error = %v", err)
+	}
+	if err := nilStore.Forget("c1", "a"); err != nil {
+		t.Fatalf("nil Forget() error = %v", err)
+	}
+}
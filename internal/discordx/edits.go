@@ -0,0 +1,177 @@
+package discordx
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/sigumaa/yururi/internal/dedup"
+)
+
+const (
+	AuditActionEdit   = "edit"
+	AuditActionDelete = "delete"
+)
+
+// AuditRecord is one line of the audit log.
+type AuditRecord struct {
+	Time      time.Time `json:"time"`
+	Action    string    `json:"action"`
+	ChannelID string    `json:"channel_id"`
+	MessageID string    `json:"message_id"`
+	Before    string    `json:"before,omitempty"`
+	After     string    `json:"after,omitempty"`
+}
+
+// AuditLog appends edits and deletes of the bot's messages to a JSON Lines
+// file. An empty path only logs them.
+type AuditLog struct {
+	path string
+	mu   sync.Mutex
+}
+
+func NewAuditLog(path string) *AuditLog {
+	return &AuditLog{path: strings.TrimSpace(path)}
+}
+
+func (a *AuditLog) Record(record AuditRecord) error {
+	if a == nil {
+		return nil
+	}
+	if record.Time.IsZero() {
+		record.Time = time.Now().UTC()
+	}
+	slog.Info("message_audit", "action", record.Action, "channel", record.ChannelID, "message", record.MessageID, "before_len", len(record.Before), "after_len", len(record.After))
+	if a.path == "" {
+		return nil
+	}
+	body, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("encode audit record: %w", err)
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(a.path), 0o755); err != nil {
+		return fmt.Errorf("create audit log dir: %w", err)
+	}
+	f, err := os.OpenFile(a.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("open audit log: %w", err)
+	}
+	if _, err := f.Write(append(body, '\n')); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("write audit log: %w", err)
+	}
+	return f.Close()
+}
+
+// EditMessage replaces the content of one of the bot's own messages.
+func (g *Gateway) EditMessage(ctx context.Context, channelID string, messageID string, content string) error {
+	if err := g.validateWritableChannel(channelID); err != nil {
+		return err
+	}
+	text := strings.TrimSpace(content)
+	if text == "" {
+		return errors.New("content is required")
+	}
+	before, err := g.ownMessage(ctx, channelID, messageID)
+	if err != nil {
+		return err
+	}
+	// Only exact matches count, so a small fix to the message is not taken
+	// for a near duplicate of its old text.
+	if g.isDuplicate(dedup.Post{ChannelID: strings.TrimSpace(channelID), Key: text}) {
+		return &DuplicateSuppressedError{ChannelID: channelID}
+	}
+	edit := buildMessageEdit(channelID, messageID, text, before.Flags)
+	err = g.outbound.do(ctx, channelID, PriorityMessage, "edit_message", func() error {
+		_, err := g.session.ChannelMessageEditComplex(edit, outboundOptions...)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("edit message: %w", err)
+	}
+	g.forgetContent(channelID, before.Content)
+	g.rememberContent(channelID, text)
+	g.recordAudit(AuditRecord{Action: AuditActionEdit, ChannelID: channelID, MessageID: messageID, Before: before.Content, After: text})
+	return nil
+}
+
+// buildMessageEdit keeps the embed suppression of the original message.
+// Flags left at zero are omitted, and Discord then keeps the current ones,
+// so the previews of a message sent by send_embed stay visible.
+func buildMessageEdit(channelID string, messageID string, content string, flags discordgo.MessageFlags) *discordgo.MessageEdit {
+	edit := discordgo.NewMessageEdit(channelID, messageID).SetContent(content)
+	edit.Flags = flags & discordgo.MessageFlagsSuppressEmbeds
+	return edit
+}
+
+// DeleteMessage deletes one of the bot's own messages. Its content may be
+// sent again right away.
+func (g *Gateway) DeleteMessage(ctx context.Context, channelID string, messageID string) error {
+	if err := g.validateWritableChannel(channelID); err != nil {
+		return err
+	}
+	before, err := g.ownMessage(ctx, channelID, messageID)
+	if err != nil {
+		return err
+	}
+	err = g.outbound.do(ctx, channelID, PriorityMessage, "delete_message", func() error {
+		return g.session.ChannelMessageDelete(channelID, messageID, outboundOptions...)
+	})
+	if err != nil {
+		return fmt.Errorf("delete message: %w", err)
+	}
+	g.forgetContent(channelID, before.Content)
+	g.recordAudit(AuditRecord{Action: AuditActionDelete, ChannelID: channelID, MessageID: messageID, Before: before.Content})
+	return nil
+}
+
+// ownMessage fetches messageID and fails unless the bot wrote it.
+func (g *Gateway) ownMessage(ctx context.Context, channelID string, messageID string) (*discordgo.Message, error) {
+	messageID = strings.TrimSpace(messageID)
+	if messageID == "" {
+		return nil, errors.New("message_id is required")
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	msg, err := g.session.ChannelMessage(channelID, messageID)
+	if err != nil {
+		return nil, fmt.Errorf("fetch message: %w", err)
+	}
+	botID := ""
+	if g.session.State != nil && g.session.State.User != nil {
+		botID = g.session.State.User.ID
+	}
+	if err := checkOwnMessage(msg, botID); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+func checkOwnMessage(msg *discordgo.Message, botID string) error {
+	if msg == nil || msg.Author == nil {
+		return errors.New("message author is unknown")
+	}
+	if botID == "" || msg.Author.ID != botID {
+		return fmt.Errorf("message %s was not written by the bot", msg.ID)
+	}
+	return nil
+}
+
+func (g *Gateway) recordAudit(record AuditRecord) {
+	if err := g.audit.Record(record); err != nil {
+		slog.Error("message_audit_failed", "action", record.Action, "channel", record.ChannelID, "message", record.MessageID, "err", err)
+	}
+}
@@ -0,0 +1,164 @@
+package discordx
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/sigumaa/yururi/internal/config"
+)
+
+func TestCheckOwnMessage(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		msg     *discordgo.Message
+		botID   string
+		wantErr bool
+	}{
+		{name: "own message", msg: &discordgo.Message{ID: "m1", Author: &discordgo.User{ID: "bot"}}, botID: "bot"},
+		{name: "other author", msg: &discordgo.Message{ID: "m1", Author: &discordgo.User{ID: "u1"}}, botID: "bot", wantErr: true},
+		{name: "unknown bot", msg: &discordgo.Message{ID: "m1", Author: &discordgo.User{ID: "u1"}}, wantErr: true},
+		{name: "missing author", msg: &discordgo.Message{ID: "m1"}, botID: "bot", wantErr: true},
+		{name: "nil message", botID: "bot", wantErr: true},
+	}
+	for _, tc := range tests {
+		if err := checkOwnMessage(tc.msg, tc.botID); (err != nil) != tc.wantErr {
+			t.Fatalf("%s: checkOwnMessage() error = %v, want error %t", tc.name, err, tc.wantErr)
+		}
+	}
+}
+
+func TestBuildMessageEditKeepsEmbedSuppression(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		flags discordgo.MessageFlags
+		want  discordgo.MessageFlags
+	}{
+		{name: "suppressed text", flags: discordgo.MessageFlagsSuppressEmbeds, want: discordgo.MessageFlagsSuppressEmbeds},
+		{name: "embed message", flags: 0, want: 0},
+		{name: "other flags", flags: discordgo.MessageFlagsSuppressEmbeds | discordgo.MessageFlagsCrossPosted, want: discordgo.MessageFlagsSuppressEmbeds},
+	}
+	for _, tc := range tests {
+		edit := buildMessageEdit("c1", "m1", "new", tc.flags)
+		if edit.Flags != tc.want || edit.Channel != "c1" || edit.ID != "m1" || edit.Content == nil || *edit.Content != "new" {
+			t.Fatalf("%s: edit = %+v, want flags %d", tc.name, edit, tc.want)
+		}
+	}
+}
+
+func TestGatewayForgetContent(t *testing.T) {
+	t.Parallel()
+
+	gateway := NewGateway(nil, config.DiscordConfig{WriteChannelIDs: []string{"c1"}})
+	gateway.rememberContent("c1", "Hello  world")
+	if !gateway.isDuplicateContent("c1", "hello world") {
+		t.Fatal("isDuplicateContent() = false after rememberContent")
+	}
+	gateway.forgetContent("c1", "hello world")
+	if gateway.isDuplicateContent("c1", "Hello  world") {
+		t.Fatal("isDuplicateContent() = true after forgetContent")
+	}
+}
+
+func TestAuditLogAppendsRecords(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "state", "audit.jsonl")
+	audit := NewAuditLog(path)
+	records := []AuditRecord{
+		{Action: AuditActionEdit, ChannelID: "c1", MessageID: "m1", Before: "typo", After: "fixed"},
+		{Action: AuditActionDelete, ChannelID: "c1", MessageID: "m2", Before: "oops"},
+	}
+	for _, record := range records {
+		if err := audit.Record(record); err != nil {
+			t.Fatalf("Record() error = %v", err)
+		}
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("open audit log: %v", err)
+	}
+	defer f.Close()
+	var got []AuditRecord
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var record AuditRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			t.Fatalf("decode audit line %q: %v", scanner.Text(), err)
+		}
+		got = append(got, record)
+	}
+	if len(got) != len(records) {
+		t.Fatalf("audit records = %d, want %d", len(got), len(records))
+	}
+	for i, want := range records {
+		if got[i].Time.IsZero() {
+			t.Fatalf("record[%d].Time is zero", i)
+		}
+		got[i].Time = want.Time
+		if got[i] != want {
+			t.Fatalf("record[%d] = %#v, want %#v", i, got[i], want)
+		}
+	}
+
+	if err := NewAuditLog("").Record(records[0]); err != nil {
+		t.Fatalf("Record() without path error = %v", err)
+	}
+}
+
+func TestEditAndDeleteOwnMessage(t *testing.T) {
+	t.Parallel()
+
+	session, fake := newFakeSession(t, map[string]string{
+		"GET /channels/c1/messages/m1":    `{"id":"m1","channel_id":"c1","content":"typo","flags":4,"author":{"id":"bot"}}`,
+		"PATCH /channels/c1/messages/m1":  `{"id":"m1","channel_id":"c1","content":"fixed","author":{"id":"bot"}}`,
+		"DELETE /channels/c1/messages/m1": "",
+		"GET /channels/c1/messages/m2":    `{"id":"m2","channel_id":"c1","content":"not mine","author":{"id":"u1"}}`,
+	})
+	auditPath := filepath.Join(t.TempDir(), "audit.jsonl")
+	gateway := NewGateway(session, config.DiscordConfig{WriteChannelIDs: []string{"c1"}, AuditLogPath: auditPath})
+	gateway.rememberContent("c1", "typo")
+
+	if err := gateway.EditMessage(context.Background(), "c1", "m1", "fixed"); err != nil {
+		t.Fatalf("EditMessage() error = %v", err)
+	}
+	edit, ok := fake.request("PATCH /channels/c1/messages/m1")
+	if !ok || !strings.Contains(edit.Body, `"content":"fixed"`) || !strings.Contains(edit.Body, `"flags":4`) {
+		t.Fatalf("edit request = %+v, want new content with embed suppression", edit)
+	}
+	if gateway.isDuplicateContent("c1", "typo") || !gateway.isDuplicateContent("c1", "fixed") {
+		t.Fatal("EditMessage() did not swap the remembered content")
+	}
+
+	if err := gateway.DeleteMessage(context.Background(), "c1", "m1"); err != nil {
+		t.Fatalf("DeleteMessage() error = %v", err)
+	}
+	if _, ok := fake.request("DELETE /channels/c1/messages/m1"); !ok {
+		t.Fatal("DeleteMessage() sent no DELETE request")
+	}
+
+	if err := gateway.EditMessage(context.Background(), "c1", "m2", "hijack"); err == nil || !strings.Contains(err.Error(), "not written by the bot") {
+		t.Fatalf("EditMessage(other author) error = %v, want not written by the bot", err)
+	}
+	if _, ok := fake.request("PATCH /channels/c1/messages/m2"); ok {
+		t.Fatal("EditMessage(other author) sent an edit")
+	}
+
+	body, err := os.ReadFile(auditPath)
+	if err != nil {
+		t.Fatalf("read audit log: %v", err)
+	}
+	if lines := strings.Count(string(body), "\n"); lines != 2 {
+		t.Fatalf("audit lines = %d, want edit and delete", lines)
+	}
+}
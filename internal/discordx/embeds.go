@@ -0,0 +1,346 @@
+package discordx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// Discord's limits for messages, embeds and components, in characters.
+const (
+	maxContentRunes          = 2000
+	maxEmbedTitleRunes       = 256
+	maxEmbedDescriptionRunes = 4096
+	maxEmbedFields           = 25
+	maxEmbedFieldNameRunes   = 256
+	maxEmbedFieldValueRunes  = 1024
+	maxEmbedFooterRunes      = 2048
+	maxEmbedTotalRunes       = 6000
+	maxEmbedColor            = 0xFFFFFF
+	maxActionRows            = 5
+	maxButtonsPerRow         = 5
+	maxButtonLabelRunes      = 80
+	maxCustomIDRunes         = 100
+	maxSelectOptions         = 25
+	maxSelectPlaceholder     = 150
+	maxSelectOptionRunes     = 100
+)
+
+// componentCustomIDPrefix marks components sent by the tools so their clicks
+// can be told apart from other interactions.
+const componentCustomIDPrefix = "yururi:"
+
+type Embed struct {
+	Title       string
+	Description string
+	URL         string
+	Color       int
+	Footer      string
+	Fields      []EmbedField
+}
+
+type EmbedField struct {
+	Name   string
+	Value  string
+	Inline bool
+}
+
+// Button is a clickable button. Link buttons open URL and have no custom ID;
+// the others report clicks with their custom ID.
+type Button struct {
+	CustomID string
+	Label    string
+	Style    string
+	URL      string
+}
+
+type SelectMenu struct {
+	CustomID    string
+	Placeholder string
+	MinValues   int
+	MaxValues   int
+	Options     []SelectOption
+}
+
+type SelectOption struct {
+	Label       string
+	Value       string
+	Description string
+}
+
+// EmbedMessage is a message with one embed. Buttons are laid out five per
+// row and every select menu takes a row of its own.
+type EmbedMessage struct {
+	Content string
+	Embed   Embed
+	Buttons []Button
+	Selects []SelectMenu
+}
+
+var buttonStyles = map[string]discordgo.ButtonStyle{
+	"":          discordgo.SecondaryButton,
+	"primary":   discordgo.PrimaryButton,
+	"secondary": discordgo.SecondaryButton,
+	"success":   discordgo.SuccessButton,
+	"danger":    discordgo.DangerButton,
+	"link":      discordgo.LinkButton,
+}
+
+// SendEmbed sends msg after checking it against Discord's limits.
+func (g *Gateway) SendEmbed(ctx context.Context, channelID string, msg EmbedMessage) (string, error) {
+	if err := g.validateWritableChannel(channelID); err != nil {
+		return "", err
+	}
+	send, err := buildEmbedMessageSend(msg)
+	if err != nil {
+		return "", err
+	}
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	signature := embedDedupText(msg)
+	if g.isDuplicateContent(channelID, signature) {
+		return "", &DuplicateSuppressedError{ChannelID: channelID}
+	}
+	var sent *discordgo.Message
+	err = g.outbound.do(ctx, channelID, PriorityMessage, "send_embed", func() error {
+		var err error
+		sent, err = g.session.ChannelMessageSendComplex(channelID, send, outboundOptions...)
+		return err
+	})
+	if err != nil {
+		return "", fmt.Errorf("send embed: %w", err)
+	}
+	g.rememberContent(channelID, signature)
+	return sent.ID, nil
+}
+
+// ParseComponentCustomID returns the custom ID given to the tool for a
+// component sent by SendEmbed.
+func ParseComponentCustomID(customID string) (string, bool) {
+	id, ok := strings.CutPrefix(customID, componentCustomIDPrefix)
+	if !ok || id == "" {
+		return "", false
+	}
+	return id, true
+}
+
+func buildEmbedMessageSend(msg EmbedMessage) (*discordgo.MessageSend, error) {
+	content := strings.TrimSpace(msg.Content)
+	if utf8.RuneCountInString(content) > maxContentRunes {
+		return nil, fmt.Errorf("content exceeds %d characters", maxContentRunes)
+	}
+	embed, err := buildEmbed(msg.Embed)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := buildComponentRows(msg.Buttons, msg.Selects)
+	if err != nil {
+		return nil, err
+	}
+	return &discordgo.MessageSend{
+		Content:    content,
+		Embeds:     []*discordgo.MessageEmbed{embed},
+		Components: rows,
+	}, nil
+}
+
+func buildEmbed(in Embed) (*discordgo.MessageEmbed, error) {
+	title := strings.TrimSpace(in.Title)
+	description := strings.TrimSpace(in.Description)
+	if title == "" && description == "" {
+		return nil, errors.New("embed title or description is required")
+	}
+	if err := checkRunes("embed title", title, maxEmbedTitleRunes); err != nil {
+		return nil, err
+	}
+	if err := checkRunes("embed description", description, maxEmbedDescriptionRunes); err != nil {
+		return nil, err
+	}
+	footer := strings.TrimSpace(in.Footer)
+	if err := checkRunes("embed footer", footer, maxEmbedFooterRunes); err != nil {
+		return nil, err
+	}
+	if in.Color < 0 || in.Color > maxEmbedColor {
+		return nil, fmt.Errorf("embed color must be between 0 and %#x", maxEmbedColor)
+	}
+	link := strings.TrimSpace(in.URL)
+	if link != "" && !isHTTPURL(link) {
+		return nil, fmt.Errorf("embed url must be http or https: %s", link)
+	}
+	if len(in.Fields) > maxEmbedFields {
+		return nil, fmt.Errorf("embed has %d fields, max %d", len(in.Fields), maxEmbedFields)
+	}
+
+	total := utf8.RuneCountInString(title) + utf8.RuneCountInString(description) + utf8.RuneCountInString(footer)
+	embed := &discordgo.MessageEmbed{
+		Type:        discordgo.EmbedTypeRich,
+		Title:       title,
+		Description: description,
+		URL:         link,
+		Color:       in.Color,
+	}
+	if footer != "" {
+		embed.Footer = &discordgo.MessageEmbedFooter{Text: footer}
+	}
+	for i, field := range in.Fields {
+		name := strings.TrimSpace(field.Name)
+		value := strings.TrimSpace(field.Value)
+		if name == "" || value == "" {
+			return nil, fmt.Errorf("embed field %d needs a name and a value", i)
+		}
+		if err := checkRunes(fmt.Sprintf("embed field %d name", i), name, maxEmbedFieldNameRunes); err != nil {
+			return nil, err
+		}
+		if err := checkRunes(fmt.Sprintf("embed field %d value", i), value, maxEmbedFieldValueRunes); err != nil {
+			return nil, err
+		}
+		total += utf8.RuneCountInString(name) + utf8.RuneCountInString(value)
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{Name: name, Value: value, Inline: field.Inline})
+	}
+	if total > maxEmbedTotalRunes {
+		return nil, fmt.Errorf("embed has %d characters, max %d", total, maxEmbedTotalRunes)
+	}
+	return embed, nil
+}
+
+func buildComponentRows(buttons []Button, selects []SelectMenu) ([]discordgo.MessageComponent, error) {
+	buttonRows := (len(buttons) + maxButtonsPerRow - 1) / maxButtonsPerRow
+	if buttonRows+len(selects) > maxActionRows {
+		return nil, fmt.Errorf("components need %d rows, max %d", buttonRows+len(selects), maxActionRows)
+	}
+	seen := map[string]struct{}{}
+	customID := func(raw string) (string, error) {
+		id := strings.TrimSpace(raw)
+		if id == "" {
+			return "", errors.New("component custom_id is required")
+		}
+		if _, ok := seen[id]; ok {
+			return "", fmt.Errorf("duplicate component custom_id: %s", id)
+		}
+		seen[id] = struct{}{}
+		full := componentCustomIDPrefix + id
+		if err := checkRunes("component custom_id", full, maxCustomIDRunes); err != nil {
+			return "", err
+		}
+		return full, nil
+	}
+
+	var rows []discordgo.MessageComponent
+	var row discordgo.ActionsRow
+	for i, in := range buttons {
+		style, ok := buttonStyles[strings.ToLower(strings.TrimSpace(in.Style))]
+		if !ok {
+			return nil, fmt.Errorf("unknown button style: %s", in.Style)
+		}
+		label := strings.TrimSpace(in.Label)
+		if label == "" {
+			return nil, fmt.Errorf("button %d label is required", i)
+		}
+		if err := checkRunes("button label", label, maxButtonLabelRunes); err != nil {
+			return nil, err
+		}
+		button := discordgo.Button{Label: label, Style: style}
+		if style == discordgo.LinkButton {
+			button.URL = strings.TrimSpace(in.URL)
+			if !isHTTPURL(button.URL) {
+				return nil, fmt.Errorf("link button %d needs an http or https url", i)
+			}
+		} else {
+			id, err := customID(in.CustomID)
+			if err != nil {
+				return nil, err
+			}
+			button.CustomID = id
+		}
+		row.Components = append(row.Components, button)
+		if len(row.Components) == maxButtonsPerRow || i == len(buttons)-1 {
+			rows = append(rows, row)
+			row = discordgo.ActionsRow{}
+		}
+	}
+	for i, in := range selects {
+		menu, err := buildSelectMenu(in, customID)
+		if err != nil {
+			return nil, fmt.Errorf("select %d: %w", i, err)
+		}
+		rows = append(rows, discordgo.ActionsRow{Components: []discordgo.MessageComponent{menu}})
+	}
+	return rows, nil
+}
+
+func buildSelectMenu(in SelectMenu, customID func(string) (string, error)) (discordgo.SelectMenu, error) {
+	id, err := customID(in.CustomID)
+	if err != nil {
+		return discordgo.SelectMenu{}, err
+	}
+	placeholder := strings.TrimSpace(in.Placeholder)
+	if err := checkRunes("placeholder", placeholder, maxSelectPlaceholder); err != nil {
+		return discordgo.SelectMenu{}, err
+	}
+	if len(in.Options) == 0 || len(in.Options) > maxSelectOptions {
+		return discordgo.SelectMenu{}, fmt.Errorf("needs 1 to %d options", maxSelectOptions)
+	}
+	minValues := max(in.MinValues, 0)
+	maxValues := in.MaxValues
+	if maxValues <= 0 {
+		maxValues = 1
+	}
+	if minValues > maxValues || maxValues > len(in.Options) {
+		return discordgo.SelectMenu{}, fmt.Errorf("min_values %d and max_values %d do not fit %d options", minValues, maxValues, len(in.Options))
+	}
+	menu := discordgo.SelectMenu{
+		MenuType:    discordgo.StringSelectMenu,
+		CustomID:    id,
+		Placeholder: placeholder,
+		MinValues:   &minValues,
+		MaxValues:   maxValues,
+	}
+	values := map[string]struct{}{}
+	for _, option := range in.Options {
+		label := strings.TrimSpace(option.Label)
+		value := strings.TrimSpace(option.Value)
+		if value == "" {
+			value = label
+		}
+		if label == "" {
+			return discordgo.SelectMenu{}, errors.New("option label is required")
+		}
+		if _, ok := values[value]; ok {
+			return discordgo.SelectMenu{}, fmt.Errorf("duplicate option value: %s", value)
+		}
+		values[value] = struct{}{}
+		description := strings.TrimSpace(option.Description)
+		if err := errors.Join(
+			checkRunes("option label", label, maxSelectOptionRunes),
+			checkRunes("option value", value, maxSelectOptionRunes),
+			checkRunes("option description", description, maxSelectOptionRunes),
+		); err != nil {
+			return discordgo.SelectMenu{}, err
+		}
+		menu.Options = append(menu.Options, discordgo.SelectMenuOption{Label: label, Value: value, Description: description})
+	}
+	return menu, nil
+}
+
+// embedDedupText is the text duplicate suppression compares for an embed.
+func embedDedupText(msg EmbedMessage) string {
+	return strings.Join([]string{msg.Content, msg.Embed.Title, msg.Embed.Description}, "\n")
+}
+
+func checkRunes(name string, value string, limit int) error {
+	if n := utf8.RuneCountInString(value); n > limit {
+		return fmt.Errorf("%s has %d characters, max %d", name, n, limit)
+	}
+	return nil
+}
+
+func isHTTPURL(raw string) bool {
+	u, err := url.Parse(raw)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
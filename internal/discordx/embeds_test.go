@@ -0,0 +1,128 @@
+package discordx
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/sigumaa/yururi/internal/config"
+)
+
+func TestBuildEmbedMessageSend(t *testing.T) {
+	t.Parallel()
+
+	msg := EmbedMessage{
+		Content: "まとめ",
+		Embed: Embed{
+			Title:       "調査結果",
+			Description: "概要",
+			URL:         "https://example.com",
+			Color:       0x5865F2,
+			Footer:      "yururi",
+			Fields:      []EmbedField{{Name: "要点", Value: "3つ", Inline: true}},
+		},
+		Buttons: []Button{
+			{CustomID: "more", Label: "詳しく", Style: "primary"},
+			{Label: "元記事", Style: "link", URL: "https://example.com/a"},
+		},
+		Selects: []SelectMenu{{CustomID: "topic", Options: []SelectOption{{Label: "Go"}, {Label: "Rust", Value: "rust"}}}},
+	}
+	send, err := buildEmbedMessageSend(msg)
+	if err != nil {
+		t.Fatalf("buildEmbedMessageSend() error = %v", err)
+	}
+	if send.Flags&discordgo.MessageFlagsSuppressEmbeds != 0 {
+		t.Fatal("embed message must not suppress embeds")
+	}
+	embed := send.Embeds[0]
+	if embed.Title != "調査結果" || embed.Color != 0x5865F2 || embed.Footer == nil || embed.Footer.Text != "yururi" || len(embed.Fields) != 1 {
+		t.Fatalf("embed = %+v", embed)
+	}
+	if len(send.Components) != 2 {
+		t.Fatalf("components rows = %d, want 2", len(send.Components))
+	}
+	buttons := send.Components[0].(discordgo.ActionsRow).Components
+	if got := buttons[0].(discordgo.Button); got.CustomID != "yururi:more" || got.Style != discordgo.PrimaryButton {
+		t.Fatalf("button[0] = %+v", got)
+	}
+	if got := buttons[1].(discordgo.Button); got.CustomID != "" || got.URL != "https://example.com/a" {
+		t.Fatalf("button[1] = %+v", got)
+	}
+	menu := send.Components[1].(discordgo.ActionsRow).Components[0].(discordgo.SelectMenu)
+	if menu.CustomID != "yururi:topic" || menu.MaxValues != 1 || menu.Options[0].Value != "Go" {
+		t.Fatalf("select = %+v", menu)
+	}
+	if id, ok := ParseComponentCustomID(menu.CustomID); !ok || id != "topic" {
+		t.Fatalf("ParseComponentCustomID() = %q, %t", id, ok)
+	}
+}
+
+func TestBuildEmbedMessageSendLimits(t *testing.T) {
+	t.Parallel()
+
+	manyButtons := make([]Button, 0, 26)
+	for i := 0; i < 26; i++ {
+		manyButtons = append(manyButtons, Button{CustomID: strings.Repeat("b", i+1), Label: "x"})
+	}
+	manyFields := make([]EmbedField, 26)
+	for i := range manyFields {
+		manyFields[i] = EmbedField{Name: "n", Value: "v"}
+	}
+	tests := []struct {
+		name    string
+		msg     EmbedMessage
+		wantErr string
+	}{
+		{name: "empty embed", msg: EmbedMessage{}, wantErr: "title or description"},
+		{name: "long title", msg: EmbedMessage{Embed: Embed{Title: strings.Repeat("あ", 257)}}, wantErr: "embed title"},
+		{name: "too many fields", msg: EmbedMessage{Embed: Embed{Title: "t", Fields: manyFields}}, wantErr: "fields"},
+		{
+			name:    "total length",
+			msg:     EmbedMessage{Embed: Embed{Description: strings.Repeat("a", 4096), Fields: []EmbedField{{Name: "n", Value: strings.Repeat("b", 1024)}, {Name: "n", Value: strings.Repeat("c", 1024)}}}},
+			wantErr: "max 6000",
+		},
+		{name: "bad color", msg: EmbedMessage{Embed: Embed{Title: "t", Color: 0x1000000}}, wantErr: "color"},
+		{name: "bad url", msg: EmbedMessage{Embed: Embed{Title: "t", URL: "javascript:alert(1)"}}, wantErr: "http"},
+		{name: "too many rows", msg: EmbedMessage{Embed: Embed{Title: "t"}, Buttons: manyButtons}, wantErr: "rows"},
+		{name: "duplicate custom id", msg: EmbedMessage{Embed: Embed{Title: "t"}, Buttons: []Button{{CustomID: "a", Label: "1"}, {CustomID: "a", Label: "2"}}}, wantErr: "duplicate"},
+		{name: "missing custom id", msg: EmbedMessage{Embed: Embed{Title: "t"}, Buttons: []Button{{Label: "1"}}}, wantErr: "custom_id"},
+		{name: "unknown style", msg: EmbedMessage{Embed: Embed{Title: "t"}, Buttons: []Button{{CustomID: "a", Label: "1", Style: "blurple"}}}, wantErr: "style"},
+		{name: "select without options", msg: EmbedMessage{Embed: Embed{Title: "t"}, Selects: []SelectMenu{{CustomID: "s"}}}, wantErr: "options"},
+		{
+			name:    "select max values",
+			msg:     EmbedMessage{Embed: Embed{Title: "t"}, Selects: []SelectMenu{{CustomID: "s", MaxValues: 3, Options: []SelectOption{{Label: "a"}}}}},
+			wantErr: "max_values",
+		},
+	}
+	for _, tc := range tests {
+		if _, err := buildEmbedMessageSend(tc.msg); err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+			t.Fatalf("%s: buildEmbedMessageSend() error = %v, want containing %q", tc.name, err, tc.wantErr)
+		}
+	}
+}
+
+func TestSendEmbed(t *testing.T) {
+	t.Parallel()
+
+	session, fake := newFakeSession(t, map[string]string{
+		"POST /channels/c1/messages": `{"id":"m1","channel_id":"c1"}`,
+	})
+	gateway := NewGateway(session, config.DiscordConfig{WriteChannelIDs: []string{"c1"}})
+	msg := EmbedMessage{
+		Embed:   Embed{Title: "調査結果", Description: "概要"},
+		Buttons: []Button{{CustomID: "more", Label: "詳しく"}},
+	}
+
+	id, err := gateway.SendEmbed(context.Background(), "c1", msg)
+	if err != nil || id != "m1" {
+		t.Fatalf("SendEmbed() = (%q, %v), want m1", id, err)
+	}
+	req, ok := fake.request("POST /channels/c1/messages")
+	if !ok || !strings.Contains(req.Body, "調査結果") || !strings.Contains(req.Body, `"custom_id":"yururi:more"`) {
+		t.Fatalf("send request = %+v, want the embed and button", req)
+	}
+	if _, err := gateway.SendEmbed(context.Background(), "c1", msg); !IsDuplicateSuppressed(err) {
+		t.Fatalf("repeated SendEmbed() error = %v, want duplicate suppressed", err)
+	}
+}
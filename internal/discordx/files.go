@@ -0,0 +1,73 @@
+package discordx
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/sigumaa/yururi/internal/dedup"
+)
+
+// FileUpload is a file attached to a message.
+type FileUpload struct {
+	Name        string
+	ContentType string
+	Data        []byte
+}
+
+// SendFile sends file with an optional message. Sending the same file with
+// the same message again is suppressed like SendMessage.
+func (g *Gateway) SendFile(ctx context.Context, channelID string, content string, file FileUpload) (string, error) {
+	if err := g.validateWritableChannel(channelID); err != nil {
+		return "", err
+	}
+	name := strings.TrimSpace(file.Name)
+	if name == "" {
+		return "", errors.New("file name is required")
+	}
+	if len(file.Data) == 0 {
+		return "", errors.New("file is empty")
+	}
+	text := strings.TrimSpace(content)
+	if err := checkRunes("content", text, maxContentRunes); err != nil {
+		return "", err
+	}
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	post := dedup.Post{ChannelID: strings.TrimSpace(channelID), Key: fileDedupText(text, name, file.Data)}
+	if g.isDuplicate(post) {
+		return "", &DuplicateSuppressedError{ChannelID: channelID}
+	}
+	var sent *discordgo.Message
+	err := g.outbound.do(ctx, channelID, PriorityMessage, "upload_file", func() error {
+		// The reader is consumed by each attempt, so a retry needs a new one.
+		send := buildMessageSend(text)
+		send.Files = []*discordgo.File{{
+			Name:        name,
+			ContentType: file.ContentType,
+			Reader:      bytes.NewReader(file.Data),
+		}}
+		var err error
+		sent, err = g.session.ChannelMessageSendComplex(channelID, send, outboundOptions...)
+		return err
+	})
+	if err != nil {
+		return "", fmt.Errorf("send file: %w", err)
+	}
+	g.remember(post)
+	return sent.ID, nil
+}
+
+// fileDedupText identifies a file for duplicate suppression. It includes a
+// hash of the data so a changed file is not suppressed, and is only matched
+// exactly.
+func fileDedupText(content string, name string, data []byte) string {
+	sum := sha256.Sum256(data)
+	return strings.Join([]string{content, name, hex.EncodeToString(sum[:])}, "\n")
+}
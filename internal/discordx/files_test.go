@@ -0,0 +1,54 @@
+package discordx
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/sigumaa/yururi/internal/config"
+)
+
+func TestSendFileValidatesBeforeSending(t *testing.T) {
+	t.Parallel()
+
+	g := NewGateway(nil, config.DiscordConfig{
+		GuildID:         "g1",
+		ReadChannelIDs:  []string{"c-read", "c-write"},
+		WriteChannelIDs: []string{"c-write"},
+	})
+	file := FileUpload{Name: "a.txt", ContentType: "text/plain", Data: []byte("hello")}
+
+	tests := []struct {
+		name    string
+		channel string
+		content string
+		file    FileUpload
+		wantErr string
+	}{
+		{name: "read only channel", channel: "c-read", file: file, wantErr: "write_channel_ids"},
+		{name: "no name", channel: "c-write", file: FileUpload{Data: []byte("x")}, wantErr: "name is required"},
+		{name: "empty", channel: "c-write", file: FileUpload{Name: "a.txt"}, wantErr: "empty"},
+		{name: "long content", channel: "c-write", content: strings.Repeat("あ", maxContentRunes+1), file: file, wantErr: "max 2000"},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			if _, err := g.SendFile(context.Background(), tc.channel, tc.content, tc.file); err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Fatalf("SendFile() error = %v, want %q", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestFileDedupTextChangesWithData(t *testing.T) {
+	t.Parallel()
+
+	a := fileDedupText("report", "a.csv", []byte("1,2"))
+	if a != fileDedupText("report", "a.csv", []byte("1,2")) {
+		t.Fatal("fileDedupText() differs for the same file")
+	}
+	if a == fileDedupText("report", "a.csv", []byte("1,3")) {
+		t.Fatal("fileDedupText() is the same for different data")
+	}
+}
@@ -2,22 +2,23 @@ package discordx
 
 import (
 	"context"
-	"crypto/sha256"
-	"encoding/hex"
 	"errors"
 	"fmt"
+	"log/slog"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/bwmarrin/discordgo"
 	"github.com/sigumaa/yururi/internal/config"
+	"github.com/sigumaa/yururi/internal/dedup"
 )
 
 const (
 	defaultHistoryLimit = 20
 	maxHistoryLimit     = 100
-	duplicateWindow     = 10 * time.Minute
+	// duplicateWindow applies when the config sets no dedup window.
+	duplicateWindow = 10 * time.Minute
 )
 
 type Message struct {
@@ -29,6 +30,15 @@ type Message struct {
 	AuthorIsBot bool
 	Content     string
 	CreatedAt   time.Time
+	Attachments []Attachment
+}
+
+type Attachment struct {
+	ID          string
+	Filename    string
+	URL         string
+	ContentType string
+	Size        int
 }
 
 type ChannelInfo struct {
@@ -49,12 +59,23 @@ type Gateway struct {
 	writableChannels map[string]struct{}
 	readableChannels map[string]struct{}
 	excludedChannel  map[string]struct{}
+	dmEnabled        bool
+	dmUsers          map[string]struct{}
+
+	dmMu       sync.RWMutex
+	dmChannels map[string]string
+
+	threadMu      sync.RWMutex
+	threadParents map[string]string
 
 	typingMu    sync.Mutex
 	typingStops map[string]context.CancelFunc
 
-	dedupMu          sync.Mutex
-	recentContentMap map[string]map[string]time.Time
+	dedup       *dedup.Store
+	dedupConfig config.DedupConfig
+
+	audit    *AuditLog
+	outbound *outboundQueue
 }
 
 type DuplicateSuppressedError struct {
@@ -98,15 +119,34 @@ func NewGateway(session *discordgo.Session, cfg config.DiscordConfig) *Gateway {
 	for _, id := range cfg.ExcludedChannelIDs {
 		excluded[strings.TrimSpace(id)] = struct{}{}
 	}
+	dmUsers := make(map[string]struct{}, len(cfg.DM.AllowedUserIDs))
+	for _, id := range cfg.DM.AllowedUserIDs {
+		trimmed := strings.TrimSpace(id)
+		if trimmed == "" {
+			continue
+		}
+		dmUsers[trimmed] = struct{}{}
+	}
 
+	outbound := newOutboundQueue()
+	if session != nil {
+		session.Client = outbound.watchGlobalRateLimit(session.Client)
+	}
 	return &Gateway{
 		session:          session,
 		guildID:          strings.TrimSpace(cfg.GuildID),
 		writableChannels: writable,
 		readableChannels: readable,
 		excludedChannel:  excluded,
+		dmEnabled:        cfg.DM.Enabled,
+		dmUsers:          dmUsers,
+		dmChannels:       map[string]string{},
+		threadParents:    map[string]string{},
 		typingStops:      map[string]context.CancelFunc{},
-		recentContentMap: map[string]map[string]time.Time{},
+		dedup:            dedup.NewStore(cfg.Dedup.StorePath, cfg.Dedup.SimilarityThreshold),
+		dedupConfig:      cfg.Dedup,
+		audit:            NewAuditLog(cfg.AuditLogPath),
+		outbound:         outbound,
 	}
 }
 
@@ -137,24 +177,12 @@ func (g *Gateway) ReadMessageHistory(ctx context.Context, channelID string, befo
 			continue
 		}
 		content := strings.TrimSpace(msg.Content)
-		if len(msg.Attachments) > 0 {
-			attachmentNames := make([]string, 0, len(msg.Attachments))
-			for _, a := range msg.Attachments {
-				if a == nil || strings.TrimSpace(a.URL) == "" {
-					continue
-				}
-				name := strings.TrimSpace(a.Filename)
-				if name == "" {
-					name = "attachment"
-				}
-				attachmentNames = append(attachmentNames, fmt.Sprintf("%s(%s)", name, a.URL))
-			}
-			if len(attachmentNames) > 0 {
-				if content != "" {
-					content += "\n"
-				}
-				content += "attachments: " + strings.Join(attachmentNames, ", ")
+		var attachments []Attachment
+		for _, a := range msg.Attachments {
+			if a == nil || strings.TrimSpace(a.URL) == "" {
+				continue
 			}
+			attachments = append(attachments, Attachment{ID: a.ID, Filename: a.Filename, URL: a.URL, ContentType: a.ContentType, Size: a.Size})
 		}
 		out = append(out, Message{
 			ID:          msg.ID,
@@ -165,57 +193,130 @@ func (g *Gateway) ReadMessageHistory(ctx context.Context, channelID string, befo
 			AuthorIsBot: msg.Author.Bot,
 			Content:     content,
 			CreatedAt:   msg.Timestamp,
+			Attachments: attachments,
 		})
 	}
 
 	return out, nil
 }
 
-func (g *Gateway) SendMessage(ctx context.Context, channelID string, content string) (string, error) {
+// SendMessage sends content, split into parts that fit Discord's length
+// limit, and returns the IDs of the parts in order.
+func (g *Gateway) SendMessage(ctx context.Context, channelID string, content string) ([]string, error) {
+	return g.sendParts(ctx, channelID, "", content)
+}
+
+// ReplyMessage is SendMessage with the first part sent as a reply.
+func (g *Gateway) ReplyMessage(ctx context.Context, channelID string, replyToMessageID string, content string) ([]string, error) {
+	if strings.TrimSpace(replyToMessageID) == "" {
+		return nil, errors.New("reply_to_message_id is required")
+	}
+	return g.sendParts(ctx, channelID, replyToMessageID, content)
+}
+
+func (g *Gateway) sendParts(ctx context.Context, channelID string, replyToMessageID string, content string) ([]string, error) {
 	if err := g.validateWritableChannel(channelID); err != nil {
-		return "", err
+		return nil, err
 	}
 	text := strings.TrimSpace(content)
 	if text == "" {
-		return "", errors.New("content is required")
+		return nil, errors.New("content is required")
 	}
 	if err := ctx.Err(); err != nil {
-		return "", err
+		return nil, err
 	}
 	if g.isDuplicateContent(channelID, text) {
-		return "", &DuplicateSuppressedError{ChannelID: channelID}
+		return nil, &DuplicateSuppressedError{ChannelID: channelID}
 	}
-	msg, err := g.session.ChannelMessageSendComplex(channelID, buildMessageSend(text))
-	if err != nil {
-		return "", fmt.Errorf("send message: %w", err)
+	parts := splitMessage(text, maxContentRunes)
+	ids := make([]string, 0, len(parts))
+	for i, part := range parts {
+		if err := ctx.Err(); err != nil {
+			return ids, err
+		}
+		send := buildMessageSend(part)
+		if i == 0 && replyToMessageID != "" {
+			send = buildReplyMessageSend(g.guildIDFor(channelID), channelID, replyToMessageID, part)
+		}
+		var msg *discordgo.Message
+		err := g.outbound.do(ctx, channelID, PriorityMessage, "send_message", func() error {
+			var err error
+			msg, err = g.session.ChannelMessageSendComplex(channelID, send, outboundOptions...)
+			return err
+		})
+		if err != nil {
+			if replyToMessageID != "" && i == 0 {
+				return ids, fmt.Errorf("send reply: %w", err)
+			}
+			// The parts already posted stay in the channel, so a retry of the
+			// same content is still a duplicate.
+			if len(ids) > 0 {
+				g.rememberContent(channelID, text)
+			}
+			return ids, fmt.Errorf("send message part %d/%d: %w", i+1, len(parts), err)
+		}
+		ids = append(ids, msg.ID)
 	}
 	g.rememberContent(channelID, text)
-	return msg.ID, nil
+	return ids, nil
 }
 
-func (g *Gateway) ReplyMessage(ctx context.Context, channelID string, replyToMessageID string, content string) (string, error) {
-	if err := g.validateWritableChannel(channelID); err != nil {
-		return "", err
+// RegisterDMChannel lets the tools read and write a DM channel with an
+// allowlisted user. It reports whether the channel was accepted.
+func (g *Gateway) RegisterDMChannel(channelID string, userID string) bool {
+	channelID = strings.TrimSpace(channelID)
+	userID = strings.TrimSpace(userID)
+	if !g.dmAllowed(userID) || channelID == "" {
+		return false
 	}
-	if strings.TrimSpace(replyToMessageID) == "" {
-		return "", errors.New("reply_to_message_id is required")
+	g.dmMu.Lock()
+	g.dmChannels[channelID] = userID
+	g.dmMu.Unlock()
+	return true
+}
+
+// SendDirectMessage opens a DM with an allowlisted user and sends content.
+func (g *Gateway) SendDirectMessage(ctx context.Context, userID string, content string) (string, []string, error) {
+	userID = strings.TrimSpace(userID)
+	if userID == "" {
+		return "", nil, errors.New("user_id is required")
 	}
-	text := strings.TrimSpace(content)
-	if text == "" {
-		return "", errors.New("content is required")
+	if !g.dmAllowed(userID) {
+		return "", nil, fmt.Errorf("user %s is not in dm.allowed_user_ids", userID)
 	}
 	if err := ctx.Err(); err != nil {
-		return "", err
-	}
-	if g.isDuplicateContent(channelID, text) {
-		return "", &DuplicateSuppressedError{ChannelID: channelID}
+		return "", nil, err
 	}
-	msg, err := g.session.ChannelMessageSendComplex(channelID, buildReplyMessageSend(g.guildID, channelID, replyToMessageID, text))
+	channel, err := g.session.UserChannelCreate(userID)
 	if err != nil {
-		return "", fmt.Errorf("send reply: %w", err)
+		return "", nil, fmt.Errorf("open dm channel: %w", err)
 	}
-	g.rememberContent(channelID, text)
-	return msg.ID, nil
+	g.RegisterDMChannel(channel.ID, userID)
+	ids, err := g.SendMessage(ctx, channel.ID, content)
+	return channel.ID, ids, err
+}
+
+func (g *Gateway) dmAllowed(userID string) bool {
+	if !g.dmEnabled {
+		return false
+	}
+	_, ok := g.dmUsers[userID]
+	return ok
+}
+
+func (g *Gateway) isDMChannel(channelID string) bool {
+	g.dmMu.RLock()
+	defer g.dmMu.RUnlock()
+	_, ok := g.dmChannels[channelID]
+	return ok
+}
+
+// guildIDFor returns the guild of channelID; DM channels have none.
+func (g *Gateway) guildIDFor(channelID string) string {
+	if g.isDMChannel(strings.TrimSpace(channelID)) {
+		return ""
+	}
+	return g.guildID
 }
 
 func buildMessageSend(content string) *discordgo.MessageSend {
@@ -248,9 +349,17 @@ func (g *Gateway) AddReaction(ctx context.Context, channelID string, messageID s
 	if err := ctx.Err(); err != nil {
 		return err
 	}
-	if err := g.session.MessageReactionAdd(channelID, messageID, emoji); err != nil {
+	post := reactionPost(channelID, messageID, emoji)
+	if g.isDuplicate(post) {
+		return &DuplicateSuppressedError{ChannelID: channelID}
+	}
+	err := g.outbound.do(ctx, channelID, PriorityReaction, "add_reaction", func() error {
+		return g.session.MessageReactionAdd(channelID, messageID, emoji, outboundOptions...)
+	})
+	if err != nil {
 		return fmt.Errorf("add reaction: %w", err)
 	}
+	g.remember(post)
 	return nil
 }
 
@@ -283,7 +392,7 @@ func (g *Gateway) StartTyping(_ context.Context, channelID string, duration time
 		deadline := time.NewTimer(duration)
 		defer deadline.Stop()
 
-		_ = g.session.ChannelTyping(channelID)
+		g.typing(typingCtx, channelID)
 		for {
 			select {
 			case <-typingCtx.Done():
@@ -291,12 +400,23 @@ func (g *Gateway) StartTyping(_ context.Context, channelID string, duration time
 			case <-deadline.C:
 				return
 			case <-ticker.C:
-				_ = g.session.ChannelTyping(channelID)
+				g.typing(typingCtx, channelID)
 			}
 		}
 	}()
 }
 
+func (g *Gateway) typing(ctx context.Context, channelID string) {
+	_ = g.outbound.do(ctx, channelID, PriorityTyping, "typing", func() error {
+		return g.session.ChannelTyping(channelID, outboundOptions...)
+	})
+}
+
+// OutboundStats reports the outbound queue's counters.
+func (g *Gateway) OutboundStats() OutboundStats {
+	return g.outbound.stats()
+}
+
 func (g *Gateway) ListChannels(ctx context.Context) ([]ChannelInfo, error) {
 	ids := make([]string, 0, len(g.readableChannels))
 	for channelID := range g.readableChannels {
@@ -358,10 +478,20 @@ func (g *Gateway) validateWritableChannel(channelID string) error {
 	if _, excluded := g.excludedChannel[channelID]; excluded {
 		return fmt.Errorf("channel %s is excluded", channelID)
 	}
-	if _, ok := g.writableChannels[channelID]; !ok {
-		return fmt.Errorf("channel %s is not in write_channel_ids", channelID)
+	if g.isDMChannel(channelID) {
+		return nil
 	}
-	return nil
+	if _, ok := g.writableChannels[channelID]; ok {
+		return nil
+	}
+	if parentID := g.ParentChannelID(channelID); parentID != "" {
+		if _, excluded := g.excludedChannel[parentID]; !excluded {
+			if _, ok := g.writableChannels[parentID]; ok {
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("channel %s is not in write_channel_ids", channelID)
 }
 
 func (g *Gateway) validateReadableChannel(channelID string) error {
@@ -372,10 +502,20 @@ func (g *Gateway) validateReadableChannel(channelID string) error {
 	if _, excluded := g.excludedChannel[channelID]; excluded {
 		return fmt.Errorf("channel %s is excluded", channelID)
 	}
-	if _, ok := g.readableChannels[channelID]; !ok {
-		return fmt.Errorf("channel %s is not in read_channel_ids or observe_channel_ids", channelID)
+	if g.isDMChannel(channelID) {
+		return nil
 	}
-	return nil
+	if _, ok := g.readableChannels[channelID]; ok {
+		return nil
+	}
+	if parentID := g.ParentChannelID(channelID); parentID != "" {
+		if _, excluded := g.excludedChannel[parentID]; !excluded {
+			if _, ok := g.readableChannels[parentID]; ok {
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("channel %s is not in read_channel_ids or observe_channel_ids", channelID)
 }
 
 func authorDisplayName(msg *discordgo.Message) string {
@@ -407,62 +547,52 @@ func sortStrings(values []string) {
 	}
 }
 
+// isDuplicateContent reports whether content, or text nearly the same, was
+// sent to channelID within its dedup window.
 func (g *Gateway) isDuplicateContent(channelID string, content string) bool {
+	return g.isDuplicate(contentPost(channelID, content))
+}
+
+func (g *Gateway) rememberContent(channelID string, content string) {
+	g.remember(contentPost(channelID, content))
+}
+
+// forgetContent lets content be sent again to channelID, e.g. after the
+// message carrying it was edited or deleted.
+func (g *Gateway) forgetContent(channelID string, content string) {
 	channelID = strings.TrimSpace(channelID)
-	signature := dedupSignature(content)
-	if channelID == "" || signature == "" {
-		return false
+	if err := g.dedup.Forget(channelID, content); err != nil {
+		slog.Error("dedup_store_save_failed", "channel", channelID, "err", err)
 	}
-	now := time.Now().UTC()
-	threshold := now.Add(-duplicateWindow)
+}
 
-	g.dedupMu.Lock()
-	defer g.dedupMu.Unlock()
+func (g *Gateway) isDuplicate(post dedup.Post) bool {
+	return g.dedup.Seen(post)
+}
 
-	channelMap, ok := g.recentContentMap[channelID]
-	if !ok {
-		return false
-	}
-	for hash, ts := range channelMap {
-		if ts.Before(threshold) {
-			delete(channelMap, hash)
-		}
+func (g *Gateway) remember(post dedup.Post) {
+	window := g.dedupConfig.WindowFor(post.ChannelID, g.ParentChannelID(post.ChannelID))
+	if window <= 0 {
+		window = duplicateWindow
 	}
-	if len(channelMap) == 0 {
-		delete(g.recentContentMap, channelID)
-		return false
-	}
-	last, ok := channelMap[signature]
-	if !ok {
-		return false
+	if err := g.dedup.Remember(post, window); err != nil {
+		slog.Error("dedup_store_save_failed", "channel", post.ChannelID, "err", err)
 	}
-	return !last.Before(threshold)
 }
 
-func (g *Gateway) rememberContent(channelID string, content string) {
-	channelID = strings.TrimSpace(channelID)
-	signature := dedupSignature(content)
-	if channelID == "" || signature == "" {
-		return
-	}
-	now := time.Now().UTC()
-
-	g.dedupMu.Lock()
-	defer g.dedupMu.Unlock()
+func contentPost(channelID string, content string) dedup.Post {
+	return dedup.Post{ChannelID: strings.TrimSpace(channelID), Key: content, Text: content}
+}
 
-	channelMap, ok := g.recentContentMap[channelID]
-	if !ok {
-		channelMap = map[string]time.Time{}
-		g.recentContentMap[channelID] = channelMap
+// reactionPost identifies a reaction, which is only matched exactly.
+func reactionPost(channelID string, messageID string, emoji string) dedup.Post {
+	return dedup.Post{
+		ChannelID: strings.TrimSpace(channelID),
+		Key:       strings.Join([]string{"reaction", strings.TrimSpace(messageID), strings.TrimSpace(emoji)}, "\n"),
 	}
-	channelMap[signature] = now
 }
 
-func dedupSignature(content string) string {
-	normalized := strings.ToLower(strings.Join(strings.Fields(strings.TrimSpace(content)), " "))
-	if normalized == "" {
-		return ""
-	}
-	sum := sha256.Sum256([]byte(normalized))
-	return hex.EncodeToString(sum[:])
+// RestoreDedup loads the posts remembered before a restart.
+func (g *Gateway) RestoreDedup() (int, error) {
+	return g.dedup.Load()
 }
@@ -1,6 +1,11 @@
 package discordx
 
 import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
 	"testing"
 
 	"github.com/bwmarrin/discordgo"
@@ -92,16 +97,56 @@ func TestGatewayChannelValidation(t *testing.T) {
 	}
 }
 
+func TestGatewayRegisterDMChannel(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		dm      config.DMConfig
+		userID  string
+		wantOK  bool
+		wantErr bool
+	}{
+		{name: "allowed user", dm: config.DMConfig{Enabled: true, AllowedUserIDs: []string{"owner"}}, userID: "owner", wantOK: true},
+		{name: "other user", dm: config.DMConfig{Enabled: true, AllowedUserIDs: []string{"owner"}}, userID: "stranger", wantErr: true},
+		{name: "dm disabled", dm: config.DMConfig{AllowedUserIDs: []string{"owner"}}, userID: "owner", wantErr: true},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			gateway := NewGateway(nil, config.DiscordConfig{GuildID: "g1", DM: tc.dm})
+			if got := gateway.RegisterDMChannel("dm1", tc.userID); got != tc.wantOK {
+				t.Fatalf("RegisterDMChannel() = %t, want %t", got, tc.wantOK)
+			}
+			if err := gateway.validateWritableChannel("dm1"); (err != nil) != tc.wantErr {
+				t.Fatalf("validateWritableChannel(dm1) error = %v, wantErr %t", err, tc.wantErr)
+			}
+			if err := gateway.validateReadableChannel("dm1"); (err != nil) != tc.wantErr {
+				t.Fatalf("validateReadableChannel(dm1) error = %v, wantErr %t", err, tc.wantErr)
+			}
+			wantGuild := "g1"
+			if tc.wantOK {
+				wantGuild = ""
+			}
+			if got := gateway.guildIDFor("dm1"); got != wantGuild {
+				t.Fatalf("guildIDFor(dm1) = %q, want %q", got, wantGuild)
+			}
+		})
+	}
+}
+
 func TestGatewayDuplicateSignatureNormalization(t *testing.T) {
 	t.Parallel()
 
-	first := dedupSignature(" Hello   World ")
-	second := dedupSignature("hello world")
-	if first == "" || second == "" {
-		t.Fatal("dedupSignature() returned empty")
+	gateway := NewGateway(nil, config.DiscordConfig{WriteChannelIDs: []string{"c1"}})
+	gateway.rememberContent("c1", " Hello   World ")
+	if !gateway.isDuplicateContent("c1", "hello world") {
+		t.Fatal("isDuplicateContent() = false for text differing only in case and spacing")
 	}
-	if first != second {
-		t.Fatalf("dedupSignature mismatch: %q != %q", first, second)
+	if gateway.isDuplicateContent("c2", "hello world") {
+		t.Fatal("isDuplicateContent() = true in another channel")
 	}
 }
 
@@ -137,3 +182,113 @@ func TestBuildReplyMessageSendSuppressesEmbedsAndReference(t *testing.T) {
 		t.Fatalf("reference = %#v, want guild/channel/message = g1/c1/m1", msg.Reference)
 	}
 }
+
+func TestAddReactionSuppressesRepeats(t *testing.T) {
+	t.Parallel()
+
+	gateway := NewGateway(nil, config.DiscordConfig{WriteChannelIDs: []string{"c1"}})
+	gateway.remember(reactionPost("c1", "m1", "👍"))
+
+	err := gateway.AddReaction(context.Background(), "c1", "m1", "👍")
+	if !IsDuplicateSuppressed(err) {
+		t.Fatalf("AddReaction() error = %v, want duplicate suppressed", err)
+	}
+	if gateway.isDuplicate(reactionPost("c1", "m2", "👍")) || gateway.isDuplicate(reactionPost("c1", "m1", "🎉")) {
+		t.Fatal("isDuplicate() = true for a different message or emoji")
+	}
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// fakeDiscord answers REST calls with canned JSON keyed by "METHOD path"
+// (path without the API prefix) and records every request. Unknown routes
+// get a 404.
+type fakeDiscord struct {
+	mu        sync.Mutex
+	responses map[string]string
+	requests  []fakeRequest
+}
+
+type fakeRequest struct {
+	Route string
+	Body  string
+}
+
+// newFakeSession returns a session for bot user "bot" whose REST calls go to
+// a fakeDiscord.
+func newFakeSession(t *testing.T, responses map[string]string) (*discordgo.Session, *fakeDiscord) {
+	t.Helper()
+	session, err := discordgo.New("Bot test")
+	if err != nil {
+		t.Fatalf("discordgo.New() error = %v", err)
+	}
+	session.State.User = &discordgo.User{ID: "bot"}
+	fake := &fakeDiscord{responses: responses}
+	session.Client = &http.Client{Transport: roundTripFunc(fake.roundTrip)}
+	return session, fake
+}
+
+func (f *fakeDiscord) roundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		if body, err = io.ReadAll(req.Body); err != nil {
+			return nil, err
+		}
+	}
+	route := req.Method + " " + strings.TrimPrefix(req.URL.Path, "/api/v"+discordgo.APIVersion)
+	f.mu.Lock()
+	f.requests = append(f.requests, fakeRequest{Route: route, Body: string(body)})
+	resp, ok := f.responses[route]
+	f.mu.Unlock()
+	if !ok {
+		return &http.Response{StatusCode: http.StatusNotFound, Header: http.Header{}, Body: io.NopCloser(strings.NewReader(`{"message":"unknown"}`))}, nil
+	}
+	if resp == "" {
+		return &http.Response{StatusCode: http.StatusNoContent, Header: http.Header{}, Body: http.NoBody}, nil
+	}
+	return &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: io.NopCloser(strings.NewReader(resp))}, nil
+}
+
+// request returns the last request sent to route.
+func (f *fakeDiscord) request(route string) (fakeRequest, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i := len(f.requests) - 1; i >= 0; i-- {
+		if f.requests[i].Route == route {
+			return f.requests[i], true
+		}
+	}
+	return fakeRequest{}, false
+}
+
+func TestSendMessageReturnsPartialIDs(t *testing.T) {
+	t.Parallel()
+
+	session, err := discordgo.New("Bot test")
+	if err != nil {
+		t.Fatalf("discordgo.New() error = %v", err)
+	}
+	var posts int
+	session.Client = &http.Client{Transport: roundTripFunc(func(*http.Request) (*http.Response, error) {
+		posts++
+		if posts > 1 {
+			return &http.Response{StatusCode: http.StatusBadRequest, Header: http.Header{}, Body: io.NopCloser(strings.NewReader(`{"message":"bad"}`))}, nil
+		}
+		return &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: io.NopCloser(strings.NewReader(`{"id":"m1","channel_id":"c1"}`))}, nil
+	})}
+	gateway := NewGateway(session, config.DiscordConfig{WriteChannelIDs: []string{"c1"}})
+	content := strings.Repeat("あ", maxContentRunes+10)
+
+	ids, err := gateway.SendMessage(context.Background(), "c1", content)
+	if err == nil || len(ids) != 1 || ids[0] != "m1" {
+		t.Fatalf("SendMessage() = (%v, %v), want partial m1 and error", ids, err)
+	}
+	if _, err := gateway.SendMessage(context.Background(), "c1", content); !IsDuplicateSuppressed(err) {
+		t.Fatalf("retry SendMessage() error = %v, want duplicate suppressed", err)
+	}
+}
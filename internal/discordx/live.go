@@ -0,0 +1,270 @@
+package discordx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	maxLiveMessageRunes        = 2000
+	defaultLiveEditInterval    = 1500 * time.Millisecond
+	liveMessageTruncatedPrefix = "…"
+)
+
+type LiveMessageOptions struct {
+	StartDelay   time.Duration
+	EditInterval time.Duration
+}
+
+// LiveMessage is a progress message that is posted once the turn runs past
+// StartDelay and then edited in place, at most once per EditInterval.
+type LiveMessage struct {
+	send   func(content string) (string, error)
+	edit   func(messageID string, content string) error
+	remove func(messageID string) error
+
+	startDelay   time.Duration
+	editInterval time.Duration
+
+	mu        sync.Mutex
+	pending   string
+	shown     string
+	messageID string
+	final     string
+	finished  bool
+	err       error
+
+	wake chan struct{}
+	stop chan struct{}
+	done chan struct{}
+}
+
+func (g *Gateway) StartLiveMessage(channelID string, replyToMessageID string, opts LiveMessageOptions) (*LiveMessage, error) {
+	if err := g.validateWritableChannel(channelID); err != nil {
+		return nil, err
+	}
+	replyTo := strings.TrimSpace(replyToMessageID)
+	send := func(content string) (string, error) {
+		payload := buildMessageSend(content)
+		if replyTo != "" {
+			payload = buildReplyMessageSend(g.guildIDFor(channelID), channelID, replyTo, content)
+		}
+		var msg *discordgo.Message
+		err := g.outbound.do(context.Background(), channelID, PriorityLive, "live_send", func() error {
+			var err error
+			msg, err = g.session.ChannelMessageSendComplex(channelID, payload, outboundOptions...)
+			return err
+		})
+		if err != nil {
+			return "", fmt.Errorf("send live message: %w", err)
+		}
+		return msg.ID, nil
+	}
+	edit := func(messageID string, content string) error {
+		err := g.outbound.do(context.Background(), channelID, PriorityLive, "live_edit", func() error {
+			_, err := g.session.ChannelMessageEdit(channelID, messageID, content, outboundOptions...)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("edit live message: %w", err)
+		}
+		return nil
+	}
+	remove := func(messageID string) error {
+		err := g.outbound.do(context.Background(), channelID, PriorityLive, "live_delete", func() error {
+			return g.session.ChannelMessageDelete(channelID, messageID, outboundOptions...)
+		})
+		if err != nil {
+			return fmt.Errorf("delete live message: %w", err)
+		}
+		return nil
+	}
+	return newLiveMessage(send, edit, remove, opts), nil
+}
+
+func newLiveMessage(send func(string) (string, error), edit func(string, string) error, remove func(string) error, opts LiveMessageOptions) *LiveMessage {
+	interval := opts.EditInterval
+	if interval <= 0 {
+		interval = defaultLiveEditInterval
+	}
+	delay := opts.StartDelay
+	if delay < 0 {
+		delay = 0
+	}
+	l := &LiveMessage{
+		send:         send,
+		edit:         edit,
+		remove:       remove,
+		startDelay:   delay,
+		editInterval: interval,
+		wake:         make(chan struct{}, 1),
+		stop:         make(chan struct{}),
+		done:         make(chan struct{}),
+	}
+	go l.run()
+	return l
+}
+
+// Update replaces the displayed content. It never blocks on Discord.
+func (l *LiveMessage) Update(content string) {
+	l.mu.Lock()
+	if l.finished {
+		l.mu.Unlock()
+		return
+	}
+	l.pending = liveMessageContent(content)
+	l.mu.Unlock()
+
+	select {
+	case l.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Finish stops updates. If a message was posted it is edited to finalContent,
+// or deleted when finalContent is empty. Nothing is posted otherwise.
+func (l *LiveMessage) Finish(finalContent string) error {
+	l.mu.Lock()
+	if l.finished {
+		l.mu.Unlock()
+		<-l.done
+		return l.joinedError()
+	}
+	l.finished = true
+	l.final = liveMessageContent(finalContent)
+	l.mu.Unlock()
+
+	close(l.stop)
+	<-l.done
+	return l.joinedError()
+}
+
+func (l *LiveMessage) MessageID() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.messageID
+}
+
+func (l *LiveMessage) run() {
+	defer close(l.done)
+
+	if l.startDelay > 0 {
+		timer := time.NewTimer(l.startDelay)
+		select {
+		case <-l.stop:
+			timer.Stop()
+			l.finalize()
+			return
+		case <-timer.C:
+		}
+	}
+
+	for {
+		l.flush()
+		lastFlush := time.Now()
+
+		select {
+		case <-l.stop:
+			l.finalize()
+			return
+		case <-l.wake:
+		}
+		if wait := l.editInterval - time.Since(lastFlush); wait > 0 {
+			timer := time.NewTimer(wait)
+			select {
+			case <-l.stop:
+				timer.Stop()
+				l.finalize()
+				return
+			case <-timer.C:
+			}
+		}
+	}
+}
+
+func (l *LiveMessage) flush() {
+	l.mu.Lock()
+	content := l.pending
+	messageID := l.messageID
+	shown := l.shown
+	l.mu.Unlock()
+
+	if content == "" || content == shown {
+		return
+	}
+	if messageID == "" {
+		id, err := l.send(content)
+		if err != nil {
+			l.recordError(err)
+			return
+		}
+		l.mu.Lock()
+		l.messageID = id
+		l.shown = content
+		l.mu.Unlock()
+		return
+	}
+	if err := l.edit(messageID, content); err != nil {
+		l.recordError(err)
+		return
+	}
+	l.mu.Lock()
+	l.shown = content
+	l.mu.Unlock()
+}
+
+func (l *LiveMessage) finalize() {
+	l.mu.Lock()
+	messageID := l.messageID
+	final := l.final
+	shown := l.shown
+	l.mu.Unlock()
+
+	if messageID == "" {
+		return
+	}
+	if final == "" {
+		if err := l.remove(messageID); err != nil {
+			l.recordError(err)
+		}
+		return
+	}
+	if final == shown {
+		return
+	}
+	if err := l.edit(messageID, final); err != nil {
+		l.recordError(err)
+		return
+	}
+	l.mu.Lock()
+	l.shown = final
+	l.mu.Unlock()
+}
+
+func (l *LiveMessage) recordError(err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.err = errors.Join(l.err, err)
+}
+
+func (l *LiveMessage) joinedError() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.err
+}
+
+func liveMessageContent(content string) string {
+	text := strings.TrimSpace(content)
+	runes := []rune(text)
+	if len(runes) <= maxLiveMessageRunes {
+		return text
+	}
+	prefix := []rune(liveMessageTruncatedPrefix)
+	return string(prefix) + string(runes[len(runes)-(maxLiveMessageRunes-len(prefix)):])
+}
@@ -0,0 +1,127 @@
+package discordx
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type liveMessageRecorder struct {
+	mu      sync.Mutex
+	sent    []string
+	edits   []string
+	removed []string
+}
+
+func (r *liveMessageRecorder) send(content string) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sent = append(r.sent, content)
+	return "live-1", nil
+}
+
+func (r *liveMessageRecorder) edit(_ string, content string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.edits = append(r.edits, content)
+	return nil
+}
+
+func (r *liveMessageRecorder) remove(messageID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.removed = append(r.removed, messageID)
+	return nil
+}
+
+func (r *liveMessageRecorder) snapshot() (sent []string, edits []string, removed []string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.sent...), append([]string(nil), r.edits...), append([]string(nil), r.removed...)
+}
+
+func waitForLiveMessage(t *testing.T, live *LiveMessage) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for live.MessageID() == "" {
+		if time.Now().After(deadline) {
+			t.Fatal("live message was not posted")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestLiveMessageSkipsShortTurns(t *testing.T) {
+	t.Parallel()
+
+	recorder := &liveMessageRecorder{}
+	live := newLiveMessage(recorder.send, recorder.edit, recorder.remove, LiveMessageOptions{StartDelay: time.Hour})
+	live.Update("考え中")
+	if err := live.Finish("final"); err != nil {
+		t.Fatalf("Finish() error = %v", err)
+	}
+	sent, edits, removed := recorder.snapshot()
+	if len(sent) != 0 || len(edits) != 0 || len(removed) != 0 {
+		t.Fatalf("short turn touched discord: sent=%v edits=%v removed=%v", sent, edits, removed)
+	}
+}
+
+func TestLiveMessageCoalescesEditsAndDeletesOnFinish(t *testing.T) {
+	t.Parallel()
+
+	recorder := &liveMessageRecorder{}
+	live := newLiveMessage(recorder.send, recorder.edit, recorder.remove, LiveMessageOptions{EditInterval: time.Hour})
+	live.Update("first")
+	waitForLiveMessage(t, live)
+	live.Update("second")
+	live.Update("third")
+	if err := live.Finish(""); err != nil {
+		t.Fatalf("Finish() error = %v", err)
+	}
+
+	sent, edits, removed := recorder.snapshot()
+	if len(sent) != 1 || sent[0] != "first" {
+		t.Fatalf("sent = %v, want [first]", sent)
+	}
+	if len(edits) != 0 {
+		t.Fatalf("edits = %v, want none within the edit interval", edits)
+	}
+	if len(removed) != 1 || removed[0] != "live-1" {
+		t.Fatalf("removed = %v, want [live-1]", removed)
+	}
+}
+
+func TestLiveMessageFinishKeepsFinalText(t *testing.T) {
+	t.Parallel()
+
+	recorder := &liveMessageRecorder{}
+	live := newLiveMessage(recorder.send, recorder.edit, recorder.remove, LiveMessageOptions{EditInterval: time.Millisecond})
+	live.Update("partial")
+	waitForLiveMessage(t, live)
+	if err := live.Finish("complete answer"); err != nil {
+		t.Fatalf("Finish() error = %v", err)
+	}
+	live.Update("ignored")
+
+	_, edits, removed := recorder.snapshot()
+	if len(edits) == 0 || edits[len(edits)-1] != "complete answer" {
+		t.Fatalf("edits = %v, want final edit to complete answer", edits)
+	}
+	if len(removed) != 0 {
+		t.Fatalf("removed = %v, want none", removed)
+	}
+}
+
+func TestLiveMessageContentKeepsTail(t *testing.T) {
+	t.Parallel()
+
+	long := strings.Repeat("a", maxLiveMessageRunes) + "tail"
+	got := liveMessageContent(long)
+	if n := len([]rune(got)); n != maxLiveMessageRunes {
+		t.Fatalf("len = %d, want %d", n, maxLiveMessageRunes)
+	}
+	if !strings.HasPrefix(got, liveMessageTruncatedPrefix) || !strings.HasSuffix(got, "tail") {
+		t.Fatalf("liveMessageContent() did not keep the tail: %q...%q", got[:8], got[len(got)-8:])
+	}
+}
@@ -0,0 +1,349 @@
+package discordx
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// Priority orders the actions waiting for the same channel. Lower values go
+// first.
+type Priority int
+
+const (
+	PriorityMessage Priority = iota
+	// PriorityLive is for live progress messages, so a throttled live edit
+	// does not hold back the final reply.
+	PriorityLive
+	PriorityReaction
+	PriorityTyping
+	priorityCount
+)
+
+const (
+	outboundMaxAttempts = 4
+	outboundBaseBackoff = 500 * time.Millisecond
+	outboundMaxBackoff  = 8 * time.Second
+)
+
+// outboundOptions make discordgo return 429s instead of sleeping inside the
+// request, so the queue can hold back only the limited lane. Another process
+// sharing the token can exhaust a bucket without this process knowing.
+var outboundOptions = []discordgo.RequestOption{discordgo.WithRetryOnRatelimit(false)}
+
+// idempotentActions can be repeated after a 5xx without harm. Discord may
+// have carried out a request that failed with a 5xx, so actions that create
+// something are not retried to avoid posting twice.
+var idempotentActions = map[string]bool{
+	"add_reaction":        true,
+	"add_system_reaction": true,
+	"delete_message":      true,
+	"edit_message":        true,
+	"live_delete":         true,
+	"live_edit":           true,
+	"typing":              true,
+}
+
+// OutboundStats counts the actions that went through the outbound queue.
+// Pending is the number waiting right now.
+type OutboundStats struct {
+	Queued      int64
+	Sent        int64
+	Failed      int64
+	Dropped     int64
+	Retried     int64
+	RateLimited int64
+	Pending     int64
+}
+
+type outboundJob struct {
+	ctx      context.Context
+	priority Priority
+	action   string
+	run      func() error
+	attempts int
+	done     chan error
+}
+
+// outboundLane holds the jobs of one priority. A 429 or 5xx blocks the lane
+// until blocked, which keeps its jobs in order while other lanes move on.
+type outboundLane struct {
+	jobs    []*outboundJob
+	blocked time.Time
+}
+
+type outboundChannel struct {
+	lanes   [priorityCount]outboundLane
+	wake    chan struct{}
+	running bool
+}
+
+// outboundQueue serializes writes per channel. Each channel with queued jobs
+// has one worker, which exits once the channel is idle.
+type outboundQueue struct {
+	now         func() time.Time
+	jitter      func(time.Duration) time.Duration
+	baseBackoff time.Duration
+	maxBackoff  time.Duration
+
+	mu       sync.Mutex
+	channels map[string]*outboundChannel
+	// globalBlocked holds every lane of every channel after a global 429.
+	globalBlocked time.Time
+
+	queued      atomic.Int64
+	sent        atomic.Int64
+	failed      atomic.Int64
+	dropped     atomic.Int64
+	retried     atomic.Int64
+	rateLimited atomic.Int64
+	pending     atomic.Int64
+}
+
+func newOutboundQueue() *outboundQueue {
+	return &outboundQueue{
+		now: time.Now,
+		jitter: func(d time.Duration) time.Duration {
+			if d <= 0 {
+				return 0
+			}
+			return rand.N(d)
+		},
+		baseBackoff: outboundBaseBackoff,
+		maxBackoff:  outboundMaxBackoff,
+		channels:    map[string]*outboundChannel{},
+	}
+}
+
+// do runs fn on the channel's worker and waits for its result. A typing
+// action is dropped when one is already waiting for the channel. A nil queue
+// runs fn directly.
+func (q *outboundQueue) do(ctx context.Context, channelID string, priority Priority, action string, fn func() error) error {
+	if q == nil {
+		return fn()
+	}
+	job := &outboundJob{ctx: ctx, priority: priority, action: action, run: fn, done: make(chan error, 1)}
+
+	q.mu.Lock()
+	ch, ok := q.channels[channelID]
+	if !ok {
+		ch = &outboundChannel{wake: make(chan struct{}, 1)}
+		q.channels[channelID] = ch
+	}
+	lane := &ch.lanes[priority]
+	if priority == PriorityTyping && len(lane.jobs) > 0 {
+		q.mu.Unlock()
+		q.dropped.Add(1)
+		return nil
+	}
+	lane.jobs = append(lane.jobs, job)
+	q.queued.Add(1)
+	q.pending.Add(1)
+	if ch.running {
+		select {
+		case ch.wake <- struct{}{}:
+		default:
+		}
+	} else {
+		ch.running = true
+		go q.work(channelID, ch)
+	}
+	q.mu.Unlock()
+
+	select {
+	case err := <-job.done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (q *outboundQueue) work(channelID string, ch *outboundChannel) {
+	for {
+		job, wait := q.next(channelID, ch)
+		if job != nil {
+			q.attempt(channelID, ch, job)
+			continue
+		}
+		if wait <= 0 {
+			return
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-ch.wake:
+		}
+		timer.Stop()
+	}
+}
+
+// next pops the first job of the highest priority lane that is not blocked.
+// With nothing runnable it returns how long to wait; with nothing queued it
+// retires the worker.
+func (q *outboundQueue) next(channelID string, ch *outboundChannel) (*outboundJob, time.Duration) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	now := q.now()
+	var wait time.Duration
+	idle := true
+	for p := range ch.lanes {
+		lane := &ch.lanes[p]
+		for len(lane.jobs) > 0 && lane.jobs[0].ctx.Err() != nil {
+			q.finish(lane.jobs[0], lane.jobs[0].ctx.Err(), &q.dropped)
+			lane.jobs = lane.jobs[1:]
+		}
+		if len(lane.jobs) == 0 {
+			continue
+		}
+		idle = false
+		blocked := lane.blocked
+		if q.globalBlocked.After(blocked) {
+			blocked = q.globalBlocked
+		}
+		if until := blocked.Sub(now); until > 0 {
+			if wait == 0 || until < wait {
+				wait = until
+			}
+			continue
+		}
+		job := lane.jobs[0]
+		lane.jobs = lane.jobs[1:]
+		return job, 0
+	}
+	if idle {
+		ch.running = false
+		delete(q.channels, channelID)
+	}
+	return nil, wait
+}
+
+func (q *outboundQueue) attempt(channelID string, ch *outboundChannel, job *outboundJob) {
+	job.attempts++
+	err := job.run()
+	if err == nil {
+		q.finish(job, nil, &q.sent)
+		return
+	}
+	delay, rateLimited := q.retryDelay(err, job.action, job.attempts)
+	if delay <= 0 {
+		q.finish(job, err, &q.failed)
+		return
+	}
+	if rateLimited {
+		q.rateLimited.Add(1)
+	}
+	if job.attempts >= outboundMaxAttempts {
+		slog.Warn("outbound_dropped", "channel", channelID, "action", job.action, "attempts", job.attempts, "err", err)
+		q.finish(job, err, &q.dropped)
+		return
+	}
+	q.retried.Add(1)
+	slog.Warn("outbound_retry", "channel", channelID, "action", job.action, "attempt", job.attempts, "rate_limited", rateLimited, "retry_after_ms", delay.Milliseconds(), "err", err)
+
+	q.mu.Lock()
+	lane := &ch.lanes[job.priority]
+	lane.jobs = append([]*outboundJob{job}, lane.jobs...)
+	lane.blocked = q.now().Add(delay)
+	q.mu.Unlock()
+}
+
+// retryDelay tells how long to hold a failed job's lane, or 0 when the error
+// is not worth retrying. 429s wait as long as Discord asks; 5xx responses of
+// idempotent actions back off exponentially with jitter.
+func (q *outboundQueue) retryDelay(err error, action string, attempt int) (time.Duration, bool) {
+	var limited *discordgo.RateLimitError
+	if errors.As(err, &limited) && limited.RateLimit != nil && limited.TooManyRequests != nil {
+		return max(limited.RetryAfter, time.Millisecond), true
+	}
+	var rest *discordgo.RESTError
+	if errors.As(err, &rest) && rest.Response != nil && rest.Response.StatusCode >= http.StatusInternalServerError && idempotentActions[action] {
+		backoff := min(q.baseBackoff<<(attempt-1), q.maxBackoff)
+		return backoff + q.jitter(backoff/2), false
+	}
+	return 0, false
+}
+
+// blockAll holds every channel for d. Workers that are already waiting
+// notice it the next time they look for a job.
+func (q *outboundQueue) blockAll(d time.Duration) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	until := q.now().Add(d)
+	if until.After(q.globalBlocked) {
+		q.globalBlocked = until
+	}
+	slog.Warn("outbound_global_rate_limited", "retry_after_ms", d.Milliseconds())
+}
+
+// watchGlobalRateLimit wraps client so that a 429 Discord marks as global
+// blocks the whole queue. discordgo does not report the scope of a 429 in
+// its error, only in the response headers.
+func (q *outboundQueue) watchGlobalRateLimit(client *http.Client) *http.Client {
+	if client == nil {
+		client = &http.Client{}
+	}
+	wrapped := *client
+	next := wrapped.Transport
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	wrapped.Transport = globalRateLimitTransport{next: next, queue: q}
+	return &wrapped
+}
+
+type globalRateLimitTransport struct {
+	next  http.RoundTripper
+	queue *outboundQueue
+}
+
+func (t globalRateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+	if err == nil && resp.StatusCode == http.StatusTooManyRequests && isGlobalRateLimit(resp.Header) {
+		t.queue.blockAll(retryAfter(resp.Header))
+	}
+	return resp, err
+}
+
+func isGlobalRateLimit(h http.Header) bool {
+	return strings.EqualFold(h.Get("X-RateLimit-Global"), "true") || strings.EqualFold(h.Get("X-RateLimit-Scope"), "global")
+}
+
+// retryAfter reads Retry-After, which Discord sends in seconds.
+func retryAfter(h http.Header) time.Duration {
+	sec, err := strconv.ParseFloat(strings.TrimSpace(h.Get("Retry-After")), 64)
+	if err != nil || sec <= 0 {
+		return time.Second
+	}
+	return time.Duration(sec * float64(time.Second))
+}
+
+func (q *outboundQueue) finish(job *outboundJob, err error, counter *atomic.Int64) {
+	counter.Add(1)
+	q.pending.Add(-1)
+	job.done <- err
+}
+
+func (q *outboundQueue) stats() OutboundStats {
+	if q == nil {
+		return OutboundStats{}
+	}
+	return OutboundStats{
+		Queued:      q.queued.Load(),
+		Sent:        q.sent.Load(),
+		Failed:      q.failed.Load(),
+		Dropped:     q.dropped.Load(),
+		Retried:     q.retried.Load(),
+		RateLimited: q.rateLimited.Load(),
+		Pending:     q.pending.Load(),
+	}
+}
@@ -0,0 +1,316 @@
+package discordx
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/sigumaa/yururi/internal/config"
+)
+
+func newTestOutboundQueue() *outboundQueue {
+	q := newOutboundQueue()
+	q.jitter = func(time.Duration) time.Duration { return 0 }
+	q.baseBackoff = time.Millisecond
+	q.maxBackoff = 4 * time.Millisecond
+	return q
+}
+
+func waitPending(t *testing.T, q *outboundQueue, want int64) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for q.pending.Load() != want {
+		if time.Now().After(deadline) {
+			t.Fatalf("pending = %d, want %d", q.pending.Load(), want)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func rateLimited(retryAfter time.Duration) error {
+	return &discordgo.RateLimitError{RateLimit: &discordgo.RateLimit{TooManyRequests: &discordgo.TooManyRequests{RetryAfter: retryAfter}}}
+}
+
+func restError(status int) error {
+	return &discordgo.RESTError{Response: &http.Response{StatusCode: status}}
+}
+
+func TestOutboundQueueRunsByPriority(t *testing.T) {
+	t.Parallel()
+
+	q := newTestOutboundQueue()
+	release := make(chan struct{})
+	var mu sync.Mutex
+	var order []string
+	record := func(name string) func() error {
+		return func() error {
+			mu.Lock()
+			order = append(order, name)
+			mu.Unlock()
+			return nil
+		}
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		_ = q.do(context.Background(), "c1", PriorityMessage, "block", func() error {
+			<-release
+			return nil
+		})
+	}()
+	waitPending(t, q, 1)
+	for _, job := range []struct {
+		name     string
+		priority Priority
+	}{
+		{name: "typing", priority: PriorityTyping},
+		{name: "reaction", priority: PriorityReaction},
+		{name: "message", priority: PriorityMessage},
+	} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_ = q.do(context.Background(), "c1", job.priority, job.name, record(job.name))
+		}()
+	}
+	waitPending(t, q, 4)
+	if err := q.do(context.Background(), "c1", PriorityTyping, "typing", record("typing-again")); err != nil {
+		t.Fatalf("do(typing) error = %v", err)
+	}
+	close(release)
+	wg.Wait()
+
+	if got := strings.Join(order, ","); got != "message,reaction,typing" {
+		t.Fatalf("order = %s, want message,reaction,typing", got)
+	}
+	if got := q.stats(); got.Queued != 4 || got.Sent != 4 || got.Dropped != 1 || got.Pending != 0 {
+		t.Fatalf("stats = %+v", got)
+	}
+}
+
+func TestOutboundQueueRateLimitBlocksOnlyItsLane(t *testing.T) {
+	t.Parallel()
+
+	q := newTestOutboundQueue()
+	var mu sync.Mutex
+	var order []string
+	calls := 0
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		err := q.do(context.Background(), "c1", PriorityMessage, "send_message", func() error {
+			mu.Lock()
+			defer mu.Unlock()
+			calls++
+			if calls == 1 {
+				return rateLimited(50 * time.Millisecond)
+			}
+			order = append(order, "message")
+			return nil
+		})
+		if err != nil {
+			t.Errorf("do(message) error = %v", err)
+		}
+	}()
+	deadline := time.Now().Add(2 * time.Second)
+	for q.rateLimited.Load() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("message was not rate limited")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	err := q.do(context.Background(), "c1", PriorityReaction, "add_reaction", func() error {
+		mu.Lock()
+		defer mu.Unlock()
+		order = append(order, "reaction")
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("do(reaction) error = %v", err)
+	}
+	wg.Wait()
+
+	if got := strings.Join(order, ","); got != "reaction,message" {
+		t.Fatalf("order = %s, want reaction,message", got)
+	}
+	if got := q.stats(); got.Sent != 2 || got.Retried != 1 || got.RateLimited != 1 {
+		t.Fatalf("stats = %+v", got)
+	}
+}
+
+func TestOutboundQueueRateLimitedLiveEditDoesNotHoldMessages(t *testing.T) {
+	t.Parallel()
+
+	q := newTestOutboundQueue()
+	var mu sync.Mutex
+	var order []string
+	calls := 0
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		err := q.do(context.Background(), "c1", PriorityLive, "live_edit", func() error {
+			mu.Lock()
+			defer mu.Unlock()
+			calls++
+			if calls == 1 {
+				return rateLimited(50 * time.Millisecond)
+			}
+			order = append(order, "live")
+			return nil
+		})
+		if err != nil {
+			t.Errorf("do(live) error = %v", err)
+		}
+	}()
+	deadline := time.Now().Add(2 * time.Second)
+	for q.rateLimited.Load() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("live edit was not rate limited")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	err := q.do(context.Background(), "c1", PriorityMessage, "send_message", func() error {
+		mu.Lock()
+		defer mu.Unlock()
+		order = append(order, "message")
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("do(message) error = %v", err)
+	}
+	wg.Wait()
+
+	if got := strings.Join(order, ","); got != "message,live" {
+		t.Fatalf("order = %s, want message,live", got)
+	}
+}
+
+func TestOutboundQueueRetries(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name         string
+		action       string
+		errs         []error
+		wantErr      bool
+		wantAttempts int
+		wantStats    OutboundStats
+	}{
+		{name: "server error then success", action: "edit_message", errs: []error{restError(http.StatusServiceUnavailable), nil}, wantAttempts: 2, wantStats: OutboundStats{Queued: 1, Sent: 1, Retried: 1}},
+		{name: "server error until dropped", action: "delete_message", errs: []error{restError(500), restError(500), restError(500), restError(500)}, wantErr: true, wantAttempts: outboundMaxAttempts, wantStats: OutboundStats{Queued: 1, Dropped: 1, Retried: outboundMaxAttempts - 1}},
+		{name: "server error on send is not retried", action: "send_message", errs: []error{restError(http.StatusBadGateway)}, wantErr: true, wantAttempts: 1, wantStats: OutboundStats{Queued: 1, Failed: 1}},
+		{name: "rate limited send is retried", action: "send_message", errs: []error{rateLimited(time.Millisecond), nil}, wantAttempts: 2, wantStats: OutboundStats{Queued: 1, Sent: 1, Retried: 1, RateLimited: 1}},
+		{name: "client error is not retried", action: "edit_message", errs: []error{restError(http.StatusForbidden)}, wantErr: true, wantAttempts: 1, wantStats: OutboundStats{Queued: 1, Failed: 1}},
+		{name: "other error is not retried", action: "edit_message", errs: []error{errors.New("boom")}, wantErr: true, wantAttempts: 1, wantStats: OutboundStats{Queued: 1, Failed: 1}},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			q := newTestOutboundQueue()
+			attempts := 0
+			err := q.do(context.Background(), "c1", PriorityMessage, tc.action, func() error {
+				err := tc.errs[attempts]
+				attempts++
+				return err
+			})
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("do() error = %v, wantErr %t", err, tc.wantErr)
+			}
+			if attempts != tc.wantAttempts {
+				t.Fatalf("attempts = %d, want %d", attempts, tc.wantAttempts)
+			}
+			if got := q.stats(); got != tc.wantStats {
+				t.Fatalf("stats = %+v, want %+v", got, tc.wantStats)
+			}
+		})
+	}
+}
+
+func TestOutboundQueueGlobalRateLimitBlocksAllChannels(t *testing.T) {
+	t.Parallel()
+
+	q := newTestOutboundQueue()
+	client := q.watchGlobalRateLimit(&http.Client{Transport: roundTripFunc(func(*http.Request) (*http.Response, error) {
+		header := http.Header{}
+		header.Set("X-RateLimit-Global", "true")
+		header.Set("Retry-After", "0.1")
+		return &http.Response{StatusCode: http.StatusTooManyRequests, Header: header, Body: http.NoBody}, nil
+	})})
+	resp, err := client.Get("https://discord.example/api")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	resp.Body.Close()
+
+	started := time.Now()
+	if err := q.do(context.Background(), "other-channel", PriorityReaction, "add_reaction", func() error { return nil }); err != nil {
+		t.Fatalf("do() error = %v", err)
+	}
+	if waited := time.Since(started); waited < 80*time.Millisecond {
+		t.Fatalf("waited %s, want the global block", waited)
+	}
+}
+
+func TestOutboundQueueNilRunsDirectly(t *testing.T) {
+	t.Parallel()
+
+	var q *outboundQueue
+	ran := false
+	if err := q.do(context.Background(), "c1", PriorityMessage, "send_message", func() error {
+		ran = true
+		return nil
+	}); err != nil || !ran {
+		t.Fatalf("do() error = %v, ran = %t", err, ran)
+	}
+	if got := q.stats(); got != (OutboundStats{}) {
+		t.Fatalf("stats = %+v, want zero", got)
+	}
+}
+
+func TestOutboundQueueRetriesFileUploadWithFullBody(t *testing.T) {
+	t.Parallel()
+
+	session, err := discordgo.New("Bot test")
+	if err != nil {
+		t.Fatalf("discordgo.New() error = %v", err)
+	}
+	session.ShouldRetryOnRateLimit = false
+	var bodies []string
+	session.Client = &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		if req.Method != http.MethodPost {
+			return &http.Response{StatusCode: http.StatusNotFound, Header: http.Header{}, Body: io.NopCloser(strings.NewReader(`{"message":"unknown"}`))}, nil
+		}
+		body, err := io.ReadAll(req.Body)
+		if err != nil {
+			return nil, err
+		}
+		bodies = append(bodies, string(body))
+		if len(bodies) == 1 {
+			return &http.Response{StatusCode: http.StatusTooManyRequests, Header: http.Header{}, Body: io.NopCloser(strings.NewReader(`{"message":"rate limited","retry_after":0.001}`))}, nil
+		}
+		return &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: io.NopCloser(strings.NewReader(`{"id":"m1","channel_id":"c1"}`))}, nil
+	})}
+	gateway := NewGateway(session, config.DiscordConfig{WriteChannelIDs: []string{"c1"}})
+
+	id, err := gateway.SendFile(context.Background(), "c1", "", FileUpload{Name: "report.txt", ContentType: "text/plain", Data: []byte("file body")})
+	if err != nil || id != "m1" {
+		t.Fatalf("SendFile() = (%q, %v), want m1", id, err)
+	}
+	if len(bodies) != 2 {
+		t.Fatalf("uploads = %d, want 2", len(bodies))
+	}
+	if !strings.Contains(bodies[1], "file body") {
+		t.Fatalf("retried upload body = %q, want the file data", bodies[1])
+	}
+}
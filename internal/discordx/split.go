@@ -0,0 +1,114 @@
+package discordx
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+const codeFence = "```"
+
+// sentenceEnds are the marks a long paragraph may be split after.
+var sentenceEnds = []string{"。", "！", "？", ". ", "! ", "? "}
+
+// splitMessage splits content into parts of at most limit characters. It
+// cuts at paragraph breaks, then line breaks, then sentence ends, and only
+// cuts mid-sentence when nothing else fits. A code block cut in two is
+// closed at the end of one part and reopened with the same info string at
+// the start of the next.
+func splitMessage(content string, limit int) []string {
+	if utf8.RuneCountInString(content) <= limit {
+		return []string{content}
+	}
+
+	var parts []string
+	rest := content
+	openFence := ""
+	for rest != "" {
+		prefix := ""
+		if openFence != "" {
+			prefix = openFence + "\n"
+		}
+		if utf8.RuneCountInString(prefix)+utf8.RuneCountInString(rest) <= limit {
+			parts = append(parts, prefix+rest)
+			break
+		}
+		budget := limit - utf8.RuneCountInString(prefix) - utf8.RuneCountInString("\n"+codeFence)
+		window := runePrefix(rest, budget)
+		cut := splitPoint(window)
+		body := strings.TrimRight(rest[:cut], " \n")
+		rest = rest[cut:]
+		if body == "" {
+			rest = strings.TrimLeft(rest, " \n")
+			continue
+		}
+		openFence = fenceAfter(body, openFence)
+		// Only the break at the cut is dropped; indentation at the start of
+		// the next line is kept, and so is every space inside a code block.
+		if strings.HasPrefix(rest, "\n") {
+			rest = strings.TrimLeft(rest, "\n")
+		} else if openFence == "" {
+			rest = strings.TrimLeft(rest, " ")
+		}
+		part := prefix + body
+		if openFence != "" {
+			part += "\n" + codeFence
+		}
+		parts = append(parts, part)
+	}
+	return parts
+}
+
+// splitPoint returns the byte offset to cut window at. Boundaries in the
+// first half are skipped so parts are not needlessly short.
+func splitPoint(window string) int {
+	half := len(window) / 2
+	if i := strings.LastIndex(window, "\n\n"); i > half {
+		return i
+	}
+	if i := strings.LastIndex(window, "\n"); i > half {
+		return i
+	}
+	best := -1
+	for _, mark := range sentenceEnds {
+		if i := strings.LastIndex(window, mark); i >= 0 && i+len(mark) > best {
+			best = i + len(mark)
+		}
+	}
+	if best > half {
+		return best
+	}
+	if i := strings.LastIndex(window, " "); i > half {
+		return i
+	}
+	return len(window)
+}
+
+// fenceAfter returns the opening fence line still open after text, given the
+// one open before it, or "" when no code block is open.
+func fenceAfter(text string, open string) string {
+	for _, line := range strings.Split(text, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if !strings.HasPrefix(trimmed, codeFence) {
+			continue
+		}
+		if open == "" {
+			open = trimmed
+		} else {
+			open = ""
+		}
+	}
+	return open
+}
+
+func runePrefix(s string, n int) string {
+	if n <= 0 {
+		n = 1
+	}
+	for i := range s {
+		if n == 0 {
+			return s[:i]
+		}
+		n--
+	}
+	return s
+}
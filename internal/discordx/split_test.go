@@ -0,0 +1,81 @@
+package discordx
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestSplitMessage(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		input string
+		limit int
+		want  []string
+	}{
+		{name: "short", input: "hello", limit: 20, want: []string{"hello"}},
+		{name: "paragraphs", input: "first paragraph\n\nsecond paragraph", limit: 20, want: []string{"first paragraph", "second paragraph"}},
+		{name: "lines", input: "line one is here\nline two", limit: 22, want: []string{"line one is here", "line two"}},
+		{name: "sentences", input: "今日は晴れ。明日は雨です。明後日は曇り。", limit: 18, want: []string{"今日は晴れ。明日は雨です。", "明後日は曇り。"}},
+		{name: "words", input: "alpha beta gamma delta", limit: 15, want: []string{"alpha beta", "gamma delta"}},
+		{name: "hard cut", input: strings.Repeat("a", 25), limit: 15, want: []string{strings.Repeat("a", 11), strings.Repeat("a", 14)}},
+		{
+			name:  "code fence",
+			input: "```go\nfunc a() {}\nfunc b() {}\nfunc c() {}\n```",
+			limit: 36,
+			want:  []string{"```go\nfunc a() {}\nfunc b() {}\n```", "```go\nfunc c() {}\n```"},
+		},
+		{
+			name:  "indented code",
+			input: "```go\nfunc a() {\n    if x6 {}\n    if x7 {}\n}\n```",
+			limit: 34,
+			want:  []string{"```go\nfunc a() {\n    if x6 {}\n```", "```go\n    if x7 {}\n}\n```"},
+		},
+	}
+	for _, tc := range tests {
+		got := splitMessage(tc.input, tc.limit)
+		if strings.Join(got, "|") != strings.Join(tc.want, "|") {
+			t.Fatalf("%s: splitMessage() = %q, want %q", tc.name, got, tc.want)
+		}
+		for i, part := range got {
+			if n := utf8.RuneCountInString(part); n > tc.limit {
+				t.Fatalf("%s: part %d has %d characters, limit %d", tc.name, i, n, tc.limit)
+			}
+			if strings.Count(part, codeFence)%2 != 0 {
+				t.Fatalf("%s: part %d has unbalanced code fences: %q", tc.name, i, part)
+			}
+		}
+	}
+}
+
+func TestSplitMessageLongCodeBlock(t *testing.T) {
+	t.Parallel()
+
+	var b strings.Builder
+	b.WriteString("説明です。\n\n```python\n")
+	for i := 0; i < 300; i++ {
+		b.WriteString("print('yururi is here')\n")
+	}
+	b.WriteString("```\n\n以上です。")
+
+	parts := splitMessage(b.String(), maxContentRunes)
+	if len(parts) < 4 {
+		t.Fatalf("parts = %d, want at least 4", len(parts))
+	}
+	for i, part := range parts {
+		if n := utf8.RuneCountInString(part); n > maxContentRunes {
+			t.Fatalf("part %d has %d characters", i, n)
+		}
+		if strings.Count(part, codeFence)%2 != 0 {
+			t.Fatalf("part %d has unbalanced code fences", i)
+		}
+		if i > 0 && i < len(parts)-1 && !strings.HasPrefix(part, "```python\n") {
+			t.Fatalf("part %d does not reopen the code block: %q", i, part[:20])
+		}
+	}
+	if !strings.HasSuffix(parts[len(parts)-1], "以上です。") {
+		t.Fatalf("last part = %q", parts[len(parts)-1])
+	}
+}
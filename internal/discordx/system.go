@@ -0,0 +1,49 @@
+package discordx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// SendSystemMessage posts a message the bot needs for itself, such as an
+// approval request. It goes through the outbound queue like every other
+// write but skips write_channel_ids and duplicate suppression, which only
+// apply to what the model posts.
+func (g *Gateway) SendSystemMessage(ctx context.Context, channelID string, content string) (string, error) {
+	if strings.TrimSpace(channelID) == "" {
+		return "", errors.New("channel_id is required")
+	}
+	text := strings.TrimSpace(content)
+	if text == "" {
+		return "", errors.New("content is required")
+	}
+	var id string
+	err := g.outbound.do(ctx, channelID, PriorityMessage, "send_system_message", func() error {
+		msg, err := g.session.ChannelMessageSendComplex(channelID, buildMessageSend(text), outboundOptions...)
+		if err != nil {
+			return err
+		}
+		id = msg.ID
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("send system message: %w", err)
+	}
+	return id, nil
+}
+
+// AddSystemReaction reacts to a message posted with SendSystemMessage.
+func (g *Gateway) AddSystemReaction(ctx context.Context, channelID string, messageID string, emoji string) error {
+	if strings.TrimSpace(channelID) == "" || strings.TrimSpace(messageID) == "" || strings.TrimSpace(emoji) == "" {
+		return errors.New("channel_id, message_id and emoji are required")
+	}
+	err := g.outbound.do(ctx, channelID, PriorityReaction, "add_system_reaction", func() error {
+		return g.session.MessageReactionAdd(channelID, messageID, emoji, outboundOptions...)
+	})
+	if err != nil {
+		return fmt.Errorf("add system reaction: %w", err)
+	}
+	return nil
+}
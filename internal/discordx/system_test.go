@@ -0,0 +1,41 @@
+package discordx
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/sigumaa/yururi/internal/config"
+)
+
+func TestSystemWritesValidateBeforeSending(t *testing.T) {
+	t.Parallel()
+
+	g := NewGateway(nil, config.DiscordConfig{GuildID: "g1", ReadChannelIDs: []string{"c1"}})
+	tests := []struct {
+		name    string
+		send    func() error
+		wantErr string
+	}{
+		{name: "message without channel", send: func() error {
+			_, err := g.SendSystemMessage(context.Background(), " ", "hello")
+			return err
+		}, wantErr: "channel_id"},
+		{name: "empty message", send: func() error {
+			_, err := g.SendSystemMessage(context.Background(), "owner-channel", " ")
+			return err
+		}, wantErr: "content"},
+		{name: "reaction without emoji", send: func() error {
+			return g.AddSystemReaction(context.Background(), "owner-channel", "m1", "")
+		}, wantErr: "emoji"},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			if err := tc.send(); err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Fatalf("error = %v, want %q", err, tc.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,190 @@
+package discordx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const defaultThreadAutoArchiveMinutes = 1440
+
+// ThreadInfo describes a thread or forum post.
+type ThreadInfo struct {
+	ThreadID        string
+	ParentChannelID string
+	Name            string
+	Archived        bool
+}
+
+// RegisterThread records that threadID lives under parentID. Threads and
+// forum posts inherit read and write permissions from their parent.
+func (g *Gateway) RegisterThread(threadID string, parentID string) {
+	threadID = strings.TrimSpace(threadID)
+	parentID = strings.TrimSpace(parentID)
+	if threadID == "" || parentID == "" {
+		return
+	}
+	g.threadMu.Lock()
+	g.threadParents[threadID] = parentID
+	g.threadMu.Unlock()
+}
+
+// ParentChannelID returns the parent channel of a thread, or "" when
+// channelID is not a thread. Unknown channels are looked up through the
+// session state first and the API second; channels found not to be threads
+// are remembered too, so the API is asked once per channel.
+func (g *Gateway) ParentChannelID(channelID string) string {
+	channelID = strings.TrimSpace(channelID)
+	if channelID == "" {
+		return ""
+	}
+	g.threadMu.RLock()
+	parentID, ok := g.threadParents[channelID]
+	g.threadMu.RUnlock()
+	if ok {
+		return parentID
+	}
+	if g.session == nil {
+		return ""
+	}
+	var ch *discordgo.Channel
+	if g.session.State != nil {
+		ch, _ = g.session.State.Channel(channelID)
+	}
+	if ch == nil {
+		fetched, err := g.session.Channel(channelID)
+		if err != nil {
+			return ""
+		}
+		ch = fetched
+	}
+	if ch == nil {
+		return ""
+	}
+	if !ch.IsThread() {
+		g.threadMu.Lock()
+		g.threadParents[channelID] = ""
+		g.threadMu.Unlock()
+		return ""
+	}
+	g.RegisterThread(ch.ID, ch.ParentID)
+	return strings.TrimSpace(ch.ParentID)
+}
+
+// CreateThread starts a thread in channelID. With messageID the thread is
+// attached to that message. In forum channels a post is created and content
+// becomes its first message.
+func (g *Gateway) CreateThread(ctx context.Context, channelID string, name string, messageID string, content string, autoArchiveMinutes int) (ThreadInfo, error) {
+	channelID = strings.TrimSpace(channelID)
+	if err := g.validateWritableChannel(channelID); err != nil {
+		return ThreadInfo{}, err
+	}
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return ThreadInfo{}, errors.New("name is required")
+	}
+	if autoArchiveMinutes <= 0 {
+		autoArchiveMinutes = defaultThreadAutoArchiveMinutes
+	}
+	if err := ctx.Err(); err != nil {
+		return ThreadInfo{}, err
+	}
+
+	data := &discordgo.ThreadStart{Name: name, AutoArchiveDuration: autoArchiveMinutes}
+	var (
+		thread *discordgo.Channel
+		err    error
+	)
+	var start func() (*discordgo.Channel, error)
+	switch {
+	case strings.TrimSpace(messageID) != "":
+		start = func() (*discordgo.Channel, error) {
+			return g.session.MessageThreadStartComplex(channelID, strings.TrimSpace(messageID), data, outboundOptions...)
+		}
+	case g.isForumChannel(channelID):
+		if strings.TrimSpace(content) == "" {
+			return ThreadInfo{}, errors.New("content is required for forum posts")
+		}
+		start = func() (*discordgo.Channel, error) {
+			return g.session.ForumThreadStartComplex(channelID, data, buildMessageSend(content), outboundOptions...)
+		}
+	default:
+		data.Type = discordgo.ChannelTypeGuildPublicThread
+		start = func() (*discordgo.Channel, error) {
+			return g.session.ThreadStartComplex(channelID, data, outboundOptions...)
+		}
+	}
+	err = g.outbound.do(ctx, channelID, PriorityMessage, "create_thread", func() error {
+		var err error
+		thread, err = start()
+		return err
+	})
+	if err != nil {
+		return ThreadInfo{}, fmt.Errorf("create thread: %w", err)
+	}
+	g.RegisterThread(thread.ID, channelID)
+	return threadInfo(thread), nil
+}
+
+// ListActiveThreads returns the guild's active threads whose parent is
+// readable. A non-empty channelID limits the result to that parent.
+func (g *Gateway) ListActiveThreads(ctx context.Context, channelID string) ([]ThreadInfo, error) {
+	channelID = strings.TrimSpace(channelID)
+	if channelID != "" {
+		if err := g.validateReadableChannel(channelID); err != nil {
+			return nil, err
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	list, err := g.session.GuildThreadsActive(g.guildID)
+	if err != nil {
+		return nil, fmt.Errorf("list active threads: %w", err)
+	}
+	out := make([]ThreadInfo, 0, len(list.Threads))
+	for _, thread := range list.Threads {
+		if thread == nil {
+			continue
+		}
+		if channelID != "" && thread.ParentID != channelID {
+			continue
+		}
+		g.RegisterThread(thread.ID, thread.ParentID)
+		if g.validateReadableChannel(thread.ID) != nil {
+			continue
+		}
+		out = append(out, threadInfo(thread))
+	}
+	return out, nil
+}
+
+// ReadThreadHistory reads the history of a thread or forum post.
+func (g *Gateway) ReadThreadHistory(ctx context.Context, threadID string, beforeMessageID string, limit int) ([]Message, error) {
+	if g.ParentChannelID(threadID) == "" {
+		return nil, fmt.Errorf("channel %s is not a thread", strings.TrimSpace(threadID))
+	}
+	return g.ReadMessageHistory(ctx, threadID, beforeMessageID, limit)
+}
+
+func (g *Gateway) isForumChannel(channelID string) bool {
+	var ch *discordgo.Channel
+	if g.session.State != nil {
+		ch, _ = g.session.State.Channel(channelID)
+	}
+	if ch == nil {
+		ch, _ = g.session.Channel(channelID)
+	}
+	return ch != nil && ch.Type == discordgo.ChannelTypeGuildForum
+}
+
+func threadInfo(ch *discordgo.Channel) ThreadInfo {
+	info := ThreadInfo{ThreadID: ch.ID, ParentChannelID: ch.ParentID, Name: ch.Name}
+	if ch.ThreadMetadata != nil {
+		info.Archived = ch.ThreadMetadata.Archived
+	}
+	return info
+}
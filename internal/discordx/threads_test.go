@@ -0,0 +1,185 @@
+package discordx
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/sigumaa/yururi/internal/config"
+)
+
+func TestGatewayThreadsInheritParentPermissions(t *testing.T) {
+	t.Parallel()
+
+	gateway := NewGateway(nil, config.DiscordConfig{
+		GuildID:            "g1",
+		ReadChannelIDs:     []string{"c-write", "c-excluded"},
+		WriteChannelIDs:    []string{"c-write"},
+		ObserveChannelIDs:  []string{"c-observe"},
+		ExcludedChannelIDs: []string{"c-excluded"},
+	})
+	gateway.RegisterThread("t-write", "c-write")
+	gateway.RegisterThread("t-observe", "c-observe")
+	gateway.RegisterThread("t-excluded", "c-excluded")
+
+	tests := []struct {
+		channelID    string
+		wantReadable bool
+		wantWritable bool
+	}{
+		{channelID: "t-write", wantReadable: true, wantWritable: true},
+		{channelID: "t-observe", wantReadable: true},
+		{channelID: "t-excluded"},
+		{channelID: "t-unknown"},
+	}
+	for _, tc := range tests {
+		if err := gateway.validateReadableChannel(tc.channelID); (err == nil) != tc.wantReadable {
+			t.Fatalf("validateReadableChannel(%s) error = %v, want readable %t", tc.channelID, err, tc.wantReadable)
+		}
+		if err := gateway.validateWritableChannel(tc.channelID); (err == nil) != tc.wantWritable {
+			t.Fatalf("validateWritableChannel(%s) error = %v, want writable %t", tc.channelID, err, tc.wantWritable)
+		}
+	}
+	if got := gateway.ParentChannelID("t-write"); got != "c-write" {
+		t.Fatalf("ParentChannelID(t-write) = %q, want c-write", got)
+	}
+	if got := gateway.ParentChannelID("c-write"); got != "" {
+		t.Fatalf("ParentChannelID(c-write) = %q, want empty", got)
+	}
+}
+
+func TestParentChannelIDRemembersNonThreads(t *testing.T) {
+	t.Parallel()
+
+	session, err := discordgo.New("Bot test")
+	if err != nil {
+		t.Fatalf("discordgo.New() error = %v", err)
+	}
+	if err := session.State.GuildAdd(&discordgo.Guild{ID: "g1", Channels: []*discordgo.Channel{
+		{ID: "c1", GuildID: "g1", Type: discordgo.ChannelTypeGuildText},
+		{ID: "t1", GuildID: "g1", ParentID: "c1", Type: discordgo.ChannelTypeGuildPublicThread},
+	}}); err != nil {
+		t.Fatalf("GuildAdd() error = %v", err)
+	}
+	gateway := NewGateway(session, config.DiscordConfig{GuildID: "g1"})
+
+	if got := gateway.ParentChannelID("c1"); got != "" {
+		t.Fatalf("ParentChannelID(c1) = %q, want empty", got)
+	}
+	if got := gateway.ParentChannelID("t1"); got != "c1" {
+		t.Fatalf("ParentChannelID(t1) = %q, want c1", got)
+	}
+	gateway.threadMu.RLock()
+	parent, cached := gateway.threadParents["c1"]
+	gateway.threadMu.RUnlock()
+	if !cached || parent != "" {
+		t.Fatalf("threadParents[c1] = (%q, %t), want cached non-thread", parent, cached)
+	}
+}
+
+func TestThreadInfo(t *testing.T) {
+	t.Parallel()
+
+	got := threadInfo(&discordgo.Channel{
+		ID:             "t1",
+		ParentID:       "forum-1",
+		Name:           "質問",
+		Type:           discordgo.ChannelTypeGuildPublicThread,
+		ThreadMetadata: &discordgo.ThreadMetadata{Archived: true},
+	})
+	want := ThreadInfo{ThreadID: "t1", ParentChannelID: "forum-1", Name: "質問", Archived: true}
+	if got != want {
+		t.Fatalf("threadInfo() = %#v, want %#v", got, want)
+	}
+}
+
+func TestCreateThreadRegistersParent(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		messageID string
+		content   string
+		responses map[string]string
+		wantRoute string
+	}{
+		{
+			name: "text channel",
+			responses: map[string]string{
+				"GET /channels/c-write":          `{"id":"c-write","type":0}`,
+				"POST /channels/c-write/threads": `{"id":"t1","parent_id":"c-write","name":"topic","type":11}`,
+			},
+			wantRoute: "POST /channels/c-write/threads",
+		},
+		{
+			name:      "from message",
+			messageID: "m1",
+			responses: map[string]string{
+				"POST /channels/c-write/messages/m1/threads": `{"id":"t1","parent_id":"c-write","name":"topic","type":11}`,
+			},
+			wantRoute: "POST /channels/c-write/messages/m1/threads",
+		},
+		{
+			name:    "forum post",
+			content: "first post",
+			responses: map[string]string{
+				"GET /channels/c-write":          `{"id":"c-write","type":15}`,
+				"POST /channels/c-write/threads": `{"id":"t1","parent_id":"c-write","name":"topic","type":11}`,
+			},
+			wantRoute: "POST /channels/c-write/threads",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			session, fake := newFakeSession(t, tc.responses)
+			gateway := NewGateway(session, config.DiscordConfig{GuildID: "g1", ReadChannelIDs: []string{"c-write"}, WriteChannelIDs: []string{"c-write"}})
+
+			info, err := gateway.CreateThread(context.Background(), "c-write", "topic", tc.messageID, tc.content, 0)
+			if err != nil {
+				t.Fatalf("CreateThread() error = %v", err)
+			}
+			if info.ThreadID != "t1" || info.ParentChannelID != "c-write" || info.Name != "topic" {
+				t.Fatalf("CreateThread() = %+v", info)
+			}
+			req, ok := fake.request(tc.wantRoute)
+			if !ok {
+				t.Fatalf("no %s request", tc.wantRoute)
+			}
+			if !strings.Contains(req.Body, `"name":"topic"`) || !strings.Contains(req.Body, `"auto_archive_duration":1440`) {
+				t.Fatalf("request body = %s", req.Body)
+			}
+			if tc.content != "" && !strings.Contains(req.Body, tc.content) {
+				t.Fatalf("forum post body = %s, want the first message", req.Body)
+			}
+			if err := gateway.validateWritableChannel("t1"); err != nil {
+				t.Fatalf("new thread is not writable: %v", err)
+			}
+		})
+	}
+}
+
+func TestReadThreadHistory(t *testing.T) {
+	t.Parallel()
+
+	session, _ := newFakeSession(t, map[string]string{
+		"GET /channels/c-read": `{"id":"c-read","type":0}`,
+		"GET /channels/t1/messages": `[{"id":"m2","channel_id":"t1","content":"reply","author":{"id":"u1","username":"user"}},` +
+			`{"id":"m1","channel_id":"t1","content":" first ","author":{"id":"bot","username":"yururi","bot":true}}]`,
+	})
+	gateway := NewGateway(session, config.DiscordConfig{GuildID: "g1", ReadChannelIDs: []string{"c-read"}})
+	gateway.RegisterThread("t1", "c-read")
+
+	history, err := gateway.ReadThreadHistory(context.Background(), "t1", "", 10)
+	if err != nil {
+		t.Fatalf("ReadThreadHistory() error = %v", err)
+	}
+	if len(history) != 2 || history[0].ID != "m2" || history[1].Content != "first" || !history[1].AuthorIsBot {
+		t.Fatalf("ReadThreadHistory() = %+v", history)
+	}
+	if _, err := gateway.ReadThreadHistory(context.Background(), "c-read", "", 10); err == nil || !strings.Contains(err.Error(), "not a thread") {
+		t.Fatalf("ReadThreadHistory(c-read) error = %v, want not a thread", err)
+	}
+}
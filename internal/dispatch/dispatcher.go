@@ -3,6 +3,7 @@ package dispatch
 import (
 	"context"
 	"fmt"
+	"strings"
 	"sync"
 	"time"
 
@@ -10,16 +11,79 @@ import (
 )
 
 const (
-	defaultQueueSize      = 128
-	defaultCoalesceWindow = 1200 * time.Millisecond
+	defaultQueueSize       = 128
+	defaultCoalesceWindow  = 1200 * time.Millisecond
+	defaultAddressedWindow = 300 * time.Millisecond
+)
+
+// AddressKind tells why a message was addressed to the bot directly.
+type AddressKind string
+
+const (
+	AddressNone    AddressKind = ""
+	AddressMention AddressKind = "mention"
+	AddressReply   AddressKind = "reply"
+	AddressOwnerDM AddressKind = "owner_dm"
 )
 
 type Handler func(msg *discordgo.MessageCreate, meta CallbackMetadata)
 
+// EventKind is the type of a message event other than a new message.
+type EventKind string
+
+const (
+	EventEdit   EventKind = "edit"
+	EventDelete EventKind = "delete"
+)
+
+// MessageEvent is an edit or delete of a message. Message carries the IDs
+// and, for edits, the new content. Before is the cached message prior to
+// the event and is nil when the message was not cached.
+type MessageEvent struct {
+	Kind    EventKind
+	Message *discordgo.Message
+	Before  *discordgo.Message
+}
+
+type EventHandler func(event MessageEvent)
+
 type CallbackMetadata struct {
 	MergedCount int           `json:"merged_count"`
 	QueueWait   time.Duration `json:"queue_wait_ms"`
 	EnqueuedAt  time.Time     `json:"enqueued_at"`
+	// Addressed is set when any merged message addressed the bot directly.
+	Addressed AddressKind `json:"addressed,omitempty"`
+}
+
+type Option func(*Dispatcher)
+
+// WithEventHandler handles edits and deletes. Events are delivered in order
+// per channel but apart from new messages, so they are not held up by a
+// turn that is still running for the channel.
+func WithEventHandler(handler EventHandler) Option {
+	return func(d *Dispatcher) {
+		d.eventHandler = handler
+	}
+}
+
+// WithAddressDetection enables the fast path for mentions of the bot,
+// replies to its messages and DMs from the owner. botUserID is called per
+// message because the bot's ID is only known once the session is ready.
+func WithAddressDetection(botUserID func() string, ownerUserID string) Option {
+	return func(d *Dispatcher) {
+		d.botUserID = botUserID
+		d.ownerUserID = strings.TrimSpace(ownerUserID)
+	}
+}
+
+// WithAddressedWindow sets how long an addressed message waits for follow-up
+// messages. Zero dispatches it immediately.
+func WithAddressedWindow(window time.Duration) Option {
+	return func(d *Dispatcher) {
+		if window >= 0 {
+			d.addressedWindow = window
+		}
+	}
 }
 
 type Dispatcher struct {
@@ -28,20 +92,33 @@ type Dispatcher struct {
 	queueSize      int
 	coalesceWindow time.Duration
 
+	addressedWindow time.Duration
+	botUserID       func() string
+	ownerUserID     string
+	eventHandler    EventHandler
+
 	mu      sync.Mutex
 	workers map[string]*worker
 }
 
+// worker serves one channel. Addressed messages go to priority and are taken
+// before ambient traffic waiting in queue. Edits and deletes go to events.
+// Tasks run on the same goroutine as messages, so they never overlap a
+// message turn for the channel.
 type worker struct {
-	queue chan queuedMessage
+	queue    chan queuedMessage
+	priority chan queuedMessage
+	events   chan MessageEvent
+	tasks    chan func()
 }
 
 type queuedMessage struct {
 	msg        *discordgo.MessageCreate
 	enqueuedAt time.Time
+	addressed  AddressKind
 }
 
-func New(ctx context.Context, queueSize int, coalesceWindow time.Duration, handler Handler) *Dispatcher {
+func New(ctx context.Context, queueSize int, coalesceWindow time.Duration, handler Handler, opts ...Option) *Dispatcher {
 	if queueSize <= 0 {
 		queueSize = defaultQueueSize
 	}
@@ -51,20 +128,30 @@ func New(ctx context.Context, queueSize int, coalesceWindow time.Duration, handl
 	if handler == nil {
 		handler = func(*discordgo.MessageCreate, CallbackMetadata) {}
 	}
-	return &Dispatcher{
-		ctx:            ctx,
-		handler:        handler,
-		queueSize:      queueSize,
-		coalesceWindow: coalesceWindow,
-		workers:        map[string]*worker{},
+	d := &Dispatcher{
+		ctx:             ctx,
+		handler:         handler,
+		queueSize:       queueSize,
+		coalesceWindow:  coalesceWindow,
+		addressedWindow: defaultAddressedWindow,
+		workers:         map[string]*worker{},
+	}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(d)
+		}
+	}
+	if d.addressedWindow > d.coalesceWindow {
+		d.addressedWindow = d.coalesceWindow
 	}
+	return d
 }
 
 func (d *Dispatcher) Enqueue(msg *discordgo.MessageCreate) (dropped bool) {
 	if msg == nil {
 		return false
 	}
-	w := d.getOrCreateWorker(msg)
+	w := d.getOrCreateWorker(workerKey(msg.GuildID, msg.ChannelID))
 
 	select {
 	case <-d.ctx.Done():
@@ -72,97 +159,237 @@ func (d *Dispatcher) Enqueue(msg *discordgo.MessageCreate) (dropped bool) {
 	default:
 	}
 
+	item := queuedMessage{msg: msg, enqueuedAt: time.Now(), addressed: d.addressKind(msg)}
+	queue := w.queue
+	if item.addressed != AddressNone {
+		queue = w.priority
+	}
 	select {
-	case w.queue <- queuedMessage{msg: msg, enqueuedAt: time.Now()}:
+	case queue <- item:
 		return false
 	default:
 	}
 
 	// Queue full: drop one oldest item and prefer the newest message.
 	select {
-	case <-w.queue:
+	case <-queue:
 		dropped = true
 	default:
 	}
 	select {
-	case w.queue <- queuedMessage{msg: msg, enqueuedAt: time.Now()}:
+	case queue <- item:
 		return dropped
 	default:
 		return true
 	}
 }
 
-func (d *Dispatcher) getOrCreateWorker(msg *discordgo.MessageCreate) *worker {
-	key := workerKey(msg)
+// EnqueueEvent queues an edit or delete. Events are dropped when no event
+// handler is set.
+func (d *Dispatcher) EnqueueEvent(event MessageEvent) (dropped bool) {
+	if event.Message == nil || d.eventHandler == nil {
+		return false
+	}
+	w := d.getOrCreateWorker(workerKey(event.Message.GuildID, event.Message.ChannelID))
+
+	select {
+	case <-d.ctx.Done():
+		return false
+	default:
+	}
+	select {
+	case w.events <- event:
+		return false
+	default:
+		return true
+	}
+}
+
+// Do runs fn on the channel's worker once the batch it may be handling is
+// done, ahead of messages still waiting. It reports true when the task queue
+// is full and fn was dropped.
+func (d *Dispatcher) Do(guildID string, channelID string, fn func()) (dropped bool) {
+	if fn == nil {
+		return false
+	}
+	w := d.getOrCreateWorker(workerKey(guildID, channelID))
+
+	select {
+	case <-d.ctx.Done():
+		return false
+	default:
+	}
+	select {
+	case w.tasks <- fn:
+		return false
+	default:
+		return true
+	}
+}
+
+func (d *Dispatcher) addressKind(msg *discordgo.MessageCreate) AddressKind {
+	if d.botUserID == nil || msg.Message == nil {
+		return AddressNone
+	}
+	if msg.GuildID == "" && d.ownerUserID != "" && msg.Author != nil && msg.Author.ID == d.ownerUserID {
+		return AddressOwnerDM
+	}
+	botID := d.botUserID()
+	if botID == "" {
+		return AddressNone
+	}
+	for _, user := range msg.Mentions {
+		if user != nil && user.ID == botID {
+			return AddressMention
+		}
+	}
+	if ref := msg.ReferencedMessage; ref != nil && ref.Author != nil && ref.Author.ID == botID {
+		return AddressReply
+	}
+	return AddressNone
+}
 
+func (d *Dispatcher) getOrCreateWorker(key string) *worker {
 	d.mu.Lock()
 	defer d.mu.Unlock()
 
 	if w, ok := d.workers[key]; ok {
 		return w
 	}
-
-	w := &worker{queue: make(chan queuedMessage, d.queueSize)}
+	w := &worker{
+		queue:    make(chan queuedMessage, d.queueSize),
+		priority: make(chan queuedMessage, d.queueSize),
+		events:   make(chan MessageEvent, d.queueSize),
+		tasks:    make(chan func(), d.queueSize),
+	}
 	d.workers[key] = w
 	go d.runWorker(w)
+	if d.eventHandler != nil {
+		go d.runEvents(w)
+	}
 	return w
 }
 
+func (d *Dispatcher) runEvents(w *worker) {
+	for {
+		select {
+		case <-d.ctx.Done():
+			return
+		case event := <-w.events:
+			d.eventHandler(event)
+		}
+	}
+}
+
 func (d *Dispatcher) runWorker(w *worker) {
 	for {
+		var first queuedMessage
 		select {
 		case <-d.ctx.Done():
 			return
-		case first := <-w.queue:
-			if first.msg == nil {
+		case task := <-w.tasks:
+			task()
+			continue
+		case first = <-w.priority:
+		default:
+			select {
+			case <-d.ctx.Done():
+				return
+			case task := <-w.tasks:
+				task()
 				continue
+			case first = <-w.priority:
+			case first = <-w.queue:
 			}
+		}
+		if first.msg == nil {
+			continue
+		}
 
-			latest := first.msg
-			mergedCount := 1
-			enqueuedAt := first.enqueuedAt
-			timer := time.NewTimer(d.coalesceWindow)
-		collect:
-			for {
-				select {
-				case <-d.ctx.Done():
-					if !timer.Stop() {
-						<-timer.C
-					}
-					return
-				case next := <-w.queue:
-					if next.msg != nil {
-						latest = next.msg
-						mergedCount++
-					}
-				case <-timer.C:
-					break collect
-				}
-			}
-
-			queueWait := time.Since(enqueuedAt)
-			if queueWait < 0 {
-				queueWait = 0
-			}
-			d.handler(latest, CallbackMetadata{
-				MergedCount: mergedCount,
-				QueueWait:   queueWait,
-				EnqueuedAt:  enqueuedAt,
-			})
+		b := &batch{latest: first, mergedCount: 1, enqueuedAt: first.enqueuedAt, addressed: first.addressed}
+		if !d.collect(w, b) {
+			return
+		}
+		queueWait := time.Since(b.enqueuedAt)
+		if queueWait < 0 {
+			queueWait = 0
 		}
+		d.handler(b.latest.msg, CallbackMetadata{
+			MergedCount: b.mergedCount,
+			QueueWait:   queueWait,
+			EnqueuedAt:  b.enqueuedAt,
+			Addressed:   b.addressed,
+		})
 	}
 }
 
-func workerKey(msg *discordgo.MessageCreate) string {
-	guildID := "noguild"
-	channelID := "nochannel"
-	if msg != nil {
-		if msg.GuildID != "" {
-			guildID = msg.GuildID
+type batch struct {
+	latest      queuedMessage
+	mergedCount int
+	enqueuedAt  time.Time
+	addressed   AddressKind
+}
+
+func (b *batch) add(item queuedMessage) {
+	if item.msg == nil {
+		return
+	}
+	b.mergedCount++
+	if b.addressed == AddressNone {
+		b.addressed = item.addressed
+	}
+	// Addressed messages overtake older ambient ones, so the reply target is
+	// the newest message rather than the last one received.
+	if !item.enqueuedAt.Before(b.latest.enqueuedAt) {
+		b.latest = item
+	}
+	if item.enqueuedAt.Before(b.enqueuedAt) {
+		b.enqueuedAt = item.enqueuedAt
+	}
+}
+
+// collect merges queued messages into b until the coalesce window ends. Once
+// the batch is addressed the window shrinks to the addressed window. It
+// reports false when the dispatcher is stopped.
+func (d *Dispatcher) collect(w *worker, b *batch) bool {
+	window := d.coalesceWindow
+	if b.addressed != AddressNone {
+		window = d.addressedWindow
+	}
+	deadline := time.Now().Add(window)
+	timer := time.NewTimer(window)
+	defer timer.Stop()
+	for {
+		var next queuedMessage
+		select {
+		case <-d.ctx.Done():
+			return false
+		case next = <-w.priority:
+		case next = <-w.queue:
+		case <-timer.C:
+			return true
 		}
-		if msg.ChannelID != "" {
-			channelID = msg.ChannelID
+		wasAddressed := b.addressed != AddressNone
+		b.add(next)
+		if wasAddressed || b.addressed == AddressNone {
+			continue
 		}
+		if shortened := time.Now().Add(d.addressedWindow); shortened.Before(deadline) {
+			deadline = shortened
+			if !timer.Stop() {
+				<-timer.C
+			}
+			timer.Reset(d.addressedWindow)
+		}
+	}
+}
+
+func workerKey(guildID string, channelID string) string {
+	if guildID == "" {
+		guildID = "noguild"
+	}
+	if channelID == "" {
+		channelID = "nochannel"
 	}
 	return fmt.Sprintf("%s:%s", guildID, channelID)
 }
@@ -120,3 +120,217 @@ func TestDispatcherCallbackMetadata(t *testing.T) {
 		t.Fatal("dispatcher callback timeout")
 	}
 }
+
+type dispatchedCall struct {
+	id   string
+	meta CallbackMetadata
+}
+
+func botMessage(id string, mentionsBot bool) *discordgo.MessageCreate {
+	msg := &discordgo.Message{ID: id, GuildID: "g1", ChannelID: "c1", Author: &discordgo.User{ID: "user"}}
+	if mentionsBot {
+		msg.Mentions = []*discordgo.User{{ID: "bot"}}
+	}
+	return &discordgo.MessageCreate{Message: msg}
+}
+
+func TestDispatcherAddressedMessageSkipsCoalesceWindow(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	calls := make(chan dispatchedCall, 4)
+	d := New(ctx, 16, 2*time.Second, func(msg *discordgo.MessageCreate, meta CallbackMetadata) {
+		calls <- dispatchedCall{id: msg.ID, meta: meta}
+	}, WithAddressDetection(func() string { return "bot" }, "owner"), WithAddressedWindow(30*time.Millisecond))
+
+	started := time.Now()
+	d.Enqueue(botMessage("m1", false))
+	d.Enqueue(botMessage("m2", true))
+
+	select {
+	case got := <-calls:
+		if elapsed := time.Since(started); elapsed > time.Second {
+			t.Fatalf("addressed dispatch took %s, want shortened window", elapsed)
+		}
+		if got.id != "m2" || got.meta.MergedCount != 2 || got.meta.Addressed != AddressMention {
+			t.Fatalf("call = %+v, want m2 merged=2 addressed=mention", got)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("dispatcher callback timeout")
+	}
+}
+
+func TestDispatcherAddressedMessageJumpsAheadOfAmbientQueue(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	release := make(chan struct{})
+	calls := make(chan dispatchedCall, 4)
+	d := New(ctx, 16, 300*time.Millisecond, func(msg *discordgo.MessageCreate, meta CallbackMetadata) {
+		calls <- dispatchedCall{id: msg.ID, meta: meta}
+		if msg.ID == "busy" {
+			<-release
+		}
+	}, WithAddressDetection(func() string { return "bot" }, ""), WithAddressedWindow(0))
+
+	d.Enqueue(botMessage("busy", true))
+	select {
+	case <-calls:
+	case <-time.After(time.Second):
+		t.Fatal("first callback timeout")
+	}
+
+	// Both wait while the worker is busy; the reply to the bot goes first.
+	d.Enqueue(botMessage("ambient", false))
+	reply := botMessage("reply", false)
+	reply.ReferencedMessage = &discordgo.Message{ID: "bot-msg", Author: &discordgo.User{ID: "bot"}}
+	d.Enqueue(reply)
+	close(release)
+
+	select {
+	case got := <-calls:
+		if got.id != "reply" || got.meta.Addressed != AddressReply {
+			t.Fatalf("call = %+v, want reply addressed first", got)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("priority callback timeout")
+	}
+}
+
+func TestDispatcherAddressKind(t *testing.T) {
+	t.Parallel()
+
+	d := New(context.Background(), 1, time.Second, nil, WithAddressDetection(func() string { return "bot" }, "owner"))
+	ownerDM := &discordgo.MessageCreate{Message: &discordgo.Message{ChannelID: "dm", Author: &discordgo.User{ID: "owner"}}}
+	otherDM := &discordgo.MessageCreate{Message: &discordgo.Message{ChannelID: "dm", Author: &discordgo.User{ID: "user"}}}
+	tests := []struct {
+		name string
+		msg  *discordgo.MessageCreate
+		want AddressKind
+	}{
+		{name: "ambient", msg: botMessage("m1", false), want: AddressNone},
+		{name: "mention", msg: botMessage("m2", true), want: AddressMention},
+		{name: "owner dm", msg: ownerDM, want: AddressOwnerDM},
+		{name: "other dm", msg: otherDM, want: AddressNone},
+	}
+	for _, tc := range tests {
+		if got := d.addressKind(tc.msg); got != tc.want {
+			t.Fatalf("%s: addressKind() = %q, want %q", tc.name, got, tc.want)
+		}
+	}
+
+	disabled := New(context.Background(), 1, time.Second, nil)
+	if got := disabled.addressKind(botMessage("m3", true)); got != AddressNone {
+		t.Fatalf("addressKind() without detection = %q, want none", got)
+	}
+}
+
+func TestDispatcherDeliversEventsWhileTurnRuns(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+	events := make(chan MessageEvent, 4)
+	d := New(ctx, 16, 10*time.Millisecond, func(*discordgo.MessageCreate, CallbackMetadata) {
+		close(started)
+		<-release
+	}, WithEventHandler(func(event MessageEvent) {
+		events <- event
+	}))
+	defer close(release)
+
+	d.Enqueue(&discordgo.MessageCreate{Message: &discordgo.Message{ID: "m1", GuildID: "g1", ChannelID: "c1"}})
+	<-started
+
+	d.EnqueueEvent(MessageEvent{Kind: EventEdit, Message: &discordgo.Message{ID: "m1", GuildID: "g1", ChannelID: "c1", Content: "new"}})
+	d.EnqueueEvent(MessageEvent{Kind: EventDelete, Message: &discordgo.Message{ID: "m0", GuildID: "g1", ChannelID: "c1"}})
+
+	for _, want := range []struct {
+		kind EventKind
+		id   string
+	}{{kind: EventEdit, id: "m1"}, {kind: EventDelete, id: "m0"}} {
+		select {
+		case got := <-events:
+			if got.Kind != want.kind || got.Message.ID != want.id {
+				t.Fatalf("event = %s/%s, want %s/%s", got.Kind, got.Message.ID, want.kind, want.id)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("event callback timeout while turn is running")
+		}
+	}
+}
+
+func TestDispatcherDropsEventsWithoutHandler(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	d := New(ctx, 16, 10*time.Millisecond, nil)
+	if dropped := d.EnqueueEvent(MessageEvent{Kind: EventEdit, Message: &discordgo.Message{ID: "m1", ChannelID: "c1"}}); dropped {
+		t.Fatal("EnqueueEvent() without handler reported a queue drop")
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if len(d.workers) != 0 {
+		t.Fatalf("workers = %d, want none", len(d.workers))
+	}
+}
+
+func TestDispatcherDoWaitsForRunningMessage(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	var mu sync.Mutex
+	var order []string
+	record := func(name string) {
+		mu.Lock()
+		order = append(order, name)
+		mu.Unlock()
+	}
+	d := New(ctx, 16, 10*time.Millisecond, func(msg *discordgo.MessageCreate, _ CallbackMetadata) {
+		close(started)
+		<-release
+		record("message")
+	})
+
+	d.Enqueue(&discordgo.MessageCreate{Message: &discordgo.Message{ID: "m1", GuildID: "g1", ChannelID: "c1"}})
+	<-started
+	done := make(chan struct{})
+	if dropped := d.Do("g1", "c1", func() {
+		record("task")
+		close(done)
+	}); dropped {
+		t.Fatal("Do() dropped = true")
+	}
+	other := make(chan struct{})
+	d.Do("g1", "c2", func() { close(other) })
+	select {
+	case <-other:
+	case <-time.After(2 * time.Second):
+		t.Fatal("task for another channel did not run")
+	}
+	close(release)
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("task did not run")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(order) != 2 || order[0] != "message" || order[1] != "task" {
+		t.Fatalf("order = %v, want [message task]", order)
+	}
+}
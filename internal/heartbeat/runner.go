@@ -3,7 +3,7 @@ package heartbeat
 import (
 	"context"
 	"fmt"
-	"log"
+	"log/slog"
 	"strings"
 	"sync/atomic"
 	"time"
@@ -60,17 +60,34 @@ func (r *Runner) Start(ctx context.Context) {
 	}()
 }
 
+// RunNow starts the handler outside the schedule. It reports false when a
+// heartbeat is already running.
+func (r *Runner) RunNow() bool {
+	if !r.running.CompareAndSwap(false, true) {
+		return false
+	}
+	go func() {
+		defer r.running.Store(false)
+		r.run()
+	}()
+	return true
+}
+
 func (r *Runner) execute() {
 	if !r.running.CompareAndSwap(false, true) {
-		log.Printf("heartbeat skipped: reason=already_running timezone=%s", r.timezone)
+		slog.Info("heartbeat_skipped", "reason", "already_running", "timezone", r.timezone)
 		return
 	}
 	defer r.running.Store(false)
+	r.run()
+}
+
+func (r *Runner) run() {
 	ctx := r.rootCtx
 	if ctx == nil {
 		ctx = context.Background()
 	}
 	if err := r.handler(ctx); err != nil {
-		log.Printf("heartbeat failed: timezone=%s err=%v", r.timezone, err)
+		slog.Error("heartbeat_failed", "timezone", r.timezone, "err", err)
 	}
 }
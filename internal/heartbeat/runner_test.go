@@ -87,3 +87,38 @@ func TestExecuteUsesStartContext(t *testing.T) {
 		t.Fatal("execute() should see canceled context after cancel")
 	}
 }
+
+func TestRunnerRunNowSkipsWhileRunning(t *testing.T) {
+	t.Parallel()
+
+	release := make(chan struct{})
+	var called atomic.Int32
+	r := &Runner{
+		handler: func(context.Context) error {
+			called.Add(1)
+			<-release
+			return nil
+		},
+		timezone: "UTC",
+		rootCtx:  context.Background(),
+	}
+
+	if !r.RunNow() {
+		t.Fatal("RunNow() = false, want true")
+	}
+	if r.RunNow() {
+		t.Fatal("RunNow() while running = true, want false")
+	}
+	close(release)
+
+	deadline := time.Now().Add(time.Second)
+	for r.running.Load() && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if r.running.Load() {
+		t.Fatal("RunNow() did not finish")
+	}
+	if called.Load() != 1 {
+		t.Fatalf("handler calls = %d, want 1", called.Load())
+	}
+}
@@ -0,0 +1,185 @@
+package logging
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+const (
+	ansiReset   = "\x1b[0m"
+	ansiRed     = "\x1b[31m"
+	ansiGreen   = "\x1b[32m"
+	ansiYellow  = "\x1b[33m"
+	ansiBlue    = "\x1b[34m"
+	ansiMagenta = "\x1b[35m"
+	ansiCyan    = "\x1b[36m"
+)
+
+const consoleTimeLayout = "2006/01/02 15:04:05"
+
+// consoleHandler writes the line format the bot has always logged:
+// "<time> event=<name> key=value ...". Lines bridged from the log package
+// are written as they are. Levels other than info are added as level=.
+type consoleHandler struct {
+	mu     *sync.Mutex
+	w      io.Writer
+	level  slog.Level
+	color  bool
+	prefix string
+	attrs  []byte
+}
+
+func newConsoleHandler(w io.Writer, level slog.Level, color bool) *consoleHandler {
+	return &consoleHandler{mu: &sync.Mutex{}, w: w, level: level, color: color}
+}
+
+func (h *consoleHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level
+}
+
+func (h *consoleHandler) Handle(_ context.Context, r slog.Record) error {
+	buf := make([]byte, 0, 256)
+	if !r.Time.IsZero() {
+		buf = r.Time.AppendFormat(buf, consoleTimeLayout)
+		buf = append(buf, ' ')
+	}
+	if isEventName(r.Message) {
+		buf = append(buf, "event="...)
+	}
+	buf = append(buf, r.Message...)
+	if r.Level != slog.LevelInfo {
+		buf = append(buf, " level="...)
+		buf = append(buf, r.Level.String()...)
+	}
+	buf = append(buf, h.attrs...)
+	r.Attrs(func(a slog.Attr) bool {
+		buf = appendAttr(buf, h.prefix, a)
+		return true
+	})
+
+	line := string(buf)
+	if h.color {
+		line = colorizeLogLine(r.Level, line)
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err := io.WriteString(h.w, line+"\n")
+	return err
+}
+
+func (h *consoleHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	out := *h
+	out.attrs = append([]byte(nil), h.attrs...)
+	for _, a := range attrs {
+		out.attrs = appendAttr(out.attrs, h.prefix, a)
+	}
+	return &out
+}
+
+func (h *consoleHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	out := *h
+	out.prefix = h.prefix + name + "."
+	return &out
+}
+
+func appendAttr(buf []byte, prefix string, a slog.Attr) []byte {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return buf
+	}
+	if a.Value.Kind() == slog.KindGroup {
+		if a.Key != "" {
+			prefix += a.Key + "."
+		}
+		for _, ga := range a.Value.Group() {
+			buf = appendAttr(buf, prefix, ga)
+		}
+		return buf
+	}
+	buf = append(buf, ' ')
+	buf = append(buf, prefix...)
+	buf = append(buf, a.Key...)
+	buf = append(buf, '=')
+	var s string
+	if a.Value.Kind() == slog.KindTime {
+		s = a.Value.Time().Format(time.RFC3339)
+	} else {
+		s = a.Value.String()
+	}
+	if needsQuoting(s) {
+		return strconv.AppendQuote(buf, s)
+	}
+	return append(buf, s...)
+}
+
+func needsQuoting(s string) bool {
+	if s == "" {
+		return true
+	}
+	for _, r := range s {
+		if r == ' ' || r == '=' || r == '"' || !unicode.IsPrint(r) {
+			return true
+		}
+	}
+	return false
+}
+
+func colorizeLogLine(level slog.Level, line string) string {
+	color := colorForLine(line)
+	if color == "" {
+		switch {
+		case level >= slog.LevelError:
+			color = ansiRed
+		case level >= slog.LevelWarn:
+			color = ansiYellow
+		}
+	}
+	if color == "" {
+		return line
+	}
+	return color + line + ansiReset
+}
+
+func colorForLine(line string) string {
+	event := eventFromLogLine(line)
+	if event == "" {
+		return ""
+	}
+	switch {
+	case strings.Contains(event, "failed") || strings.Contains(event, "error"):
+		return ansiRed
+	case strings.Contains(event, "suppressed"):
+		return ansiYellow
+	case strings.Contains(event, "tool_call"):
+		return ansiMagenta
+	case strings.Contains(event, "completed") || strings.Contains(event, "posted") || strings.Contains(event, "resolved"):
+		return ansiGreen
+	case strings.Contains(event, "started"):
+		return ansiBlue
+	case strings.Contains(event, "tick"):
+		return ansiCyan
+	default:
+		return ""
+	}
+}
+
+func eventFromLogLine(line string) string {
+	i := strings.Index(line, "event=")
+	if i < 0 {
+		return ""
+	}
+	raw := line[i+len("event="):]
+	if end := strings.IndexAny(raw, " \t\r\n"); end >= 0 {
+		raw = raw[:end]
+	}
+	return strings.TrimSpace(raw)
+}
@@ -0,0 +1,187 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestConsoleHandlerFormatsLine(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		level  slog.Level
+		msg    string
+		attrs  []any
+		with   []any
+		group  string
+		want   string
+		filter slog.Level
+	}{
+		{
+			name:  "event with fields",
+			level: slog.LevelInfo,
+			msg:   "mcp_tool_completed",
+			attrs: []any{"tool", "send_message", "latency_ms", 12, "ok", true},
+			want:  "2026/03/01 12:00:00 event=mcp_tool_completed tool=send_message latency_ms=12 ok=true\n",
+		},
+		{
+			name:  "quoted values and error level",
+			level: slog.LevelError,
+			msg:   "heartbeat_turn_failed",
+			attrs: []any{"content", "おはよう 世界", "empty", "", "err", errors.New(`bad "input"`)},
+			want:  "2026/03/01 12:00:00 event=heartbeat_turn_failed level=ERROR content=\"おはよう 世界\" empty=\"\" err=\"bad \\\"input\\\"\"\n",
+		},
+		{
+			name:  "bridged line is kept",
+			level: slog.LevelInfo,
+			msg:   "[DG0] gateway reconnecting",
+			want:  "2026/03/01 12:00:00 [DG0] gateway reconnecting\n",
+		},
+		{
+			name:  "logger fields and groups",
+			level: slog.LevelInfo,
+			msg:   "codex_turn_started",
+			with:  []any{"run_id", "msg-1"},
+			group: "usage",
+			attrs: []any{"input_tokens", 10},
+			want:  "2026/03/01 12:00:00 event=codex_turn_started run_id=msg-1 usage.input_tokens=10\n",
+		},
+		{
+			name:   "below level is dropped",
+			level:  slog.LevelDebug,
+			msg:    "mcp_tool_started",
+			filter: slog.LevelInfo,
+			want:   "",
+		},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			var buf bytes.Buffer
+			var handler slog.Handler = newConsoleHandler(&buf, tc.filter, false)
+			if len(tc.with) > 0 {
+				handler = slog.New(handler).With(tc.with...).Handler()
+			}
+			if tc.group != "" {
+				handler = handler.WithGroup(tc.group)
+			}
+			if handler.Enabled(context.Background(), tc.level) {
+				r := slog.NewRecord(time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC), tc.level, tc.msg, 0)
+				r.Add(tc.attrs...)
+				if err := handler.Handle(context.Background(), r); err != nil {
+					t.Fatalf("Handle() error = %v", err)
+				}
+			}
+			if got := buf.String(); got != tc.want {
+				t.Fatalf("line = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestConsoleHandlerColors(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	logger := slog.New(newConsoleHandler(&buf, slog.LevelInfo, true))
+	logger.Error("heartbeat_turn_failed", "run_id", "hb-1")
+	logger.Warn("outbound_retry", "channel", "c1")
+	logger.Info("message_received", "run_id", "msg-1")
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("lines = %q, want 3", lines)
+	}
+	if !strings.HasPrefix(lines[0], ansiRed) || !strings.HasSuffix(lines[0], ansiReset) {
+		t.Fatalf("failed line = %q, want red", lines[0])
+	}
+	if !strings.HasPrefix(lines[1], ansiYellow) {
+		t.Fatalf("warn line = %q, want yellow", lines[1])
+	}
+	if strings.Contains(lines[2], "\x1b[") {
+		t.Fatalf("plain line = %q, want no color", lines[2])
+	}
+}
+
+func TestEventFromLogLine(t *testing.T) {
+	t.Parallel()
+
+	line := `2026/02/27 01:23:45 event=heartbeat_turn_failed run_id=hb-1 err="timeout"`
+	got := eventFromLogLine(line)
+	if got != "heartbeat_turn_failed" {
+		t.Fatalf("eventFromLogLine() = %q, want %q", got, "heartbeat_turn_failed")
+	}
+}
+
+func TestColorForLine(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		line string
+		want string
+	}{
+		{
+			name: "failed is red",
+			line: "event=heartbeat_turn_failed run_id=h1",
+			want: ansiRed,
+		},
+		{
+			name: "completed is green",
+			line: "event=mcp_tool_completed tool=send_message",
+			want: ansiGreen,
+		},
+		{
+			name: "suppressed is yellow",
+			line: "event=message_times_suppressed run_id=msg-1",
+			want: ansiYellow,
+		},
+		{
+			name: "tool call is magenta",
+			line: "event=message_tool_call run_id=msg-1",
+			want: ansiMagenta,
+		},
+		{
+			name: "started is blue",
+			line: "event=mcp_tool_started tool=send_message",
+			want: ansiBlue,
+		},
+		{
+			name: "tick is cyan",
+			line: "event=heartbeat_tick run_id=hb-1",
+			want: ansiCyan,
+		},
+		{
+			name: "no event no color",
+			line: "plain text log",
+			want: "",
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			if got := colorForLine(tc.line); got != tc.want {
+				t.Fatalf("colorForLine(%q) = %q, want %q", tc.line, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestColorizeLogLine(t *testing.T) {
+	t.Parallel()
+
+	line := "event=heartbeat_turn_failed run_id=hb-1"
+	got := colorizeLogLine(slog.LevelInfo, line)
+	if got != ansiRed+line+ansiReset {
+		t.Fatalf("colorizeLogLine() = %q, want colorized line", got)
+	}
+}
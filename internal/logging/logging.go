@@ -0,0 +1,135 @@
+package logging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+)
+
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// Options selects the handlers New builds. The console always gets one; a
+// FilePath adds a rotating file next to it.
+type Options struct {
+	Format         string
+	Level          slog.Level
+	Color          bool
+	FilePath       string
+	FileFormat     string
+	FileMaxBytes   int64
+	FileMaxBackups int
+}
+
+// New builds the process logger. Records carry their event name as the
+// message and the fields as attributes, and every handler drops records
+// below Level. The returned closer closes the log file, if any.
+func New(console io.Writer, opts Options) (*slog.Logger, io.Closer, error) {
+	handlers := []slog.Handler{newHandler(console, opts.Format, opts.Level, opts.Color)}
+	var closer io.Closer = nopCloser{}
+	if path := strings.TrimSpace(opts.FilePath); path != "" {
+		file, err := OpenRotatingFile(path, opts.FileMaxBytes, opts.FileMaxBackups)
+		if err != nil {
+			return nil, nil, err
+		}
+		format := opts.FileFormat
+		if strings.TrimSpace(format) == "" {
+			format = opts.Format
+		}
+		handlers = append(handlers, newHandler(file, format, opts.Level, false))
+		closer = file
+	}
+	if len(handlers) == 1 {
+		return slog.New(handlers[0]), closer, nil
+	}
+	return slog.New(fanout(handlers)), closer, nil
+}
+
+// ParseLevel reads debug, info, warn or error. An empty level is info.
+func ParseLevel(s string) (slog.Level, error) {
+	var level slog.Level
+	if strings.TrimSpace(s) == "" {
+		return slog.LevelInfo, nil
+	}
+	if err := level.UnmarshalText([]byte(strings.TrimSpace(s))); err != nil {
+		return 0, fmt.Errorf("parse log level: %w", err)
+	}
+	return level, nil
+}
+
+func newHandler(w io.Writer, format string, level slog.Level, color bool) slog.Handler {
+	if strings.EqualFold(strings.TrimSpace(format), FormatJSON) {
+		return slog.NewJSONHandler(w, &slog.HandlerOptions{Level: level, ReplaceAttr: renameMessage})
+	}
+	return newConsoleHandler(w, level, color)
+}
+
+// renameMessage stores event names under "event", matching the text
+// output. Lines bridged from the log package keep "msg".
+func renameMessage(groups []string, a slog.Attr) slog.Attr {
+	if len(groups) == 0 && a.Key == slog.MessageKey && isEventName(a.Value.String()) {
+		a.Key = "event"
+	}
+	return a
+}
+
+// isEventName reports whether msg looks like an event name such as
+// mcp_tool_completed rather than free text.
+func isEventName(msg string) bool {
+	if msg == "" {
+		return false
+	}
+	for _, r := range msg {
+		if (r < 'a' || r > 'z') && (r < '0' || r > '9') && r != '_' {
+			return false
+		}
+	}
+	return true
+}
+
+// fanout sends each record to every handler that accepts its level.
+type fanout []slog.Handler
+
+func (f fanout) Enabled(ctx context.Context, level slog.Level) bool {
+	for _, h := range f {
+		if h.Enabled(ctx, level) {
+			return true
+		}
+	}
+	return false
+}
+
+func (f fanout) Handle(ctx context.Context, r slog.Record) error {
+	var err error
+	for _, h := range f {
+		if h.Enabled(ctx, r.Level) {
+			err = errors.Join(err, h.Handle(ctx, r.Clone()))
+		}
+	}
+	return err
+}
+
+func (f fanout) WithAttrs(attrs []slog.Attr) slog.Handler {
+	out := make(fanout, len(f))
+	for i, h := range f {
+		out[i] = h.WithAttrs(attrs)
+	}
+	return out
+}
+
+func (f fanout) WithGroup(name string) slog.Handler {
+	out := make(fanout, len(f))
+	for i, h := range f {
+		out[i] = h.WithGroup(name)
+	}
+	return out
+}
+
+type nopCloser struct{}
+
+func (nopCloser) Close() error { return nil }
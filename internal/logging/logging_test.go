@@ -0,0 +1,127 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNewJSONRenamesEvent(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	logger, closer, err := New(&buf, Options{Format: FormatJSON, Level: slog.LevelInfo})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer closer.Close()
+	logger.Info("mcp_tool_completed", "tool", "send_message", "run_id", "msg-1")
+	logger.Info("free text from the log package")
+	logger.Debug("mcp_tool_started", "tool", "send_message")
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("lines = %q, want 2", lines)
+	}
+	var event map[string]any
+	if err := json.Unmarshal([]byte(lines[0]), &event); err != nil {
+		t.Fatalf("decode %q: %v", lines[0], err)
+	}
+	if event["event"] != "mcp_tool_completed" || event["tool"] != "send_message" || event["run_id"] != "msg-1" || event["level"] != "INFO" {
+		t.Fatalf("event = %v", event)
+	}
+	if _, ok := event["msg"]; ok {
+		t.Fatalf("event still has msg: %v", event)
+	}
+	var bridged map[string]any
+	if err := json.Unmarshal([]byte(lines[1]), &bridged); err != nil {
+		t.Fatalf("decode %q: %v", lines[1], err)
+	}
+	if bridged["msg"] != "free text from the log package" {
+		t.Fatalf("bridged = %v", bridged)
+	}
+}
+
+func TestNewWritesFile(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "logs", "yururi.log")
+	var console bytes.Buffer
+	logger, closer, err := New(&console, Options{Format: FormatText, Level: slog.LevelWarn, FilePath: path, FileFormat: FormatJSON})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	logger.Info("message_received", "run_id", "msg-1")
+	logger.Error("codex_turn_failed", "run_id", "msg-1", "thread", "th-1")
+	if err := closer.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	if got := console.String(); !strings.Contains(got, "event=codex_turn_failed level=ERROR run_id=msg-1 thread=th-1") || strings.Contains(got, "message_received") {
+		t.Fatalf("console = %q", got)
+	}
+	body, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	var record map[string]any
+	if err := json.Unmarshal(bytes.TrimSpace(body), &record); err != nil {
+		t.Fatalf("decode %q: %v", body, err)
+	}
+	if record["event"] != "codex_turn_failed" || record["thread"] != "th-1" {
+		t.Fatalf("file record = %v", record)
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		in      string
+		want    slog.Level
+		wantErr bool
+	}{
+		{in: "", want: slog.LevelInfo},
+		{in: "debug", want: slog.LevelDebug},
+		{in: " WARN ", want: slog.LevelWarn},
+		{in: "error", want: slog.LevelError},
+		{in: "verbose", wantErr: true},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.in, func(t *testing.T) {
+			t.Parallel()
+			got, err := ParseLevel(tc.in)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("ParseLevel(%q) error = %v, wantErr %t", tc.in, err, tc.wantErr)
+			}
+			if err == nil && got != tc.want {
+				t.Fatalf("ParseLevel(%q) = %v, want %v", tc.in, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestIsEventName(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		in   string
+		want bool
+	}{
+		{in: "mcp_tool_completed", want: true},
+		{in: "heartbeat_tick2", want: true},
+		{in: "", want: false},
+		{in: "yururi stopped", want: false},
+		{in: "Event", want: false},
+	}
+	for _, tc := range tests {
+		if got := isEventName(tc.in); got != tc.want {
+			t.Fatalf("isEventName(%q) = %t, want %t", tc.in, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package logging
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRotatingFile(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		backups int
+		want    map[string]string
+		missing []string
+	}{
+		{
+			name:    "keeps backups",
+			backups: 2,
+			want:    map[string]string{"": "dddd\n", ".1": "cccc\n", ".2": "bbbb\n"},
+			missing: []string{".3"},
+		},
+		{
+			name:    "no backups",
+			backups: 0,
+			want:    map[string]string{"": "dddd\n"},
+			missing: []string{".1"},
+		},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			path := filepath.Join(t.TempDir(), "yururi.log")
+			f, err := OpenRotatingFile(path, 8, tc.backups)
+			if err != nil {
+				t.Fatalf("OpenRotatingFile() error = %v", err)
+			}
+			for _, line := range []string{"aaaa\n", "bbbb\n", "cccc\n", "dddd\n"} {
+				if _, err := f.Write([]byte(line)); err != nil {
+					t.Fatalf("Write(%q) error = %v", line, err)
+				}
+			}
+			if err := f.Close(); err != nil {
+				t.Fatalf("Close() error = %v", err)
+			}
+			for suffix, want := range tc.want {
+				body, err := os.ReadFile(path + suffix)
+				if err != nil {
+					t.Fatalf("ReadFile(%s) error = %v", suffix, err)
+				}
+				if string(body) != want {
+					t.Fatalf("%s = %q, want %q", suffix, body, want)
+				}
+			}
+			for _, suffix := range tc.missing {
+				if _, err := os.Stat(path + suffix); !os.IsNotExist(err) {
+					t.Fatalf("Stat(%s) error = %v, want not exist", suffix, err)
+				}
+			}
+		})
+	}
+}
+
+func TestRotatingFileAppendsAndClose(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "yururi.log")
+	if err := os.WriteFile(path, []byte("old\n"), 0o600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	f, err := OpenRotatingFile(path, 0, 1)
+	if err != nil {
+		t.Fatalf("OpenRotatingFile() error = %v", err)
+	}
+	if _, err := f.Write([]byte(strings.Repeat("x", 64) + "\n")); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if _, err := f.Write([]byte("late\n")); err == nil {
+		t.Fatal("Write() after Close error = nil")
+	}
+	body, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	if !strings.HasPrefix(string(body), "old\n") || len(body) != 4+65 {
+		t.Fatalf("body = %q", body)
+	}
+	if _, err := os.Stat(path + ".1"); !os.IsNotExist(err) {
+		t.Fatalf("Stat(.1) error = %v, want not exist", err)
+	}
+}
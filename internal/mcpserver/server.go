@@ -7,7 +7,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
 	"strings"
 	"sync"
@@ -27,6 +27,7 @@ type Server struct {
 	xai             *xai.Client
 	mcpServer       *mcp.Server
 	httpServer      *http.Server
+	uploads         uploadConfig
 
 	toolUsageMu     sync.Mutex
 	toolUsageBySess map[string]*toolUsageState
@@ -56,6 +57,15 @@ type HistoryMessage struct {
 	AuthorIsBot bool   `json:"author_is_bot"`
 	Content     string `json:"content"`
 	CreatedAt   string `json:"created_at"`
+	// Attachments lists the message's files; Content is the text alone.
+	Attachments []HistoryAttachment `json:"attachments,omitempty"`
+}
+
+type HistoryAttachment struct {
+	Filename    string `json:"filename"`
+	URL         string `json:"url"`
+	ContentType string `json:"content_type,omitempty"`
+	Size        int    `json:"size"`
 }
 
 type SendMessageArgs struct {
@@ -69,10 +79,83 @@ type ReplyMessageArgs struct {
 	Content          string `json:"content" jsonschema:"返信本文"`
 }
 
+type SendEmbedArgs struct {
+	ChannelID   string           `json:"channel_id" jsonschema:"送信先チャンネルID"`
+	Content     string           `json:"content,omitempty" jsonschema:"embedの上に表示する本文(任意)"`
+	Title       string           `json:"title,omitempty" jsonschema:"タイトル。最大256文字"`
+	Description string           `json:"description,omitempty" jsonschema:"本文。最大4096文字"`
+	URL         string           `json:"url,omitempty" jsonschema:"タイトルのリンク先URL(任意)"`
+	Color       int              `json:"color,omitempty" jsonschema:"左端の色(0xRRGGBBの整数)"`
+	Footer      string           `json:"footer,omitempty" jsonschema:"フッター(任意)"`
+	Fields      []EmbedFieldArgs `json:"fields,omitempty" jsonschema:"フィールド。最大25件"`
+	Buttons     []ButtonArgs     `json:"buttons,omitempty" jsonschema:"ボタン。5個ごとに1行"`
+	Selects     []SelectMenuArgs `json:"selects,omitempty" jsonschema:"セレクトメニュー。1つごとに1行、ボタンと合わせて最大5行"`
+}
+
+type EmbedFieldArgs struct {
+	Name   string `json:"name" jsonschema:"フィールド名。最大256文字"`
+	Value  string `json:"value" jsonschema:"フィールド値。最大1024文字"`
+	Inline bool   `json:"inline,omitempty" jsonschema:"横並びで表示する"`
+}
+
+type ButtonArgs struct {
+	CustomID string `json:"custom_id,omitempty" jsonschema:"押されたときに通知されるID。linkボタン以外は必須"`
+	Label    string `json:"label" jsonschema:"ボタンの文言。最大80文字"`
+	Style    string `json:"style,omitempty" jsonschema:"primary/secondary/success/danger/link。省略時secondary"`
+	URL      string `json:"url,omitempty" jsonschema:"linkボタンの遷移先URL"`
+}
+
+type SelectMenuArgs struct {
+	CustomID    string             `json:"custom_id" jsonschema:"選択されたときに通知されるID"`
+	Placeholder string             `json:"placeholder,omitempty" jsonschema:"未選択時の表示"`
+	MinValues   int                `json:"min_values,omitempty" jsonschema:"最小選択数。省略時0"`
+	MaxValues   int                `json:"max_values,omitempty" jsonschema:"最大選択数。省略時1"`
+	Options     []SelectOptionArgs `json:"options" jsonschema:"選択肢。1〜25件"`
+}
+
+type SelectOptionArgs struct {
+	Label       string `json:"label" jsonschema:"選択肢の表示名"`
+	Value       string `json:"value,omitempty" jsonschema:"通知される値。省略時はlabel"`
+	Description string `json:"description,omitempty" jsonschema:"選択肢の説明(任意)"`
+}
+
+type SendDirectMessageArgs struct {
+	UserID  string `json:"user_id" jsonschema:"送信先ユーザーID(dm.allowed_user_idsに含まれること)"`
+	Content string `json:"content" jsonschema:"送信本文"`
+}
+
+type DirectMessageResult struct {
+	ChannelID  string   `json:"channel_id,omitempty"`
+	MessageID  string   `json:"message_id,omitempty"`
+	MessageIDs []string `json:"message_ids,omitempty"`
+	Suppressed bool     `json:"suppressed,omitempty"`
+	Reason     string   `json:"reason,omitempty"`
+}
+
+// MessageResult carries the first message's ID in MessageID. Long content is
+// sent in several messages, whose IDs are all listed in MessageIDs.
 type MessageResult struct {
-	MessageID  string `json:"message_id,omitempty"`
-	Suppressed bool   `json:"suppressed,omitempty"`
-	Reason     string `json:"reason,omitempty"`
+	MessageID  string   `json:"message_id,omitempty"`
+	MessageIDs []string `json:"message_ids,omitempty"`
+	Suppressed bool     `json:"suppressed,omitempty"`
+	Reason     string   `json:"reason,omitempty"`
+}
+
+func messageResult(ids []string) MessageResult {
+	result := MessageResult{MessageIDs: ids}
+	if len(ids) > 0 {
+		result.MessageID = ids[0]
+	}
+	return result
+}
+
+// partialSendResult reports a send that failed after some parts were posted.
+// The IDs are returned with the error so the model does not post them again.
+func partialSendResult(err error, ids []string) *mcp.CallToolResult {
+	return &mcp.CallToolResult{
+		IsError: true,
+		Content: []mcp.Content{&mcp.TextContent{Text: fmt.Sprintf("%v (already sent message_ids: %s)", err, strings.Join(ids, ", "))}},
+	}
 }
 
 type AddReactionArgs struct {
@@ -81,8 +164,21 @@ type AddReactionArgs struct {
 	Emoji     string `json:"emoji" jsonschema:"絵文字(Unicodeまたはカスタム絵文字)"`
 }
 
+type EditMessageArgs struct {
+	ChannelID string `json:"channel_id" jsonschema:"対象チャンネルID"`
+	MessageID string `json:"message_id" jsonschema:"編集するbot自身のメッセージID"`
+	Content   string `json:"content" jsonschema:"編集後の本文"`
+}
+
+type DeleteMessageArgs struct {
+	ChannelID string `json:"channel_id" jsonschema:"対象チャンネルID"`
+	MessageID string `json:"message_id" jsonschema:"削除するbot自身のメッセージID"`
+}
+
 type SimpleOK struct {
-	OK bool `json:"ok"`
+	OK         bool   `json:"ok"`
+	Suppressed bool   `json:"suppressed,omitempty"`
+	Reason     string `json:"reason,omitempty"`
 }
 
 type StartTypingArgs struct {
@@ -99,6 +195,35 @@ type ChannelItem struct {
 	Name      string `json:"name"`
 }
 
+type CreateThreadArgs struct {
+	ChannelID          string `json:"channel_id" jsonschema:"親チャンネルID(テキストまたはフォーラム)"`
+	Name               string `json:"name" jsonschema:"スレッド名"`
+	MessageID          string `json:"message_id,omitempty" jsonschema:"スレッドを紐付けるメッセージID(任意)"`
+	Content            string `json:"content,omitempty" jsonschema:"フォーラム投稿の本文。フォーラムでは必須"`
+	AutoArchiveMinutes int    `json:"auto_archive_minutes,omitempty" jsonschema:"自動アーカイブまでの分数(60/1440/4320/10080)。省略時1440"`
+}
+
+type ListActiveThreadsArgs struct {
+	ChannelID string `json:"channel_id,omitempty" jsonschema:"親チャンネルIDで絞り込む(任意)"`
+}
+
+type ThreadItem struct {
+	ThreadID        string `json:"thread_id"`
+	ParentChannelID string `json:"parent_channel_id"`
+	Name            string `json:"name"`
+	Archived        bool   `json:"archived,omitempty"`
+}
+
+type ListActiveThreadsResult struct {
+	Threads []ThreadItem `json:"threads"`
+}
+
+type ReadThreadHistoryArgs struct {
+	ThreadID        string `json:"thread_id" jsonschema:"対象スレッドID"`
+	BeforeMessageID string `json:"before_message_id,omitempty" jsonschema:"このメッセージより前を取得(任意)"`
+	Limit           int    `json:"limit,omitempty" jsonschema:"取得件数。最大100"`
+}
+
 type UserDetailArgs struct {
 	ChannelID string `json:"channel_id" jsonschema:"対象チャンネルID"`
 	UserID    string `json:"user_id" jsonschema:"対象ユーザーID"`
@@ -233,16 +358,16 @@ func (s *Server) Start(ctx context.Context) error {
 
 func logMCPToolStart(toolName string, args any) time.Time {
 	started := time.Now()
-	log.Printf("event=mcp_tool_started tool=%s args=%q", toolName, trimLogAny(args, maxMCPToolLogValueLen))
+	slog.Debug("mcp_tool_started", "tool", toolName, "args", trimLogAny(args, maxMCPToolLogValueLen))
 	return started
 }
 
 func logMCPToolFailed(toolName string, started time.Time, err error) {
-	log.Printf("event=mcp_tool_failed tool=%s latency_ms=%d err=%v", toolName, durationMS(time.Since(started)), err)
+	slog.Error("mcp_tool_failed", "tool", toolName, "latency_ms", durationMS(time.Since(started)), "err", err)
 }
 
 func logMCPToolCompleted(toolName string, started time.Time, result any) {
-	log.Printf("event=mcp_tool_completed tool=%s latency_ms=%d result=%q", toolName, durationMS(time.Since(started)), trimLogAny(result, maxMCPToolLogValueLen))
+	slog.Info("mcp_tool_completed", "tool", toolName, "latency_ms", durationMS(time.Since(started)), "result", trimLogAny(result, maxMCPToolLogValueLen))
 }
 
 func (s *Server) registerTools() {
@@ -261,6 +386,26 @@ func (s *Server) registerTools() {
 		Description: "Discordメッセージに返信する",
 	}, s.handleReplyMessage)
 
+	mcp.AddTool(s.mcpServer, &mcp.Tool{
+		Name:        "send_embed",
+		Description: "Discordチャンネルにembed(ボタン・セレクトメニュー付き可)を送信する。ボタンやメニューの操作はこのチャンネルのセッションに通知される",
+	}, s.handleSendEmbed)
+
+	mcp.AddTool(s.mcpServer, &mcp.Tool{
+		Name:        "send_direct_message",
+		Description: "許可されたDiscordユーザーにDMを送信する",
+	}, s.handleSendDirectMessage)
+
+	mcp.AddTool(s.mcpServer, &mcp.Tool{
+		Name:        "edit_message",
+		Description: "bot自身が送信したDiscordメッセージを編集する",
+	}, s.handleEditMessage)
+
+	mcp.AddTool(s.mcpServer, &mcp.Tool{
+		Name:        "delete_message",
+		Description: "bot自身が送信したDiscordメッセージを削除する",
+	}, s.handleDeleteMessage)
+
 	mcp.AddTool(s.mcpServer, &mcp.Tool{
 		Name:        "add_reaction",
 		Description: "Discordメッセージにリアクションする",
@@ -276,6 +421,21 @@ func (s *Server) registerTools() {
 		Description: "対象チャンネル一覧を取得する",
 	}, s.handleListChannels)
 
+	mcp.AddTool(s.mcpServer, &mcp.Tool{
+		Name:        "create_thread",
+		Description: "Discordチャンネルにスレッドまたはフォーラム投稿を作成する",
+	}, s.handleCreateThread)
+
+	mcp.AddTool(s.mcpServer, &mcp.Tool{
+		Name:        "list_active_threads",
+		Description: "対象チャンネル配下のアクティブなスレッド一覧を取得する",
+	}, s.handleListActiveThreads)
+
+	mcp.AddTool(s.mcpServer, &mcp.Tool{
+		Name:        "read_thread_history",
+		Description: "Discordスレッドのメッセージ履歴を取得する",
+	}, s.handleReadThreadHistory)
+
 	mcp.AddTool(s.mcpServer, &mcp.Tool{
 		Name:        "get_user_detail",
 		Description: "Discordユーザー詳細を取得する",
@@ -294,6 +454,35 @@ func (s *Server) registerTools() {
 	}
 }
 
+func toHistoryMessages(messages []discordx.Message) []HistoryMessage {
+	out := make([]HistoryMessage, 0, len(messages))
+	for _, msg := range messages {
+		out = append(out, HistoryMessage{
+			MessageID:   msg.ID,
+			ChannelID:   msg.ChannelID,
+			GuildID:     msg.GuildID,
+			AuthorID:    msg.AuthorID,
+			AuthorName:  msg.AuthorName,
+			AuthorIsBot: msg.AuthorIsBot,
+			Content:     msg.Content,
+			CreatedAt:   msg.CreatedAt.UTC().Format(time.RFC3339),
+			Attachments: toHistoryAttachments(msg.Attachments),
+		})
+	}
+	return out
+}
+
+func toHistoryAttachments(attachments []discordx.Attachment) []HistoryAttachment {
+	if len(attachments) == 0 {
+		return nil
+	}
+	out := make([]HistoryAttachment, 0, len(attachments))
+	for _, a := range attachments {
+		out = append(out, HistoryAttachment{Filename: a.Filename, URL: a.URL, ContentType: a.ContentType, Size: a.Size})
+	}
+	return out
+}
+
 func (s *Server) handleReadMessageHistory(ctx context.Context, req *mcp.CallToolRequest, args ReadHistoryArgs) (*mcp.CallToolResult, ReadHistoryResult, error) {
 	started := logMCPToolStart("read_message_history", args)
 	if err := s.enforceToolPolicy("read_message_history"); err != nil {
@@ -309,24 +498,84 @@ func (s *Server) handleReadMessageHistory(ctx context.Context, req *mcp.CallTool
 		logMCPToolFailed("read_message_history", started, err)
 		return nil, ReadHistoryResult{}, err
 	}
-	out := make([]HistoryMessage, 0, len(messages))
-	for _, msg := range messages {
-		out = append(out, HistoryMessage{
-			MessageID:   msg.ID,
-			ChannelID:   msg.ChannelID,
-			GuildID:     msg.GuildID,
-			AuthorID:    msg.AuthorID,
-			AuthorName:  msg.AuthorName,
-			AuthorIsBot: msg.AuthorIsBot,
-			Content:     msg.Content,
-			CreatedAt:   msg.CreatedAt.UTC().Format(time.RFC3339),
-		})
-	}
-	result := ReadHistoryResult{Messages: out}
+	result := ReadHistoryResult{Messages: toHistoryMessages(messages)}
 	logMCPToolCompleted("read_message_history", started, result)
 	return nil, result, nil
 }
 
+func (s *Server) handleReadThreadHistory(ctx context.Context, req *mcp.CallToolRequest, args ReadThreadHistoryArgs) (*mcp.CallToolResult, ReadHistoryResult, error) {
+	started := logMCPToolStart("read_thread_history", args)
+	if err := s.enforceToolPolicy("read_thread_history"); err != nil {
+		logMCPToolFailed("read_thread_history", started, err)
+		return nil, ReadHistoryResult{}, err
+	}
+	if err := s.enforceToolUsage(req, "read_thread_history", args); err != nil {
+		logMCPToolFailed("read_thread_history", started, err)
+		return nil, ReadHistoryResult{}, err
+	}
+	messages, err := s.discord.ReadThreadHistory(ctx, args.ThreadID, args.BeforeMessageID, args.Limit)
+	if err != nil {
+		logMCPToolFailed("read_thread_history", started, err)
+		return nil, ReadHistoryResult{}, err
+	}
+	result := ReadHistoryResult{Messages: toHistoryMessages(messages)}
+	logMCPToolCompleted("read_thread_history", started, result)
+	return nil, result, nil
+}
+
+func (s *Server) handleCreateThread(ctx context.Context, req *mcp.CallToolRequest, args CreateThreadArgs) (*mcp.CallToolResult, ThreadItem, error) {
+	started := logMCPToolStart("create_thread", args)
+	if err := s.enforceToolPolicy("create_thread"); err != nil {
+		logMCPToolFailed("create_thread", started, err)
+		return nil, ThreadItem{}, err
+	}
+	if err := s.enforceToolUsage(req, "create_thread", args); err != nil {
+		logMCPToolFailed("create_thread", started, err)
+		return nil, ThreadItem{}, err
+	}
+	thread, err := s.discord.CreateThread(ctx, args.ChannelID, args.Name, args.MessageID, args.Content, args.AutoArchiveMinutes)
+	if err != nil {
+		logMCPToolFailed("create_thread", started, err)
+		return nil, ThreadItem{}, err
+	}
+	result := toThreadItem(thread)
+	logMCPToolCompleted("create_thread", started, result)
+	return nil, result, nil
+}
+
+func (s *Server) handleListActiveThreads(ctx context.Context, req *mcp.CallToolRequest, args ListActiveThreadsArgs) (*mcp.CallToolResult, ListActiveThreadsResult, error) {
+	started := logMCPToolStart("list_active_threads", args)
+	if err := s.enforceToolPolicy("list_active_threads"); err != nil {
+		logMCPToolFailed("list_active_threads", started, err)
+		return nil, ListActiveThreadsResult{}, err
+	}
+	if err := s.enforceToolUsage(req, "list_active_threads", args); err != nil {
+		logMCPToolFailed("list_active_threads", started, err)
+		return nil, ListActiveThreadsResult{}, err
+	}
+	threads, err := s.discord.ListActiveThreads(ctx, args.ChannelID)
+	if err != nil {
+		logMCPToolFailed("list_active_threads", started, err)
+		return nil, ListActiveThreadsResult{}, err
+	}
+	out := make([]ThreadItem, 0, len(threads))
+	for _, thread := range threads {
+		out = append(out, toThreadItem(thread))
+	}
+	result := ListActiveThreadsResult{Threads: out}
+	logMCPToolCompleted("list_active_threads", started, result)
+	return nil, result, nil
+}
+
+func toThreadItem(thread discordx.ThreadInfo) ThreadItem {
+	return ThreadItem{
+		ThreadID:        thread.ThreadID,
+		ParentChannelID: thread.ParentChannelID,
+		Name:            thread.Name,
+		Archived:        thread.Archived,
+	}
+}
+
 func (s *Server) handleSendMessage(ctx context.Context, req *mcp.CallToolRequest, args SendMessageArgs) (*mcp.CallToolResult, MessageResult, error) {
 	started := logMCPToolStart("send_message", args)
 	if err := s.enforceToolPolicy("send_message"); err != nil {
@@ -337,21 +586,24 @@ func (s *Server) handleSendMessage(ctx context.Context, req *mcp.CallToolRequest
 		logMCPToolFailed("send_message", started, err)
 		return nil, MessageResult{}, err
 	}
-	id, err := s.discord.SendMessage(ctx, args.ChannelID, args.Content)
+	ids, err := s.discord.SendMessage(ctx, args.ChannelID, args.Content)
 	if err != nil {
 		if discordx.IsDuplicateSuppressed(err) {
 			result := MessageResult{
 				Suppressed: true,
 				Reason:     "duplicate_content",
 			}
-			log.Printf("event=message_duplicate_suppressed tool=send_message channel=%s", strings.TrimSpace(args.ChannelID))
+			slog.Warn("message_duplicate_suppressed", "tool", "send_message", "channel", strings.TrimSpace(args.ChannelID))
 			logMCPToolCompleted("send_message", started, result)
 			return nil, result, nil
 		}
 		logMCPToolFailed("send_message", started, err)
+		if len(ids) > 0 {
+			return partialSendResult(err, ids), messageResult(ids), nil
+		}
 		return nil, MessageResult{}, err
 	}
-	result := MessageResult{MessageID: id}
+	result := messageResult(ids)
 	logMCPToolCompleted("send_message", started, result)
 	return nil, result, nil
 }
@@ -366,25 +618,172 @@ func (s *Server) handleReplyMessage(ctx context.Context, req *mcp.CallToolReques
 		logMCPToolFailed("reply_message", started, err)
 		return nil, MessageResult{}, err
 	}
-	id, err := s.discord.ReplyMessage(ctx, args.ChannelID, args.ReplyToMessageID, args.Content)
+	ids, err := s.discord.ReplyMessage(ctx, args.ChannelID, args.ReplyToMessageID, args.Content)
 	if err != nil {
 		if discordx.IsDuplicateSuppressed(err) {
 			result := MessageResult{
 				Suppressed: true,
 				Reason:     "duplicate_content",
 			}
-			log.Printf("event=message_duplicate_suppressed tool=reply_message channel=%s", strings.TrimSpace(args.ChannelID))
+			slog.Warn("message_duplicate_suppressed", "tool", "reply_message", "channel", strings.TrimSpace(args.ChannelID))
 			logMCPToolCompleted("reply_message", started, result)
 			return nil, result, nil
 		}
 		logMCPToolFailed("reply_message", started, err)
+		if len(ids) > 0 {
+			return partialSendResult(err, ids), messageResult(ids), nil
+		}
 		return nil, MessageResult{}, err
 	}
-	result := MessageResult{MessageID: id}
+	result := messageResult(ids)
 	logMCPToolCompleted("reply_message", started, result)
 	return nil, result, nil
 }
 
+func (s *Server) handleSendEmbed(ctx context.Context, req *mcp.CallToolRequest, args SendEmbedArgs) (*mcp.CallToolResult, MessageResult, error) {
+	started := logMCPToolStart("send_embed", args)
+	if err := s.enforceToolPolicy("send_embed"); err != nil {
+		logMCPToolFailed("send_embed", started, err)
+		return nil, MessageResult{}, err
+	}
+	if err := s.enforceToolUsage(req, "send_embed", args); err != nil {
+		logMCPToolFailed("send_embed", started, err)
+		return nil, MessageResult{}, err
+	}
+	id, err := s.discord.SendEmbed(ctx, args.ChannelID, toEmbedMessage(args))
+	if err != nil {
+		if discordx.IsDuplicateSuppressed(err) {
+			result := MessageResult{
+				Suppressed: true,
+				Reason:     "duplicate_content",
+			}
+			slog.Warn("message_duplicate_suppressed", "tool", "send_embed", "channel", strings.TrimSpace(args.ChannelID))
+			logMCPToolCompleted("send_embed", started, result)
+			return nil, result, nil
+		}
+		logMCPToolFailed("send_embed", started, err)
+		return nil, MessageResult{}, err
+	}
+	result := MessageResult{MessageID: id}
+	logMCPToolCompleted("send_embed", started, result)
+	return nil, result, nil
+}
+
+func toEmbedMessage(args SendEmbedArgs) discordx.EmbedMessage {
+	msg := discordx.EmbedMessage{
+		Content: args.Content,
+		Embed: discordx.Embed{
+			Title:       args.Title,
+			Description: args.Description,
+			URL:         args.URL,
+			Color:       args.Color,
+			Footer:      args.Footer,
+		},
+	}
+	for _, field := range args.Fields {
+		msg.Embed.Fields = append(msg.Embed.Fields, discordx.EmbedField{Name: field.Name, Value: field.Value, Inline: field.Inline})
+	}
+	for _, button := range args.Buttons {
+		msg.Buttons = append(msg.Buttons, discordx.Button{CustomID: button.CustomID, Label: button.Label, Style: button.Style, URL: button.URL})
+	}
+	for _, menu := range args.Selects {
+		selectMenu := discordx.SelectMenu{
+			CustomID:    menu.CustomID,
+			Placeholder: menu.Placeholder,
+			MinValues:   menu.MinValues,
+			MaxValues:   menu.MaxValues,
+		}
+		for _, option := range menu.Options {
+			selectMenu.Options = append(selectMenu.Options, discordx.SelectOption{Label: option.Label, Value: option.Value, Description: option.Description})
+		}
+		msg.Selects = append(msg.Selects, selectMenu)
+	}
+	return msg
+}
+
+func (s *Server) handleSendDirectMessage(ctx context.Context, req *mcp.CallToolRequest, args SendDirectMessageArgs) (*mcp.CallToolResult, DirectMessageResult, error) {
+	started := logMCPToolStart("send_direct_message", args)
+	if err := s.enforceToolPolicy("send_direct_message"); err != nil {
+		logMCPToolFailed("send_direct_message", started, err)
+		return nil, DirectMessageResult{}, err
+	}
+	if err := s.enforceToolUsage(req, "send_direct_message", args); err != nil {
+		logMCPToolFailed("send_direct_message", started, err)
+		return nil, DirectMessageResult{}, err
+	}
+	channelID, ids, err := s.discord.SendDirectMessage(ctx, args.UserID, args.Content)
+	if err != nil {
+		if discordx.IsDuplicateSuppressed(err) {
+			result := DirectMessageResult{
+				ChannelID:  channelID,
+				Suppressed: true,
+				Reason:     "duplicate_content",
+			}
+			slog.Warn("message_duplicate_suppressed", "tool", "send_direct_message", "channel", channelID)
+			logMCPToolCompleted("send_direct_message", started, result)
+			return nil, result, nil
+		}
+		logMCPToolFailed("send_direct_message", started, err)
+		if len(ids) > 0 {
+			sent := messageResult(ids)
+			return partialSendResult(err, ids), DirectMessageResult{ChannelID: channelID, MessageID: sent.MessageID, MessageIDs: sent.MessageIDs}, nil
+		}
+		return nil, DirectMessageResult{}, err
+	}
+	sent := messageResult(ids)
+	result := DirectMessageResult{ChannelID: channelID, MessageID: sent.MessageID, MessageIDs: sent.MessageIDs}
+	logMCPToolCompleted("send_direct_message", started, result)
+	return nil, result, nil
+}
+
+func (s *Server) handleEditMessage(ctx context.Context, req *mcp.CallToolRequest, args EditMessageArgs) (*mcp.CallToolResult, MessageResult, error) {
+	started := logMCPToolStart("edit_message", args)
+	if err := s.enforceToolPolicy("edit_message"); err != nil {
+		logMCPToolFailed("edit_message", started, err)
+		return nil, MessageResult{}, err
+	}
+	if err := s.enforceToolUsage(req, "edit_message", args); err != nil {
+		logMCPToolFailed("edit_message", started, err)
+		return nil, MessageResult{}, err
+	}
+	if err := s.discord.EditMessage(ctx, args.ChannelID, args.MessageID, args.Content); err != nil {
+		if discordx.IsDuplicateSuppressed(err) {
+			result := MessageResult{
+				MessageID:  args.MessageID,
+				Suppressed: true,
+				Reason:     "duplicate_content",
+			}
+			slog.Warn("message_duplicate_suppressed", "tool", "edit_message", "channel", strings.TrimSpace(args.ChannelID))
+			logMCPToolCompleted("edit_message", started, result)
+			return nil, result, nil
+		}
+		logMCPToolFailed("edit_message", started, err)
+		return nil, MessageResult{}, err
+	}
+	result := MessageResult{MessageID: args.MessageID}
+	logMCPToolCompleted("edit_message", started, result)
+	return nil, result, nil
+}
+
+func (s *Server) handleDeleteMessage(ctx context.Context, req *mcp.CallToolRequest, args DeleteMessageArgs) (*mcp.CallToolResult, SimpleOK, error) {
+	started := logMCPToolStart("delete_message", args)
+	if err := s.enforceToolPolicy("delete_message"); err != nil {
+		logMCPToolFailed("delete_message", started, err)
+		return nil, SimpleOK{}, err
+	}
+	if err := s.enforceToolUsage(req, "delete_message", args); err != nil {
+		logMCPToolFailed("delete_message", started, err)
+		return nil, SimpleOK{}, err
+	}
+	if err := s.discord.DeleteMessage(ctx, args.ChannelID, args.MessageID); err != nil {
+		logMCPToolFailed("delete_message", started, err)
+		return nil, SimpleOK{}, err
+	}
+	result := SimpleOK{OK: true}
+	logMCPToolCompleted("delete_message", started, result)
+	return nil, result, nil
+}
+
 func (s *Server) handleAddReaction(ctx context.Context, req *mcp.CallToolRequest, args AddReactionArgs) (*mcp.CallToolResult, SimpleOK, error) {
 	started := logMCPToolStart("add_reaction", args)
 	if err := s.enforceToolPolicy("add_reaction"); err != nil {
@@ -396,6 +795,12 @@ func (s *Server) handleAddReaction(ctx context.Context, req *mcp.CallToolRequest
 		return nil, SimpleOK{}, err
 	}
 	if err := s.discord.AddReaction(ctx, args.ChannelID, args.MessageID, args.Emoji); err != nil {
+		if discordx.IsDuplicateSuppressed(err) {
+			result := SimpleOK{OK: true, Suppressed: true, Reason: "duplicate_reaction"}
+			slog.Warn("reaction_duplicate_suppressed", "tool", "add_reaction", "channel", strings.TrimSpace(args.ChannelID), "message", strings.TrimSpace(args.MessageID))
+			logMCPToolCompleted("add_reaction", started, result)
+			return nil, result, nil
+		}
 		logMCPToolFailed("add_reaction", started, err)
 		return nil, SimpleOK{}, err
 	}
@@ -544,7 +949,7 @@ func (s *Server) enforceToolPolicy(toolName string) error {
 	if allowed {
 		return nil
 	}
-	log.Printf("mcp tool denied: tool=%s reason=%q", toolName, reason)
+	slog.Warn("mcp_tool_denied", "tool", toolName, "reason", reason)
 	return fmt.Errorf("%w: tool=%s reason=%s", ErrToolDenied, toolName, reason)
 }
 
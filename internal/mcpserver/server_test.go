@@ -8,6 +8,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/modelcontextprotocol/go-sdk/mcp"
 	"github.com/sigumaa/yururi/internal/config"
 	"github.com/sigumaa/yururi/internal/discordx"
 	"github.com/sigumaa/yururi/internal/xai"
@@ -238,3 +239,83 @@ func TestHandleGetCurrentTimeSameArgumentRetryLimit(t *testing.T) {
 		t.Fatalf("handleGetCurrentTime() error = %v, want ErrToolUsageLimited", err)
 	}
 }
+
+// newToolTestServer returns a server whose gateway can read c-read and
+// c-write and write only c-write. It has no Discord session, so it only
+// serves tests that fail before anything is sent; the send, edit and thread
+// paths are tested in discordx.
+func newToolTestServer(t *testing.T) *Server {
+	t.Helper()
+	gateway := discordx.NewGateway(nil, config.DiscordConfig{
+		GuildID:         "g1",
+		ReadChannelIDs:  []string{"c-read", "c-write"},
+		WriteChannelIDs: []string{"c-write"},
+	})
+	srv, err := New("127.0.0.1:39393", "Asia/Tokyo", gateway, nil, allowAllPolicy())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	return srv
+}
+
+func TestThreadToolsValidateChannels(t *testing.T) {
+	t.Parallel()
+
+	srv := newToolTestServer(t)
+
+	if _, _, err := srv.handleReadThreadHistory(context.Background(), nil, ReadThreadHistoryArgs{ThreadID: "c-read"}); err == nil || !strings.Contains(err.Error(), "not a thread") {
+		t.Fatalf("handleReadThreadHistory(non-thread) error = %v, want not a thread", err)
+	}
+	if _, _, err := srv.handleCreateThread(context.Background(), nil, CreateThreadArgs{ChannelID: "c-read", Name: "topic"}); err == nil {
+		t.Fatal("handleCreateThread(read-only channel) error = nil, want error")
+	}
+	if _, _, err := srv.handleCreateThread(context.Background(), nil, CreateThreadArgs{ChannelID: "c-write"}); err == nil {
+		t.Fatal("handleCreateThread(without name) error = nil, want error")
+	}
+}
+
+func TestEditAndDeleteToolsValidateArgs(t *testing.T) {
+	t.Parallel()
+
+	srv := newToolTestServer(t)
+
+	if _, _, err := srv.handleEditMessage(context.Background(), nil, EditMessageArgs{ChannelID: "c-read", MessageID: "m1", Content: "fixed"}); err == nil {
+		t.Fatal("handleEditMessage(read-only channel) error = nil, want error")
+	}
+	if _, _, err := srv.handleEditMessage(context.Background(), nil, EditMessageArgs{ChannelID: "c-write", MessageID: "m1"}); err == nil {
+		t.Fatal("handleEditMessage(without content) error = nil, want error")
+	}
+	if _, _, err := srv.handleDeleteMessage(context.Background(), nil, DeleteMessageArgs{ChannelID: "c-read", MessageID: "m1"}); err == nil {
+		t.Fatal("handleDeleteMessage(read-only channel) error = nil, want error")
+	}
+	if _, _, err := srv.handleDeleteMessage(context.Background(), nil, DeleteMessageArgs{ChannelID: "c-write"}); err == nil {
+		t.Fatal("handleDeleteMessage(without message_id) error = nil, want error")
+	}
+}
+
+func TestHandleSendEmbedValidatesBeforeSending(t *testing.T) {
+	t.Parallel()
+
+	srv := newToolTestServer(t)
+
+	if _, _, err := srv.handleSendEmbed(context.Background(), nil, SendEmbedArgs{ChannelID: "c-read", Title: "report"}); err == nil {
+		t.Fatal("handleSendEmbed(read-only channel) error = nil, want error")
+	}
+	args := SendEmbedArgs{ChannelID: "c-write", Title: "report", Buttons: []ButtonArgs{{Label: "ok"}}}
+	if _, _, err := srv.handleSendEmbed(context.Background(), nil, args); err == nil || !strings.Contains(err.Error(), "custom_id") {
+		t.Fatalf("handleSendEmbed(button without custom_id) error = %v, want custom_id error", err)
+	}
+}
+
+func TestPartialSendResult(t *testing.T) {
+	t.Parallel()
+
+	res := partialSendResult(errors.New("send message part 2/2: 500"), []string{"m1"})
+	if !res.IsError || len(res.Content) != 1 {
+		t.Fatalf("result = %+v, want one error content", res)
+	}
+	text, ok := res.Content[0].(*mcp.TextContent)
+	if !ok || !strings.Contains(text.Text, "part 2/2") || !strings.Contains(text.Text, "m1") {
+		t.Fatalf("content = %+v", res.Content[0])
+	}
+}
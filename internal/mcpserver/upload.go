@@ -0,0 +1,159 @@
+package mcpserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/sigumaa/yururi/internal/discordx"
+)
+
+type uploadConfig struct {
+	workspaceDir string
+	maxBytes     int64
+}
+
+type UploadFileArgs struct {
+	ChannelID string `json:"channel_id" jsonschema:"送信先チャンネルID"`
+	Path      string `json:"path" jsonschema:"添付するファイルのパス。ワークスペースからの相対パス"`
+	Content   string `json:"content,omitempty" jsonschema:"ファイルに添える本文(任意)"`
+	Filename  string `json:"filename,omitempty" jsonschema:"Discord上のファイル名(任意)。省略時は元のファイル名"`
+}
+
+// EnableUploads registers upload_file, which attaches files from
+// workspaceDir up to maxBytes. Call it before Start.
+func (s *Server) EnableUploads(workspaceDir string, maxBytes int64) {
+	workspaceDir = strings.TrimSpace(workspaceDir)
+	if workspaceDir == "" {
+		return
+	}
+	s.uploads = uploadConfig{workspaceDir: workspaceDir, maxBytes: maxBytes}
+	mcp.AddTool(s.mcpServer, &mcp.Tool{
+		Name:        "upload_file",
+		Description: "ワークスペース内のファイルをDiscordチャンネルに添付して送信する",
+	}, s.handleUploadFile)
+}
+
+func (s *Server) handleUploadFile(ctx context.Context, req *mcp.CallToolRequest, args UploadFileArgs) (*mcp.CallToolResult, MessageResult, error) {
+	started := logMCPToolStart("upload_file", args)
+	if err := s.enforceToolPolicy("upload_file"); err != nil {
+		logMCPToolFailed("upload_file", started, err)
+		return nil, MessageResult{}, err
+	}
+	if err := s.enforceToolUsage(req, "upload_file", args); err != nil {
+		logMCPToolFailed("upload_file", started, err)
+		return nil, MessageResult{}, err
+	}
+	file, err := readUpload(s.uploads, args.Path, args.Filename)
+	if err != nil {
+		logMCPToolFailed("upload_file", started, err)
+		return nil, MessageResult{}, err
+	}
+	id, err := s.discord.SendFile(ctx, args.ChannelID, args.Content, file)
+	if err != nil {
+		if discordx.IsDuplicateSuppressed(err) {
+			result := MessageResult{
+				Suppressed: true,
+				Reason:     "duplicate_content",
+			}
+			slog.Warn("message_duplicate_suppressed", "tool", "upload_file", "channel", strings.TrimSpace(args.ChannelID))
+			logMCPToolCompleted("upload_file", started, result)
+			return nil, result, nil
+		}
+		logMCPToolFailed("upload_file", started, err)
+		return nil, MessageResult{}, err
+	}
+	result := messageResult([]string{id})
+	logMCPToolCompleted("upload_file", started, result)
+	return nil, result, nil
+}
+
+// readUpload reads the file at path, which must resolve to a regular file
+// inside the workspace after following symlinks.
+func readUpload(cfg uploadConfig, path string, filename string) (discordx.FileUpload, error) {
+	if cfg.workspaceDir == "" {
+		return discordx.FileUpload{}, errors.New("upload_file is not configured")
+	}
+	resolved, err := resolveWorkspacePath(cfg.workspaceDir, path)
+	if err != nil {
+		return discordx.FileUpload{}, err
+	}
+	f, err := os.Open(resolved)
+	if err != nil {
+		return discordx.FileUpload{}, fmt.Errorf("open upload: %w", err)
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return discordx.FileUpload{}, fmt.Errorf("stat upload: %w", err)
+	}
+	if !info.Mode().IsRegular() {
+		return discordx.FileUpload{}, fmt.Errorf("not a regular file: %s", path)
+	}
+	if info.Size() > cfg.maxBytes {
+		return discordx.FileUpload{}, fmt.Errorf("file is %d bytes, max %d", info.Size(), cfg.maxBytes)
+	}
+	data, err := io.ReadAll(io.LimitReader(f, cfg.maxBytes+1))
+	if err != nil {
+		return discordx.FileUpload{}, fmt.Errorf("read upload: %w", err)
+	}
+	if int64(len(data)) > cfg.maxBytes {
+		return discordx.FileUpload{}, fmt.Errorf("file exceeds %d bytes", cfg.maxBytes)
+	}
+
+	name := filepath.Base(strings.TrimSpace(filename))
+	if name == "." || name == string(filepath.Separator) {
+		name = filepath.Base(resolved)
+	}
+	return discordx.FileUpload{
+		Name:        name,
+		ContentType: detectContentType(name, data),
+		Data:        data,
+	}, nil
+}
+
+func resolveWorkspacePath(workspaceDir string, path string) (string, error) {
+	path = strings.TrimSpace(path)
+	if path == "" {
+		return "", errors.New("path is required")
+	}
+	root, err := filepath.Abs(workspaceDir)
+	if err != nil {
+		return "", fmt.Errorf("resolve workspace: %w", err)
+	}
+	if root, err = filepath.EvalSymlinks(root); err != nil {
+		return "", fmt.Errorf("resolve workspace: %w", err)
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(root, path)
+	}
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return "", fmt.Errorf("resolve path: %w", err)
+	}
+	rel, err := filepath.Rel(root, resolved)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("path is outside the workspace: %s", path)
+	}
+	return resolved, nil
+}
+
+// detectContentType sniffs the content and falls back to the extension when
+// sniffing only finds generic text or binary.
+func detectContentType(name string, data []byte) string {
+	sniffed := http.DetectContentType(data)
+	if sniffed == "application/octet-stream" || strings.HasPrefix(sniffed, "text/plain") {
+		if byExt := mime.TypeByExtension(filepath.Ext(name)); byExt != "" {
+			return byExt
+		}
+	}
+	return sniffed
+}
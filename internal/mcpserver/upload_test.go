@@ -0,0 +1,100 @@
+package mcpserver
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sigumaa/yururi/internal/config"
+	"github.com/sigumaa/yururi/internal/discordx"
+)
+
+func TestReadUpload(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	workspace := filepath.Join(root, "workspace")
+	mustWrite := func(path string, data string) {
+		t.Helper()
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("MkdirAll() error = %v", err)
+		}
+		if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+			t.Fatalf("WriteFile() error = %v", err)
+		}
+	}
+	mustWrite(filepath.Join(workspace, "out", "chart.png"), "\x89PNG\r\n\x1a\nrest")
+	mustWrite(filepath.Join(workspace, "out", "data.json"), `{"a":1}`)
+	mustWrite(filepath.Join(workspace, "big.txt"), strings.Repeat("x", 33))
+	mustWrite(filepath.Join(root, "secret.txt"), "secret")
+	if err := os.Symlink(filepath.Join(root, "secret.txt"), filepath.Join(workspace, "link.txt")); err != nil {
+		t.Fatalf("Symlink() error = %v", err)
+	}
+	cfg := uploadConfig{workspaceDir: workspace, maxBytes: 32}
+
+	tests := []struct {
+		name     string
+		cfg      uploadConfig
+		path     string
+		filename string
+		wantName string
+		wantType string
+		wantErr  string
+	}{
+		{name: "sniffed", cfg: cfg, path: "out/chart.png", wantName: "chart.png", wantType: "image/png"},
+		{name: "extension fallback", cfg: cfg, path: filepath.Join(workspace, "out", "data.json"), filename: "result.json", wantName: "result.json", wantType: "application/json"},
+		{name: "too large", cfg: cfg, path: "big.txt", wantErr: "max 32"},
+		{name: "parent escape", cfg: cfg, path: "../secret.txt", wantErr: "outside the workspace"},
+		{name: "symlink escape", cfg: cfg, path: "link.txt", wantErr: "outside the workspace"},
+		{name: "directory", cfg: cfg, path: "out", wantErr: "not a regular file"},
+		{name: "missing", cfg: cfg, path: "none.txt", wantErr: "resolve path"},
+		{name: "not configured", path: "out/chart.png", wantErr: "not configured"},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := readUpload(tc.cfg, tc.path, tc.filename)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("readUpload() error = %v, want %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("readUpload() error = %v", err)
+			}
+			if got.Name != tc.wantName || got.ContentType != tc.wantType || len(got.Data) == 0 {
+				t.Fatalf("readUpload() = {%q %q %d bytes}, want {%q %q}", got.Name, got.ContentType, len(got.Data), tc.wantName, tc.wantType)
+			}
+		})
+	}
+}
+
+func TestHandleUploadFileRequiresWritableChannel(t *testing.T) {
+	t.Parallel()
+
+	workspace := t.TempDir()
+	if err := os.WriteFile(filepath.Join(workspace, "a.txt"), []byte("hello"), 0o644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	gateway := discordx.NewGateway(nil, config.DiscordConfig{
+		GuildID:         "g1",
+		ReadChannelIDs:  []string{"c-read"},
+		WriteChannelIDs: []string{"c-write"},
+	})
+	srv, err := New("127.0.0.1:39393", "Asia/Tokyo", gateway, nil, allowAllPolicy())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	srv.EnableUploads(workspace, 1024)
+
+	if _, _, err := srv.handleUploadFile(context.Background(), nil, UploadFileArgs{ChannelID: "c-read", Path: "a.txt"}); err == nil {
+		t.Fatal("handleUploadFile(read-only channel) error = nil, want error")
+	}
+	if _, _, err := srv.handleUploadFile(context.Background(), nil, UploadFileArgs{ChannelID: "c-write", Path: "../a.txt"}); err == nil {
+		t.Fatal("handleUploadFile(outside workspace) error = nil, want error")
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"log/slog"
 	"strings"
 	"sync"
 	"time"
@@ -14,21 +15,51 @@ import (
 type SessionState struct {
 	ThreadID   string
 	LastTurnID string
+	CreatedAt  time.Time
 	UpdatedAt  time.Time
+	TurnCount  int
+
+	restored bool
+}
+
+type RotationPolicy struct {
+	IdleTimeout       time.Duration
+	MaxTurns          int
+	MaxAge            time.Duration
+	DailyRolloverHour int
+	RolloverLocation  *time.Location
 }
 
 type Runtime interface {
 	StartThread(ctx context.Context, input codex.TurnInput) (string, error)
-	StartTurn(ctx context.Context, threadID string, prompt string) (codex.TurnResult, error)
-	SteerTurn(ctx context.Context, threadID string, expectedTurnID string, prompt string) (codex.TurnResult, error)
+	ResumeThread(ctx context.Context, threadID string, input codex.TurnInput) (string, error)
+	StartTurn(ctx context.Context, threadID string, input codex.TurnInput) (codex.TurnResult, error)
+	SteerTurn(ctx context.Context, threadID string, expectedTurnID string, input codex.TurnInput) (codex.TurnResult, error)
+	AppendTurnInput(ctx context.Context, threadID string, turnID string, prompt string) error
 }
 
 type Coordinator struct {
-	runtime Runtime
-	now     func() time.Time
+	runtime    Runtime
+	now        func() time.Time
+	store      SessionStore
+	sessionTTL time.Duration
+	rotation   RotationPolicy
+	usage      *UsageLedger
+	budget     UsageBudget
+	overrides  OverrideResolver
 
 	mu       sync.Mutex
 	sessions map[string]SessionState
+	active   map[string]*activeRun
+	notes    map[string][]string
+}
+
+// activeRun is a turn in progress. threadID and turnID are known once the
+// runtime reports that the turn started.
+type activeRun struct {
+	cancel   context.CancelFunc
+	threadID string
+	turnID   string
 }
 
 type Option func(*Coordinator)
@@ -41,11 +72,27 @@ func WithClock(now func() time.Time) Option {
 	}
 }
 
+func WithSessionStore(store SessionStore, ttl time.Duration) Option {
+	return func(c *Coordinator) {
+		c.store = store
+		c.sessionTTL = ttl
+	}
+}
+
+func WithRotationPolicy(policy RotationPolicy) Option {
+	return func(c *Coordinator) {
+		c.rotation = policy
+	}
+}
+
 func New(runtime Runtime, opts ...Option) *Coordinator {
 	c := &Coordinator{
 		runtime:  runtime,
 		now:      time.Now,
 		sessions: map[string]SessionState{},
+		active:   map[string]*activeRun{},
+		notes:    map[string][]string{},
+		rotation: RotationPolicy{DailyRolloverHour: -1},
 	}
 	for _, opt := range opts {
 		if opt != nil {
@@ -64,48 +111,134 @@ func (c *Coordinator) RunMessageTurn(ctx context.Context, channelKey string, inp
 		return codex.TurnResult{}, errors.New("runtime is required")
 	}
 
+	ctx, done := c.trackRun(ctx, key)
+	defer done()
+	input.OnEvent = c.observeActiveTurn(key, input.OnEvent)
+
+	input, err := c.AdmitTurn(key, c.applyOverride(key, input))
+	if err != nil {
+		return codex.TurnResult{}, err
+	}
+	notes := c.takeNotes(key)
+	input.UserPrompt = withNotes(notes, input.UserPrompt)
+	result, err := c.runMessageTurn(ctx, key, input)
+	c.RecordUsage(key, result)
+	if err != nil {
+		c.restoreNotes(key, notes)
+		return codex.TurnResult{}, err
+	}
+	return result, nil
+}
+
+func (c *Coordinator) runMessageTurn(ctx context.Context, key string, input codex.TurnInput) (codex.TurnResult, error) {
 	session, hasSession := c.session(key)
 	if !hasSession || strings.TrimSpace(session.ThreadID) == "" {
 		return c.startNewThreadTurn(ctx, key, input)
 	}
+	if reason := c.rotation.rotationReason(session, c.now()); reason != "" {
+		slog.Info("session_rotated", "session_key", key, "thread", session.ThreadID, "reason", reason, "turns", session.TurnCount, "idle_sec", secondsSince(c.now(), session.UpdatedAt), "age_sec", secondsSince(c.now(), session.CreatedAt))
+		return c.startNewThreadTurn(ctx, key, input)
+	}
 
 	threadID := strings.TrimSpace(session.ThreadID)
 	lastTurnID := strings.TrimSpace(session.LastTurnID)
+	if session.restored {
+		resumedThreadID, err := c.runtime.ResumeThread(ctx, threadID, input)
+		if err != nil {
+			if ctx.Err() != nil {
+				return codex.TurnResult{}, err
+			}
+			slog.Error("session_resume_failed", "session_key", key, "thread", threadID, "err", err)
+			return c.startNewThreadTurn(ctx, key, input)
+		}
+		if strings.TrimSpace(resumedThreadID) != "" {
+			threadID = strings.TrimSpace(resumedThreadID)
+		}
+		c.markResumed(key, threadID)
+		lastTurnID = ""
+	}
 	if lastTurnID == "" {
-		result, err := c.runtime.StartTurn(ctx, threadID, input.UserPrompt)
+		result, err := c.runtime.StartTurn(ctx, threadID, input)
 		if err == nil {
-			c.storeSession(key, withThreadFallback(result, threadID))
+			c.storeSession(key, withThreadFallback(result, threadID), true)
 			return withThreadFallback(result, threadID), nil
 		}
+		if ctx.Err() != nil {
+			return result, err
+		}
 
 		fallback, fallbackErr := c.startNewThreadTurn(ctx, key, input)
+		fallback = withFailedUsage(fallback, result)
 		if fallbackErr != nil {
-			return codex.TurnResult{}, fmt.Errorf("start turn in existing thread failed: %w", errors.Join(err, fallbackErr))
+			return fallback, fmt.Errorf("start turn in existing thread failed: %w", errors.Join(err, fallbackErr))
 		}
 		return fallback, nil
 	}
 
-	steerResult, steerErr := c.runtime.SteerTurn(ctx, threadID, lastTurnID, input.UserPrompt)
+	steerResult, steerErr := c.runtime.SteerTurn(ctx, threadID, lastTurnID, input)
 	if steerErr == nil {
 		result := withThreadFallback(steerResult, threadID)
-		c.storeSession(key, result)
+		c.storeSession(key, result, false)
 		return result, nil
 	}
+	if ctx.Err() != nil {
+		return steerResult, steerErr
+	}
 
-	startResult, startErr := c.runtime.StartTurn(ctx, threadID, input.UserPrompt)
+	startResult, startErr := c.runtime.StartTurn(ctx, threadID, input)
+	startResult = withFailedUsage(startResult, steerResult)
 	if startErr == nil {
 		result := withThreadFallback(startResult, threadID)
-		c.storeSession(key, result)
+		c.storeSession(key, result, true)
 		return result, nil
 	}
+	if ctx.Err() != nil {
+		return startResult, startErr
+	}
 
 	fallbackResult, fallbackErr := c.startNewThreadTurn(ctx, key, input)
+	fallbackResult = withFailedUsage(fallbackResult, startResult)
 	if fallbackErr != nil {
-		return codex.TurnResult{}, fmt.Errorf("turn recovery failed: %w", errors.Join(steerErr, startErr, fallbackErr))
+		return fallbackResult, fmt.Errorf("turn recovery failed: %w", errors.Join(steerErr, startErr, fallbackErr))
 	}
 	return fallbackResult, nil
 }
 
+// withFailedUsage adds the tokens a failed attempt already consumed to the
+// result of the attempt that replaced it.
+func withFailedUsage(result codex.TurnResult, failed codex.TurnResult) codex.TurnResult {
+	result.Usage = result.Usage.Add(failed.Usage)
+	return result
+}
+
+// CancelChannel cancels the turn currently running for channelKey. The
+// runtime interrupts the turn; it reports whether a turn was running.
+func (c *Coordinator) CancelChannel(channelKey string) bool {
+	key := strings.TrimSpace(channelKey)
+	if key == "" {
+		return false
+	}
+
+	c.mu.Lock()
+	run, ok := c.active[key]
+	c.mu.Unlock()
+	if !ok {
+		return false
+	}
+	run.cancel()
+	slog.Info("channel_turn_cancel_requested", "session_key", key)
+	return true
+}
+
+// Running reports whether a turn is running for channelKey.
+func (c *Coordinator) Running(channelKey string) bool {
+	key := strings.TrimSpace(channelKey)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, ok := c.active[key]
+	return ok
+}
+
 func (c *Coordinator) Session(channelKey string) (SessionState, bool) {
 	key := strings.TrimSpace(channelKey)
 	if key == "" {
@@ -127,9 +260,48 @@ func (c *Coordinator) ResetSession(channelKey string) bool {
 		return false
 	}
 	delete(c.sessions, key)
+	if c.store != nil {
+		if err := c.store.Delete(key); err != nil {
+			slog.Error("session_store_delete_failed", "session_key", key, "err", err)
+		}
+	}
 	return true
 }
 
+func (c *Coordinator) RestoreSessions() (int, error) {
+	if c.store == nil {
+		return 0, nil
+	}
+	loaded, err := c.store.Load()
+	if err != nil {
+		return 0, err
+	}
+
+	now := c.now().UTC()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	restored := 0
+	for key, state := range loaded {
+		if strings.TrimSpace(state.ThreadID) == "" {
+			continue
+		}
+		if c.sessionTTL > 0 && now.Sub(state.UpdatedAt) > c.sessionTTL {
+			if err := c.store.Delete(key); err != nil {
+				slog.Error("session_store_delete_failed", "session_key", key, "err", err)
+			}
+			continue
+		}
+		if _, exists := c.sessions[key]; exists {
+			continue
+		}
+		state.restored = true
+		c.sessions[key] = state
+		restored++
+	}
+	return restored, nil
+}
+
 func ChannelKey(guildID string, channelID string) string {
 	guildID = strings.TrimSpace(guildID)
 	channelID = strings.TrimSpace(channelID)
@@ -142,22 +314,52 @@ func ChannelKey(guildID string, channelID string) string {
 	return guildID + ":" + channelID
 }
 
+// DMChannelKey is the session key of a direct message channel. DM sessions
+// never share a thread with guild channels.
+func DMChannelKey(channelID string) string {
+	channelID = strings.TrimSpace(channelID)
+	if channelID == "" {
+		channelID = "nochannel"
+	}
+	return dmKeyPrefix + ":" + channelID
+}
+
+func (c *Coordinator) trackRun(ctx context.Context, channelKey string) (context.Context, func()) {
+	runCtx, cancel := context.WithCancel(ctx)
+	run := &activeRun{cancel: cancel}
+
+	c.mu.Lock()
+	c.active[channelKey] = run
+	c.mu.Unlock()
+
+	return runCtx, func() {
+		cancel()
+		c.mu.Lock()
+		if c.active[channelKey] == run {
+			delete(c.active, channelKey)
+		}
+		c.mu.Unlock()
+	}
+}
+
 func (c *Coordinator) startNewThreadTurn(ctx context.Context, channelKey string, input codex.TurnInput) (codex.TurnResult, error) {
 	threadID, err := c.runtime.StartThread(ctx, input)
 	if err != nil {
 		return codex.TurnResult{}, err
 	}
 
-	result, err := c.runtime.StartTurn(ctx, threadID, input.UserPrompt)
+	result, err := c.runtime.StartTurn(ctx, threadID, input)
 	if err != nil {
-		return codex.TurnResult{}, err
+		return result, err
 	}
 	result = withThreadFallback(result, threadID)
-	c.storeSession(channelKey, result)
+	c.storeSession(channelKey, result, true)
 	return result, nil
 }
 
-func (c *Coordinator) storeSession(channelKey string, result codex.TurnResult) {
+// storeSession records the turn's thread. Only a turn/start counts towards
+// TurnCount; a turn/steer adds input to a turn that was already counted.
+func (c *Coordinator) storeSession(channelKey string, result codex.TurnResult, startedTurn bool) {
 	threadID := strings.TrimSpace(result.ThreadID)
 	lastTurnID := strings.TrimSpace(result.TurnID)
 	if threadID == "" && lastTurnID == "" {
@@ -175,11 +377,80 @@ func (c *Coordinator) storeSession(channelKey string, result codex.TurnResult) {
 		lastTurnID = prev.LastTurnID
 	}
 
-	c.sessions[channelKey] = SessionState{
+	now := c.now().UTC()
+	createdAt := prev.CreatedAt
+	turnCount := prev.TurnCount
+	if startedTurn {
+		turnCount++
+	}
+	if threadID != prev.ThreadID || createdAt.IsZero() {
+		createdAt = now
+		turnCount = 1
+	}
+	state := SessionState{
 		ThreadID:   threadID,
 		LastTurnID: lastTurnID,
-		UpdatedAt:  c.now().UTC(),
+		CreatedAt:  createdAt,
+		UpdatedAt:  now,
+		TurnCount:  turnCount,
+	}
+	c.sessions[channelKey] = state
+	if c.store != nil {
+		if err := c.store.Save(channelKey, state); err != nil {
+			slog.Error("session_store_save_failed", "session_key", channelKey, "thread", threadID, "err", err)
+		}
+	}
+}
+
+func (c *Coordinator) markResumed(channelKey string, threadID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	session, ok := c.sessions[channelKey]
+	if !ok {
+		return
+	}
+	session.ThreadID = threadID
+	session.LastTurnID = ""
+	session.restored = false
+	c.sessions[channelKey] = session
+}
+
+func (p RotationPolicy) rotationReason(session SessionState, now time.Time) string {
+	if p.IdleTimeout > 0 && !session.UpdatedAt.IsZero() && now.Sub(session.UpdatedAt) > p.IdleTimeout {
+		return "idle_timeout"
+	}
+	if p.MaxTurns > 0 && session.TurnCount >= p.MaxTurns {
+		return "max_turns"
+	}
+	if p.MaxAge > 0 && !session.CreatedAt.IsZero() && now.Sub(session.CreatedAt) > p.MaxAge {
+		return "max_age"
+	}
+	if p.DailyRolloverHour >= 0 && p.DailyRolloverHour <= 23 && !session.CreatedAt.IsZero() {
+		if session.CreatedAt.Before(lastRollover(now, p.DailyRolloverHour, p.RolloverLocation)) {
+			return "daily_rollover"
+		}
+	}
+	return ""
+}
+
+func lastRollover(now time.Time, hour int, loc *time.Location) time.Time {
+	if loc == nil {
+		loc = time.UTC
+	}
+	local := now.In(loc)
+	boundary := time.Date(local.Year(), local.Month(), local.Day(), hour, 0, 0, 0, loc)
+	if local.Before(boundary) {
+		boundary = boundary.AddDate(0, 0, -1)
+	}
+	return boundary
+}
+
+func secondsSince(now time.Time, then time.Time) int64 {
+	if then.IsZero() || now.Before(then) {
+		return 0
 	}
+	return int64(now.Sub(then) / time.Second)
 }
 
 func (c *Coordinator) session(channelKey string) (SessionState, bool) {
@@ -3,6 +3,7 @@ package orchestrator
 import (
 	"context"
 	"errors"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -94,6 +95,9 @@ func TestCoordinatorReusesSessionWithSteerTurn(t *testing.T) {
 	if !session.UpdatedAt.Equal(timestamps[1]) {
 		t.Fatalf("session updated_at = %s, want %s", session.UpdatedAt, timestamps[1])
 	}
+	if session.TurnCount != 1 {
+		t.Fatalf("session turn count = %d, want 1 after a steer", session.TurnCount)
+	}
 }
 
 func TestCoordinatorSteerFallbackToStartTurnSameThread(t *testing.T) {
@@ -265,14 +269,251 @@ func TestCoordinatorResetSessionWithMissingKeyReturnsFalse(t *testing.T) {
 	}
 }
 
-type runtimeStub struct {
-	startThreadResults []threadResult
-	startTurnResults   []turnResult
-	steerTurnResults   []turnResult
+func TestCoordinatorRestoresPersistedSessionAndResumesThread(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	store := NewFileSessionStore(filepath.Join(t.TempDir(), "sessions.json"))
+	if err := store.Save("g1:c1", SessionState{ThreadID: "thread-1", LastTurnID: "turn-1", UpdatedAt: now.Add(-time.Hour)}); err != nil {
+		t.Fatalf("Save(g1:c1) error = %v", err)
+	}
+	if err := store.Save("g1:stale", SessionState{ThreadID: "thread-old", UpdatedAt: now.Add(-48 * time.Hour)}); err != nil {
+		t.Fatalf("Save(g1:stale) error = %v", err)
+	}
+
+	stub := &runtimeStub{
+		resumeThreadResults: []threadResult{
+			{threadID: "thread-1"},
+		},
+		startTurnResults: []turnResult{
+			{result: codex.TurnResult{TurnID: "turn-2", Status: "completed"}},
+		},
+	}
+	coordinator := New(stub, WithClock(func() time.Time { return now }), WithSessionStore(store, 24*time.Hour))
+
+	restored, err := coordinator.RestoreSessions()
+	if err != nil {
+		t.Fatalf("RestoreSessions() error = %v", err)
+	}
+	if restored != 1 {
+		t.Fatalf("RestoreSessions() = %d, want 1", restored)
+	}
+	if _, ok := coordinator.Session("g1:stale"); ok {
+		t.Fatal("stale session should not be restored")
+	}
+
+	got, err := coordinator.RunMessageTurn(context.Background(), "g1:c1", codex.TurnInput{
+		BaseInstructions:      "base",
+		DeveloperInstructions: "dev",
+		UserPrompt:            "after restart",
+	})
+	if err != nil {
+		t.Fatalf("RunMessageTurn() error = %v", err)
+	}
+	if got.ThreadID != "thread-1" || got.TurnID != "turn-2" {
+		t.Fatalf("RunMessageTurn() = %#v, want thread-1/turn-2", got)
+	}
+	if got := len(stub.resumeThreadCalls); got != 1 {
+		t.Fatalf("resumeThread calls = %d, want 1", got)
+	}
+	if got := len(stub.steerTurnCalls); got != 0 {
+		t.Fatalf("steerTurn calls = %d, want 0 after resume", got)
+	}
+	if got := len(stub.startThreadCalls); got != 0 {
+		t.Fatalf("startThread calls = %d, want 0", got)
+	}
 
-	startThreadCalls []codex.TurnInput
-	startTurnCalls   []startTurnCall
-	steerTurnCalls   []steerTurnCall
+	persisted, err := NewFileSessionStore(store.Path()).Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if persisted["g1:c1"].LastTurnID != "turn-2" {
+		t.Fatalf("persisted last turn id = %q, want turn-2", persisted["g1:c1"].LastTurnID)
+	}
+	if _, ok := persisted["g1:stale"]; ok {
+		t.Fatal("stale session should be pruned from store")
+	}
+}
+
+func TestCoordinatorResumeFailureStartsNewThread(t *testing.T) {
+	t.Parallel()
+
+	store := NewFileSessionStore(filepath.Join(t.TempDir(), "sessions.json"))
+	if err := store.Save("g1:c1", SessionState{ThreadID: "thread-1", UpdatedAt: time.Now().UTC()}); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	stub := &runtimeStub{
+		resumeThreadResults: []threadResult{
+			{err: errors.New("thread not found")},
+		},
+		startThreadResults: []threadResult{
+			{threadID: "thread-2"},
+		},
+		startTurnResults: []turnResult{
+			{result: codex.TurnResult{TurnID: "turn-1"}},
+		},
+	}
+	coordinator := New(stub, WithSessionStore(store, time.Hour))
+	if _, err := coordinator.RestoreSessions(); err != nil {
+		t.Fatalf("RestoreSessions() error = %v", err)
+	}
+
+	got, err := coordinator.RunMessageTurn(context.Background(), "g1:c1", codex.TurnInput{UserPrompt: "hello"})
+	if err != nil {
+		t.Fatalf("RunMessageTurn() error = %v", err)
+	}
+	if got.ThreadID != "thread-2" {
+		t.Fatalf("thread id = %q, want thread-2", got.ThreadID)
+	}
+}
+
+func TestRotationPolicyRotationReason(t *testing.T) {
+	t.Parallel()
+
+	tokyo := time.FixedZone("JST", 9*60*60)
+	now := time.Date(2026, 3, 2, 5, 0, 0, 0, tokyo)
+	fresh := SessionState{ThreadID: "thread-1", CreatedAt: now.Add(-10 * time.Minute), UpdatedAt: now.Add(-time.Minute), TurnCount: 3}
+
+	tests := []struct {
+		name    string
+		policy  RotationPolicy
+		session SessionState
+		want    string
+	}{
+		{name: "disabled", policy: RotationPolicy{DailyRolloverHour: -1}, session: fresh, want: ""},
+		{name: "idle timeout", policy: RotationPolicy{IdleTimeout: 30 * time.Second, DailyRolloverHour: -1}, session: fresh, want: "idle_timeout"},
+		{name: "max turns", policy: RotationPolicy{MaxTurns: 3, DailyRolloverHour: -1}, session: fresh, want: "max_turns"},
+		{name: "max age", policy: RotationPolicy{MaxAge: 5 * time.Minute, DailyRolloverHour: -1}, session: fresh, want: "max_age"},
+		{name: "rollover passed", policy: RotationPolicy{DailyRolloverHour: 5, RolloverLocation: tokyo}, session: SessionState{CreatedAt: now.Add(-time.Second), UpdatedAt: now}, want: "daily_rollover"},
+		{name: "rollover not reached", policy: RotationPolicy{DailyRolloverHour: 6, RolloverLocation: tokyo}, session: fresh, want: ""},
+		{name: "rollover previous day", policy: RotationPolicy{DailyRolloverHour: 6, RolloverLocation: tokyo}, session: SessionState{CreatedAt: now.Add(-24 * time.Hour), UpdatedAt: now}, want: "daily_rollover"},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			if got := tc.policy.rotationReason(tc.session, now); got != tc.want {
+				t.Fatalf("rotationReason() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestCoordinatorRotatesIdleSession(t *testing.T) {
+	t.Parallel()
+
+	stub := &runtimeStub{
+		startThreadResults: []threadResult{
+			{threadID: "thread-1"},
+			{threadID: "thread-2"},
+		},
+		startTurnResults: []turnResult{
+			{result: codex.TurnResult{TurnID: "turn-1"}},
+			{result: codex.TurnResult{TurnID: "turn-2"}},
+		},
+	}
+	now := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	coordinator := New(stub,
+		WithClock(func() time.Time { return now }),
+		WithRotationPolicy(RotationPolicy{IdleTimeout: 30 * time.Minute, DailyRolloverHour: -1}),
+	)
+
+	if _, err := coordinator.RunMessageTurn(context.Background(), "g1:c1", codex.TurnInput{UserPrompt: "first"}); err != nil {
+		t.Fatalf("first RunMessageTurn() error = %v", err)
+	}
+	now = now.Add(2 * time.Hour)
+	got, err := coordinator.RunMessageTurn(context.Background(), "g1:c1", codex.TurnInput{UserPrompt: "second"})
+	if err != nil {
+		t.Fatalf("second RunMessageTurn() error = %v", err)
+	}
+	if got.ThreadID != "thread-2" {
+		t.Fatalf("thread id = %q, want thread-2", got.ThreadID)
+	}
+	if got := len(stub.steerTurnCalls); got != 0 {
+		t.Fatalf("steerTurn calls = %d, want 0", got)
+	}
+	session, _ := coordinator.Session("g1:c1")
+	if session.TurnCount != 1 || !session.CreatedAt.Equal(now) {
+		t.Fatalf("rotated session = %#v, want fresh turn count and created_at", session)
+	}
+}
+
+func TestCoordinatorCancelChannelStopsRunningTurn(t *testing.T) {
+	t.Parallel()
+
+	runtime := &blockingRuntime{started: make(chan struct{})}
+	coordinator := New(runtime)
+	key := ChannelKey("g1", "c1")
+
+	if coordinator.CancelChannel(key) {
+		t.Fatal("CancelChannel() = true with no running turn, want false")
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		_, err := coordinator.RunMessageTurn(context.Background(), key, codex.TurnInput{UserPrompt: "hello"})
+		errCh <- err
+	}()
+	<-runtime.started
+	if !coordinator.CancelChannel(key) {
+		t.Fatal("CancelChannel() = false, want true while a turn is running")
+	}
+
+	select {
+	case err := <-errCh:
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("RunMessageTurn() error = %v, want context.Canceled", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("RunMessageTurn() did not return after CancelChannel")
+	}
+	if runtime.startThreadCalls != 1 {
+		t.Fatalf("StartThread calls = %d, want 1 (no fallback after cancel)", runtime.startThreadCalls)
+	}
+	if coordinator.CancelChannel(key) {
+		t.Fatal("CancelChannel() = true after the turn ended, want false")
+	}
+}
+
+type blockingRuntime struct {
+	started          chan struct{}
+	startThreadCalls int
+}
+
+func (r *blockingRuntime) StartThread(_ context.Context, _ codex.TurnInput) (string, error) {
+	r.startThreadCalls++
+	return "thread-1", nil
+}
+
+func (r *blockingRuntime) ResumeThread(_ context.Context, threadID string, _ codex.TurnInput) (string, error) {
+	return threadID, nil
+}
+
+func (r *blockingRuntime) StartTurn(ctx context.Context, _ string, _ codex.TurnInput) (codex.TurnResult, error) {
+	close(r.started)
+	<-ctx.Done()
+	return codex.TurnResult{}, ctx.Err()
+}
+
+func (r *blockingRuntime) SteerTurn(ctx context.Context, _ string, _ string, _ codex.TurnInput) (codex.TurnResult, error) {
+	return codex.TurnResult{}, errors.New("unexpected SteerTurn call")
+}
+
+func (r *blockingRuntime) AppendTurnInput(context.Context, string, string, string) error {
+	return errors.New("unexpected AppendTurnInput call")
+}
+
+type runtimeStub struct {
+	startThreadResults  []threadResult
+	resumeThreadResults []threadResult
+	startTurnResults    []turnResult
+	steerTurnResults    []turnResult
+
+	startThreadCalls  []codex.TurnInput
+	resumeThreadCalls []string
+	startTurnCalls    []startTurnCall
+	steerTurnCalls    []steerTurnCall
 }
 
 type startTurnCall struct {
@@ -306,8 +547,18 @@ func (s *runtimeStub) StartThread(_ context.Context, input codex.TurnInput) (str
 	return current.threadID, current.err
 }
 
-func (s *runtimeStub) StartTurn(_ context.Context, threadID string, prompt string) (codex.TurnResult, error) {
-	s.startTurnCalls = append(s.startTurnCalls, startTurnCall{ThreadID: threadID, Prompt: prompt})
+func (s *runtimeStub) ResumeThread(_ context.Context, threadID string, _ codex.TurnInput) (string, error) {
+	s.resumeThreadCalls = append(s.resumeThreadCalls, threadID)
+	if len(s.resumeThreadResults) == 0 {
+		return "", errors.New("unexpected ResumeThread call")
+	}
+	current := s.resumeThreadResults[0]
+	s.resumeThreadResults = s.resumeThreadResults[1:]
+	return current.threadID, current.err
+}
+
+func (s *runtimeStub) StartTurn(_ context.Context, threadID string, input codex.TurnInput) (codex.TurnResult, error) {
+	s.startTurnCalls = append(s.startTurnCalls, startTurnCall{ThreadID: threadID, Prompt: input.UserPrompt})
 	if len(s.startTurnResults) == 0 {
 		return codex.TurnResult{}, errors.New("unexpected StartTurn call")
 	}
@@ -316,11 +567,11 @@ func (s *runtimeStub) StartTurn(_ context.Context, threadID string, prompt strin
 	return current.result, current.err
 }
 
-func (s *runtimeStub) SteerTurn(_ context.Context, threadID string, expectedTurnID string, prompt string) (codex.TurnResult, error) {
+func (s *runtimeStub) SteerTurn(_ context.Context, threadID string, expectedTurnID string, input codex.TurnInput) (codex.TurnResult, error) {
 	s.steerTurnCalls = append(s.steerTurnCalls, steerTurnCall{
 		ThreadID:       threadID,
 		ExpectedTurnID: expectedTurnID,
-		Prompt:         prompt,
+		Prompt:         input.UserPrompt,
 	})
 	if len(s.steerTurnResults) == 0 {
 		return codex.TurnResult{}, errors.New("unexpected SteerTurn call")
@@ -329,3 +580,7 @@ func (s *runtimeStub) SteerTurn(_ context.Context, threadID string, expectedTurn
 	s.steerTurnResults = s.steerTurnResults[1:]
 	return current.result, current.err
 }
+
+func (s *runtimeStub) AppendTurnInput(context.Context, string, string, string) error {
+	return errors.New("unexpected AppendTurnInput call")
+}
@@ -0,0 +1,67 @@
+package orchestrator
+
+import (
+	"strings"
+
+	"github.com/sigumaa/yururi/internal/codex"
+	"github.com/sigumaa/yururi/internal/config"
+)
+
+// ChannelOverride replaces codex settings for the threads of one channel.
+// Empty fields keep the client defaults.
+type ChannelOverride struct {
+	Model           string
+	ReasoningEffort string
+	Sandbox         string
+	MCPServers      map[string]config.CodexMCPServerConfig
+}
+
+// OverrideResolver returns the override for a channel key.
+type OverrideResolver func(channelKey string) ChannelOverride
+
+func WithChannelOverrides(resolve OverrideResolver) Option {
+	return func(c *Coordinator) {
+		c.overrides = resolve
+	}
+}
+
+const dmKeyPrefix = "dm"
+
+// ParseChannelKey splits a key built by ChannelKey or DMChannelKey.
+// Placeholder parts and the DM prefix come back empty.
+func ParseChannelKey(channelKey string) (string, string) {
+	guildID, channelID, ok := strings.Cut(strings.TrimSpace(channelKey), ":")
+	if !ok {
+		return "", ""
+	}
+	if guildID == "noguild" || guildID == dmKeyPrefix {
+		guildID = ""
+	}
+	if channelID == "nochannel" {
+		channelID = ""
+	}
+	return guildID, channelID
+}
+
+// applyOverride applies the channel's override. Its non-empty fields win
+// over the caller's, so a per-turn choice such as model routing only fills
+// what the channel leaves open.
+func (c *Coordinator) applyOverride(channelKey string, input codex.TurnInput) codex.TurnInput {
+	if c.overrides == nil {
+		return input
+	}
+	override := c.overrides(channelKey)
+	if strings.TrimSpace(override.Model) != "" {
+		input.Model = override.Model
+	}
+	if strings.TrimSpace(override.ReasoningEffort) != "" {
+		input.ReasoningEffort = override.ReasoningEffort
+	}
+	if strings.TrimSpace(override.Sandbox) != "" {
+		input.Sandbox = override.Sandbox
+	}
+	if len(override.MCPServers) > 0 {
+		input.MCPServers = override.MCPServers
+	}
+	return input
+}
@@ -0,0 +1,92 @@
+package orchestrator
+
+import (
+	"context"
+	"testing"
+
+	"github.com/sigumaa/yururi/internal/codex"
+	"github.com/sigumaa/yururi/internal/config"
+)
+
+func TestCoordinatorAppliesChannelOverrideToThreadStart(t *testing.T) {
+	t.Parallel()
+
+	stub := &runtimeStub{
+		startThreadResults: []threadResult{{threadID: "thread-1"}},
+		startTurnResults:   []turnResult{{result: codex.TurnResult{TurnID: "turn-1"}}},
+	}
+	var resolvedKey string
+	coordinator := New(stub, WithChannelOverrides(func(channelKey string) ChannelOverride {
+		resolvedKey = channelKey
+		return ChannelOverride{
+			Model:           "gpt-5.3-codex-mini",
+			ReasoningEffort: "low",
+			Sandbox:         config.SandboxReadOnly,
+			MCPServers:      map[string]config.CodexMCPServerConfig{"notes": {URL: "http://127.0.0.1:40000/mcp"}},
+		}
+	}))
+
+	input := codex.TurnInput{UserPrompt: "hi", ReasoningEffort: "high"}
+	if _, err := coordinator.RunMessageTurn(context.Background(), "g1:c1", input); err != nil {
+		t.Fatalf("RunMessageTurn() error = %v", err)
+	}
+	if resolvedKey != "g1:c1" {
+		t.Fatalf("resolver key = %q, want g1:c1", resolvedKey)
+	}
+	if len(stub.startThreadCalls) != 1 {
+		t.Fatalf("startThreadCalls = %d, want 1", len(stub.startThreadCalls))
+	}
+	got := stub.startThreadCalls[0]
+	if got.Model != "gpt-5.3-codex-mini" || got.Sandbox != config.SandboxReadOnly {
+		t.Fatalf("thread input = %+v", got)
+	}
+	if got.ReasoningEffort != "low" {
+		t.Fatalf("ReasoningEffort = %q, want override value low over the caller's high", got.ReasoningEffort)
+	}
+	if _, ok := got.MCPServers["notes"]; !ok {
+		t.Fatalf("MCPServers = %#v, want notes", got.MCPServers)
+	}
+}
+
+func TestCoordinatorRoutedTierFillsFieldsOverrideLeavesEmpty(t *testing.T) {
+	t.Parallel()
+
+	stub := &runtimeStub{
+		startThreadResults: []threadResult{{threadID: "thread-1"}},
+		startTurnResults:   []turnResult{{result: codex.TurnResult{TurnID: "turn-1"}}},
+	}
+	coordinator := New(stub, WithChannelOverrides(func(string) ChannelOverride {
+		return ChannelOverride{Model: "gpt-5.3-codex-research"}
+	}))
+
+	input := codex.TurnInput{UserPrompt: "hi", Model: "gpt-5.3-codex-mini", ReasoningEffort: "minimal"}
+	if _, err := coordinator.RunMessageTurn(context.Background(), "g1:c1", input); err != nil {
+		t.Fatalf("RunMessageTurn() error = %v", err)
+	}
+	got := stub.startThreadCalls[0]
+	if got.Model != "gpt-5.3-codex-research" || got.ReasoningEffort != "minimal" {
+		t.Fatalf("thread model/effort = %q/%q, want override model and routed effort", got.Model, got.ReasoningEffort)
+	}
+}
+
+func TestParseChannelKey(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		key         string
+		wantGuild   string
+		wantChannel string
+	}{
+		{key: "g1:c1", wantGuild: "g1", wantChannel: "c1"},
+		{key: ChannelKey("", "c1"), wantChannel: "c1"},
+		{key: ChannelKey("g1", ""), wantGuild: "g1"},
+		{key: DMChannelKey("d1"), wantChannel: "d1"},
+		{key: "heartbeat"},
+	}
+	for _, tc := range tests {
+		guildID, channelID := ParseChannelKey(tc.key)
+		if guildID != tc.wantGuild || channelID != tc.wantChannel {
+			t.Fatalf("ParseChannelKey(%q) = %q, %q, want %q, %q", tc.key, guildID, channelID, tc.wantGuild, tc.wantChannel)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package orchestrator
+
+import (
+	"context"
+	"strings"
+
+	"github.com/sigumaa/yururi/internal/codex"
+)
+
+const (
+	maxPendingNotes    = 20
+	pendingNotesHeader = "## 前回のターン以降の出来事"
+)
+
+// observeActiveTurn wraps onEvent to remember the running turn of channelKey
+// so that SteerActiveTurn can reach it.
+func (c *Coordinator) observeActiveTurn(channelKey string, onEvent codex.StreamHandler) codex.StreamHandler {
+	return func(event codex.TurnEvent) {
+		if event.Kind == codex.TurnEventStarted && strings.TrimSpace(event.TurnID) != "" {
+			c.mu.Lock()
+			if run := c.active[channelKey]; run != nil {
+				run.threadID = strings.TrimSpace(event.ThreadID)
+				run.turnID = strings.TrimSpace(event.TurnID)
+			}
+			c.mu.Unlock()
+		}
+		if onEvent != nil {
+			onEvent(event)
+		}
+	}
+}
+
+// SteerActiveTurn adds prompt to the turn running for channelKey. It reports
+// false when no turn is running or the turn has not started yet.
+func (c *Coordinator) SteerActiveTurn(ctx context.Context, channelKey string, prompt string) (bool, error) {
+	key := strings.TrimSpace(channelKey)
+	c.mu.Lock()
+	run := c.active[key]
+	var threadID, turnID string
+	if run != nil {
+		threadID, turnID = run.threadID, run.turnID
+	}
+	c.mu.Unlock()
+	if threadID == "" || turnID == "" {
+		return false, nil
+	}
+	if err := c.runtime.AppendTurnInput(ctx, threadID, turnID, prompt); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// DeferNote keeps note for the next message turn of channelKey, which sees
+// it ahead of its own prompt. Only the newest notes are kept.
+func (c *Coordinator) DeferNote(channelKey string, note string) {
+	key := strings.TrimSpace(channelKey)
+	note = strings.TrimSpace(note)
+	if key == "" || note == "" {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	notes := append(c.notes[key], note)
+	if len(notes) > maxPendingNotes {
+		notes = notes[len(notes)-maxPendingNotes:]
+	}
+	c.notes[key] = notes
+}
+
+func (c *Coordinator) takeNotes(channelKey string) []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	notes := c.notes[channelKey]
+	delete(c.notes, channelKey)
+	return notes
+}
+
+// restoreNotes puts back notes taken for a turn that failed, ahead of any
+// deferred while it ran.
+func (c *Coordinator) restoreNotes(channelKey string, notes []string) {
+	if len(notes) == 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	restored := append(append([]string(nil), notes...), c.notes[channelKey]...)
+	if len(restored) > maxPendingNotes {
+		restored = restored[len(restored)-maxPendingNotes:]
+	}
+	c.notes[channelKey] = restored
+}
+
+func withNotes(notes []string, prompt string) string {
+	if len(notes) == 0 {
+		return prompt
+	}
+	return pendingNotesHeader + "\n\n" + strings.Join(notes, "\n\n") + "\n\n" + prompt
+}
@@ -0,0 +1,144 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/sigumaa/yururi/internal/codex"
+)
+
+type steeringRuntime struct {
+	started chan struct{}
+	release chan struct{}
+
+	mu       sync.Mutex
+	appended []string
+}
+
+func (r *steeringRuntime) StartThread(context.Context, codex.TurnInput) (string, error) {
+	return "thread-1", nil
+}
+
+func (r *steeringRuntime) ResumeThread(_ context.Context, threadID string, _ codex.TurnInput) (string, error) {
+	return threadID, nil
+}
+
+func (r *steeringRuntime) StartTurn(_ context.Context, threadID string, input codex.TurnInput) (codex.TurnResult, error) {
+	input.OnEvent(codex.TurnEvent{Kind: codex.TurnEventStarted, ThreadID: threadID, TurnID: "turn-1"})
+	close(r.started)
+	<-r.release
+	return codex.TurnResult{ThreadID: threadID, TurnID: "turn-1", Status: "completed"}, nil
+}
+
+func (r *steeringRuntime) SteerTurn(context.Context, string, string, codex.TurnInput) (codex.TurnResult, error) {
+	return codex.TurnResult{}, nil
+}
+
+func (r *steeringRuntime) AppendTurnInput(_ context.Context, threadID string, turnID string, prompt string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.appended = append(r.appended, threadID+"/"+turnID+"/"+prompt)
+	return nil
+}
+
+func TestCoordinatorSteerActiveTurn(t *testing.T) {
+	t.Parallel()
+
+	runtime := &steeringRuntime{started: make(chan struct{}), release: make(chan struct{})}
+	coordinator := New(runtime)
+
+	if steered, err := coordinator.SteerActiveTurn(context.Background(), "g1:c1", "edited"); steered || err != nil {
+		t.Fatalf("SteerActiveTurn() without turn = %t, %v, want false, nil", steered, err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := coordinator.RunMessageTurn(context.Background(), "g1:c1", codex.TurnInput{UserPrompt: "hi"})
+		done <- err
+	}()
+	<-runtime.started
+
+	steered, err := coordinator.SteerActiveTurn(context.Background(), "g1:c1", "edited")
+	if err != nil || !steered {
+		t.Fatalf("SteerActiveTurn() = %t, %v, want true, nil", steered, err)
+	}
+	if steered, _ := coordinator.SteerActiveTurn(context.Background(), "g1:c2", "edited"); steered {
+		t.Fatal("SteerActiveTurn() for another channel = true, want false")
+	}
+	close(runtime.release)
+	if err := <-done; err != nil {
+		t.Fatalf("RunMessageTurn() error = %v", err)
+	}
+	if len(runtime.appended) != 1 || runtime.appended[0] != "thread-1/turn-1/edited" {
+		t.Fatalf("appended = %#v", runtime.appended)
+	}
+	if steered, _ := coordinator.SteerActiveTurn(context.Background(), "g1:c1", "late"); steered {
+		t.Fatal("SteerActiveTurn() after the turn = true, want false")
+	}
+}
+
+func TestCoordinatorDeferNotePrefixesNextTurn(t *testing.T) {
+	t.Parallel()
+
+	stub := &runtimeStub{
+		startThreadResults: []threadResult{{threadID: "thread-1"}},
+		startTurnResults: []turnResult{
+			{result: codex.TurnResult{TurnID: "turn-1"}},
+			{result: codex.TurnResult{TurnID: "turn-2"}},
+		},
+		steerTurnResults: []turnResult{{err: context.DeadlineExceeded}},
+	}
+	coordinator := New(stub)
+	coordinator.DeferNote("g1:c1", "メッセージ m1 が編集されました。")
+	coordinator.DeferNote("g1:c2", "別チャンネル")
+
+	if _, err := coordinator.RunMessageTurn(context.Background(), "g1:c1", codex.TurnInput{UserPrompt: "hi"}); err != nil {
+		t.Fatalf("RunMessageTurn() error = %v", err)
+	}
+	if _, err := coordinator.RunMessageTurn(context.Background(), "g1:c1", codex.TurnInput{UserPrompt: "again"}); err != nil {
+		t.Fatalf("second RunMessageTurn() error = %v", err)
+	}
+
+	first := stub.startTurnCalls[0].Prompt
+	if !strings.HasPrefix(first, pendingNotesHeader) || !strings.Contains(first, "m1 が編集されました") || !strings.HasSuffix(first, "hi") {
+		t.Fatalf("first prompt = %q", first)
+	}
+	if strings.Contains(first, "別チャンネル") {
+		t.Fatalf("first prompt includes another channel's note: %q", first)
+	}
+	if second := stub.startTurnCalls[1].Prompt; second != "again" {
+		t.Fatalf("second prompt = %q, want notes consumed", second)
+	}
+}
+
+func TestCoordinatorKeepsNotesWhenTurnFails(t *testing.T) {
+	t.Parallel()
+
+	stub := &runtimeStub{
+		startThreadResults: []threadResult{{threadID: "thread-1"}, {threadID: "thread-2"}},
+		startTurnResults: []turnResult{
+			{err: errors.New("turn failed")},
+			{result: codex.TurnResult{TurnID: "turn-1"}},
+		},
+	}
+	coordinator := New(stub)
+	coordinator.DeferNote("g1:c1", "メッセージ m1 が編集されました。")
+
+	if _, err := coordinator.RunMessageTurn(context.Background(), "g1:c1", codex.TurnInput{UserPrompt: "hi"}); err == nil {
+		t.Fatal("RunMessageTurn() error = nil, want turn failure")
+	}
+	coordinator.DeferNote("g1:c1", "メッセージ m2 が削除されました。")
+	if _, err := coordinator.RunMessageTurn(context.Background(), "g1:c1", codex.TurnInput{UserPrompt: "again"}); err != nil {
+		t.Fatalf("second RunMessageTurn() error = %v", err)
+	}
+
+	retried := stub.startTurnCalls[1].Prompt
+	edited := strings.Index(retried, "m1 が編集されました")
+	deleted := strings.Index(retried, "m2 が削除されました")
+	if edited < 0 || deleted < edited || !strings.HasSuffix(retried, "again") {
+		t.Fatalf("retried prompt = %q, want the restored note before the new one", retried)
+	}
+}
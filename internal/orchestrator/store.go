@@ -0,0 +1,183 @@
+package orchestrator
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const sessionStoreVersion = 1
+
+type SessionStore interface {
+	Load() (map[string]SessionState, error)
+	Save(channelKey string, state SessionState) error
+	Delete(channelKey string) error
+}
+
+type FileSessionStore struct {
+	path string
+
+	mu       sync.Mutex
+	sessions map[string]SessionState
+}
+
+type sessionStoreFile struct {
+	Version  int                           `json:"version"`
+	Sessions map[string]sessionStoreRecord `json:"sessions"`
+}
+
+type sessionStoreRecord struct {
+	ThreadID   string    `json:"thread_id"`
+	LastTurnID string    `json:"last_turn_id,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+	TurnCount  int       `json:"turn_count,omitempty"`
+}
+
+func NewFileSessionStore(path string) *FileSessionStore {
+	return &FileSessionStore{
+		path:     strings.TrimSpace(path),
+		sessions: map[string]SessionState{},
+	}
+}
+
+func (s *FileSessionStore) Path() string {
+	return s.path
+}
+
+func (s *FileSessionStore) Load() (map[string]SessionState, error) {
+	if s.path == "" {
+		return nil, errors.New("session store path is required")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	body, err := os.ReadFile(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			s.sessions = map[string]SessionState{}
+			return map[string]SessionState{}, nil
+		}
+		return nil, fmt.Errorf("read session store: %w", err)
+	}
+
+	var file sessionStoreFile
+	if err := json.Unmarshal(body, &file); err != nil {
+		s.sessions = map[string]SessionState{}
+		corruptPath := s.path + ".corrupt"
+		if renameErr := os.Rename(s.path, corruptPath); renameErr != nil {
+			return nil, fmt.Errorf("decode session store: %w (move aside: %v)", err, renameErr)
+		}
+		return nil, fmt.Errorf("decode session store (moved to %s): %w", corruptPath, err)
+	}
+	loaded := make(map[string]SessionState, len(file.Sessions))
+	for key, record := range file.Sessions {
+		key = strings.TrimSpace(key)
+		threadID := strings.TrimSpace(record.ThreadID)
+		if key == "" || threadID == "" {
+			continue
+		}
+		loaded[key] = SessionState{
+			ThreadID:   threadID,
+			LastTurnID: strings.TrimSpace(record.LastTurnID),
+			CreatedAt:  record.CreatedAt.UTC(),
+			UpdatedAt:  record.UpdatedAt.UTC(),
+			TurnCount:  record.TurnCount,
+		}
+	}
+	s.sessions = loaded
+	return copySessions(loaded), nil
+}
+
+func (s *FileSessionStore) Save(channelKey string, state SessionState) error {
+	key := strings.TrimSpace(channelKey)
+	if key == "" {
+		return errors.New("channel key is required")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sessions[key] = state
+	return s.flushLocked()
+}
+
+func (s *FileSessionStore) Delete(channelKey string) error {
+	key := strings.TrimSpace(channelKey)
+	if key == "" {
+		return errors.New("channel key is required")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.sessions[key]; !ok {
+		return nil
+	}
+	delete(s.sessions, key)
+	return s.flushLocked()
+}
+
+func (s *FileSessionStore) flushLocked() error {
+	if s.path == "" {
+		return errors.New("session store path is required")
+	}
+	file := sessionStoreFile{
+		Version:  sessionStoreVersion,
+		Sessions: make(map[string]sessionStoreRecord, len(s.sessions)),
+	}
+	for key, state := range s.sessions {
+		file.Sessions[key] = sessionStoreRecord{
+			ThreadID:   state.ThreadID,
+			LastTurnID: state.LastTurnID,
+			CreatedAt:  state.CreatedAt.UTC(),
+			UpdatedAt:  state.UpdatedAt.UTC(),
+			TurnCount:  state.TurnCount,
+		}
+	}
+	body, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode session store: %w", err)
+	}
+	return writeFileAtomic(s.path, body)
+}
+
+func writeFileAtomic(path string, body []byte) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return fmt.Errorf("create store dir: %w", err)
+	}
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("create temp store file: %w", err)
+	}
+	tmpPath := tmp.Name()
+	if _, err := tmp.Write(body); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("write temp store file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("close temp store file: %w", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("replace store file: %w", err)
+	}
+	return nil
+}
+
+func copySessions(src map[string]SessionState) map[string]SessionState {
+	dst := make(map[string]SessionState, len(src))
+	for key, state := range src {
+		dst[key] = state
+	}
+	return dst
+}
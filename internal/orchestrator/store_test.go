@@ -0,0 +1,77 @@
+package orchestrator
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFileSessionStoreRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "state", "sessions.json")
+	store := NewFileSessionStore(path)
+
+	loaded, err := store.Load()
+	if err != nil {
+		t.Fatalf("Load() on missing file error = %v", err)
+	}
+	if len(loaded) != 0 {
+		t.Fatalf("Load() on missing file = %v, want empty", loaded)
+	}
+
+	updatedAt := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	if err := store.Save("g1:c1", SessionState{ThreadID: "thread-1", LastTurnID: "turn-1", UpdatedAt: updatedAt}); err != nil {
+		t.Fatalf("Save(g1:c1) error = %v", err)
+	}
+	if err := store.Save("g1:c2", SessionState{ThreadID: "thread-2", UpdatedAt: updatedAt}); err != nil {
+		t.Fatalf("Save(g1:c2) error = %v", err)
+	}
+	if err := store.Delete("g1:c2"); err != nil {
+		t.Fatalf("Delete(g1:c2) error = %v", err)
+	}
+
+	reopened := NewFileSessionStore(path)
+	got, err := reopened.Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if len(got) != 1 {
+		t.Fatalf("Load() len = %d, want 1 (%v)", len(got), got)
+	}
+	session := got["g1:c1"]
+	if session.ThreadID != "thread-1" || session.LastTurnID != "turn-1" {
+		t.Fatalf("Load()[g1:c1] = %#v, want thread-1/turn-1", session)
+	}
+	if !session.UpdatedAt.Equal(updatedAt) {
+		t.Fatalf("Load()[g1:c1].UpdatedAt = %s, want %s", session.UpdatedAt, updatedAt)
+	}
+}
+
+func TestFileSessionStoreLoadMovesBrokenFileAside(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "sessions.json")
+	if err := os.WriteFile(path, []byte("{broken"), 0o600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	store := NewFileSessionStore(path)
+	if _, err := store.Load(); err == nil {
+		t.Fatal("Load() error = nil, want decode error")
+	}
+	body, err := os.ReadFile(path + ".corrupt")
+	if err != nil {
+		t.Fatalf("ReadFile(.corrupt) error = %v", err)
+	}
+	if string(body) != "{broken" {
+		t.Fatalf(".corrupt content = %q, want original file", body)
+	}
+
+	if err := store.Save("channel:1", SessionState{ThreadID: "thread-1"}); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	if body, err := os.ReadFile(path + ".corrupt"); err != nil || string(body) != "{broken" {
+		t.Fatalf(".corrupt after Save() = %q, %v, want original file", body, err)
+	}
+}
@@ -0,0 +1,268 @@
+package orchestrator
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sigumaa/yururi/internal/codex"
+)
+
+const (
+	usageLedgerVersion       = 1
+	usageLedgerRetentionDays = 7
+	usageDayLayout           = "2006-01-02"
+
+	// HeartbeatUsageScope is the ledger scope for heartbeat turns; message
+	// turns use their channel key.
+	HeartbeatUsageScope = "heartbeat"
+
+	UsageBudgetActionRefuse    = "refuse"
+	UsageBudgetActionDowngrade = "downgrade"
+)
+
+var ErrUsageBudgetExhausted = errors.New("usage budget exhausted")
+
+// UsageBudget limits total tokens per day. Zero limits are disabled.
+type UsageBudget struct {
+	DailyTokens              int64
+	ChannelDailyTokens       int64
+	HeartbeatDailyTokens     int64
+	Action                   string
+	DowngradeReasoningEffort string
+}
+
+// UsageLedger aggregates token usage per scope and per day. Days follow the
+// ledger's location. When a path is set the ledger is saved after every
+// record so budgets survive restarts.
+type UsageLedger struct {
+	path string
+	loc  *time.Location
+
+	mu   sync.Mutex
+	days map[string]map[string]codex.TokenUsage
+}
+
+type usageLedgerFile struct {
+	Version int                                     `json:"version"`
+	Days    map[string]map[string]usageLedgerRecord `json:"days"`
+}
+
+type usageLedgerRecord struct {
+	InputTokens           int64 `json:"input_tokens"`
+	CachedInputTokens     int64 `json:"cached_input_tokens,omitempty"`
+	OutputTokens          int64 `json:"output_tokens"`
+	ReasoningOutputTokens int64 `json:"reasoning_output_tokens,omitempty"`
+	TotalTokens           int64 `json:"total_tokens"`
+}
+
+func NewUsageLedger(path string, loc *time.Location) *UsageLedger {
+	if loc == nil {
+		loc = time.UTC
+	}
+	return &UsageLedger{
+		path: strings.TrimSpace(path),
+		loc:  loc,
+		days: map[string]map[string]codex.TokenUsage{},
+	}
+}
+
+func (l *UsageLedger) Load() error {
+	if l.path == "" {
+		return nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	body, err := os.ReadFile(l.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("read usage ledger: %w", err)
+	}
+	var file usageLedgerFile
+	if err := json.Unmarshal(body, &file); err != nil {
+		corruptPath := l.path + ".corrupt"
+		if renameErr := os.Rename(l.path, corruptPath); renameErr != nil {
+			return fmt.Errorf("decode usage ledger: %w (move aside: %v)", err, renameErr)
+		}
+		return fmt.Errorf("decode usage ledger (moved to %s): %w", corruptPath, err)
+	}
+	days := make(map[string]map[string]codex.TokenUsage, len(file.Days))
+	for day, scopes := range file.Days {
+		if _, err := time.Parse(usageDayLayout, day); err != nil {
+			continue
+		}
+		days[day] = make(map[string]codex.TokenUsage, len(scopes))
+		for scope, record := range scopes {
+			days[day][scope] = codex.TokenUsage{
+				InputTokens:           record.InputTokens,
+				CachedInputTokens:     record.CachedInputTokens,
+				OutputTokens:          record.OutputTokens,
+				ReasoningOutputTokens: record.ReasoningOutputTokens,
+				TotalTokens:           record.TotalTokens,
+			}
+		}
+	}
+	l.days = days
+	return nil
+}
+
+func (l *UsageLedger) Record(now time.Time, scope string, usage codex.TokenUsage) error {
+	scope = strings.TrimSpace(scope)
+	if scope == "" || usage.IsZero() {
+		return nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	day := l.day(now)
+	if l.days[day] == nil {
+		l.days[day] = map[string]codex.TokenUsage{}
+	}
+	l.days[day][scope] = l.days[day][scope].Add(usage)
+	l.pruneLocked(now)
+	if l.path == "" {
+		return nil
+	}
+	return l.flushLocked()
+}
+
+// Day returns the usage of scope on the day containing now.
+func (l *UsageLedger) Day(now time.Time, scope string) codex.TokenUsage {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.days[l.day(now)][strings.TrimSpace(scope)]
+}
+
+// DayTotal returns the usage of every scope on the day containing now.
+func (l *UsageLedger) DayTotal(now time.Time) codex.TokenUsage {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	total := codex.TokenUsage{}
+	for _, usage := range l.days[l.day(now)] {
+		total = total.Add(usage)
+	}
+	return total
+}
+
+func (l *UsageLedger) day(now time.Time) string {
+	return now.In(l.loc).Format(usageDayLayout)
+}
+
+func (l *UsageLedger) pruneLocked(now time.Time) {
+	cutoff := now.In(l.loc).AddDate(0, 0, -usageLedgerRetentionDays).Format(usageDayLayout)
+	for day := range l.days {
+		if day < cutoff {
+			delete(l.days, day)
+		}
+	}
+}
+
+func (l *UsageLedger) flushLocked() error {
+	file := usageLedgerFile{
+		Version: usageLedgerVersion,
+		Days:    make(map[string]map[string]usageLedgerRecord, len(l.days)),
+	}
+	for day, scopes := range l.days {
+		file.Days[day] = make(map[string]usageLedgerRecord, len(scopes))
+		for scope, usage := range scopes {
+			file.Days[day][scope] = usageLedgerRecord{
+				InputTokens:           usage.InputTokens,
+				CachedInputTokens:     usage.CachedInputTokens,
+				OutputTokens:          usage.OutputTokens,
+				ReasoningOutputTokens: usage.ReasoningOutputTokens,
+				TotalTokens:           usage.TotalTokens,
+			}
+		}
+	}
+	body, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode usage ledger: %w", err)
+	}
+	return writeFileAtomic(l.path, body)
+}
+
+// exhausted returns the name of the first budget that scope has used up.
+func (b UsageBudget) exhausted(ledger *UsageLedger, now time.Time, scope string) string {
+	if ledger == nil {
+		return ""
+	}
+	if b.DailyTokens > 0 && ledger.DayTotal(now).TotalTokens >= b.DailyTokens {
+		return "daily"
+	}
+	if scope == HeartbeatUsageScope {
+		if b.HeartbeatDailyTokens > 0 && ledger.Day(now, scope).TotalTokens >= b.HeartbeatDailyTokens {
+			return "heartbeat_daily"
+		}
+		return ""
+	}
+	if b.ChannelDailyTokens > 0 && ledger.Day(now, scope).TotalTokens >= b.ChannelDailyTokens {
+		return "channel_daily"
+	}
+	return ""
+}
+
+func WithUsageBudget(ledger *UsageLedger, budget UsageBudget) Option {
+	return func(c *Coordinator) {
+		c.usage = ledger
+		c.budget = budget
+	}
+}
+
+// AdmitTurn checks scope against the usage budgets. Once a budget is used up
+// the turn is refused with ErrUsageBudgetExhausted, or its reasoning effort
+// is lowered when the budget action is downgrade.
+func (c *Coordinator) AdmitTurn(scope string, input codex.TurnInput) (codex.TurnInput, error) {
+	scope = strings.TrimSpace(scope)
+	reason := c.budget.exhausted(c.usage, c.now(), scope)
+	if reason == "" {
+		return input, nil
+	}
+	effort := strings.TrimSpace(c.budget.DowngradeReasoningEffort)
+	if c.budget.Action == UsageBudgetActionDowngrade && effort != "" {
+		slog.Warn("usage_budget_downgraded", "scope", scope, "budget", reason, "reasoning_effort", effort)
+		input.ReasoningEffort = effort
+		return input, nil
+	}
+	slog.Warn("usage_budget_refused", "scope", scope, "budget", reason)
+	return input, fmt.Errorf("%w: %s budget for %s", ErrUsageBudgetExhausted, reason, scope)
+}
+
+// RecordUsage adds the turn's token usage to the ledger.
+func (c *Coordinator) RecordUsage(scope string, result codex.TurnResult) {
+	scope = strings.TrimSpace(scope)
+	if c.usage == nil || result.Usage.IsZero() {
+		return
+	}
+	now := c.now()
+	if err := c.usage.Record(now, scope, result.Usage); err != nil {
+		slog.Error("usage_ledger_save_failed", "scope", scope, "err", err)
+	}
+	slog.Info(
+		"usage_recorded",
+		"scope", scope,
+		"thread", result.ThreadID,
+		"turn", result.TurnID,
+		"total_tokens", result.Usage.TotalTokens,
+		"scope_day_tokens", c.usage.Day(now, scope).TotalTokens,
+		"day_tokens", c.usage.DayTotal(now).TotalTokens,
+	)
+}
+
+// UsageToday returns today's usage of scope and of every scope.
+func (c *Coordinator) UsageToday(scope string) (codex.TokenUsage, codex.TokenUsage) {
+	if c.usage == nil {
+		return codex.TokenUsage{}, codex.TokenUsage{}
+	}
+	now := c.now()
+	return c.usage.Day(now, scope), c.usage.DayTotal(now)
+}
@@ -0,0 +1,204 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sigumaa/yururi/internal/codex"
+)
+
+func TestUsageLedgerAggregatesPerScopeAndDay(t *testing.T) {
+	t.Parallel()
+
+	tokyo := time.FixedZone("JST", 9*60*60)
+	path := filepath.Join(t.TempDir(), "usage.json")
+	ledger := NewUsageLedger(path, tokyo)
+
+	// 14:30 UTC is already the next day in Tokyo.
+	day1 := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	day2 := time.Date(2026, 3, 1, 15, 30, 0, 0, time.UTC)
+	records := []struct {
+		now   time.Time
+		scope string
+		total int64
+	}{
+		{now: day1, scope: "g1:c1", total: 100},
+		{now: day1, scope: "g1:c1", total: 50},
+		{now: day1, scope: HeartbeatUsageScope, total: 30},
+		{now: day2, scope: "g1:c1", total: 7},
+	}
+	for _, r := range records {
+		if err := ledger.Record(r.now, r.scope, codex.TokenUsage{InputTokens: r.total, TotalTokens: r.total}); err != nil {
+			t.Fatalf("Record() error = %v", err)
+		}
+	}
+
+	if got := ledger.Day(day1, "g1:c1").TotalTokens; got != 150 {
+		t.Fatalf("Day(day1, channel) = %d, want 150", got)
+	}
+	if got := ledger.DayTotal(day1).TotalTokens; got != 180 {
+		t.Fatalf("DayTotal(day1) = %d, want 180", got)
+	}
+	if got := ledger.DayTotal(day2).TotalTokens; got != 7 {
+		t.Fatalf("DayTotal(day2) = %d, want 7", got)
+	}
+
+	reloaded := NewUsageLedger(path, tokyo)
+	if err := reloaded.Load(); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if got := reloaded.Day(day1, "g1:c1"); got != ledger.Day(day1, "g1:c1") {
+		t.Fatalf("reloaded Day() = %+v, want %+v", got, ledger.Day(day1, "g1:c1"))
+	}
+
+	later := day1.AddDate(0, 0, usageLedgerRetentionDays+1)
+	if err := ledger.Record(later, "g1:c1", codex.TokenUsage{TotalTokens: 1}); err != nil {
+		t.Fatalf("Record() error = %v", err)
+	}
+	if got := ledger.DayTotal(day1).TotalTokens; got != 0 {
+		t.Fatalf("DayTotal(day1) after retention = %d, want 0", got)
+	}
+}
+
+func TestUsageLedgerLoadMovesBrokenFileAside(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "usage.json")
+	if err := os.WriteFile(path, []byte("{broken"), 0o600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	ledger := NewUsageLedger(path, time.UTC)
+	if err := ledger.Load(); err == nil {
+		t.Fatal("Load() error = nil, want decode error")
+	}
+	if err := ledger.Record(time.Now(), "g1:c1", codex.TokenUsage{TotalTokens: 1}); err != nil {
+		t.Fatalf("Record() error = %v", err)
+	}
+	if body, err := os.ReadFile(path + ".corrupt"); err != nil || string(body) != "{broken" {
+		t.Fatalf(".corrupt after Record() = %q, %v, want original file", body, err)
+	}
+}
+
+func TestCoordinatorAdmitTurnAppliesBudgets(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name       string
+		budget     UsageBudget
+		scope      string
+		wantErr    bool
+		wantEffort string
+	}{
+		{name: "within budget", budget: UsageBudget{ChannelDailyTokens: 1000}, scope: "g1:c1"},
+		{name: "channel exhausted", budget: UsageBudget{ChannelDailyTokens: 100}, scope: "g1:c1", wantErr: true},
+		{name: "other channel unaffected", budget: UsageBudget{ChannelDailyTokens: 100}, scope: "g1:c2"},
+		{name: "daily exhausted", budget: UsageBudget{DailyTokens: 150}, scope: "g1:c2", wantErr: true},
+		{name: "heartbeat exhausted", budget: UsageBudget{HeartbeatDailyTokens: 50}, scope: HeartbeatUsageScope, wantErr: true},
+		{
+			name:       "downgrade",
+			budget:     UsageBudget{ChannelDailyTokens: 100, Action: UsageBudgetActionDowngrade, DowngradeReasoningEffort: "low"},
+			scope:      "g1:c1",
+			wantEffort: "low",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ledger := NewUsageLedger("", time.UTC)
+			_ = ledger.Record(now, "g1:c1", codex.TokenUsage{TotalTokens: 120})
+			_ = ledger.Record(now, HeartbeatUsageScope, codex.TokenUsage{TotalTokens: 60})
+			coordinator := New(&runtimeStub{},
+				WithClock(func() time.Time { return now }),
+				WithUsageBudget(ledger, tc.budget),
+			)
+
+			input, err := coordinator.AdmitTurn(tc.scope, codex.TurnInput{UserPrompt: "hi"})
+			if tc.wantErr {
+				if !errors.Is(err, ErrUsageBudgetExhausted) {
+					t.Fatalf("AdmitTurn() error = %v, want ErrUsageBudgetExhausted", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("AdmitTurn() error = %v", err)
+			}
+			if input.ReasoningEffort != tc.wantEffort {
+				t.Fatalf("ReasoningEffort = %q, want %q", input.ReasoningEffort, tc.wantEffort)
+			}
+		})
+	}
+}
+
+func TestCoordinatorRecordsUsageAndRefusesOverBudget(t *testing.T) {
+	t.Parallel()
+
+	stub := &runtimeStub{
+		startThreadResults: []threadResult{{threadID: "thread-1"}},
+		startTurnResults: []turnResult{
+			{result: codex.TurnResult{TurnID: "turn-1", Usage: codex.TokenUsage{InputTokens: 80, OutputTokens: 40, TotalTokens: 120}}},
+		},
+	}
+	now := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	ledger := NewUsageLedger("", time.UTC)
+	coordinator := New(stub,
+		WithClock(func() time.Time { return now }),
+		WithUsageBudget(ledger, UsageBudget{ChannelDailyTokens: 100}),
+	)
+
+	if _, err := coordinator.RunMessageTurn(context.Background(), "g1:c1", codex.TurnInput{UserPrompt: "first"}); err != nil {
+		t.Fatalf("first RunMessageTurn() error = %v", err)
+	}
+	if got := ledger.Day(now, "g1:c1").TotalTokens; got != 120 {
+		t.Fatalf("recorded usage = %d, want 120", got)
+	}
+	if _, err := coordinator.RunMessageTurn(context.Background(), "g1:c1", codex.TurnInput{UserPrompt: "second"}); !errors.Is(err, ErrUsageBudgetExhausted) {
+		t.Fatalf("second RunMessageTurn() error = %v, want ErrUsageBudgetExhausted", err)
+	}
+	if got := len(stub.steerTurnCalls) + len(stub.startTurnCalls); got != 1 {
+		t.Fatalf("runtime turn calls = %d, want 1", got)
+	}
+}
+
+func TestCoordinatorRecordsUsageOfFailedTurns(t *testing.T) {
+	t.Parallel()
+
+	stub := &runtimeStub{
+		startThreadResults: []threadResult{{threadID: "thread-1"}},
+		startTurnResults: []turnResult{
+			{result: codex.TurnResult{TurnID: "turn-1", Usage: codex.TokenUsage{TotalTokens: 100}}},
+			{result: codex.TurnResult{TurnID: "turn-2", Usage: codex.TokenUsage{TotalTokens: 20}}},
+		},
+		steerTurnResults: []turnResult{
+			{result: codex.TurnResult{Usage: codex.TokenUsage{TotalTokens: 30}}, err: errors.New("expected turn mismatch")},
+			{result: codex.TurnResult{TurnID: "turn-3", Status: "interrupted", Usage: codex.TokenUsage{TotalTokens: 7}}, err: context.Canceled},
+		},
+	}
+	now := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	ledger := NewUsageLedger("", time.UTC)
+	coordinator := New(stub, WithClock(func() time.Time { return now }), WithUsageBudget(ledger, UsageBudget{}))
+
+	if _, err := coordinator.RunMessageTurn(context.Background(), "g1:c1", codex.TurnInput{UserPrompt: "first"}); err != nil {
+		t.Fatalf("first RunMessageTurn() error = %v", err)
+	}
+	got, err := coordinator.RunMessageTurn(context.Background(), "g1:c1", codex.TurnInput{UserPrompt: "second"})
+	if err != nil {
+		t.Fatalf("second RunMessageTurn() error = %v", err)
+	}
+	if got.Usage.TotalTokens != 50 {
+		t.Fatalf("second usage = %d, want steer and start attempts", got.Usage.TotalTokens)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := coordinator.RunMessageTurn(ctx, "g1:c1", codex.TurnInput{UserPrompt: "third"}); !errors.Is(err, context.Canceled) {
+		t.Fatalf("third RunMessageTurn() error = %v, want context.Canceled", err)
+	}
+	if got := ledger.Day(now, "g1:c1").TotalTokens; got != 157 {
+		t.Fatalf("recorded usage = %d, want 157", got)
+	}
+}
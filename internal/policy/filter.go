@@ -3,24 +3,34 @@ package policy
 import "github.com/sigumaa/yururi/internal/config"
 
 type Incoming struct {
-	GuildID     string
-	ChannelID   string
-	AuthorID    string
-	AuthorIsBot bool
-	WebhookID   string
+	GuildID   string
+	ChannelID string
+	// ParentChannelID is set for threads and forum posts, which inherit the
+	// permissions of their parent channel.
+	ParentChannelID string
+	AuthorID        string
+	AuthorIsBot     bool
+	WebhookID       string
 }
 
 func Evaluate(discordCfg config.DiscordConfig, msg Incoming) (bool, string) {
+	if msg.GuildID == "" && msg.ChannelID != "" && discordCfg.DM.Enabled {
+		return evaluateDM(discordCfg.DM, msg)
+	}
 	if msg.GuildID == "" || msg.ChannelID == "" {
 		return false, "missing_guild_or_channel"
 	}
 	if msg.GuildID != discordCfg.GuildID {
 		return false, "guild_not_allowed"
 	}
+	thread := false
 	if !contains(discordCfg.ReadChannelIDs, msg.ChannelID) {
-		return false, "channel_not_readable"
+		if msg.ParentChannelID == "" || !contains(discordCfg.ReadChannelIDs, msg.ParentChannelID) {
+			return false, "channel_not_readable"
+		}
+		thread = true
 	}
-	if contains(discordCfg.ExcludedChannelIDs, msg.ChannelID) {
+	if contains(discordCfg.ExcludedChannelIDs, msg.ChannelID) || (msg.ParentChannelID != "" && contains(discordCfg.ExcludedChannelIDs, msg.ParentChannelID)) {
 		return false, "channel_excluded"
 	}
 	if msg.AuthorID == "" {
@@ -31,9 +41,36 @@ func Evaluate(discordCfg config.DiscordConfig, msg Incoming) (bool, string) {
 			return false, "bot_or_webhook_not_allowed"
 		}
 	}
+	if thread {
+		return true, "allowed_thread"
+	}
 	return true, "allowed"
 }
 
+// evaluateDM admits direct messages from allowlisted humans only.
+func evaluateDM(dmCfg config.DMConfig, msg Incoming) (bool, string) {
+	if msg.AuthorID == "" {
+		return false, "missing_author"
+	}
+	if msg.AuthorIsBot || msg.WebhookID != "" {
+		return false, "dm_from_bot"
+	}
+	if !contains(dmCfg.AllowedUserIDs, msg.AuthorID) {
+		return false, "dm_user_not_allowed"
+	}
+	return true, "allowed_dm"
+}
+
+// NeedsParentChannel reports whether Evaluate depends on the parent channel
+// of msg, which is only the case for guild channels that are not listed in
+// read_channel_ids or excluded_channel_ids themselves.
+func NeedsParentChannel(discordCfg config.DiscordConfig, msg Incoming) bool {
+	if msg.GuildID == "" || msg.GuildID != discordCfg.GuildID || msg.ChannelID == "" {
+		return false
+	}
+	return !contains(discordCfg.ReadChannelIDs, msg.ChannelID) && !contains(discordCfg.ExcludedChannelIDs, msg.ChannelID)
+}
+
 func ShouldProcess(discordCfg config.DiscordConfig, msg Incoming) bool {
 	allowed, _ := Evaluate(discordCfg, msg)
 	return allowed
@@ -230,3 +230,90 @@ func TestEvaluate(t *testing.T) {
 		})
 	}
 }
+
+func TestEvaluateDirectMessages(t *testing.T) {
+	t.Parallel()
+
+	cfg := config.DiscordConfig{
+		GuildID:        "guild-1",
+		ReadChannelIDs: []string{"chan-a"},
+		DM: config.DMConfig{
+			Enabled:        true,
+			AllowedUserIDs: []string{"owner"},
+		},
+	}
+
+	tests := []struct {
+		name       string
+		cfg        config.DiscordConfig
+		msg        Incoming
+		want       bool
+		wantReason string
+	}{
+		{name: "owner dm", cfg: cfg, msg: Incoming{ChannelID: "dm-1", AuthorID: "owner"}, want: true, wantReason: "allowed_dm"},
+		{name: "other user dm", cfg: cfg, msg: Incoming{ChannelID: "dm-2", AuthorID: "user-1"}, wantReason: "dm_user_not_allowed"},
+		{name: "bot dm", cfg: cfg, msg: Incoming{ChannelID: "dm-3", AuthorID: "owner", AuthorIsBot: true}, wantReason: "dm_from_bot"},
+		{name: "dm disabled", cfg: config.DiscordConfig{GuildID: "guild-1"}, msg: Incoming{ChannelID: "dm-1", AuthorID: "owner"}, wantReason: "missing_guild_or_channel"},
+	}
+	for _, tc := range tests {
+		got, gotReason := Evaluate(tc.cfg, tc.msg)
+		if got != tc.want || gotReason != tc.wantReason {
+			t.Fatalf("%s: Evaluate() = (%v, %q), want (%v, %q)", tc.name, got, gotReason, tc.want, tc.wantReason)
+		}
+	}
+}
+
+func TestEvaluateThreadsInheritParentChannel(t *testing.T) {
+	t.Parallel()
+
+	cfg := config.DiscordConfig{
+		GuildID:            "guild-1",
+		ReadChannelIDs:     []string{"chan-a", "forum-1", "chan-b"},
+		ExcludedChannelIDs: []string{"chan-b", "thread-x"},
+	}
+
+	tests := []struct {
+		name       string
+		msg        Incoming
+		want       bool
+		wantReason string
+	}{
+		{name: "thread under readable channel", msg: Incoming{GuildID: "guild-1", ChannelID: "thread-1", ParentChannelID: "chan-a", AuthorID: "user-1"}, want: true, wantReason: "allowed_thread"},
+		{name: "forum post", msg: Incoming{GuildID: "guild-1", ChannelID: "post-1", ParentChannelID: "forum-1", AuthorID: "user-1"}, want: true, wantReason: "allowed_thread"},
+		{name: "thread under other channel", msg: Incoming{GuildID: "guild-1", ChannelID: "thread-2", ParentChannelID: "chan-x", AuthorID: "user-1"}, wantReason: "channel_not_readable"},
+		{name: "thread under excluded channel", msg: Incoming{GuildID: "guild-1", ChannelID: "thread-3", ParentChannelID: "chan-b", AuthorID: "user-1"}, wantReason: "channel_excluded"},
+		{name: "excluded thread", msg: Incoming{GuildID: "guild-1", ChannelID: "thread-x", ParentChannelID: "chan-a", AuthorID: "user-1"}, wantReason: "channel_excluded"},
+	}
+	for _, tc := range tests {
+		got, gotReason := Evaluate(cfg, tc.msg)
+		if got != tc.want || gotReason != tc.wantReason {
+			t.Fatalf("%s: Evaluate() = (%v, %q), want (%v, %q)", tc.name, got, gotReason, tc.want, tc.wantReason)
+		}
+	}
+}
+
+func TestNeedsParentChannel(t *testing.T) {
+	t.Parallel()
+
+	cfg := config.DiscordConfig{
+		GuildID:            "guild-1",
+		ReadChannelIDs:     []string{"chan-a"},
+		ExcludedChannelIDs: []string{"chan-b"},
+	}
+	tests := []struct {
+		name string
+		msg  Incoming
+		want bool
+	}{
+		{name: "unlisted channel", msg: Incoming{GuildID: "guild-1", ChannelID: "thread-1"}, want: true},
+		{name: "readable channel", msg: Incoming{GuildID: "guild-1", ChannelID: "chan-a"}},
+		{name: "excluded channel", msg: Incoming{GuildID: "guild-1", ChannelID: "chan-b"}},
+		{name: "other guild", msg: Incoming{GuildID: "guild-2", ChannelID: "thread-1"}},
+		{name: "direct message", msg: Incoming{ChannelID: "dm-1"}},
+	}
+	for _, tc := range tests {
+		if got := NeedsParentChannel(cfg, tc.msg); got != tc.want {
+			t.Fatalf("%s: NeedsParentChannel() = %t, want %t", tc.name, got, tc.want)
+		}
+	}
+}
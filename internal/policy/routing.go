@@ -0,0 +1,96 @@
+package policy
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/sigumaa/yururi/internal/config"
+)
+
+// MessageTraits are the parts of a message that routing rules look at.
+type MessageTraits struct {
+	Content     string
+	Attachments int
+	MentionsBot bool
+	FromOwner   bool
+}
+
+// Route is the tier chosen for a message. Rule is the index of the matched
+// rule, or -1 when the default tier was used.
+type Route struct {
+	Tier            string
+	Rule            int
+	Model           string
+	ReasoningEffort string
+}
+
+// RouteMessage classifies msg with the configured rules. Disabled routing
+// returns an empty Route, which keeps the client defaults.
+func RouteMessage(routingCfg config.RoutingConfig, msg MessageTraits) Route {
+	if !routingCfg.Enabled {
+		return Route{Rule: -1}
+	}
+	features := messageFeatures(msg)
+	for i, rule := range routingCfg.Rules {
+		if ruleMatches(rule, features) {
+			return newRoute(routingCfg, rule.Tier, i)
+		}
+	}
+	return newRoute(routingCfg, routingCfg.DefaultTier, -1)
+}
+
+type routingFeatures struct {
+	length      int
+	questions   int
+	links       int
+	attachments int
+	mentionsBot bool
+	fromOwner   bool
+}
+
+func messageFeatures(msg MessageTraits) routingFeatures {
+	content := strings.TrimSpace(msg.Content)
+	return routingFeatures{
+		length:      utf8.RuneCountInString(content),
+		questions:   strings.Count(content, "?") + strings.Count(content, "？"),
+		links:       strings.Count(content, "http://") + strings.Count(content, "https://"),
+		attachments: msg.Attachments,
+		mentionsBot: msg.MentionsBot,
+		fromOwner:   msg.FromOwner,
+	}
+}
+
+func ruleMatches(r config.RoutingRuleConfig, f routingFeatures) bool {
+	if r.MinLength > 0 && f.length < r.MinLength {
+		return false
+	}
+	if r.MaxLength > 0 && f.length > r.MaxLength {
+		return false
+	}
+	if r.MinQuestions > 0 && f.questions < r.MinQuestions {
+		return false
+	}
+	if r.MinLinks > 0 && f.links < r.MinLinks {
+		return false
+	}
+	if r.MinAttachments > 0 && f.attachments < r.MinAttachments {
+		return false
+	}
+	if r.MentionsBot != nil && *r.MentionsBot != f.mentionsBot {
+		return false
+	}
+	if r.FromOwner != nil && *r.FromOwner != f.fromOwner {
+		return false
+	}
+	return true
+}
+
+func newRoute(routingCfg config.RoutingConfig, tier string, rule int) Route {
+	settings := routingCfg.Tiers[tier]
+	return Route{
+		Tier:            tier,
+		Rule:            rule,
+		Model:           settings.Model,
+		ReasoningEffort: settings.ReasoningEffort,
+	}
+}
@@ -0,0 +1,74 @@
+package policy
+
+import (
+	"testing"
+
+	"github.com/sigumaa/yururi/internal/config"
+)
+
+func TestRouteMessage(t *testing.T) {
+	t.Parallel()
+
+	yes := true
+	cfg := config.RoutingConfig{
+		Enabled:     true,
+		DefaultTier: "standard",
+		Tiers: map[string]config.RoutingTierConfig{
+			"light":    {Model: "gpt-5.3-codex-mini", ReasoningEffort: "low"},
+			"standard": {},
+			"deep":     {ReasoningEffort: "high"},
+		},
+		Rules: []config.RoutingRuleConfig{
+			{Tier: "deep", FromOwner: &yes, MinQuestions: 2},
+			{Tier: "deep", MinLength: 200},
+			{Tier: "deep", MinLinks: 1, MentionsBot: &yes},
+			{Tier: "standard", MinAttachments: 1},
+			{Tier: "light", MaxLength: 20},
+		},
+	}
+
+	long := make([]rune, 200)
+	for i := range long {
+		long[i] = 'あ'
+	}
+
+	tests := []struct {
+		name     string
+		msg      MessageTraits
+		wantTier string
+		wantRule int
+	}{
+		{name: "greeting", msg: MessageTraits{Content: "おはよう"}, wantTier: "light", wantRule: 4},
+		{name: "owner questions", msg: MessageTraits{Content: "これは何？あれは?", FromOwner: true}, wantTier: "deep", wantRule: 0},
+		{name: "questions from others", msg: MessageTraits{Content: "これは何？あれは?"}, wantTier: "light", wantRule: 4},
+		{name: "long", msg: MessageTraits{Content: string(long)}, wantTier: "deep", wantRule: 1},
+		{name: "link with mention", msg: MessageTraits{Content: "見て https://example.com", MentionsBot: true}, wantTier: "deep", wantRule: 2},
+		{name: "link without mention", msg: MessageTraits{Content: "見てほしいリンクがあります https://example.com"}, wantTier: "standard", wantRule: -1},
+		{name: "attachment", msg: MessageTraits{Content: "画像", Attachments: 1}, wantTier: "standard", wantRule: 3},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := RouteMessage(cfg, tc.msg)
+			if got.Tier != tc.wantTier || got.Rule != tc.wantRule {
+				t.Fatalf("RouteMessage() = %+v, want tier=%s rule=%d", got, tc.wantTier, tc.wantRule)
+			}
+			if want := cfg.Tiers[tc.wantTier]; got.Model != want.Model || got.ReasoningEffort != want.ReasoningEffort {
+				t.Fatalf("RouteMessage() settings = %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestRouteMessageDisabled(t *testing.T) {
+	t.Parallel()
+
+	got := RouteMessage(config.RoutingConfig{
+		Tiers: map[string]config.RoutingTierConfig{"deep": {ReasoningEffort: "high"}},
+		Rules: []config.RoutingRuleConfig{{Tier: "deep"}},
+	}, MessageTraits{Content: "hi"})
+	if got.Tier != "" || got.Model != "" || got.ReasoningEffort != "" {
+		t.Fatalf("RouteMessage() = %+v, want empty route", got)
+	}
+}
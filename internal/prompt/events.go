@@ -0,0 +1,120 @@
+package prompt
+
+import (
+	"fmt"
+	"strings"
+)
+
+// MessageEventInput describes an edit ("edit") or delete ("delete") of a
+// message. Before is empty when the previous content is unknown.
+type MessageEventInput struct {
+	Kind        string
+	GuildID     string
+	ChannelID   string
+	ChannelName string
+	MessageID   string
+	AuthorID    string
+	AuthorName  string
+	Before      string
+	After       string
+}
+
+// BuildMessageEventNote renders an edit or delete as text handed to the model
+// alongside a turn.
+func BuildMessageEventNote(input MessageEventInput) string {
+	author := fmt.Sprintf("%s (%s)", valueOrFallback(input.AuthorName, "unknown"), valueOrFallback(input.AuthorID, "unknown"))
+	before := quoteEventContent(input.Before, "(不明)")
+	switch strings.TrimSpace(input.Kind) {
+	case "delete":
+		return strings.Join([]string{
+			fmt.Sprintf("チャンネル %s のメッセージ %s（%s）が削除されました。", valueOrFallback(input.ChannelID, "unknown"), valueOrFallback(input.MessageID, "unknown"), author),
+			"削除前: " + before,
+		}, "\n")
+	default:
+		return strings.Join([]string{
+			fmt.Sprintf("チャンネル %s のメッセージ %s（%s）が編集されました。", valueOrFallback(input.ChannelID, "unknown"), valueOrFallback(input.MessageID, "unknown"), author),
+			"編集前: " + before,
+			"編集後: " + quoteEventContent(input.After, "(empty)"),
+		}, "\n")
+	}
+}
+
+// BuildMessageEventBundle is used when an edit or delete starts a turn of
+// its own.
+func BuildMessageEventBundle(instructions WorkspaceInstructions, input MessageEventInput) Bundle {
+	prompt := strings.Join([]string{
+		"以下は現在の入力情報です。",
+		fmt.Sprintf("Guild ID: %s", input.GuildID),
+		fmt.Sprintf("チャンネル: %s (ID: %s)", input.ChannelName, input.ChannelID),
+		"",
+		"## メッセージの変更",
+		"",
+		BuildMessageEventNote(input),
+		"",
+		"既存の返信や判断を見直す必要がある場合だけ対応してください。",
+	}, "\n")
+	return Bundle{
+		BaseInstructions:      buildBaseInstructions(instructions),
+		DeveloperInstructions: buildDeveloperInstructions(),
+		UserPrompt:            prompt,
+	}
+}
+
+// ComponentEventInput describes a click on a button or a choice in a select
+// menu sent by send_embed. Values is empty for buttons.
+type ComponentEventInput struct {
+	GuildID     string
+	ChannelID   string
+	ChannelName string
+	MessageID   string
+	UserID      string
+	UserName    string
+	CustomID    string
+	Values      []string
+}
+
+// BuildComponentEventNote renders a component interaction as text handed to
+// the model alongside a turn.
+func BuildComponentEventNote(input ComponentEventInput) string {
+	user := fmt.Sprintf("%s (%s)", valueOrFallback(input.UserName, "unknown"), valueOrFallback(input.UserID, "unknown"))
+	lines := []string{
+		fmt.Sprintf("チャンネル %s のメッセージ %s のコンポーネント %q を %s が操作しました。", valueOrFallback(input.ChannelID, "unknown"), valueOrFallback(input.MessageID, "unknown"), input.CustomID, user),
+	}
+	if len(input.Values) > 0 {
+		quoted := make([]string, 0, len(input.Values))
+		for _, value := range input.Values {
+			quoted = append(quoted, fmt.Sprintf("%q", value))
+		}
+		lines = append(lines, "選択された値: "+strings.Join(quoted, ", "))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// BuildComponentEventBundle is used when a component interaction starts a
+// turn of its own.
+func BuildComponentEventBundle(instructions WorkspaceInstructions, input ComponentEventInput) Bundle {
+	prompt := strings.Join([]string{
+		"以下は現在の入力情報です。",
+		fmt.Sprintf("Guild ID: %s", input.GuildID),
+		fmt.Sprintf("チャンネル: %s (ID: %s)", input.ChannelName, input.ChannelID),
+		"",
+		"## コンポーネントの操作",
+		"",
+		BuildComponentEventNote(input),
+		"",
+		"操作の意図に沿って対応してください。",
+	}, "\n")
+	return Bundle{
+		BaseInstructions:      buildBaseInstructions(instructions),
+		DeveloperInstructions: buildDeveloperInstructions(),
+		UserPrompt:            prompt,
+	}
+}
+
+func quoteEventContent(content string, fallback string) string {
+	content = strings.TrimSpace(content)
+	if content == "" {
+		return fallback
+	}
+	return fmt.Sprintf("%q", content)
+}
@@ -0,0 +1,98 @@
+package prompt
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBuildMessageEventNote(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		input MessageEventInput
+		want  []string
+	}{
+		{
+			name:  "edit",
+			input: MessageEventInput{Kind: "edit", ChannelID: "c1", MessageID: "m1", AuthorID: "u1", AuthorName: "shiyui", Before: "明日は雨？", After: "明後日は雨？"},
+			want:  []string{"メッセージ m1（shiyui (u1)）が編集されました", `編集前: "明日は雨？"`, `編集後: "明後日は雨？"`},
+		},
+		{
+			name:  "edit without cache",
+			input: MessageEventInput{Kind: "edit", ChannelID: "c1", MessageID: "m1", After: "new"},
+			want:  []string{"編集前: (不明)", `編集後: "new"`},
+		},
+		{
+			name:  "delete",
+			input: MessageEventInput{Kind: "delete", ChannelID: "c1", MessageID: "m2", AuthorID: "u1", Before: "消したい"},
+			want:  []string{"メッセージ m2（unknown (u1)）が削除されました", `削除前: "消したい"`},
+		},
+	}
+	for _, tc := range tests {
+		got := BuildMessageEventNote(tc.input)
+		for _, want := range tc.want {
+			if !strings.Contains(got, want) {
+				t.Fatalf("%s: BuildMessageEventNote() = %q, want to contain %q", tc.name, got, want)
+			}
+		}
+	}
+}
+
+func TestBuildMessageEventBundle(t *testing.T) {
+	t.Parallel()
+
+	bundle := BuildMessageEventBundle(WorkspaceInstructions{Content: map[string]string{"YURURI.md": "# YURURI"}}, MessageEventInput{
+		Kind:        "edit",
+		GuildID:     "g1",
+		ChannelID:   "c1",
+		ChannelName: "chat",
+		MessageID:   "m1",
+		After:       "new",
+	})
+	if !strings.Contains(bundle.UserPrompt, "チャンネル: chat (ID: c1)") || !strings.Contains(bundle.UserPrompt, "が編集されました") {
+		t.Fatalf("UserPrompt = %q", bundle.UserPrompt)
+	}
+	if !strings.Contains(bundle.BaseInstructions, "YURURI.md") {
+		t.Fatalf("BaseInstructions missing YURURI.md: %q", bundle.BaseInstructions)
+	}
+}
+
+func TestBuildComponentEventNote(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		input   ComponentEventInput
+		want    []string
+		notWant string
+	}{
+		{
+			name:    "button",
+			input:   ComponentEventInput{ChannelID: "c1", MessageID: "m1", UserID: "u1", UserName: "shiyui", CustomID: "approve"},
+			want:    []string{`メッセージ m1 のコンポーネント "approve" を shiyui (u1) が操作しました`},
+			notWant: "選択された値",
+		},
+		{
+			name:  "select",
+			input: ComponentEventInput{ChannelID: "c1", MessageID: "m1", UserID: "u1", CustomID: "topic", Values: []string{"rust", "go"}},
+			want:  []string{"unknown (u1)", `選択された値: "rust", "go"`},
+		},
+	}
+	for _, tc := range tests {
+		got := BuildComponentEventNote(tc.input)
+		for _, want := range tc.want {
+			if !strings.Contains(got, want) {
+				t.Fatalf("%s: BuildComponentEventNote() = %q, want to contain %q", tc.name, got, want)
+			}
+		}
+		if tc.notWant != "" && strings.Contains(got, tc.notWant) {
+			t.Fatalf("%s: BuildComponentEventNote() = %q, want not to contain %q", tc.name, got, tc.notWant)
+		}
+	}
+
+	bundle := BuildComponentEventBundle(WorkspaceInstructions{}, ComponentEventInput{GuildID: "g1", ChannelID: "c1", ChannelName: "chat", CustomID: "approve"})
+	if !strings.Contains(bundle.UserPrompt, "チャンネル: chat (ID: c1)") || !strings.Contains(bundle.UserPrompt, "## コンポーネントの操作") {
+		t.Fatalf("UserPrompt = %q", bundle.UserPrompt)
+	}
+}
@@ -28,11 +28,25 @@ type Bundle struct {
 }
 
 type RuntimeMessage struct {
-	ID         string
-	AuthorID   string
-	AuthorName string
-	Content    string
-	CreatedAt  time.Time
+	ID          string
+	AuthorID    string
+	AuthorName  string
+	Content     string
+	CreatedAt   time.Time
+	Attachments []Attachment
+}
+
+// Attachment is an attachment of a message. Kind is "image", "text" or
+// "other". Path is the cached copy in the workspace, Text the inlined
+// content of a text file, and Skipped why it was not ingested.
+type Attachment struct {
+	Name      string
+	URL       string
+	Kind      string
+	Path      string
+	Text      string
+	Truncated bool
+	Skipped   string
 }
 
 type MessageInput struct {
@@ -41,6 +55,8 @@ type MessageInput struct {
 	ChannelName string
 	MergedCount int
 	IsOwner     bool
+	Addressed   string
+	IsDM        bool
 	Current     RuntimeMessage
 	Recent      []RuntimeMessage
 }
@@ -127,12 +143,24 @@ func BuildMessageBundle(instructions WorkspaceInstructions, input MessageInput)
 		ownerText = "true"
 	}
 
-	prompt := strings.Join([]string{
+	lines := []string{
 		"以下は現在の入力情報です。",
 		fmt.Sprintf("Guild ID: %s", input.GuildID),
 		fmt.Sprintf("チャンネル: %s (ID: %s)", input.ChannelName, input.ChannelID),
 		fmt.Sprintf("バースト統合件数: %d", mergedCountForPrompt(input.MergedCount)),
 		fmt.Sprintf("owner_user_idか: %s", ownerText),
+		fmt.Sprintf("直接の呼びかけ: %s", addressedForPrompt(input.Addressed)),
+	}
+	if input.IsDM {
+		lines = append(lines,
+			"この会話は非公開のDMです。内容をサーバーのチャンネルへ持ち出さないでください。",
+			"DMへの返信は reply_message または send_message でこのチャンネルIDへ送ってください。",
+		)
+	}
+	if hasImageInput(input.Current) {
+		lines = append(lines, "今回のメッセージの添付画像は画像入力として渡しています。")
+	}
+	prompt := strings.Join(append(lines,
 		"",
 		"## 直近のメッセージ",
 		"",
@@ -141,7 +169,7 @@ func BuildMessageBundle(instructions WorkspaceInstructions, input MessageInput)
 		"## 今回のメッセージ",
 		"",
 		formatRuntimeMessage(input.Current),
-	}, "\n")
+	), "\n")
 
 	return Bundle{
 		BaseInstructions:      buildBaseInstructions(instructions),
@@ -205,10 +233,44 @@ func buildDeveloperInstructions() string {
 func formatRuntimeMessage(message RuntimeMessage) string {
 	meta := fmt.Sprintf("%s (%s, Message ID: %s)", valueOrFallback(message.AuthorName, "unknown"), valueOrFallback(message.AuthorID, "unknown"), valueOrFallback(message.ID, "unknown"))
 	content := strings.TrimSpace(message.Content)
-	if content == "" {
+	if content == "" && len(message.Attachments) == 0 {
 		content = "(empty)"
 	}
-	return meta + "\n" + content
+	lines := []string{meta}
+	if content != "" {
+		lines = append(lines, content)
+	}
+	for _, attachment := range message.Attachments {
+		lines = append(lines, formatAttachment(attachment))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func formatAttachment(a Attachment) string {
+	name := valueOrFallback(a.Name, "attachment")
+	switch {
+	case a.Skipped != "":
+		return fmt.Sprintf("添付: %s（未取り込み: %s, %s）", name, a.Skipped, a.URL)
+	case a.Text != "":
+		text := "添付: " + name + "（テキスト, " + a.Path + "）\n```\n" + strings.TrimRight(a.Text, "\n") + "\n```"
+		if a.Truncated {
+			text += "\n（長いため以降を省略。全文はファイルを参照）"
+		}
+		return text
+	case a.Path != "":
+		return fmt.Sprintf("添付: %s（%s, %s）", name, valueOrFallback(a.Kind, "file"), a.Path)
+	default:
+		return fmt.Sprintf("添付: %s(%s)", name, a.URL)
+	}
+}
+
+func hasImageInput(message RuntimeMessage) bool {
+	for _, a := range message.Attachments {
+		if a.Kind == "image" && a.Path != "" && a.Skipped == "" {
+			return true
+		}
+	}
+	return false
 }
 
 func valueOrFallback(value string, fallback string) string {
@@ -219,6 +281,23 @@ func valueOrFallback(value string, fallback string) string {
 	return value
 }
 
+// addressedForPrompt describes why the message addressed the bot directly:
+// "mention", "reply" or "owner_dm". Ambient messages have no reason.
+func addressedForPrompt(addressed string) string {
+	switch strings.TrimSpace(addressed) {
+	case "":
+		return "なし"
+	case "mention":
+		return "あなたへのメンションです。原則として返信してください。"
+	case "reply":
+		return "あなたのメッセージへの返信です。原則として返信してください。"
+	case "owner_dm":
+		return "オーナーからのDMです。原則として返信してください。"
+	default:
+		return addressed + "（原則として返信してください）"
+	}
+}
+
 func mergedCountForPrompt(v int) int {
 	if v <= 0 {
 		return 1
@@ -44,6 +44,7 @@ func TestBuildMessageBundle(t *testing.T) {
 		ChannelName: "chat",
 		MergedCount: 4,
 		IsOwner:     true,
+		Addressed:   "mention",
 		Current: RuntimeMessage{
 			ID:         "m2",
 			AuthorID:   "u1",
@@ -78,6 +79,9 @@ func TestBuildMessageBundle(t *testing.T) {
 	if strings.Contains(bundle.UserPrompt, "2026-02-26T12:00:00Z") {
 		t.Fatalf("UserPrompt should not include timestamp: %q", bundle.UserPrompt)
 	}
+	if !strings.Contains(bundle.UserPrompt, "直接の呼びかけ: あなたへのメンションです") {
+		t.Fatalf("UserPrompt missing addressed marker: %q", bundle.UserPrompt)
+	}
 	if !strings.Contains(bundle.UserPrompt, "バースト統合件数: 4") {
 		t.Fatalf("UserPrompt missing merged count: %q", bundle.UserPrompt)
 	}
@@ -96,6 +100,56 @@ func TestBuildMessageBundle(t *testing.T) {
 	if strings.Contains(bundle.UserPrompt, "## MEMORY参照（今回の話者関連）") {
 		t.Fatalf("UserPrompt should not include memory focus section: %q", bundle.UserPrompt)
 	}
+	if strings.Contains(bundle.UserPrompt, "非公開のDM") {
+		t.Fatalf("UserPrompt should not include DM marker: %q", bundle.UserPrompt)
+	}
+}
+
+func TestBuildMessageBundleMarksDirectMessages(t *testing.T) {
+	t.Parallel()
+
+	bundle := BuildMessageBundle(WorkspaceInstructions{}, MessageInput{
+		ChannelID:   "d1",
+		ChannelName: "DM",
+		IsOwner:     true,
+		Addressed:   "owner_dm",
+		IsDM:        true,
+		Current:     RuntimeMessage{ID: "m1", AuthorID: "owner", Content: "内緒の話"},
+	})
+	if !strings.Contains(bundle.UserPrompt, "この会話は非公開のDMです") {
+		t.Fatalf("UserPrompt missing DM marker: %q", bundle.UserPrompt)
+	}
+	if !strings.Contains(bundle.UserPrompt, "内緒の話") {
+		t.Fatalf("UserPrompt missing current message: %q", bundle.UserPrompt)
+	}
+}
+
+func TestBuildMessageBundleRendersAttachments(t *testing.T) {
+	t.Parallel()
+
+	bundle := BuildMessageBundle(WorkspaceInstructions{}, MessageInput{
+		ChannelID: "c1",
+		Current: RuntimeMessage{ID: "m1", AuthorID: "u1", Attachments: []Attachment{
+			{Name: "photo.png", Kind: "image", Path: "/work/.yururi/attachments/1-photo.png"},
+			{Name: "notes.md", Kind: "text", Path: "/work/.yururi/attachments/2-notes.md", Text: "# メモ\n", Truncated: true},
+			{Name: "movie.mp4", Kind: "other", URL: "https://cdn.example/movie.mp4", Skipped: "unsupported"},
+		}},
+		Recent: []RuntimeMessage{{ID: "m0", AuthorID: "u1", Content: "前の画像", Attachments: []Attachment{{Name: "old.png", Kind: "image", Path: "/work/.yururi/attachments/0-old.png"}}}},
+	})
+	for _, want := range []string{
+		"今回のメッセージの添付画像は画像入力として渡しています。",
+		"添付: photo.png（image, /work/.yururi/attachments/1-photo.png）",
+		"添付: notes.md（テキスト, /work/.yururi/attachments/2-notes.md）\n```\n# メモ\n```\n（長いため以降を省略",
+		"添付: movie.mp4（未取り込み: unsupported, https://cdn.example/movie.mp4）",
+		"前の画像\n添付: old.png（image, /work/.yururi/attachments/0-old.png）",
+	} {
+		if !strings.Contains(bundle.UserPrompt, want) {
+			t.Fatalf("UserPrompt missing %q: %q", want, bundle.UserPrompt)
+		}
+	}
+	if strings.Contains(bundle.UserPrompt, "(empty)") {
+		t.Fatalf("UserPrompt marks a message with attachments as empty: %q", bundle.UserPrompt)
+	}
 }
 
 func TestBuildHeartbeatBundle(t *testing.T) {